@@ -42,6 +42,19 @@ func newBackupCommand() *cobra.Command {
 The "backup" command creates a new snapshot and saves the files and directories
 given as the arguments.
 
+The --commands-from option reads a JSON file which lists several commands, for
+example one database dump per database. The standard output of each command is
+stored as a separate file in the snapshot:
+
+  [
+    {"filename": "mysql/shop.sql", "command": ["mysqldump", "shop"], "timeout": "2h"},
+    {"filename": "mysql/blog.sql", "command": ["mysqldump", "blog"]}
+  ]
+
+If a command exits with a non-zero status or exceeds its timeout, its file is
+not stored, the remaining commands are still run and the snapshot is marked as
+incomplete.
+
 EXIT STATUS
 ===========
 
@@ -90,6 +103,8 @@ type BackupOptions struct {
 	Stdin             bool
 	StdinFilename     string
 	StdinCommand      bool
+	CommandsFrom      string
+	ChangedFiles      string
 	Tags              restic.TagLists
 	Host              string
 	FilesFrom         []string
@@ -102,9 +117,12 @@ type BackupOptions struct {
 	UseFsSnapshot     bool
 	DryRun            bool
 	ReadConcurrency   uint
+	WalkConcurrency   uint
 	NoScan            bool
 	SkipIfUnchanged   bool
-	ScopeSymlinks     string
+	ScopeSymlinks     []string
+	DanglingSymlinks  hostinger.DanglingSymlinkPolicy
+	SplitBySubdir     string
 }
 
 func (opts *BackupOptions) AddFlags(f *pflag.FlagSet) {
@@ -122,8 +140,10 @@ func (opts *BackupOptions) AddFlags(f *pflag.FlagSet) {
 	f.BoolVar(&opts.Stdin, "stdin", false, "read backup from stdin")
 	f.StringVar(&opts.StdinFilename, "stdin-filename", "stdin", "`filename` to use when reading from stdin")
 	f.BoolVar(&opts.StdinCommand, "stdin-from-command", false, "interpret arguments as command to execute and store its stdout")
+	f.StringVar(&opts.CommandsFrom, "commands-from", "", "read a list of commands from `file` and store the stdout of each command as a separate file")
 	f.Var(&opts.Tags, "tag", "add `tags` for the new snapshot in the format `tag[,tag,...]` (can be specified multiple times)")
 	f.UintVar(&opts.ReadConcurrency, "read-concurrency", 0, "read `n` files concurrently (default: $RESTIC_READ_CONCURRENCY or 2)")
+	f.UintVar(&opts.WalkConcurrency, "walk-concurrency", 0, "traverse `n` directories concurrently (default: $RESTIC_WALK_CONCURRENCY or 1)")
 	f.StringVarP(&opts.Host, "host", "H", "", "set the `hostname` for the snapshot manually (default: $RESTIC_HOST). To prevent an expensive rescan use the \"parent\" flag")
 	f.StringVar(&opts.Host, "hostname", "", "set the `hostname` for the snapshot manually")
 	err := f.MarkDeprecated("hostname", "use --host")
@@ -134,6 +154,7 @@ func (opts *BackupOptions) AddFlags(f *pflag.FlagSet) {
 	f.StringArrayVar(&opts.FilesFrom, "files-from", nil, "read the files to backup from `file` (can be combined with file args; can be specified multiple times)")
 	f.StringArrayVar(&opts.FilesFromVerbatim, "files-from-verbatim", nil, "read the files to backup from `file` (can be combined with file args; can be specified multiple times)")
 	f.StringArrayVar(&opts.FilesFromRaw, "files-from-raw", nil, "read the files to backup from `file` (can be combined with file args; can be specified multiple times)")
+	f.StringVar(&opts.ChangedFiles, "changed-files", "", "only check the paths listed in `file` for changes since the snapshot specified by --parent, all other files and directories are copied from it")
 	f.StringVar(&opts.TimeStamp, "time", "", "`time` of the backup (ex. '2012-11-01 22:08:41') (default: now)")
 	f.BoolVar(&opts.WithAtime, "with-atime", false, "store the atime for all files and directories")
 	f.BoolVar(&opts.IgnoreInode, "ignore-inode", false, "ignore inode number and ctime changes when checking for modified files")
@@ -145,11 +166,15 @@ func (opts *BackupOptions) AddFlags(f *pflag.FlagSet) {
 		f.BoolVar(&opts.ExcludeCloudFiles, "exclude-cloud-files", false, "excludes online-only cloud files (such as OneDrive Files On-Demand)")
 	}
 	f.BoolVar(&opts.SkipIfUnchanged, "skip-if-unchanged", false, "skip snapshot creation if identical to parent snapshot")
-	f.StringVar(&opts.ScopeSymlinks, "scope-symlinks", "", "exclude symlinks that are targeting files outside this path")
+	f.StringArrayVar(&opts.ScopeSymlinks, "scope-symlinks", nil, "exclude symlinks that are targeting files outside this `path` (can be specified multiple times)")
+	f.StringVar(&opts.SplitBySubdir, "split-by-subdir", "", "create a separate snapshot for each subdirectory of `dir`")
+	f.Var(&opts.DanglingSymlinks, "dangling-symlinks", "how to handle symlinks whose target does not exist when using --scope-symlinks (drop|keep|keep-as-is)")
 
 	// parse read concurrency from env, on error the default value will be used
 	readConcurrency, _ := strconv.ParseUint(os.Getenv("RESTIC_READ_CONCURRENCY"), 10, 32)
 	opts.ReadConcurrency = uint(readConcurrency)
+	walkConcurrency, _ := strconv.ParseUint(os.Getenv("RESTIC_WALK_CONCURRENCY"), 10, 32)
+	opts.WalkConcurrency = uint(walkConcurrency)
 
 	// parse host from env, if not exists or empty the default value will be used
 	if host := os.Getenv("RESTIC_HOST"); host != "" {
@@ -304,9 +329,69 @@ func (opts BackupOptions) Check(gopts GlobalOptions, args []string) error {
 		}
 	}
 
+	if opts.CommandsFrom != "" {
+		if opts.Stdin || opts.StdinCommand {
+			return errors.Fatal("--commands-from and --stdin cannot be used together")
+		}
+		if len(args) > 0 || len(opts.FilesFrom) > 0 || len(opts.FilesFromVerbatim) > 0 || len(opts.FilesFromRaw) > 0 {
+			return errors.Fatal("--commands-from cannot be combined with other files/dirs to backup")
+		}
+		if opts.SplitBySubdir != "" {
+			return errors.Fatal("--commands-from and --split-by-subdir cannot be used together")
+		}
+	}
+
+	if opts.ChangedFiles != "" {
+		if opts.readsFromCommands() {
+			return errors.Fatal("--changed-files cannot be used when reading from stdin or commands")
+		}
+		if opts.Force {
+			return errors.Fatal("--changed-files and --force cannot be used together")
+		}
+		// the list only describes the changes since one specific snapshot
+		if opts.Parent == "" {
+			return errors.Fatal("--changed-files requires --parent to specify the snapshot the changes refer to")
+		}
+	}
+
+	if opts.SplitBySubdir != "" {
+		if opts.Stdin || opts.StdinCommand {
+			return errors.Fatal("--split-by-subdir and --stdin cannot be used together")
+		}
+		if len(args) > 0 || len(opts.FilesFrom) > 0 || len(opts.FilesFromVerbatim) > 0 || len(opts.FilesFromRaw) > 0 {
+			return errors.Fatal("--split-by-subdir cannot be combined with other files/dirs to backup")
+		}
+		if opts.Parent != "" {
+			return errors.Fatal("--split-by-subdir and --parent cannot be used together")
+		}
+	}
+
 	return nil
 }
 
+// readsFromCommands returns true if the data is read from stdin or from
+// commands instead of the local file system.
+func (opts BackupOptions) readsFromCommands() bool {
+	return opts.Stdin || opts.StdinCommand || opts.CommandsFrom != ""
+}
+
+// readChangeList reads the list of changed paths from filename.
+func readChangeList(filename string) (*archiver.ChangeList, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Fatalf("unable to read changed files: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	changes, err := archiver.ReadChangeList(f)
+	if err != nil {
+		return nil, errors.Fatalf("unable to read changed files from %v: %v", filename, err)
+	}
+	return changes, nil
+}
+
 // collectRejectByNameFuncs returns a list of all functions which may reject data
 // from being saved in a snapshot based on path only
 func collectRejectByNameFuncs(opts BackupOptions, repo *repository.Repository) (fs []archiver.RejectByNameFunc, err error) {
@@ -333,9 +418,9 @@ func collectRejectByNameFuncs(opts BackupOptions, repo *repository.Repository) (
 
 // collectRejectFuncs returns a list of all functions which may reject data
 // from being saved in a snapshot based on path and file info
-func collectRejectFuncs(opts BackupOptions, targets []string, fs fs.FS) (funcs []archiver.RejectFunc, err error) {
+func collectRejectFuncs(opts BackupOptions, targets []string, fs fs.FS, excluded func(item, reason string)) (funcs []archiver.RejectFunc, err error) {
 	// allowed devices
-	if opts.ExcludeOtherFS && !opts.Stdin && !opts.StdinCommand {
+	if opts.ExcludeOtherFS && !opts.readsFromCommands() {
 		f, err := archiver.RejectByDevice(targets, fs)
 		if err != nil {
 			return nil, err
@@ -343,7 +428,7 @@ func collectRejectFuncs(opts BackupOptions, targets []string, fs fs.FS) (funcs [
 		funcs = append(funcs, f)
 	}
 
-	if len(opts.ExcludeLargerThan) != 0 && !opts.Stdin && !opts.StdinCommand {
+	if len(opts.ExcludeLargerThan) != 0 && !opts.readsFromCommands() {
 		maxSize, err := ui.ParseBytes(opts.ExcludeLargerThan)
 		if err != nil {
 			return nil, err
@@ -356,7 +441,7 @@ func collectRejectFuncs(opts BackupOptions, targets []string, fs fs.FS) (funcs [
 		funcs = append(funcs, f)
 	}
 
-	if opts.ExcludeCloudFiles && !opts.Stdin && !opts.StdinCommand {
+	if opts.ExcludeCloudFiles && !opts.readsFromCommands() {
 		if runtime.GOOS != "windows" {
 			return nil, errors.Fatalf("exclude-cloud-files is only supported on Windows")
 		}
@@ -380,13 +465,14 @@ func collectRejectFuncs(opts BackupOptions, targets []string, fs fs.FS) (funcs [
 		funcs = append(funcs, f)
 	}
 
-	if opts.ScopeSymlinks != "" {
-		f, err := hostinger.RejectSymlinksOutsideScope(opts.ScopeSymlinks)
+	if len(opts.ScopeSymlinks) > 0 && !opts.readsFromCommands() {
+		scope, err := hostinger.NewSymlinkScope(opts.ScopeSymlinks, opts.DanglingSymlinks)
 		if err != nil {
 			return nil, err
 		}
+		scope.Excluded = excluded
 
-		funcs = append(funcs, f)
+		funcs = append(funcs, scope.RejectFunc())
 	}
 
 	return funcs, nil
@@ -394,10 +480,14 @@ func collectRejectFuncs(opts BackupOptions, targets []string, fs fs.FS) (funcs [
 
 // collectTargets returns a list of target files/dirs from several sources.
 func collectTargets(opts BackupOptions, args []string) (targets []string, err error) {
-	if opts.Stdin || opts.StdinCommand {
+	if opts.readsFromCommands() {
 		return nil, nil
 	}
 
+	if opts.SplitBySubdir != "" {
+		return collectSubdirTargets(opts.SplitBySubdir)
+	}
+
 	for _, file := range opts.FilesFrom {
 		fromfile, err := readLines(file)
 		if err != nil {
@@ -509,6 +599,25 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		return err
 	}
 
+	var sources []*commandSource
+	if opts.CommandsFrom != "" {
+		sources, err = readCommandSources(opts.CommandsFrom)
+		if err != nil {
+			return err
+		}
+		for _, src := range sources {
+			targets = append(targets, src.Filename)
+		}
+	}
+
+	var changes *archiver.ChangeList
+	if opts.ChangedFiles != "" {
+		changes, err = readChangeList(opts.ChangedFiles)
+		if err != nil {
+			return err
+		}
+	}
+
 	timeStamp := time.Now()
 	backupStart := timeStamp
 	if opts.TimeStamp != "" {
@@ -544,8 +653,29 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		return err
 	}
 
+	if err := repo.RequireCapability(repository.CapabilityBackup); err != nil {
+		return errors.Fatal(err.Error())
+	}
+
+	if repo.WriteOnly() {
+		// write-only keys cannot decrypt snapshots and trees, thus there is
+		// no parent snapshot and all files are read
+		if opts.Parent != "" || opts.ChangedFiles != "" {
+			return errors.Fatal("--parent and --changed-files cannot be used with a write-only key")
+		}
+		opts.Force = true
+	}
+
 	var parentSnapshot *restic.Snapshot
-	if !opts.Stdin {
+	var subdirParents []*restic.Snapshot
+	if opts.SplitBySubdir != "" {
+		// the parent snapshot is determined separately for each subdirectory
+		targets, subdirParents, err = findSubdirParents(ctx, repo, opts, targets, timeStamp,
+			archiver.CombineRejectByNames(rejectByNameFuncs), progressPrinter, gopts.JSON)
+		if err != nil {
+			return err
+		}
+	} else if !opts.Stdin {
 		parentSnapshot, err = findParentSnapshot(ctx, repo, opts, targets, timeStamp)
 		if err != nil {
 			return err
@@ -612,12 +742,30 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		targets = []string{filename}
 	}
 
+	if len(sources) > 0 {
+		if !gopts.JSON {
+			progressPrinter.V("read data from %d commands", len(sources))
+		}
+		multiFS := &fs.MultiReader{}
+		for _, src := range sources {
+			src.ctx = ctx
+			src.logOutput = globalOptions.stderr
+			multiFS.Readers = append(multiFS.Readers, &fs.Reader{
+				ModTime:    timeStamp,
+				Name:       src.Filename,
+				Mode:       0644,
+				ReadCloser: src,
+			})
+		}
+		targetFS = multiFS
+	}
+
 	if backupFSTestHook != nil {
 		targetFS = backupFSTestHook(targetFS)
 	}
 
 	// rejectFuncs collect functions that can reject items from the backup based on path and file info
-	rejectFuncs, err := collectRejectFuncs(opts, targets, targetFS)
+	rejectFuncs, err := collectRejectFuncs(opts, targets, targetFS, progressPrinter.ExcludedItem)
 	if err != nil {
 		return err
 	}
@@ -642,7 +790,10 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		wg.Go(func() error { return sc.Scan(cancelCtx, targets) })
 	}
 
-	arch := archiver.New(repo, targetFS, archiver.Options{ReadConcurrency: opts.ReadConcurrency})
+	arch := archiver.New(repo, targetFS, archiver.Options{
+		ReadConcurrency: opts.ReadConcurrency,
+		WalkConcurrency: opts.WalkConcurrency,
+	})
 	arch.SelectByName = selectByNameFilter
 	arch.Select = selectFilter
 	arch.WithAtime = opts.WithAtime
@@ -661,6 +812,10 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 	arch.StartFile = progressReporter.StartFile
 	arch.CompleteBlob = progressReporter.CompleteBlob
 
+	if changes != nil {
+		arch.ChangeSource = changes
+	}
+
 	if opts.IgnoreInode {
 		// --ignore-inode implies --ignore-ctime: on FUSE, the ctime is not
 		// reliable either.
@@ -680,11 +835,30 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		ProgramVersion:  "restic " + version,
 		SkipIfUnchanged: opts.SkipIfUnchanged,
 	}
+	if len(sources) > 0 {
+		snapshotOpts.Incomplete = func() bool {
+			for _, src := range sources {
+				if src.Status().Status == "failed" {
+					return true
+				}
+			}
+			return false
+		}
+	}
 
 	if !gopts.JSON {
 		progressPrinter.V("start backup on %v", targets)
 	}
-	_, id, summary, err := arch.Snapshot(ctx, targets, snapshotOpts)
+	var id restic.ID
+	var summary *archiver.Summary
+	var batchItems []archiver.BatchItem
+	var batchResults []archiver.BatchResult
+	if opts.SplitBySubdir != "" {
+		batchItems = newBatchItems(targets, subdirParents, snapshotOpts)
+		batchResults, err = arch.SnapshotBatch(ctx, batchItems)
+	} else {
+		_, id, summary, err = arch.Snapshot(ctx, targets, snapshotOpts)
+	}
 
 	// cleanly shutdown all running goroutines
 	cancel()
@@ -697,8 +871,19 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		return errors.Fatalf("unable to save snapshot: %v", err)
 	}
 
+	if len(sources) > 0 && reportCommandSources(sources, term, progressPrinter, gopts.JSON) {
+		success = false
+	}
+
 	// Report finished execution
-	progressReporter.Finish(id, summary, opts.DryRun)
+	if opts.SplitBySubdir != "" {
+		failed := reportBatchResults(batchItems, batchResults, progressReporter, progressPrinter, gopts.JSON, opts.DryRun)
+		if failed > 0 {
+			return errors.Fatalf("unable to save %d of %d snapshots", failed, len(batchItems))
+		}
+	} else {
+		progressReporter.Finish(id, summary, opts.DryRun)
+	}
 	if !success {
 		return ErrInvalidSourceData
 	}
@@ -207,6 +207,41 @@ func TestBackupParentSelection(t *testing.T) {
 	rtest.Assert(t, latestSn.Parent != nil && latestSn.Parent.Equal(firstSnapshotID), "third snapshot selected unexpected parent %v instead of %v", latestSn.Parent, firstSnapshotID)
 }
 
+func TestBackupChangedFiles(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{}, env.gopts)
+	parentID := testListSnapshots(t, env.gopts, 1)[0]
+
+	newFile := filepath.Join(env.testdata, "0", "new-file")
+	rtest.OK(t, os.WriteFile(newFile, []byte("new file"), 0o600))
+	changes := filepath.Join(env.base, "changes.txt")
+	rtest.OK(t, os.WriteFile(changes, []byte(newFile+"\n"), 0o600))
+
+	// the changes only refer to a specific snapshot
+	err := testRunBackupAssumeFailure(t, "", []string{env.testdata}, BackupOptions{ChangedFiles: changes}, env.gopts)
+	rtest.Assert(t, err != nil, "expected --changed-files without --parent to fail")
+	testListSnapshots(t, env.gopts, 1)
+
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{ChangedFiles: changes, Parent: parentID.String()}, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 2)
+	var found bool
+	for _, id := range snapshotIDs {
+		if id.Equal(parentID) {
+			continue
+		}
+		for _, item := range testRunLs(t, env.gopts, id.String()) {
+			if item == filepath.ToSlash(newFile) {
+				found = true
+			}
+		}
+	}
+	rtest.Assert(t, found, "new file is missing in snapshot")
+	testRunCheck(t, env.gopts)
+}
+
 func TestDryRunBackup(t *testing.T) {
 	env, cleanup := withTestEnvironment(t)
 	defer cleanup()
@@ -695,6 +730,60 @@ func TestStdinFromCommandFailNoOutputAndExitCode(t *testing.T) {
 	testRunCheck(t, env.gopts)
 }
 
+func TestBackupCommandsFrom(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+
+	commands := `[
+  {"filename": "db/one.sql", "command": ["python", "-c", "print('one')"]},
+  {"filename": "db/two.sql", "command": ["python", "-c", "import sys; sys.stderr.write('dump failed'); sys.exit(2)"], "timeout": "1m"},
+  {"filename": "three.sql", "command": ["python", "-c", "print('three')"]}
+]`
+	commandsFile := filepath.Join(env.base, "commands.json")
+	rtest.OK(t, os.WriteFile(commandsFile, []byte(commands), 0o600))
+
+	opts := BackupOptions{CommandsFrom: commandsFile}
+	err := testRunBackupAssumeFailure(t, filepath.Dir(env.testdata), nil, opts, env.gopts)
+	rtest.Assert(t, err != nil, "Expected error while backing up")
+
+	ids := testListSnapshots(t, env.gopts, 1)
+	sn := testLoadSnapshot(t, env.gopts, ids[0])
+	rtest.Assert(t, sn.Incomplete, "snapshot should be marked as incomplete")
+
+	files := testRunLs(t, env.gopts, ids[0].String())
+	for _, name := range []string{"/db/one.sql", "/three.sql"} {
+		rtest.Assert(t, includes(files, name), "expected file %q in snapshot, but it's not included", name)
+	}
+	rtest.Assert(t, !includes(files, "/db/two.sql"), "expected file %q not in snapshot, but it's included", "/db/two.sql")
+
+	testRunCheck(t, env.gopts)
+}
+
+func TestBackupCommandsFromInvalid(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+
+	for _, commands := range []string{
+		`[]`,
+		`[{"filename": "a", "command": []}]`,
+		`[{"filename": "a", "command": ["true"], "timeout": "soon"}]`,
+		`[{"filename": "a", "command": ["true"]}, {"filename": "/a", "command": ["true"]}]`,
+		`[{"filename": "a", "command": ["true"]}, {"filename": "a/b", "command": ["true"]}]`,
+	} {
+		commandsFile := filepath.Join(env.base, "commands.json")
+		rtest.OK(t, os.WriteFile(commandsFile, []byte(commands), 0o600))
+
+		opts := BackupOptions{CommandsFrom: commandsFile}
+		err := testRunBackupAssumeFailure(t, filepath.Dir(env.testdata), nil, opts, env.gopts)
+		rtest.Assert(t, err != nil, "expected error for %v", commands)
+	}
+	testListSnapshots(t, env.gopts, 0)
+}
+
 func TestBackupEmptyPassword(t *testing.T) {
 	// basic sanity test that empty passwords work
 	env, cleanup := withTestEnvironment(t)
@@ -723,3 +812,47 @@ func TestBackupSkipIfUnchanged(t *testing.T) {
 
 	testRunCheck(t, env.gopts)
 }
+
+func TestBackupSplitBySubdir(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	home := filepath.Join(env.base, "home")
+	for _, user := range []string{"user1", "user2"} {
+		rtest.OK(t, os.MkdirAll(filepath.Join(home, user), 0o755))
+		rtest.OK(t, os.WriteFile(filepath.Join(home, user, "file"), []byte(user), 0o644))
+	}
+	// files in the split directory are not part of any snapshot
+	rtest.OK(t, os.WriteFile(filepath.Join(home, "file"), []byte("ignored"), 0o644))
+
+	testRunInit(t, env.gopts)
+	// use a relative path, as the metadata of parent directories like /tmp may
+	// change between backups
+	opts := BackupOptions{SplitBySubdir: "home", SkipIfUnchanged: true}
+
+	testRunBackup(t, env.base, nil, opts, env.gopts)
+	firstIDs := testListSnapshots(t, env.gopts, 2)
+
+	parents := make(map[string]restic.ID)
+	for _, id := range firstIDs {
+		sn := testLoadSnapshot(t, env.gopts, id)
+		rtest.Equals(t, 1, len(sn.Paths))
+		rtest.Assert(t, sn.Parent == nil, "unexpected parent for first snapshot of %v", sn.Paths)
+		parents[sn.Paths[0]] = id
+	}
+	rtest.Assert(t, parents[filepath.Join(home, "user1")] != restic.ID{} && parents[filepath.Join(home, "user2")] != restic.ID{},
+		"missing snapshot for subdirectory, got %v", parents)
+
+	// only the modified subdirectory gets a new snapshot, which uses the
+	// previous snapshot of the same subdirectory as parent
+	rtest.OK(t, os.WriteFile(filepath.Join(home, "user2", "file"), []byte("modified"), 0o644))
+	testRunBackup(t, env.base, nil, opts, env.gopts)
+	testListSnapshots(t, env.gopts, 3)
+
+	latestSn, _ := testRunSnapshots(t, env.gopts)
+	rtest.Equals(t, []string{filepath.Join(home, "user2")}, latestSn.Paths)
+	rtest.Assert(t, latestSn.Parent != nil && latestSn.Parent.Equal(parents[filepath.Join(home, "user2")]),
+		"unexpected parent %v", latestSn.Parent)
+
+	testRunCheck(t, env.gopts)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/restic/restic/internal/archiver"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui/backup"
+)
+
+// collectSubdirTargets returns all directories directly contained in dir.
+// Symlinks to directories are not included.
+func collectSubdirTargets(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Fatalf("unable to list subdirectories: %v", err)
+	}
+
+	var targets []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			targets = append(targets, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.Fatalf("no subdirectories found in %v", dir)
+	}
+	return targets, nil
+}
+
+// snapshotFileCache memorizes the list of snapshots and the loaded snapshot
+// files. This allows searching the parent snapshot for many targets without
+// loading all snapshots from the repository again for each target.
+type snapshotFileCache struct {
+	restic.ListerLoaderUnpacked
+	lister restic.Lister
+
+	m     sync.Mutex
+	files map[restic.ID][]byte
+}
+
+func newSnapshotFileCache(ctx context.Context, repo restic.ListerLoaderUnpacked) (*snapshotFileCache, error) {
+	lister, err := restic.MemorizeList(ctx, repo, restic.SnapshotFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshotFileCache{
+		ListerLoaderUnpacked: repo,
+		lister:               lister,
+		files:                make(map[restic.ID][]byte),
+	}, nil
+}
+
+func (c *snapshotFileCache) List(ctx context.Context, t restic.FileType, fn func(restic.ID, int64) error) error {
+	if t != restic.SnapshotFile {
+		return c.ListerLoaderUnpacked.List(ctx, t, fn)
+	}
+	return c.lister.List(ctx, t, fn)
+}
+
+func (c *snapshotFileCache) LoadUnpacked(ctx context.Context, t restic.FileType, id restic.ID) ([]byte, error) {
+	if t != restic.SnapshotFile {
+		return c.ListerLoaderUnpacked.LoadUnpacked(ctx, t, id)
+	}
+
+	c.m.Lock()
+	buf, ok := c.files[id]
+	c.m.Unlock()
+	if ok {
+		return buf, nil
+	}
+
+	buf, err := c.ListerLoaderUnpacked.LoadUnpacked(ctx, t, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.m.Lock()
+	c.files[id] = buf
+	c.m.Unlock()
+	return buf, nil
+}
+
+// findSubdirParents drops all excluded targets and returns the remaining ones
+// along with the latest snapshot of each target, which is used as its parent.
+func findSubdirParents(ctx context.Context, repo restic.ListerLoaderUnpacked, opts BackupOptions, targets []string, timeStampLimit time.Time, selectByName archiver.SelectByNameFunc, printer backup.ProgressPrinter, json bool) ([]string, []*restic.Snapshot, error) {
+	cache, err := newSnapshotFileCache(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var selected []string
+	var parents []*restic.Snapshot
+	for _, target := range targets {
+		abstarget, err := filepath.Abs(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !selectByName(abstarget) {
+			if !json {
+				printer.V("skipping excluded directory %v", target)
+			}
+			continue
+		}
+
+		parent, err := findParentSnapshot(ctx, cache, opts, []string{target}, timeStampLimit)
+		if err != nil {
+			return nil, nil, err
+		}
+		if parent != nil && !json {
+			printer.V("using parent snapshot %v for %v", parent.ID().Str(), target)
+		}
+
+		selected = append(selected, target)
+		parents = append(parents, parent)
+	}
+
+	if len(selected) == 0 {
+		return nil, nil, errors.Fatal("all subdirectories are excluded, nothing to backup")
+	}
+	return selected, parents, nil
+}
+
+// newBatchItems returns one item per target, which uses the corresponding
+// parent snapshot.
+func newBatchItems(targets []string, parents []*restic.Snapshot, snapshotOpts archiver.SnapshotOptions) []archiver.BatchItem {
+	items := make([]archiver.BatchItem, 0, len(targets))
+	for i, target := range targets {
+		itemOpts := snapshotOpts
+		itemOpts.ParentSnapshot = parents[i]
+		items = append(items, archiver.BatchItem{
+			Targets: []string{target},
+			Options: itemOpts,
+		})
+	}
+	return items
+}
+
+// reportBatchResults prints the summary of each snapshot and returns the
+// number of snapshots which could not be created.
+func reportBatchResults(items []archiver.BatchItem, results []archiver.BatchResult, progress *backup.Progress, printer backup.ProgressPrinter, json bool, dryRun bool) (failed int) {
+	for i, res := range results {
+		target := items[i].Targets[0]
+		if res.Err != nil {
+			failed++
+			_ = progress.Error(target, res.Err)
+			continue
+		}
+
+		if !json {
+			printer.P("\nbackup of %v:", target)
+		}
+		progress.Finish(res.ID, res.Summary, dryRun)
+	}
+	return failed
+}
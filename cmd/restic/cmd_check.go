@@ -363,6 +363,21 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 		return summary, ctx.Err()
 	}
 
+	if repo.Config().AppendOnly {
+		printer.P("check deletion journal\n")
+	}
+	errChan = make(chan error)
+	go chkr.Journal(ctx, errChan)
+
+	for err := range errChan {
+		errorsFound = true
+		summary.NumErrors++
+		printer.E("%v\n", err)
+	}
+	if ctx.Err() != nil {
+		return summary, ctx.Err()
+	}
+
 	if opts.CheckUnused {
 		unused, err := chkr.UnusedBlobs(ctx)
 		if err != nil {
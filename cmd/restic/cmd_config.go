@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/ui/table"
+)
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the settings from profiles",
+		Long: `
+The "config" command allows inspecting the settings which are read from the
+profiles file. A profile is selected via --profile or $RESTIC_PROFILE.
+	`,
+		DisableAutoGenTag: true,
+		GroupID:           cmdGroupDefault,
+	}
+
+	cmd.AddCommand(
+		newConfigShowCommand(),
+	)
+	return cmd
+}
+
+func newConfigShowCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [flags] [command] [...]",
+		Short: "Print the resolved settings",
+		Long: `
+The "config show" command prints the settings which are in effect for the
+global options and the given commands, along with the source of each setting.
+Settings are taken from the command line, the environment and the profile
+selected via --profile, in this order of precedence.
+
+If no command is given, the settings of all commands listed in the profile
+are printed.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigShow(cmd, globalOptions, args)
+		},
+	}
+	return cmd
+}
+
+// configSetting is a single resolved setting.
+type configSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// configSection contains the resolved settings of the global options or a
+// command.
+type configSection struct {
+	Name     string          `json:"name"`
+	Settings []configSetting `json:"settings"`
+}
+
+type configShowOutput struct {
+	Profile      string          `json:"profile,omitempty"`
+	ProfilesFile string          `json:"profiles_file,omitempty"`
+	Sections     []configSection `json:"sections"`
+}
+
+// resolvedSettings returns the settings of all flags which are set via the
+// command line, the environment or a profile.
+func resolvedSettings(flags *pflag.FlagSet) []configSetting {
+	var result []configSetting
+	flags.VisitAll(func(f *pflag.Flag) {
+		source := settingSource(flags, f.Name)
+		if source == "" {
+			return
+		}
+		result = append(result, configSetting{Name: f.Name, Value: f.Value.String(), Source: source})
+	})
+	return result
+}
+
+func runConfigShow(cmd *cobra.Command, gopts GlobalOptions, args []string) error {
+	var p *configProfile
+	if gopts.Profile != "" {
+		var err error
+		p, err = loadProfile(gopts.ProfilesFile, gopts.Profile)
+		if err != nil {
+			return err
+		}
+	}
+
+	sections := args
+	if len(sections) == 0 && p != nil {
+		for name := range p.Sections {
+			if name != profileGlobalSection {
+				sections = append(sections, name)
+			}
+		}
+		sort.Strings(sections)
+	}
+
+	out := configShowOutput{}
+	if p != nil {
+		out.Profile = p.Name
+		out.ProfilesFile = p.Filename
+	}
+
+	// the profile was already applied to the global options of this command
+	out.Sections = append(out.Sections, configSection{
+		Name:     profileGlobalSection,
+		Settings: resolvedSettings(cmd.Root().PersistentFlags()),
+	})
+
+	for _, name := range sections {
+		c, _, err := cmd.Root().Find(strings.Fields(name))
+		if err != nil || sectionName(c) != name {
+			return errors.Fatalf("unknown command %q", name)
+		}
+
+		if p != nil {
+			if err := applySection(c.LocalFlags(), name, p.Sections[name]); err != nil {
+				return err
+			}
+		}
+		out.Sections = append(out.Sections, configSection{
+			Name:     name,
+			Settings: resolvedSettings(c.LocalFlags()),
+		})
+	}
+
+	if gopts.JSON {
+		return json.NewEncoder(globalOptions.stdout).Encode(out)
+	}
+
+	if p != nil {
+		Printf("profile %v from %v\n", p.Name, p.Filename)
+	}
+	for _, section := range out.Sections {
+		Printf("\n[%v]\n", section.Name)
+		if len(section.Settings) == 0 {
+			Printf("no settings\n")
+			continue
+		}
+
+		tab := table.New()
+		tab.AddColumn("Option", "{{ .Name }}")
+		tab.AddColumn("Value", "{{ .Value }}")
+		tab.AddColumn("Source", "{{ .Source }}")
+		for _, setting := range section.Settings {
+			tab.AddRow(setting)
+		}
+		if err := tab.Write(globalOptions.stdout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
@@ -44,6 +45,7 @@ func newDebugCommand() *cobra.Command {
 	}
 	cmd.AddCommand(newDebugDumpCommand())
 	cmd.AddCommand(newDebugExamineCommand())
+	cmd.AddCommand(newDebugChunkStatsCommand())
 	return cmd
 }
 
@@ -523,7 +525,7 @@ func examinePack(ctx context.Context, opts DebugExamineOptions, repo restic.Repo
 			continue
 		}
 
-		checkPackSize(blobs, len(buf))
+		checkPackSize(blobs, b.PackKey, len(buf))
 
 		err = loadBlobs(ctx, opts, repo, id, blobs)
 		if err != nil {
@@ -536,11 +538,11 @@ func examinePack(ctx context.Context, opts DebugExamineOptions, repo restic.Repo
 	Printf("  ========================================\n")
 	Printf("  inspect the pack itself\n")
 
-	blobs, _, err := repo.ListPack(ctx, id, int64(len(buf)))
+	blobs, packKey, _, err := pack.ListWithKey(repo.Key(), bytes.NewReader(buf), int64(len(buf)))
 	if err != nil {
 		return fmt.Errorf("pack %v: %v", id.Str(), err)
 	}
-	checkPackSize(blobs, len(buf))
+	checkPackSize(blobs, packKey, len(buf))
 
 	if !blobsLoaded {
 		return loadBlobs(ctx, opts, repo, id, blobs)
@@ -548,7 +550,7 @@ func examinePack(ctx context.Context, opts DebugExamineOptions, repo restic.Repo
 	return nil
 }
 
-func checkPackSize(blobs []restic.Blob, fileSize int) {
+func checkPackSize(blobs []restic.Blob, packKey crypto.PublicKey, fileSize int) {
 	// track current size and offset
 	var size, offset uint64
 
@@ -564,7 +566,7 @@ func checkPackSize(blobs []restic.Blob, fileSize int) {
 		offset = uint64(pb.Offset + pb.Length)
 		size += uint64(pb.Length)
 	}
-	size += uint64(pack.CalculateHeaderSize(blobs))
+	size += uint64(pack.CalculateHeaderSize(blobs, packKey))
 
 	if uint64(fileSize) != size {
 		Printf("      file sizes do not match: computed %v, file size is %v\n", size, fileSize)
@@ -0,0 +1,234 @@
+//go:build debug
+// +build debug
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui"
+	"github.com/restic/restic/internal/ui/table"
+)
+
+func newDebugChunkStatsCommand() *cobra.Command {
+	var opts DebugChunkStatsOptions
+
+	cmd := &cobra.Command{
+		Use:   "chunk-stats [flags] file/dir...",
+		Short: "Compare the deduplication of sample data for different chunk sizes",
+		Long: `
+The "chunk-stats" command splits the given files into chunks, once using the
+chunker parameters of the repository and once for each --sizes option. For each
+set of parameters, it reports the number and total size of all chunks and of the
+unique chunks. Directories are read recursively. No data is written to the
+repository.
+
+The --sizes option expects the minimum, average and maximum chunk size
+separated by slashes, for example "64K/256K/1M".
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+Exit status is 10 if the repository does not exist.
+Exit status is 12 if the password is incorrect.
+`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDebugChunkStats(cmd.Context(), globalOptions, opts, args)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+type DebugChunkStatsOptions struct {
+	Sizes []string
+}
+
+func (opts *DebugChunkStatsOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringArrayVar(&opts.Sizes, "sizes", nil, "compare with chunk `min/avg/max` sizes (can be specified multiple times)")
+}
+
+// parseChunkSizes parses chunk sizes in the format min/avg/max.
+func parseChunkSizes(s string, current restic.ChunkerParams) (restic.ChunkerParams, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return restic.ChunkerParams{}, errors.Fatalf("invalid chunk sizes %q, expected min/avg/max", s)
+	}
+
+	var sizes [3]uint
+	for i, part := range parts {
+		v, err := ui.ParseBytes(part)
+		if err != nil || v <= 0 {
+			return restic.ChunkerParams{}, errors.Fatalf("invalid chunk size %q", part)
+		}
+		sizes[i] = uint(v)
+	}
+
+	params := restic.ChunkerParams{
+		Pol:     current.Pol,
+		MinSize: sizes[0],
+		AvgSize: sizes[1],
+		MaxSize: sizes[2],
+	}
+	if err := params.Check(); err != nil {
+		return restic.ChunkerParams{}, errors.Fatalf("invalid chunk sizes %q: %v", s, err)
+	}
+	return params, nil
+}
+
+// chunkStats contains the statistics of splitting the sample data using the
+// chunker parameters.
+type chunkStats struct {
+	MinSize      uint    `json:"min_size"`
+	AvgSize      uint    `json:"avg_size"`
+	MaxSize      uint    `json:"max_size"`
+	Chunks       uint64  `json:"chunks"`
+	TotalSize    uint64  `json:"total_size"`
+	UniqueChunks uint64  `json:"unique_chunks"`
+	UniqueSize   uint64  `json:"unique_size"`
+	Savings      float64 `json:"savings_percent"`
+}
+
+func (s chunkStats) MeanChunkSize() uint64 {
+	if s.Chunks == 0 {
+		return 0
+	}
+	return s.TotalSize / s.Chunks
+}
+
+// collectChunkStats splits all regular files below paths into chunks.
+func collectChunkStats(ctx context.Context, params restic.ChunkerParams, paths []string) (chunkStats, error) {
+	stats := chunkStats{
+		MinSize: params.MinSize,
+		AvgSize: params.AvgSize,
+		MaxSize: params.MaxSize,
+	}
+	seen := restic.NewIDSet()
+	chnker := params.NewChunker(nil)
+	buf := make([]byte, params.MaxSize)
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(name string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = f.Close()
+			}()
+
+			params.ResetChunker(chnker, f)
+			for {
+				chunk, err := chnker.Next(buf)
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				stats.Chunks++
+				stats.TotalSize += uint64(chunk.Length)
+				id := restic.Hash(chunk.Data)
+				if !seen.Has(id) {
+					seen.Insert(id)
+					stats.UniqueChunks++
+					stats.UniqueSize += uint64(chunk.Length)
+				}
+			}
+		})
+		if err != nil {
+			return chunkStats{}, err
+		}
+	}
+
+	if stats.TotalSize > 0 {
+		stats.Savings = 100 * float64(stats.TotalSize-stats.UniqueSize) / float64(stats.TotalSize)
+	}
+	return stats, nil
+}
+
+func runDebugChunkStats(ctx context.Context, gopts GlobalOptions, opts DebugChunkStatsOptions, args []string) error {
+	if len(args) == 0 {
+		return errors.Fatal("no files or directories specified")
+	}
+
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	current := repo.Config().ChunkerParams()
+	paramSets := []restic.ChunkerParams{current}
+	for _, s := range opts.Sizes {
+		params, err := parseChunkSizes(s, current)
+		if err != nil {
+			return err
+		}
+		paramSets = append(paramSets, params)
+	}
+
+	var results []chunkStats
+	for _, params := range paramSets {
+		stats, err := collectChunkStats(ctx, params, args)
+		if err != nil {
+			return err
+		}
+		results = append(results, stats)
+	}
+
+	if gopts.JSON {
+		return json.NewEncoder(globalOptions.stdout).Encode(results)
+	}
+
+	tab := table.New()
+	tab.AddColumn("Min", "{{ .Min }}")
+	tab.AddColumn("Avg", "{{ .Avg }}")
+	tab.AddColumn("Max", "{{ .Max }}")
+	tab.AddColumn("Chunks", "{{ .Chunks }}")
+	tab.AddColumn("Mean Size", "{{ .MeanSize }}")
+	tab.AddColumn("Unique", "{{ .UniqueChunks }}")
+	tab.AddColumn("Unique Size", "{{ .UniqueSize }}")
+	tab.AddColumn("Savings", "{{ .Savings }}")
+	for _, stats := range results {
+		tab.AddRow(struct {
+			Min, Avg, Max, MeanSize, UniqueSize, Savings string
+			Chunks, UniqueChunks                         uint64
+		}{
+			Min:          ui.FormatBytes(uint64(stats.MinSize)),
+			Avg:          ui.FormatBytes(uint64(stats.AvgSize)),
+			Max:          ui.FormatBytes(uint64(stats.MaxSize)),
+			MeanSize:     ui.FormatBytes(stats.MeanChunkSize()),
+			UniqueSize:   ui.FormatBytes(stats.UniqueSize),
+			Savings:      fmt.Sprintf("%.1f%%", stats.Savings),
+			Chunks:       stats.Chunks,
+			UniqueChunks: stats.UniqueChunks,
+		})
+	}
+	return tab.Write(globalOptions.stdout)
+}
@@ -6,6 +6,7 @@ import (
 	"path"
 	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
@@ -36,6 +37,13 @@ directory:
 Metadata comparison will likely not work if a backup was created using the
 '--ignore-inode' or '--ignore-ctime' option.
 
+The --content-only option only reports items whose content was added, removed
+or modified. Directories and changes which only affect metadata are omitted.
+
+With --json, each change additionally contains its kind, the metadata of the old
+and new item and, for files, the number of content blobs which were added,
+removed or are shared between both versions.
+
 To only compare files in specific subfolders, you can use the
 "snapshotID:subfolder" syntax, where "subfolder" is a path within the
 snapshot.
@@ -63,10 +71,12 @@ Exit status is 12 if the password is incorrect.
 // DiffOptions collects all options for the diff command.
 type DiffOptions struct {
 	ShowMetadata bool
+	ContentOnly  bool
 }
 
 func (opts *DiffOptions) AddFlags(f *pflag.FlagSet) {
 	f.BoolVar(&opts.ShowMetadata, "metadata", false, "print changes in metadata")
+	f.BoolVar(&opts.ContentOnly, "content-only", false, "only print items whose content was added, removed or modified")
 }
 
 func loadSnapshot(ctx context.Context, be restic.Lister, repo restic.LoaderUnpacked, desc string) (*restic.Snapshot, string, error) {
@@ -85,13 +95,83 @@ type Comparer struct {
 }
 
 type Change struct {
-	MessageType string `json:"message_type"` // "change"
-	Path        string `json:"path"`
-	Modifier    string `json:"modifier"`
+	MessageType string       `json:"message_type"` // "change"
+	Path        string       `json:"path"`
+	Modifier    string       `json:"modifier"`
+	Kind        string       `json:"kind"`
+	Old         *restic.Node `json:"old,omitempty"`
+	New         *restic.Node `json:"new,omitempty"`
+	Blobs       *BlobChange  `json:"blobs,omitempty"`
+}
+
+// BlobChange counts the content blobs of a file which were added, removed or
+// are shared between the old and new version.
+type BlobChange struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Shared  int `json:"shared"`
+}
+
+// NewChange returns the change from node1 to node2 at path, either node may
+// be nil if the item was added or removed.
+func NewChange(path string, mode string, node1, node2 *restic.Node) *Change {
+	c := &Change{
+		MessageType: "change",
+		Path:        path,
+		Modifier:    mode,
+		Kind:        changeKind(mode),
+		Old:         withoutContent(node1),
+		New:         withoutContent(node2),
+	}
+
+	if (node1 != nil && node1.Type == restic.NodeTypeFile) || (node2 != nil && node2.Type == restic.NodeTypeFile) {
+		before := fileBlobs(node1)
+		after := fileBlobs(node2)
+		c.Blobs = &BlobChange{
+			Added:   len(after.Sub(before)),
+			Removed: len(before.Sub(after)),
+			Shared:  len(before.Intersect(after)),
+		}
+	}
+
+	return c
 }
 
-func NewChange(path string, mode string) *Change {
-	return &Change{MessageType: "change", Path: path, Modifier: mode}
+// changeKind returns a descriptive name for the most relevant change of the
+// modifier.
+func changeKind(mode string) string {
+	switch {
+	case strings.Contains(mode, "+"):
+		return "added"
+	case strings.Contains(mode, "-"):
+		return "removed"
+	case strings.Contains(mode, "T"):
+		return "type_changed"
+	case strings.Contains(mode, "?"):
+		return "bitrot"
+	case strings.Contains(mode, "M"):
+		return "modified"
+	default:
+		return "metadata"
+	}
+}
+
+// withoutContent returns a copy of node without the list of content blobs.
+func withoutContent(node *restic.Node) *restic.Node {
+	if node == nil {
+		return nil
+	}
+	n := *node
+	n.Content = nil
+	return &n
+}
+
+// fileBlobs returns the content blobs of node if it is a file.
+func fileBlobs(node *restic.Node) restic.IDSet {
+	if node == nil || node.Type != restic.NodeTypeFile {
+		return restic.NewIDSet()
+	}
+	return restic.NewIDSet(node.Content...)
 }
 
 // DiffStat collects stats for all types of items.
@@ -190,7 +270,13 @@ func (c *Comparer) printDir(ctx context.Context, mode string, stats *DiffStat, b
 		if node.Type == restic.NodeTypeDir {
 			name += "/"
 		}
-		c.printChange(NewChange(name, mode))
+		if !c.opts.ContentOnly || node.Type != restic.NodeTypeDir {
+			if mode == "+" {
+				c.printChange(NewChange(name, mode, nil, node))
+			} else {
+				c.printChange(NewChange(name, mode, node, nil))
+			}
+		}
 		stats.Add(node)
 		addBlobs(blobs, node)
 
@@ -306,12 +392,12 @@ func (c *Comparer) diffTree(ctx context.Context, stats *DiffStatsContainer, pref
 					// probable bitrot detected
 					mod += "?"
 				}
-			} else if c.opts.ShowMetadata && !node1.Equals(*node2) {
+			} else if c.opts.ShowMetadata && !c.opts.ContentOnly && !node1.Equals(*node2) {
 				mod += "U"
 			}
 
 			if mod != "" {
-				c.printChange(NewChange(name, mod))
+				c.printChange(NewChange(name, mod, node1, node2))
 			}
 
 			if node1.Type == restic.NodeTypeDir && node2.Type == restic.NodeTypeDir {
@@ -330,7 +416,9 @@ func (c *Comparer) diffTree(ctx context.Context, stats *DiffStatsContainer, pref
 			if node1.Type == restic.NodeTypeDir {
 				prefix += "/"
 			}
-			c.printChange(NewChange(prefix, "-"))
+			if !c.opts.ContentOnly || node1.Type != restic.NodeTypeDir {
+				c.printChange(NewChange(prefix, "-", node1, nil))
+			}
 			stats.Removed.Add(node1)
 
 			if node1.Type == restic.NodeTypeDir {
@@ -344,7 +432,9 @@ func (c *Comparer) diffTree(ctx context.Context, stats *DiffStatsContainer, pref
 			if node2.Type == restic.NodeTypeDir {
 				prefix += "/"
 			}
-			c.printChange(NewChange(prefix, "+"))
+			if !c.opts.ContentOnly || node2.Type != restic.NodeTypeDir {
+				c.printChange(NewChange(prefix, "+", nil, node2))
+			}
 			stats.Added.Add(node2)
 
 			if node2.Type == restic.NodeTypeDir {
@@ -363,6 +453,9 @@ func runDiff(ctx context.Context, opts DiffOptions, gopts GlobalOptions, args []
 	if len(args) != 2 {
 		return errors.Fatalf("specify two snapshot IDs")
 	}
+	if opts.ContentOnly && opts.ShowMetadata {
+		return errors.Fatal("--content-only and --metadata cannot be used together")
+	}
 
 	ctx, repo, unlock, err := openWithReadLock(ctx, gopts, gopts.NoLock)
 	if err != nil {
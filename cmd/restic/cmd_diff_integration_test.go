@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -15,10 +16,11 @@ import (
 )
 
 func testRunDiffOutput(gopts GlobalOptions, firstSnapshotID string, secondSnapshotID string) (string, error) {
+	return testRunDiffOutputWithOpts(gopts, DiffOptions{ShowMetadata: false}, firstSnapshotID, secondSnapshotID)
+}
+
+func testRunDiffOutputWithOpts(gopts GlobalOptions, opts DiffOptions, firstSnapshotID string, secondSnapshotID string) (string, error) {
 	buf, err := withCaptureStdout(func() error {
-		opts := DiffOptions{
-			ShowMetadata: false,
-		}
 		return runDiff(context.TODO(), opts, gopts, []string{firstSnapshotID, secondSnapshotID})
 	})
 	return buf.String(), err
@@ -159,6 +161,7 @@ func TestDiffJSON(t *testing.T) {
 
 	var stat DiffStatsContainer
 	var changes int
+	changeByPath := make(map[string]Change)
 
 	scanner := bufio.NewScanner(strings.NewReader(out))
 	for scanner.Scan() {
@@ -168,6 +171,9 @@ func TestDiffJSON(t *testing.T) {
 		switch sniffer.MessageType {
 		case "change":
 			changes++
+			var change Change
+			rtest.OK(t, json.Unmarshal([]byte(line), &change))
+			changeByPath[path.Base(change.Path)] = change
 		case "statistics":
 			rtest.OK(t, json.Unmarshal([]byte(line), &stat))
 		default:
@@ -175,6 +181,24 @@ func TestDiffJSON(t *testing.T) {
 		}
 	}
 	rtest.Equals(t, 9, changes)
+
+	modified := changeByPath["modfile1"]
+	rtest.Equals(t, "modified", modified.Kind)
+	rtest.Assert(t, modified.Old != nil && modified.New != nil && modified.New.Size > modified.Old.Size,
+		"unexpected nodes for modified file: %v, %v", modified.Old, modified.New)
+	rtest.Assert(t, modified.Blobs != nil && modified.Blobs.Added > 0, "expected added blobs, got %v", modified.Blobs)
+	rtest.Assert(t, modified.New.Content == nil, "content should be omitted from node")
+
+	added := changeByPath["modfile3"]
+	rtest.Equals(t, "added", added.Kind)
+	rtest.Assert(t, added.Old == nil && added.New != nil, "unexpected nodes for added file: %v, %v", added.Old, added.New)
+	rtest.Assert(t, added.Blobs != nil && added.Blobs.Added > 0 && added.Blobs.Removed == 0 && added.Blobs.Shared == 0,
+		"unexpected blobs for added file: %v", added.Blobs)
+
+	removedDir := changeByPath["submoddir"]
+	rtest.Equals(t, "removed", removedDir.Kind)
+	rtest.Assert(t, removedDir.Old != nil && removedDir.New == nil && removedDir.Blobs == nil,
+		"unexpected change for removed dir: %v", removedDir)
 	rtest.Assert(t, stat.Added.Files == 2 && stat.Added.Dirs == 3 && stat.Added.DataBlobs == 2 &&
 		stat.Removed.Files == 1 && stat.Removed.Dirs == 2 && stat.Removed.DataBlobs == 1 &&
 		stat.ChangedFiles == 1, "unexpected statistics")
@@ -191,3 +215,28 @@ func TestDiffJSON(t *testing.T) {
 		stat.ChangedFiles == 1, "unexpected statistics")
 	rtest.Assert(t, stat.SourceSnapshot == firstSnapshotID && stat.TargetSnapshot == secondSnapshotID, "unexpected snapshot ids")
 }
+
+func TestDiffContentOnly(t *testing.T) {
+	env, cleanup, firstSnapshotID, secondSnapshotID := setupDiffRepo(t)
+	defer cleanup()
+
+	env.gopts.Quiet = false
+	env.gopts.JSON = true
+	out, err := testRunDiffOutputWithOpts(env.gopts, DiffOptions{ContentOnly: true}, firstSnapshotID, secondSnapshotID)
+	rtest.OK(t, err)
+
+	var paths []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		var change Change
+		rtest.OK(t, json.Unmarshal(scanner.Bytes(), &change))
+		if change.MessageType == "change" {
+			rtest.Assert(t, !strings.HasSuffix(change.Path, "/"), "unexpected directory %v", change.Path)
+			paths = append(paths, path.Base(change.Path))
+		}
+	}
+	rtest.Equals(t, []string{"modfile", "modfile1", "modfile2", "modfile3"}, paths)
+
+	_, err = testRunDiffOutputWithOpts(env.gopts, DiffOptions{ContentOnly: true, ShowMetadata: true}, firstSnapshotID, secondSnapshotID)
+	rtest.Assert(t, err != nil, "expected error for --content-only with --metadata")
+}
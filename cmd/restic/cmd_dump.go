@@ -24,7 +24,8 @@ func newDumpCommand() *cobra.Command {
 		Long: `
 The "dump" command extracts files from a snapshot from the repository. If a
 single file is selected, it prints its contents to stdout. Folders are output
-as a tar (default) or zip file containing the contents of the specified folder.
+as a tar (default), tar.gz, tar.zst, zip or cpio file containing the contents
+of the specified folder.
 Pass "/" as file name to dump the whole snapshot as an archive file.
 
 The special snapshotID "latest" can be used to use the latest snapshot in the
@@ -57,14 +58,16 @@ Exit status is 12 if the password is incorrect.
 // DumpOptions collects all options for the dump command.
 type DumpOptions struct {
 	restic.SnapshotFilter
+	ownerMapOptions
 	Archive string
 	Target  string
 }
 
 func (opts *DumpOptions) AddFlags(f *pflag.FlagSet) {
 	initSingleSnapshotFilter(f, &opts.SnapshotFilter)
-	f.StringVarP(&opts.Archive, "archive", "a", "tar", "set archive `format` as \"tar\" or \"zip\"")
+	f.StringVarP(&opts.Archive, "archive", "a", "tar", "set archive `format` as \"tar\", \"tar.gz\", \"tar.zst\", \"zip\" or \"cpio\"")
 	f.StringVarP(&opts.Target, "target", "t", "", "write the output to target `path`")
+	opts.ownerMapOptions.AddFlags(f)
 }
 
 func splitPath(p string) []string {
@@ -130,11 +133,19 @@ func runDump(ctx context.Context, opts DumpOptions, gopts GlobalOptions, args []
 	}
 
 	switch opts.Archive {
-	case "tar", "zip":
+	case "tar", "tar.gz", "tar.zst", "zip", "cpio":
 	default:
 		return fmt.Errorf("unknown archive format %q", opts.Archive)
 	}
 
+	ownerMap, err := opts.OwnerMap()
+	if err != nil {
+		return err
+	}
+	if ownerMap != nil && opts.Archive == "zip" {
+		return errors.Fatal("--map-uid, --map-gid and --map-owner-by-name are not supported for zip archives, which do not store ownership")
+	}
+
 	snapshotIDString := args[0]
 	pathToPrint := args[1]
 
@@ -190,6 +201,7 @@ func runDump(ctx context.Context, opts DumpOptions, gopts GlobalOptions, args []
 	}
 
 	d := dump.New(opts.Archive, repo, outputFileWriter)
+	d.OwnerMap = ownerMap
 	err = printFromTree(ctx, tree, repo, "/", splittedPath, d, canWriteArchiveFunc)
 	if err != nil {
 		return errors.Fatalf("cannot dump file: %v", err)
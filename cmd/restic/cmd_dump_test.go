@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"github.com/restic/restic/internal/errors"
+
 	rtest "github.com/restic/restic/internal/test"
 )
 
@@ -25,3 +29,15 @@ func TestDumpSplitPath(t *testing.T) {
 		rtest.Equals(t, path.result, parts)
 	}
 }
+
+func TestDumpOwnerMapZip(t *testing.T) {
+	for _, opts := range []ownerMapOptions{
+		{MapUID: []string{"1001:2005"}},
+		{MapGID: []string{"1001:2005"}},
+		{MapOwnerByName: true},
+	} {
+		err := runDump(context.TODO(), DumpOptions{ownerMapOptions: opts, Archive: "zip"}, GlobalOptions{}, []string{"latest", "/"})
+		rtest.Assert(t, err != nil && errors.IsFatal(err), "expected fatal error, got %v", err)
+		rtest.Assert(t, strings.Contains(err.Error(), "not supported for zip archives"), "unexpected error %v", err)
+	}
+}
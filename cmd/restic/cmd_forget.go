@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"time"
 
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/restic"
@@ -27,7 +28,7 @@ first divided into groups according to "--group-by", and after that the policy
 specified by the "--keep-*" options is applied to each group individually.
 If there are not enough snapshots to keep one for each duration related
 "--keep-{within-,}*" option, the oldest snapshot in the group is kept
-additionally.
+additionally. Snapshots with an active hold, see "restic hold", are always kept.
 
 Please note that this command really only deletes the snapshot object in the
 repository, which is a reference to data stored there. In order to remove the
@@ -191,6 +192,12 @@ func runForget(ctx context.Context, opts ForgetOptions, pruneOptions PruneOption
 	}
 	defer unlock()
 
+	if !opts.DryRun {
+		if err := repo.CheckRemove(restic.SnapshotFile); err != nil {
+			return errors.Fatal(err.Error())
+		}
+	}
+
 	verbosity := gopts.verbosity
 	if gopts.JSON {
 		verbosity = 0
@@ -207,11 +214,19 @@ func runForget(ctx context.Context, opts ForgetOptions, pruneOptions PruneOption
 		return ctx.Err()
 	}
 
+	holds, err := restic.LoadHolds(ctx, repo, repo, time.Now())
+	if err != nil {
+		return err
+	}
+
 	var jsonGroups []*ForgetGroup
 
 	if len(args) > 0 {
 		// When explicit snapshots args are given, remove them immediately.
 		for _, sn := range snapshots {
+			if reason, ok := holds.Reason(*sn.ID()); ok {
+				return errors.Fatalf("refusing to remove snapshot %v, it is %v", sn.ID().Str(), reason)
+			}
 			removeSnIDs.Insert(*sn.ID())
 		}
 	} else {
@@ -234,6 +249,7 @@ func runForget(ctx context.Context, opts ForgetOptions, pruneOptions PruneOption
 			WithinMonthly: opts.WithinMonthly,
 			WithinYearly:  opts.WithinYearly,
 			Tags:          opts.KeepTags,
+			Holds:         holds,
 		}
 
 		if policy.Empty() {
@@ -306,6 +322,10 @@ func runForget(ctx context.Context, opts ForgetOptions, pruneOptions PruneOption
 
 	if len(removeSnIDs) > 0 {
 		if !opts.DryRun {
+			// record all removals using a single journal entry
+			if err := repo.JournalRemoval(ctx, restic.SnapshotFile, removeSnIDs); err != nil {
+				return errors.Fatal(err.Error())
+			}
 			bar := printer.NewCounter("files deleted")
 			err := restic.ParallelRemove(ctx, repo, removeSnIDs, restic.WriteableSnapshotFile, func(id restic.ID, err error) error {
 				if err != nil {
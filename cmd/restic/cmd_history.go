@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui"
+	"github.com/restic/restic/internal/ui/table"
+	"github.com/restic/restic/internal/walker"
+)
+
+func newHistoryCommand() *cobra.Command {
+	var opts HistoryOptions
+
+	cmd := &cobra.Command{
+		Use:   "history [flags] path",
+		Short: "Show the versions of a file or directory across snapshots",
+		Long: `
+The "history" command looks up the given path in all snapshots, from the oldest
+to the newest, and lists each distinct version. Consecutive snapshots in which
+the content of the item is unchanged are collapsed into a single version, even if
+only its metadata changed. Snapshots which do not contain the path are listed as
+missing.
+
+The content of files is identified by the list of their data blobs, directories
+by their tree and symlinks by their target.
+
+Use --host, --tag and --path to restrict the snapshots which are searched, and
+--group-by to show a separate history for each group of snapshots.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+Exit status is 10 if the repository does not exist.
+Exit status is 11 if the repository is already locked.
+Exit status is 12 if the password is incorrect.
+`,
+		GroupID:           cmdGroupDefault,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistory(cmd.Context(), opts, globalOptions, args)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// HistoryOptions collects all options for the history command.
+type HistoryOptions struct {
+	restic.SnapshotFilter
+	GroupBy restic.SnapshotGroupByOptions
+}
+
+func (opts *HistoryOptions) AddFlags(f *pflag.FlagSet) {
+	initMultiSnapshotFilter(f, &opts.SnapshotFilter, true)
+	f.VarP(&opts.GroupBy, "group-by", "g", "`group` snapshots by host, paths and/or tags, separated by comma")
+}
+
+// historyVersion is a version of an item which was the same in one or more
+// consecutive snapshots.
+type historyVersion struct {
+	SnapshotID     string          `json:"snapshot_id"`
+	Time           time.Time       `json:"time"`
+	LastSnapshotID string          `json:"last_snapshot_id"`
+	LastTime       time.Time       `json:"last_time"`
+	Snapshots      int             `json:"snapshots"`
+	Missing        bool            `json:"missing,omitempty"`
+	Type           restic.NodeType `json:"type,omitempty"`
+	ModTime        *time.Time      `json:"mtime,omitempty"`
+	Size           uint64          `json:"size"`
+	ContentID      string          `json:"content_id,omitempty"`
+}
+
+// historyGroup is the history of the item for a group of snapshots.
+type historyGroup struct {
+	GroupKey restic.SnapshotGroupKey `json:"group_key"`
+	Versions []historyVersion        `json:"versions"`
+}
+
+type historyCacheKey struct {
+	path string
+	id   restic.ID
+}
+
+// historyLookup finds an item in snapshots. The result of the lookup is
+// remembered for each tree on the way to the item, thus trees which are
+// shared between snapshots are only loaded once.
+type historyLookup struct {
+	repo   restic.BlobLoader
+	target string
+	// cache contains the item found below a tree, or nil if the item does not
+	// exist in that tree
+	cache map[historyCacheKey]*restic.Node
+}
+
+func newHistoryLookup(repo restic.BlobLoader, target string) *historyLookup {
+	return &historyLookup{
+		repo:   repo,
+		target: target,
+		cache:  make(map[historyCacheKey]*restic.Node),
+	}
+}
+
+// onPath returns true if the directory p is a parent of the target.
+func (l *historyLookup) onPath(p string) bool {
+	return strings.HasPrefix(l.target, p+"/")
+}
+
+// find returns the node of the target in the tree root, or nil if it does not
+// exist.
+func (l *historyLookup) find(ctx context.Context, root restic.ID) (*restic.Node, error) {
+	rootKey := historyCacheKey{path: "/", id: root}
+	if node, ok := l.cache[rootKey]; ok {
+		return node, nil
+	}
+
+	visited := []historyCacheKey{rootKey}
+	var found *restic.Node
+	err := walker.Walk(ctx, l.repo, root, walker.WalkVisitor{
+		ProcessNode: func(_ restic.ID, p string, node *restic.Node, err error) error {
+			if err != nil {
+				return err
+			}
+			if node == nil {
+				return nil
+			}
+			if p == l.target {
+				found = node
+				// skip the remaining items in this directory
+				return walker.ErrSkipNode
+			}
+			if node.Type == restic.NodeTypeDir {
+				visited = append(visited, historyCacheKey{path: p, id: *node.Subtree})
+			}
+			return nil
+		},
+		SkipDir: func(p string, node *restic.Node) bool {
+			if p == l.target {
+				found = node
+				return true
+			}
+			if !l.onPath(p) {
+				return true
+			}
+			if n, ok := l.cache[historyCacheKey{path: p, id: *node.Subtree}]; ok {
+				found = n
+				return true
+			}
+			return false
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range visited {
+		l.cache[key] = found
+	}
+	return found, nil
+}
+
+// collectHistory returns the distinct versions of the target in the snapshots,
+// which must be sorted by time.
+func collectHistory(ctx context.Context, lookup *historyLookup, snapshots restic.Snapshots) ([]historyVersion, error) {
+	var versions []historyVersion
+	for _, sn := range snapshots {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if sn.Tree == nil {
+			return nil, errors.Errorf("snapshot %v has nil tree", sn.ID().Str())
+		}
+
+		node, err := lookup.find(ctx, *sn.Tree)
+		if err != nil {
+			return nil, errors.Fatalf("unable to search snapshot %v: %v", sn.ID().Str(), err)
+		}
+
+		v := historyVersion{
+			SnapshotID:     sn.ID().String(),
+			Time:           sn.Time,
+			LastSnapshotID: sn.ID().String(),
+			LastTime:       sn.Time,
+			Snapshots:      1,
+			Missing:        node == nil,
+		}
+		if node != nil {
+			modTime := node.ModTime
+			v.Type = node.Type
+			v.ModTime = &modTime
+			v.Size = node.Size
+			if id := node.ContentID(); !id.IsNull() {
+				v.ContentID = id.String()
+			}
+		}
+
+		if len(versions) > 0 {
+			last := &versions[len(versions)-1]
+			if last.Missing == v.Missing && last.Type == v.Type && last.ContentID == v.ContentID {
+				last.LastSnapshotID = v.LastSnapshotID
+				last.LastTime = v.LastTime
+				last.Snapshots++
+				continue
+			}
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func printHistory(versions []historyVersion) error {
+	tab := table.New()
+	tab.AddColumn("Snapshot", "{{ .Snapshot }}")
+	tab.AddColumn("Time", "{{ .Time }}")
+	tab.AddColumn("Until", "{{ .Until }}")
+	tab.AddColumn("Snapshots", "{{ .Snapshots }}")
+	tab.AddColumn("Type", "{{ .Type }}")
+	tab.AddColumn("Modified", "{{ .ModTime }}")
+	tab.AddColumn("Size", "{{ .Size }}")
+	tab.AddColumn("Content", "{{ .Content }}")
+
+	for _, v := range versions {
+		row := struct {
+			Snapshot, Time, Until, Type, ModTime, Size, Content string
+			Snapshots                                           int
+		}{
+			Snapshot:  v.SnapshotID[:8],
+			Time:      v.Time.Local().Format(TimeFormat),
+			Until:     v.LastTime.Local().Format(TimeFormat),
+			Snapshots: v.Snapshots,
+			Type:      string(v.Type),
+		}
+		if v.Missing {
+			row.Type = "(missing)"
+		} else {
+			row.ModTime = v.ModTime.Local().Format(TimeFormat)
+			row.Size = ui.FormatBytes(v.Size)
+		}
+		if v.ContentID != "" {
+			row.Content = v.ContentID[:8]
+		}
+		tab.AddRow(row)
+	}
+
+	return tab.Write(globalOptions.stdout)
+}
+
+func runHistory(ctx context.Context, opts HistoryOptions, gopts GlobalOptions, args []string) error {
+	if len(args) != 1 {
+		return errors.Fatal("specify exactly one path")
+	}
+	target := path.Clean(path.Join("/", args[0]))
+	if target == "/" {
+		return errors.Fatal("the path must not be the root directory")
+	}
+
+	ctx, repo, unlock, err := openWithReadLock(ctx, gopts, gopts.NoLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var snapshots restic.Snapshots
+	for sn := range FindFilteredSnapshots(ctx, repo, repo, &opts.SnapshotFilter, nil) {
+		snapshots = append(snapshots, sn)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	snapshotGroups, grouped, err := restic.GroupSnapshots(snapshots, opts.GroupBy)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(snapshotGroups))
+	for k := range snapshotGroups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	bar := newIndexProgress(gopts.Quiet, gopts.JSON)
+	if err = repo.LoadIndex(ctx, bar); err != nil {
+		return err
+	}
+
+	lookup := newHistoryLookup(repo, target)
+	var groups []historyGroup
+	for _, k := range keys {
+		list := snapshotGroups[k]
+		// oldest snapshot first
+		sort.Sort(sort.Reverse(list))
+
+		versions, err := collectHistory(ctx, lookup, list)
+		if err != nil {
+			return err
+		}
+
+		var key restic.SnapshotGroupKey
+		if err := json.Unmarshal([]byte(k), &key); err != nil {
+			return err
+		}
+		groups = append(groups, historyGroup{GroupKey: key, Versions: versions})
+	}
+
+	if gopts.JSON {
+		enc := json.NewEncoder(globalOptions.stdout)
+		if grouped {
+			return enc.Encode(groups)
+		}
+		versions := []historyVersion{}
+		if len(groups) > 0 {
+			versions = groups[0].Versions
+		}
+		return enc.Encode(versions)
+	}
+
+	for i, k := range keys {
+		if grouped {
+			if err := PrintSnapshotGroupHeader(globalOptions.stdout, k); err != nil {
+				return err
+			}
+		}
+		if err := printHistory(groups[i].Versions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
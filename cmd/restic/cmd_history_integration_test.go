@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testRunHistory(t testing.TB, gopts GlobalOptions, opts HistoryOptions, item string) []historyVersion {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+		return runHistory(context.TODO(), opts, gopts, []string{item})
+	})
+	rtest.OK(t, err)
+
+	var versions []historyVersion
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &versions))
+	return versions
+}
+
+func TestHistory(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testRunInit(t, env.gopts)
+
+	datadir := filepath.Join(env.base, "data")
+	testfile := filepath.Join(datadir, "file")
+	rtest.OK(t, os.MkdirAll(filepath.Join(datadir, "other"), 0755))
+
+	backup := func() {
+		testRunBackup(t, env.base, []string{"data"}, BackupOptions{}, env.gopts)
+	}
+
+	rtest.OK(t, os.WriteFile(testfile, []byte("version 1"), 0644))
+	backup()
+	// unchanged
+	backup()
+	// metadata only
+	rtest.OK(t, os.Chtimes(testfile, time.Now(), time.Now().Add(-time.Hour)))
+	rtest.OK(t, os.WriteFile(filepath.Join(datadir, "other", "file"), []byte("unrelated"), 0644))
+	backup()
+	// new content
+	rtest.OK(t, os.WriteFile(testfile, []byte("version 2"), 0644))
+	backup()
+	// removed
+	rtest.OK(t, os.Remove(testfile))
+	backup()
+	// restored
+	rtest.OK(t, os.WriteFile(testfile, []byte("version 1"), 0644))
+	backup()
+
+	versions := testRunHistory(t, env.gopts, HistoryOptions{}, "/data/file")
+	rtest.Equals(t, 4, len(versions))
+
+	rtest.Equals(t, 3, versions[0].Snapshots)
+	rtest.Equals(t, uint64(9), versions[0].Size)
+	rtest.Equals(t, 1, versions[1].Snapshots)
+	rtest.Assert(t, versions[0].ContentID != versions[1].ContentID, "expected different content for versions 1 and 2")
+	rtest.Assert(t, versions[2].Missing && versions[2].ContentID == "", "expected missing version, got %v", versions[2])
+	rtest.Equals(t, versions[0].ContentID, versions[3].ContentID)
+
+	versions = testRunHistory(t, env.gopts, HistoryOptions{}, "/data/nonexistent")
+	rtest.Equals(t, 1, len(versions))
+	rtest.Assert(t, versions[0].Missing && versions[0].Snapshots == 6, "unexpected versions %v", versions)
+
+	// directories are identified by their tree
+	versions = testRunHistory(t, env.gopts, HistoryOptions{}, "/data/other")
+	rtest.Equals(t, 2, len(versions))
+	rtest.Equals(t, 2, versions[0].Snapshots)
+}
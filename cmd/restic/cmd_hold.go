@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newHoldCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hold",
+		Short: "Manage holds which prevent removing snapshots",
+		Long: `
+The "hold" command manages holds on snapshots, for example to retain them for
+legal reasons. Held snapshots are never removed by "forget", "prune" or
+"rewrite --forget", regardless of the retention policy.
+	`,
+		DisableAutoGenTag: true,
+		GroupID:           cmdGroupDefault,
+	}
+
+	cmd.AddCommand(
+		newHoldAddCommand(),
+		newHoldListCommand(),
+		newHoldRemoveCommand(),
+	)
+	return cmd
+}
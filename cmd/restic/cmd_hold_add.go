@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newHoldAddCommand() *cobra.Command {
+	var opts HoldAddOptions
+
+	cmd := &cobra.Command{
+		Use:   "add [flags] snapshotID [...]",
+		Short: "Place a hold on snapshots",
+		Long: `
+The "add" sub-command places a hold on the given snapshots. A hold records the
+reason and optionally an expiry time after which it no longer applies.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+Exit status is 10 if the repository does not exist.
+Exit status is 11 if the repository is already locked.
+Exit status is 12 if the password is incorrect.
+	`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHoldAdd(cmd.Context(), opts, globalOptions, args)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// HoldAddOptions bundles all options for the 'hold add' command.
+type HoldAddOptions struct {
+	Reason  string
+	Expires string
+}
+
+func (opts *HoldAddOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&opts.Reason, "reason", "", "the `reason` for the hold")
+	f.StringVar(&opts.Expires, "expires", "", "the hold no longer applies after `time` (format: YYYY-MM-DD [hh:mm[:ss]])")
+}
+
+func runHoldAdd(ctx context.Context, opts HoldAddOptions, gopts GlobalOptions, args []string) error {
+	if len(args) == 0 {
+		return errors.Fatal("no snapshot ID specified")
+	}
+
+	var expires time.Time
+	if opts.Expires != "" {
+		var err error
+		expires, err = parseTime(opts.Expires)
+		if err != nil {
+			return err
+		}
+		if expires.Before(time.Now()) {
+			return errors.Fatal("expiry time is in the past")
+		}
+	}
+
+	ctx, repo, unlock, err := openWithAppendLock(ctx, gopts, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for sn := range FindFilteredSnapshots(ctx, repo, repo, &restic.SnapshotFilter{}, args) {
+		id, err := restic.SaveHold(ctx, repo, restic.NewHold(*sn.ID(), opts.Reason, expires))
+		if err != nil {
+			return errors.Fatalf("unable to save hold for snapshot %v: %v", sn.ID().Str(), err)
+		}
+		Verbosef("added hold %v for snapshot %v\n", id.Str(), sn.ID().Str())
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testRunHoldList(t testing.TB, gopts GlobalOptions) []holdInfo {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+		return runHoldList(context.TODO(), gopts, nil)
+	})
+	rtest.OK(t, err)
+
+	var holds []holdInfo
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &holds))
+	return holds
+}
+
+func TestHold(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	for i := 0; i < 3; i++ {
+		testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9")}, BackupOptions{}, env.gopts)
+	}
+	newest, snapshots := testRunSnapshots(t, env.gopts)
+	var held restic.ID
+	for id := range snapshots {
+		if id != *newest.ID {
+			held = id
+			break
+		}
+	}
+
+	rtest.OK(t, runHoldAdd(context.TODO(), HoldAddOptions{Reason: "litigation"}, env.gopts, []string{held.String()}))
+	holds := testRunHoldList(t, env.gopts)
+	rtest.Equals(t, 1, len(holds))
+	rtest.Equals(t, held, holds[0].Snapshot)
+	rtest.Equals(t, "litigation", holds[0].Reason)
+	rtest.Assert(t, holds[0].Active, "hold is not active")
+
+	// held snapshots are kept regardless of the policy
+	rtest.Assert(t, testRunForgetMayFail(env.gopts, ForgetOptions{}, held.String()) != nil,
+		"expected forget of held snapshot to fail")
+	testRunForget(t, env.gopts, ForgetOptions{Last: 1})
+	snapshotIDs := testListSnapshots(t, env.gopts, 2)
+	rtest.Assert(t, restic.NewIDSet(snapshotIDs...).Has(held), "held snapshot was removed")
+
+	// rewrite keeps the original held snapshot
+	testRunRewriteExclude(t, env.gopts, []string{"*"}, true, snapshotMetadataArgs{})
+	snapshotIDs = testListSnapshots(t, env.gopts, 3)
+	rtest.Assert(t, restic.NewIDSet(snapshotIDs...).Has(held), "held snapshot was removed")
+
+	// prune refuses to remove the data of a missing held snapshot
+	snapshotFile := filepath.Join(env.repo, "snapshots", held.String())
+	buf, err := os.ReadFile(snapshotFile)
+	rtest.OK(t, err)
+	rtest.OK(t, os.Remove(snapshotFile))
+	testRunPruneMustFail(t, env.gopts, PruneOptions{MaxUnused: "0"})
+	rtest.OK(t, os.WriteFile(snapshotFile, buf, 0o600))
+
+	rtest.OK(t, runHoldRemove(context.TODO(), env.gopts, []string{held.Str()}))
+	rtest.Equals(t, 0, len(testRunHoldList(t, env.gopts)))
+	testRunForget(t, env.gopts, ForgetOptions{Last: 1})
+	testListSnapshots(t, env.gopts, 1)
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0"})
+	testRunCheck(t, env.gopts)
+}
+
+func TestHoldTag(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9")}, BackupOptions{}, env.gopts)
+	held := testListSnapshots(t, env.gopts, 1)[0]
+	rtest.OK(t, runHoldAdd(context.TODO(), HoldAddOptions{Reason: "litigation"}, env.gopts, []string{held.String()}))
+
+	// changing the tags moves the hold to the new snapshot
+	testRunTag(t, TagOptions{AddTags: restic.TagLists{[]string{"audit"}}}, env.gopts)
+	tagged := testListSnapshots(t, env.gopts, 1)[0]
+	rtest.Assert(t, tagged != held, "snapshot was not replaced")
+	holds := testRunHoldList(t, env.gopts)
+	rtest.Equals(t, 1, len(holds))
+	rtest.Equals(t, tagged, holds[0].Snapshot)
+	rtest.Equals(t, "litigation", holds[0].Reason)
+
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0"})
+	testRunCheck(t, env.gopts)
+}
+
+func TestHoldRemoveAmbiguous(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+	testRunInit(t, env.gopts)
+
+	// holds do not require the snapshots to exist
+	first := restic.TestParseID("aa11111111111111111111111111111111111111111111111111111111111111")
+	second := restic.TestParseID("aa22222222222222222222222222222222222222222222222222222222222222")
+	ctx, repo, unlock, err := openWithAppendLock(context.TODO(), env.gopts, false)
+	rtest.OK(t, err)
+	for _, id := range []restic.ID{first, second} {
+		_, err = restic.SaveHold(ctx, repo, restic.NewHold(id, "litigation", time.Time{}))
+		rtest.OK(t, err)
+	}
+	unlock()
+
+	for _, prefix := range []string{"", "aa"} {
+		err := runHoldRemove(context.TODO(), env.gopts, []string{prefix})
+		rtest.Assert(t, err != nil, "expected error for prefix %q", prefix)
+		rtest.Equals(t, 2, len(testRunHoldList(t, env.gopts)))
+	}
+	// no hold is removed if one of the arguments is ambiguous
+	rtest.Assert(t, runHoldRemove(context.TODO(), env.gopts, []string{"aa1", "a"}) != nil, "expected error")
+	rtest.Equals(t, 2, len(testRunHoldList(t, env.gopts)))
+
+	rtest.OK(t, runHoldRemove(context.TODO(), env.gopts, []string{"aa1"}))
+	holds := testRunHoldList(t, env.gopts)
+	rtest.Equals(t, 1, len(holds))
+	rtest.Equals(t, second, holds[0].Snapshot)
+}
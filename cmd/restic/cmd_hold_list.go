@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui/table"
+	"github.com/spf13/cobra"
+)
+
+func newHoldListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List holds",
+		Long: `
+The "list" sub-command lists all holds in the repository, including expired
+holds.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+Exit status is 10 if the repository does not exist.
+Exit status is 11 if the repository is already locked.
+Exit status is 12 if the password is incorrect.
+	`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHoldList(cmd.Context(), globalOptions, args)
+		},
+	}
+	return cmd
+}
+
+type holdInfo struct {
+	ID       restic.ID  `json:"id"`
+	ShortID  string     `json:"-"`
+	Snapshot restic.ID  `json:"snapshot"`
+	Reason   string     `json:"reason,omitempty"`
+	Time     time.Time  `json:"time"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Active   bool       `json:"active"`
+	Hostname string     `json:"hostname,omitempty"`
+	Username string     `json:"username,omitempty"`
+}
+
+func runHoldList(ctx context.Context, gopts GlobalOptions, args []string) error {
+	if len(args) > 0 {
+		return errors.Fatal("the hold list command expects no arguments, only options - please see `restic help hold list` for usage and flags")
+	}
+
+	ctx, repo, unlock, err := openWithReadLock(ctx, gopts, gopts.NoLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	now := time.Now()
+	holds := []holdInfo{}
+	err = restic.ForAllHolds(ctx, repo, repo, func(id restic.ID, h *restic.Hold, err error) error {
+		if err != nil {
+			Warnf("%v\n", err)
+			return nil
+		}
+		holds = append(holds, holdInfo{
+			ID:       id,
+			ShortID:  id.Str(),
+			Snapshot: h.Snapshot,
+			Reason:   h.Reason,
+			Time:     h.Time,
+			Expires:  h.Expires,
+			Active:   h.Active(now),
+			Hostname: h.Hostname,
+			Username: h.Username,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		return holds[i].Time.Before(holds[j].Time)
+	})
+
+	if gopts.JSON {
+		return json.NewEncoder(globalOptions.stdout).Encode(holds)
+	}
+
+	tab := table.New()
+	tab.AddColumn("ID", "{{ .ShortID }}")
+	tab.AddColumn("Snapshot", "{{ .Snapshot.Str }}")
+	tab.AddColumn("Created", "{{ .Time.Local.Format \""+TimeFormat+"\" }}")
+	tab.AddColumn("Expires", "{{ if .Expires }}{{ .Expires.Local.Format \""+TimeFormat+"\" }}{{ end }}{{ if not .Active }} (expired){{ end }}")
+	tab.AddColumn("Reason", "{{ .Reason }}")
+	for _, h := range holds {
+		tab.AddRow(h)
+	}
+	return tab.Write(globalOptions.stdout)
+}
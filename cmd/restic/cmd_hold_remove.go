@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/spf13/cobra"
+)
+
+func newHoldRemoveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove snapshotID [...]",
+		Short: "Remove all holds on snapshots",
+		Long: `
+The "remove" sub-command removes all holds, including expired ones, on the
+given snapshots. The snapshots are matched by ID prefix, they do not have to
+exist in the repository anymore. A prefix must match exactly one held snapshot.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+Exit status is 10 if the repository does not exist.
+Exit status is 11 if the repository is already locked.
+Exit status is 12 if the password is incorrect.
+	`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHoldRemove(cmd.Context(), globalOptions, args)
+		},
+	}
+	return cmd
+}
+
+func runHoldRemove(ctx context.Context, gopts GlobalOptions, args []string) error {
+	if len(args) == 0 {
+		return errors.Fatal("no snapshot ID specified")
+	}
+
+	ctx, repo, unlock, err := openWithExclusiveLock(ctx, gopts, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	holds := make(map[restic.ID]restic.IDs)
+	err = restic.ForAllHolds(ctx, repo, repo, func(id restic.ID, h *restic.Hold, err error) error {
+		if err != nil {
+			return err
+		}
+		holds[h.Snapshot] = append(holds[h.Snapshot], id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// resolve all arguments first, such that no hold is removed if one of
+	// them is ambiguous
+	removeIDs := restic.NewIDSet()
+	for _, arg := range args {
+		snapshot, err := findHeldSnapshot(holds, arg)
+		if err != nil {
+			return err
+		}
+		if snapshot.IsNull() {
+			Warnf("no holds found for snapshot %v\n", arg)
+			continue
+		}
+		for _, id := range holds[snapshot] {
+			removeIDs.Insert(id)
+		}
+	}
+
+	for _, id := range removeIDs.List() {
+		if err := repo.RemoveUnpacked(ctx, restic.WriteableHoldFile, id); err != nil {
+			return errors.Fatalf("unable to remove hold %v: %v", id.Str(), err)
+		}
+		Verbosef("removed hold %v\n", id.Str())
+	}
+	return nil
+}
+
+// findHeldSnapshot returns the ID of the held snapshot which starts with
+// prefix. A null ID is returned if no held snapshot matches.
+func findHeldSnapshot(holds map[restic.ID]restic.IDs, prefix string) (restic.ID, error) {
+	if prefix == "" {
+		return restic.ID{}, errors.Fatal("empty snapshot ID specified")
+	}
+
+	var match restic.ID
+	for snapshot := range holds {
+		if !strings.HasPrefix(snapshot.String(), prefix) {
+			continue
+		}
+		if !match.IsNull() {
+			return restic.ID{}, errors.Fatalf("multiple held snapshots with prefix %q found", prefix)
+		}
+		match = snapshot
+	}
+	return match, nil
+}
@@ -5,11 +5,12 @@ import (
 	"encoding/json"
 	"strconv"
 
-	"github.com/restic/chunker"
 	"github.com/restic/restic/internal/backend/location"
+	"github.com/restic/restic/internal/crypto"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -24,6 +25,28 @@ func newInitCommand() *cobra.Command {
 		Long: `
 The "init" command initializes a new repository.
 
+With --append-only, the repository refuses to remove files other than locks
+unless an admin key is used. The key created by "init" is an admin key, further
+keys are regular keys unless they are added using "key add --admin". Every
+removal is recorded in a signed deletion journal, which is verified by "check".
+
+The options --chunker-min-size, --chunker-max-size and --chunker-avg-size
+configure the sizes of the chunks into which files are split by "backup". By
+default, chunks are between 512 KiB and 8 MiB large. Smaller chunks can improve
+deduplication, for example for database dumps, at the cost of a larger index.
+The average size must be a power of two. Use "restic debug chunk-stats" to
+compare the deduplication of sample data for different chunk sizes. The chunk
+sizes cannot be changed after the repository has been created.
+
+The option --kdf selects the key derivation function which derives the key from
+the password, either "scrypt" (default) or "argon2id". Further keys can use a
+different function.
+
+With --asymmetric, data and snapshots are encrypted using a public key stored in
+the repository config. Only keys holding the matching private key, like the key
+created by "init", can decrypt them. Use "key add --write-only" to add keys for
+clients which should only be able to create new backups.
+
 EXIT STATUS
 ===========
 
@@ -45,12 +68,24 @@ type InitOptions struct {
 	secondaryRepoOptions
 	CopyChunkerParameters bool
 	RepositoryVersion     string
+	AppendOnly            bool
+	ChunkerMinSize        string
+	ChunkerMaxSize        string
+	ChunkerAvgSize        string
+	KDF                   string
+	Asymmetric            bool
 }
 
 func (opts *InitOptions) AddFlags(f *pflag.FlagSet) {
 	opts.secondaryRepoOptions.AddFlags(f, "secondary", "to copy chunker parameters from")
 	f.BoolVar(&opts.CopyChunkerParameters, "copy-chunker-params", false, "copy chunker parameters from the secondary repository (useful with the copy command)")
 	f.StringVar(&opts.RepositoryVersion, "repository-version", "stable", "repository format version to use, allowed values are a format version, 'latest' and 'stable'")
+	f.BoolVar(&opts.AppendOnly, "append-only", false, "only allow admin keys to remove files other than locks")
+	f.StringVar(&opts.ChunkerMinSize, "chunker-min-size", "", "minimum chunk `size` (allowed suffixes: k/K, m/M)")
+	f.StringVar(&opts.ChunkerMaxSize, "chunker-max-size", "", "maximum chunk `size` (allowed suffixes: k/K, m/M)")
+	f.StringVar(&opts.ChunkerAvgSize, "chunker-avg-size", "", "average chunk `size`, must be a power of two (allowed suffixes: k/K, m/M)")
+	f.StringVar(&opts.KDF, "kdf", crypto.KDFScrypt, "key derivation `function` for the key, either \"scrypt\" or \"argon2id\"")
+	f.BoolVar(&opts.Asymmetric, "asymmetric", false, "encrypt data and snapshots such that write-only keys cannot decrypt them")
 }
 
 func runInit(ctx context.Context, opts InitOptions, gopts GlobalOptions, args []string) error {
@@ -59,6 +94,8 @@ func runInit(ctx context.Context, opts InitOptions, gopts GlobalOptions, args []
 	}
 
 	var version uint
+	// an explicitly specified version is not raised automatically
+	explicitVersion := false
 	if opts.RepositoryVersion == "latest" || opts.RepositoryVersion == "" {
 		version = restic.MaxRepoVersion
 	} else if opts.RepositoryVersion == "stable" {
@@ -69,15 +106,30 @@ func runInit(ctx context.Context, opts InitOptions, gopts GlobalOptions, args []
 			return errors.Fatal("invalid repository version")
 		}
 		version = uint(v)
+		explicitVersion = true
 	}
 	if version < restic.MinRepoVersion || version > restic.MaxRepoVersion {
 		return errors.Fatalf("only repository versions between %v and %v are allowed", restic.MinRepoVersion, restic.MaxRepoVersion)
 	}
+	if err := verifyKDF(opts.KDF); err != nil {
+		return err
+	}
 
-	chunkerPolynomial, err := maybeReadChunkerPolynomial(ctx, opts, gopts)
+	chunkerParams, err := maybeReadChunkerParams(ctx, opts, gopts)
 	if err != nil {
 		return err
 	}
+	if explicitVersion && version < restic.ExtendedRepoVersion {
+		if opts.Asymmetric {
+			return errors.Fatalf("--asymmetric requires repository version %v or newer", restic.ExtendedRepoVersion)
+		}
+		if chunkerParams != nil && !chunkerParams.IsDefault() {
+			return errors.Fatalf("custom chunk sizes require repository version %v or newer", restic.ExtendedRepoVersion)
+		}
+		if opts.AppendOnly {
+			return errors.Fatalf("--append-only requires repository version %v or newer", restic.ExtendedRepoVersion)
+		}
+	}
 
 	gopts.Repo, err = ReadRepo(gopts)
 	if err != nil {
@@ -104,14 +156,28 @@ func runInit(ctx context.Context, opts InitOptions, gopts GlobalOptions, args []
 		return errors.Fatal(err.Error())
 	}
 
-	err = s.Init(ctx, version, gopts.password, chunkerPolynomial)
+	initOpts := repository.InitOptions{
+		AppendOnly: opts.AppendOnly,
+		KDF:        opts.KDF,
+		Asymmetric: opts.Asymmetric,
+	}
+	if chunkerParams != nil {
+		if chunkerParams.Pol != 0 {
+			initOpts.ChunkerPolynomial = &chunkerParams.Pol
+		}
+		initOpts.ChunkerMinSize = chunkerParams.MinSize
+		initOpts.ChunkerMaxSize = chunkerParams.MaxSize
+		initOpts.ChunkerAvgSize = chunkerParams.AvgSize
+	}
+
+	err = s.Init(ctx, version, gopts.password, initOpts)
 	if err != nil {
 		return errors.Fatalf("create key in repository at %s failed: %v\n", location.StripPassword(gopts.backends, gopts.Repo), err)
 	}
 
 	if !gopts.JSON {
 		Verbosef("created restic repository %v at %s", s.Config().ID[:10], location.StripPassword(gopts.backends, gopts.Repo))
-		if opts.CopyChunkerParameters && chunkerPolynomial != nil {
+		if opts.CopyChunkerParameters {
 			Verbosef(" with chunker parameters copied from secondary repository\n")
 		} else {
 			Verbosef("\n")
@@ -133,8 +199,17 @@ func runInit(ctx context.Context, opts InitOptions, gopts GlobalOptions, args []
 	return nil
 }
 
-func maybeReadChunkerPolynomial(ctx context.Context, opts InitOptions, gopts GlobalOptions) (*chunker.Pol, error) {
+// maybeReadChunkerParams returns the chunker parameters copied from the
+// secondary repository or set via the options. If neither is the case, nil
+// is returned.
+func maybeReadChunkerParams(ctx context.Context, opts InitOptions, gopts GlobalOptions) (*restic.ChunkerParams, error) {
+	customSizes := opts.ChunkerMinSize != "" || opts.ChunkerMaxSize != "" || opts.ChunkerAvgSize != ""
+
 	if opts.CopyChunkerParameters {
+		if customSizes {
+			return nil, errors.Fatal("--copy-chunker-params cannot be combined with custom chunk sizes")
+		}
+
 		otherGopts, _, err := fillSecondaryGlobalOpts(ctx, opts.secondaryRepoOptions, gopts, "secondary")
 		if err != nil {
 			return nil, err
@@ -145,14 +220,43 @@ func maybeReadChunkerPolynomial(ctx context.Context, opts InitOptions, gopts Glo
 			return nil, err
 		}
 
-		pol := otherRepo.Config().ChunkerPolynomial
-		return &pol, nil
+		params := otherRepo.Config().ChunkerParams()
+		return &params, nil
 	}
 
 	if opts.Repo != "" || opts.RepositoryFile != "" || opts.LegacyRepo != "" || opts.LegacyRepositoryFile != "" {
 		return nil, errors.Fatal("Secondary repository must only be specified when copying the chunker parameters")
 	}
-	return nil, nil
+	if !customSizes {
+		return nil, nil
+	}
+
+	var cfg restic.Config
+	for _, size := range []struct {
+		flag  string
+		value string
+		dst   *uint
+	}{
+		{"--chunker-min-size", opts.ChunkerMinSize, &cfg.ChunkerMinSize},
+		{"--chunker-max-size", opts.ChunkerMaxSize, &cfg.ChunkerMaxSize},
+		{"--chunker-avg-size", opts.ChunkerAvgSize, &cfg.ChunkerAvgSize},
+	} {
+		if size.value == "" {
+			continue
+		}
+		v, err := ui.ParseBytes(size.value)
+		if err != nil || v <= 0 {
+			return nil, errors.Fatalf("invalid size %q for %v", size.value, size.flag)
+		}
+		*size.dst = uint(v)
+	}
+
+	// unset sizes use the defaults
+	params := cfg.ChunkerParams()
+	if err := params.Check(); err != nil {
+		return nil, errors.Fatalf("invalid chunk sizes: %v", err)
+	}
+	return &params, nil
 }
 
 type initSuccess struct {
@@ -54,3 +54,86 @@ func TestInitCopyChunkerParams(t *testing.T) {
 		"expected equal chunker polynomials, got %v expected %v", repo.Config().ChunkerPolynomial,
 		otherRepo.Config().ChunkerPolynomial)
 }
+
+func TestInitChunkerSizes(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+	env2, cleanup2 := withTestEnvironment(t)
+	defer cleanup2()
+
+	err := runInit(context.TODO(), InitOptions{ChunkerAvgSize: "300K"}, env2.gopts, nil)
+	rtest.Assert(t, err != nil, "expected average size which is not a power of two to fail")
+	err = runInit(context.TODO(), InitOptions{ChunkerMinSize: "64K", RepositoryVersion: "2"}, env2.gopts, nil)
+	rtest.Assert(t, err != nil, "expected custom chunk sizes with repository version 2 to fail")
+
+	rtest.OK(t, runInit(context.TODO(), InitOptions{ChunkerMinSize: "64K", ChunkerAvgSize: "256K", ChunkerMaxSize: "2M"}, env2.gopts, nil))
+	rtest.SetupTarTestFixture(t, env2.testdata, filepath.Join("testdata", "backup-data.tar.gz"))
+	testRunBackup(t, "", []string{env2.testdata}, BackupOptions{}, env2.gopts)
+	testRunCheck(t, env2.gopts)
+
+	otherRepo, err := OpenRepository(context.TODO(), env2.gopts)
+	rtest.OK(t, err)
+	params := otherRepo.Config().ChunkerParams()
+	rtest.Equals(t, restic.ChunkerParams{Pol: params.Pol, MinSize: 64 * 1024, AvgSize: 256 * 1024, MaxSize: 2 * 1024 * 1024}, params)
+	rtest.Equals(t, uint(restic.ExtendedRepoVersion), otherRepo.Config().Version)
+
+	// copying the chunker parameters includes the chunk sizes
+	initOpts := InitOptions{
+		secondaryRepoOptions: secondaryRepoOptions{
+			Repo:     env2.gopts.Repo,
+			password: env2.gopts.password,
+		},
+		CopyChunkerParameters: true,
+	}
+	rtest.OK(t, runInit(context.TODO(), initOpts, env.gopts, nil))
+	repo, err := OpenRepository(context.TODO(), env.gopts)
+	rtest.OK(t, err)
+	rtest.Equals(t, params, repo.Config().ChunkerParams())
+}
+
+func TestInitAppendOnly(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	err := runInit(context.TODO(), InitOptions{AppendOnly: true, RepositoryVersion: "2"}, env.gopts, nil)
+	rtest.Assert(t, err != nil, "expected append-only repository with version 2 to fail")
+
+	rtest.OK(t, runInit(context.TODO(), InitOptions{AppendOnly: true}, env.gopts, nil))
+	rtest.SetupTarTestFixture(t, env.testdata, filepath.Join("testdata", "backup-data.tar.gz"))
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{}, env.gopts)
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{}, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 2)
+
+	// regular keys must not remove snapshots
+	// the key list is loaded more than once when adding keys
+	env.gopts.backendTestHook = nil
+	testRunKeyAddNewKey(t, "user", env.gopts)
+	userGopts := env.gopts
+	userGopts.password = "user"
+	rtest.Assert(t, testRunForgetMayFail(userGopts, ForgetOptions{}, snapshotIDs[0].String()) != nil,
+		"expected forget using a regular key to fail")
+	testListSnapshots(t, env.gopts, 2)
+
+	testRunPruneMustFail(t, userGopts, PruneOptions{MaxUnused: "0"})
+
+	// the admin key records the removals in the journal
+	testRunForget(t, env.gopts, ForgetOptions{}, snapshotIDs[0].String())
+	testListSnapshots(t, env.gopts, 1)
+	rtest.Equals(t, 1, len(testRunList(t, "journal", env.gopts)))
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0"})
+	rtest.Assert(t, len(testRunList(t, "journal", env.gopts)) > 1, "expected prune to add journal entries")
+	testRunCheck(t, env.gopts)
+}
+
+func TestInitAsymmetricVersion(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	err := runInit(context.TODO(), InitOptions{Asymmetric: true, RepositoryVersion: "2"}, env.gopts, nil)
+	rtest.Assert(t, err != nil, "expected asymmetric repository with version 2 to fail")
+
+	rtest.OK(t, runInit(context.TODO(), InitOptions{Asymmetric: true}, env.gopts, nil))
+	repo, err := OpenRepository(context.TODO(), env.gopts)
+	rtest.OK(t, err)
+	rtest.Equals(t, uint(restic.ExtendedRepoVersion), repo.Config().Version)
+}
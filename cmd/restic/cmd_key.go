@@ -21,6 +21,7 @@ per repository.
 		newKeyListCommand(),
 		newKeyPasswdCommand(),
 		newKeyRemoveCommand(),
+		newKeyRotateMasterCommand(),
 	)
 	return cmd
 }
@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
+	"github.com/restic/restic/internal/crypto"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/repository"
 	"github.com/spf13/cobra"
@@ -19,6 +22,26 @@ func newKeyAddCommand() *cobra.Command {
 		Long: `
 The "add" sub-command creates a new key and validates the key. Returns the new key ID.
 
+For append-only repositories, --admin creates an admin key which is allowed to
+remove files. This requires that the current key is an admin key.
+
+The option --kdf selects the key derivation function which derives the key from
+the password, either "scrypt" (default) or "argon2id".
+
+For repositories created using "init --asymmetric", --write-only creates a key
+which can add new backups, but cannot decrypt data or snapshots. Keys added
+using a write-only key are always write-only.
+
+The option --capabilities restricts the operations allowed using the new key to
+a comma separated list of "backup-only" (create backups, but not read the
+content of files), "read-only" (read snapshots and files, but not modify the
+repository) and "admin" (all operations, including removing snapshots, data
+and keys). Only admin keys can run commands like "forget", "prune" or
+"key remove". Keys added using a key without the admin capability inherit its
+capabilities. The key cannot be used to open the repository after the time
+specified using --expires, and it cannot expire later than the current key.
+A description of the key can be set using --label.
+
 EXIT STATUS
 ===========
 
@@ -35,6 +58,12 @@ Exit status is 12 if the password is incorrect.
 	}
 
 	opts.Add(cmd.Flags())
+	cmd.Flags().BoolVar(&opts.Admin, "admin", false, "create an admin key for an append-only repository")
+	cmd.Flags().StringVar(&opts.KDF, "kdf", crypto.KDFScrypt, "key derivation `function` for the new key, either \"scrypt\" or \"argon2id\"")
+	cmd.Flags().BoolVar(&opts.WriteOnly, "write-only", false, "create a key which cannot decrypt data of an asymmetric repository")
+	cmd.Flags().StringVar(&opts.Label, "label", "", "a description of the new key")
+	cmd.Flags().StringVar(&opts.Expires, "expires", "", "the new key cannot be used after `time` (format: YYYY-MM-DD [hh:mm[:ss]])")
+	cmd.Flags().StringVar(&opts.Capabilities, "capabilities", "", "comma separated `list` of capabilities of the new key (backup-only, read-only, admin)")
 	return cmd
 }
 
@@ -43,6 +72,12 @@ type KeyAddOptions struct {
 	InsecureNoPassword bool
 	Username           string
 	Hostname           string
+	Admin              bool
+	KDF                string
+	WriteOnly          bool
+	Label              string
+	Expires            string
+	Capabilities       string
 }
 
 func (opts *KeyAddOptions) Add(flags *pflag.FlagSet) {
@@ -56,6 +91,9 @@ func runKeyAdd(ctx context.Context, gopts GlobalOptions, opts KeyAddOptions, arg
 	if len(args) > 0 {
 		return fmt.Errorf("the key add command expects no arguments, only options - please see `restic help key add` for usage and flags")
 	}
+	if err := verifyKDF(opts.KDF); err != nil {
+		return err
+	}
 
 	ctx, repo, unlock, err := openWithAppendLock(ctx, gopts, false)
 	if err != nil {
@@ -67,12 +105,74 @@ func runKeyAdd(ctx context.Context, gopts GlobalOptions, opts KeyAddOptions, arg
 }
 
 func addKey(ctx context.Context, repo *repository.Repository, gopts GlobalOptions, opts KeyAddOptions) error {
+	keyOpts := repository.KeyOptions{
+		Username: opts.Username,
+		Hostname: opts.Hostname,
+		KDF:      opts.KDF,
+		Label:    opts.Label,
+	}
+
+	// the new key must not have more capabilities than the current key
+	if opts.Capabilities != "" {
+		caps, err := repository.ParseCapabilities(opts.Capabilities)
+		if err != nil {
+			return errors.Fatal(err.Error())
+		}
+		if !repo.Capabilities().Contains(caps) {
+			return errors.Fatal("the new key cannot have capabilities which the current key lacks")
+		}
+		keyOpts.Capabilities = caps
+	} else if !repo.Capabilities().Has(repository.CapabilityAdmin) {
+		keyOpts.Capabilities = repo.Capabilities()
+	}
+
+	if opts.Expires != "" {
+		expires, err := parseTime(opts.Expires)
+		if err != nil {
+			return errors.Fatal(err.Error())
+		}
+		if expires.Before(time.Now()) {
+			return errors.Fatal("--expires must be in the future")
+		}
+		keyOpts.Expires = expires
+	}
+	if current := repo.KeyExpires(); current != nil {
+		if opts.Expires != "" && keyOpts.Expires.After(*current) {
+			return errors.Fatalf("the new key cannot expire after the current key, which expires on %v", current.Local().Format(TimeFormat))
+		}
+		if opts.Expires == "" {
+			keyOpts.Expires = *current
+		}
+	}
+
+	if opts.Admin || (repo.Config().AppendOnly && slices.Contains(keyOpts.Capabilities, repository.CapabilityAdmin)) {
+		if !repo.Config().AppendOnly {
+			return errors.Fatal("--admin requires an append-only repository")
+		}
+		keyOpts.Admin = repo.AdminKey()
+		if keyOpts.Admin == nil {
+			return errors.Fatal("only admin keys can add admin keys")
+		}
+	}
+
+	template := repo.Key()
+	if opts.WriteOnly {
+		if repo.Config().DataPublicKey == "" {
+			return errors.Fatal("--write-only requires a repository created using --asymmetric")
+		}
+		var err error
+		template, err = template.WriteOnly()
+		if err != nil {
+			return err
+		}
+	}
+
 	pw, err := getNewPassword(ctx, gopts, opts.NewPasswordFile, opts.InsecureNoPassword)
 	if err != nil {
 		return err
 	}
 
-	id, err := repository.AddKey(ctx, repo, pw, opts.Username, opts.Hostname, repo.Key())
+	id, err := repository.AddKey(ctx, repo, pw, template, keyOpts)
 	if err != nil {
 		return errors.Fatalf("creating new key failed: %v\n", err)
 	}
@@ -87,6 +187,16 @@ func addKey(ctx context.Context, repo *repository.Repository, gopts GlobalOption
 	return nil
 }
 
+// verifyKDF checks that kdf is the name of a supported key derivation function.
+func verifyKDF(kdf string) error {
+	switch kdf {
+	case "", crypto.KDFScrypt, crypto.KDFArgon2id:
+		return nil
+	default:
+		return errors.Fatalf("unsupported key derivation function %q, use %q or %q", kdf, crypto.KDFScrypt, crypto.KDFArgon2id)
+	}
+}
+
 // testKeyNewPassword is used to set a new password during integration testing.
 var testKeyNewPassword string
 
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -12,6 +13,7 @@ import (
 	"github.com/restic/restic/internal/backend"
 	"github.com/restic/restic/internal/repository"
 	rtest "github.com/restic/restic/internal/test"
+	"github.com/restic/restic/internal/ui/termstatus"
 )
 
 func testRunKeyListOtherIDs(t testing.TB, gopts GlobalOptions) []string {
@@ -212,3 +214,233 @@ func TestKeyCommandInvalidArguments(t *testing.T) {
 	t.Log(err)
 	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "one argument"), "unexpected error for key remove: %v", err)
 }
+
+func testRunKeyListKDFs(t testing.TB, gopts GlobalOptions) map[string]string {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+		return runKeyList(context.TODO(), gopts, []string{})
+	})
+	rtest.OK(t, err)
+
+	var keys []struct {
+		ID  string `json:"id"`
+		KDF string `json:"kdf"`
+	}
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &keys))
+	kdfs := make(map[string]string)
+	for _, key := range keys {
+		kdfs[key.ID] = key.KDF
+	}
+	return kdfs
+}
+
+func TestKeyKDF(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	// must list keys more than once
+	env.gopts.backendTestHook = nil
+	defer cleanup()
+
+	rtest.OK(t, runInit(context.TODO(), InitOptions{KDF: "argon2id"}, env.gopts, nil))
+	for _, kdf := range testRunKeyListKDFs(t, env.gopts) {
+		rtest.Equals(t, "argon2id", kdf)
+	}
+
+	testKeyNewPassword = "scrypt password"
+	defer func() {
+		testKeyNewPassword = ""
+	}()
+	rtest.OK(t, runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{KDF: "scrypt"}, []string{}))
+	err := runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{KDF: "pbkdf2"}, []string{})
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "unsupported key derivation function"), "unexpected error: %v", err)
+
+	// the new password uses the KDF of the replaced key by default
+	env.gopts.password = testKeyNewPassword
+	testKeyNewPassword = "argon2id password"
+	rtest.OK(t, runKeyPasswd(context.TODO(), env.gopts, KeyPasswdOptions{}, []string{}))
+	env.gopts.password = testKeyNewPassword
+	counts := make(map[string]int)
+	for _, kdf := range testRunKeyListKDFs(t, env.gopts) {
+		counts[kdf]++
+	}
+	rtest.Equals(t, map[string]int{"argon2id": 1, "scrypt": 1}, counts)
+
+	testKeyNewPassword = "another argon2id password"
+	rtest.OK(t, runKeyPasswd(context.TODO(), env.gopts, KeyPasswdOptions{KeyAddOptions: KeyAddOptions{KDF: "argon2id"}}, []string{}))
+	env.gopts.password = testKeyNewPassword
+	counts = make(map[string]int)
+	for _, kdf := range testRunKeyListKDFs(t, env.gopts) {
+		counts[kdf]++
+	}
+	rtest.Equals(t, map[string]int{"argon2id": 2}, counts)
+	testRunCheck(t, env.gopts)
+}
+
+func TestKeyWriteOnly(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	// must list keys more than once
+	env.gopts.backendTestHook = nil
+	defer cleanup()
+
+	testKeyNewPassword = "write-only password"
+	defer func() {
+		testKeyNewPassword = ""
+	}()
+
+	rtest.OK(t, runInit(context.TODO(), InitOptions{}, env.gopts, nil))
+	err := runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{WriteOnly: true}, []string{})
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "--asymmetric"), "unexpected error: %v", err)
+}
+
+func TestKeyWriteOnlyAsymmetric(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	env.gopts.backendTestHook = nil
+	defer cleanup()
+
+	rtest.OK(t, runInit(context.TODO(), InitOptions{Asymmetric: true}, env.gopts, nil))
+	rtest.SetupTarTestFixture(t, env.testdata, filepath.Join("testdata", "backup-data.tar.gz"))
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{}, env.gopts)
+
+	testKeyNewPassword = "write-only password"
+	defer func() {
+		testKeyNewPassword = ""
+	}()
+	rtest.OK(t, runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{WriteOnly: true}, []string{}))
+
+	writeOnly := env.gopts
+	writeOnly.password = testKeyNewPassword
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{}, writeOnly)
+	snapshotIDs := testListSnapshots(t, writeOnly, 2)
+
+	err := testRunBackupAssumeFailure(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{Parent: snapshotIDs[0].String()}, writeOnly)
+	rtest.Assert(t, err != nil, "backup with --parent and a write-only key succeeded")
+
+	// only the full key can decrypt the snapshots
+	restoreDir := filepath.Join(env.base, "restore")
+	err = testRunRestoreAssumeFailure(snapshotIDs[0].String(), RestoreOptions{Target: restoreDir}, writeOnly)
+	rtest.Assert(t, err != nil, "restore with a write-only key succeeded")
+	testRunRestore(t, env.gopts, restoreDir, snapshotIDs[0].String())
+	testRunCheck(t, env.gopts)
+}
+
+func TestKeyCapabilities(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	env.gopts.backendTestHook = nil
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{}, env.gopts)
+
+	testKeyNewPassword = "backup password"
+	defer func() {
+		testKeyNewPassword = ""
+	}()
+	rtest.OK(t, runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{
+		Label:        "laptop",
+		Expires:      "2999-01-01",
+		Capabilities: "backup-only",
+	}, []string{}))
+	err := runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{Capabilities: "write"}, []string{})
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "invalid capability"), "unexpected error: %v", err)
+	err = runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{Expires: "2000-01-01"}, []string{})
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "future"), "unexpected error: %v", err)
+
+	buf, err := withCaptureStdout(func() error {
+		gopts := env.gopts
+		gopts.JSON = true
+		return runKeyList(context.TODO(), gopts, []string{})
+	})
+	rtest.OK(t, err)
+	var keys []struct {
+		Current      bool     `json:"current"`
+		Label        string   `json:"label"`
+		Expires      string   `json:"expires"`
+		Capabilities []string `json:"capabilities"`
+	}
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &keys))
+	rtest.Equals(t, 2, len(keys))
+	for _, key := range keys {
+		if key.Current {
+			rtest.Equals(t, []string{"admin"}, key.Capabilities)
+			continue
+		}
+		rtest.Equals(t, "laptop", key.Label)
+		rtest.Assert(t, strings.HasPrefix(key.Expires, "2999-01-01"), "unexpected expiry time %q", key.Expires)
+		rtest.Equals(t, []string{"backup-only"}, key.Capabilities)
+	}
+
+	// the backup-only key can create backups, but neither restore nor remove anything
+	backupOnly := env.gopts
+	backupOnly.password = testKeyNewPassword
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{}, backupOnly)
+	snapshotIDs := testListSnapshots(t, backupOnly, 2)
+
+	err = testRunForgetMayFail(backupOnly, ForgetOptions{}, snapshotIDs[0].String())
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "admin"), "unexpected error: %v", err)
+	err = testRunRestoreAssumeFailure(snapshotIDs[0].String(), RestoreOptions{Target: filepath.Join(env.base, "restore")}, backupOnly)
+	rtest.Assert(t, err != nil, "restore with a backup-only key succeeded")
+	otherIDs := testRunKeyListOtherIDs(t, backupOnly)
+	rtest.Equals(t, 1, len(otherIDs))
+	err = runKeyRemove(context.TODO(), backupOnly, otherIDs)
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "admin"), "unexpected error: %v", err)
+
+	// keys added using the backup-only key cannot have more capabilities
+	testKeyNewPassword = "another backup password"
+	err = runKeyAdd(context.TODO(), backupOnly, KeyAddOptions{Capabilities: "admin"}, []string{})
+	rtest.Assert(t, err != nil, "adding an admin key using a backup-only key succeeded")
+	err = runKeyAdd(context.TODO(), backupOnly, KeyAddOptions{Expires: "3000-01-01"}, []string{})
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "expire after"), "unexpected error: %v", err)
+	rtest.OK(t, runKeyAdd(context.TODO(), backupOnly, KeyAddOptions{}, []string{}))
+	inherited := env.gopts
+	inherited.password = testKeyNewPassword
+	err = testRunForgetMayFail(inherited, ForgetOptions{}, snapshotIDs[0].String())
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "admin"), "unexpected error: %v", err)
+
+	testRunForget(t, env.gopts, ForgetOptions{}, snapshotIDs[0].String())
+	testRunCheck(t, env.gopts)
+}
+
+func testRunKeyRotateMaster(t testing.TB, gopts GlobalOptions) {
+	rtest.OK(t, withTermStatus(gopts, func(ctx context.Context, term *termstatus.Terminal) error {
+		return runKeyRotateMaster(ctx, gopts, KeyRotateMasterOptions{}, []string{}, term)
+	}))
+}
+
+func TestKeyRotateMaster(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	env.gopts.backendTestHook = nil
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{}, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 1)
+
+	testKeyNewPassword = "other password"
+	defer func() {
+		testKeyNewPassword = ""
+	}()
+	rtest.OK(t, runKeyAdd(context.TODO(), env.gopts, KeyAddOptions{Label: "other"}, []string{}))
+
+	testKeyNewPassword = "rotated password"
+	testRunKeyRotateMaster(t, env.gopts)
+
+	// the old keys were removed
+	err := runKeyList(context.TODO(), env.gopts, []string{})
+	rtest.Assert(t, err != nil, "old password can still open the repository")
+	rotated := env.gopts
+	rotated.password = testKeyNewPassword
+	rtest.Equals(t, 0, len(testRunKeyListOtherIDs(t, rotated)))
+
+	newIDs := testListSnapshots(t, rotated, 1)
+	rtest.Assert(t, newIDs[0] != snapshotIDs[0], "snapshot was not re-encrypted")
+	testRunCheck(t, rotated)
+	testRunRestore(t, rotated, filepath.Join(env.base, "restore"), newIDs[0].String())
+	diff := directoriesContentsDiff(env.testdata, filepath.Join(env.base, "restore", "testdata"))
+	rtest.Assert(t, diff == "", "directories are not equal %v", diff)
+
+	// running the command again starts a new rotation
+	testKeyNewPassword = "rotated again"
+	testRunKeyRotateMaster(t, rotated)
+	rotated.password = testKeyNewPassword
+	testListSnapshots(t, rotated, 1)
+	testRunCheck(t, rotated)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
@@ -19,7 +20,11 @@ func newKeyListCommand() *cobra.Command {
 		Long: `
 The "list" sub-command lists all the keys (passwords) associated with the repository.
 Returns the key ID, username, hostname, created time and if it's the current key being
-used to access the repository.
+used to access the repository. The "KDF" column shows the key derivation function
+used by each key. The "Capabilities" column shows which operations a key is allowed
+to perform, this includes whether a key is an admin key of an append-only repository
+or a write-only key of an asymmetric repository. Labels and expiry times are shown
+if any key has one.
 
 EXIT STATUS
 ===========
@@ -54,16 +59,25 @@ func runKeyList(ctx context.Context, gopts GlobalOptions, args []string) error {
 
 func listKeys(ctx context.Context, s *repository.Repository, gopts GlobalOptions) error {
 	type keyInfo struct {
-		Current  bool   `json:"current"`
-		ID       string `json:"id"`
-		ShortID  string `json:"-"`
-		UserName string `json:"userName"`
-		HostName string `json:"hostName"`
-		Created  string `json:"created"`
+		Current      bool     `json:"current"`
+		ID           string   `json:"id"`
+		ShortID      string   `json:"-"`
+		UserName     string   `json:"userName"`
+		HostName     string   `json:"hostName"`
+		Created      string   `json:"created"`
+		KDF          string   `json:"kdf"`
+		Label        string   `json:"label,omitempty"`
+		Expires      string   `json:"expires,omitempty"`
+		Expired      bool     `json:"expired,omitempty"`
+		Capabilities []string `json:"capabilities"`
+		Admin        bool     `json:"admin,omitempty"`
+		WriteOnly    bool     `json:"writeOnly,omitempty"`
 	}
 
 	var m sync.Mutex
 	var keys []keyInfo
+	var hasLabel, hasExpires bool
+	now := time.Now()
 
 	err := restic.ParallelList(ctx, s, restic.KeyFile, s.Connections(), func(ctx context.Context, id restic.ID, _ int64) error {
 		k, err := repository.LoadKey(ctx, s, id)
@@ -73,17 +87,30 @@ func listKeys(ctx context.Context, s *repository.Repository, gopts GlobalOptions
 		}
 
 		key := keyInfo{
-			Current:  id == s.KeyID(),
-			ID:       id.String(),
-			ShortID:  id.Str(),
-			UserName: k.Username,
-			HostName: k.Hostname,
-			Created:  k.Created.Local().Format(TimeFormat),
+			Current:   id == s.KeyID(),
+			ID:        id.String(),
+			ShortID:   id.Str(),
+			UserName:  k.Username,
+			HostName:  k.Hostname,
+			Created:   k.Created.Local().Format(TimeFormat),
+			KDF:       k.KDF,
+			Label:     k.Label,
+			Expired:   k.Expired(now),
+			Admin:     k.IsAdmin(),
+			WriteOnly: k.WriteOnly,
+		}
+		if k.Expires != nil {
+			key.Expires = k.Expires.Local().Format(TimeFormat)
+		}
+		for _, c := range k.EffectiveCapabilities(s.Config()) {
+			key.Capabilities = append(key.Capabilities, string(c))
 		}
 
 		m.Lock()
 		defer m.Unlock()
 		keys = append(keys, key)
+		hasLabel = hasLabel || key.Label != ""
+		hasExpires = hasExpires || key.Expires != ""
 		return nil
 	})
 
@@ -100,6 +127,14 @@ func listKeys(ctx context.Context, s *repository.Repository, gopts GlobalOptions
 	tab.AddColumn("User", "{{ .UserName }}")
 	tab.AddColumn("Host", "{{ .HostName }}")
 	tab.AddColumn("Created", "{{ .Created }}")
+	tab.AddColumn("KDF", "{{ .KDF }}")
+	tab.AddColumn("Capabilities", "{{ join .Capabilities \",\" }}")
+	if hasLabel {
+		tab.AddColumn("Label", "{{ .Label }}")
+	}
+	if hasExpires {
+		tab.AddColumn("Expires", "{{ .Expires }}{{if .Expired}} (expired){{end}}")
+	}
 
 	for _, key := range keys {
 		tab.AddRow(key)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -20,6 +21,10 @@ func newKeyPasswdCommand() *cobra.Command {
 The "passwd" sub-command creates a new key, validates the key and remove the old key ID.
 Returns the new key ID. 
 
+The new key uses the same key derivation function as the old key, unless a
+different one is selected using --kdf. This allows changing existing keys from
+"scrypt" to "argon2id".
+
 EXIT STATUS
 ===========
 
@@ -45,12 +50,16 @@ type KeyPasswdOptions struct {
 
 func (opts *KeyPasswdOptions) AddFlags(flags *pflag.FlagSet) {
 	opts.KeyAddOptions.Add(flags)
+	flags.StringVar(&opts.KDF, "kdf", "", "key derivation `function` for the new key, either \"scrypt\" or \"argon2id\" (default: same as the current key)")
 }
 
 func runKeyPasswd(ctx context.Context, gopts GlobalOptions, opts KeyPasswdOptions, args []string) error {
 	if len(args) > 0 {
 		return fmt.Errorf("the key passwd command expects no arguments, only options - please see `restic help key passwd` for usage and flags")
 	}
+	if err := verifyKDF(opts.KDF); err != nil {
+		return err
+	}
 
 	ctx, repo, unlock, err := openWithExclusiveLock(ctx, gopts, false)
 	if err != nil {
@@ -62,12 +71,35 @@ func runKeyPasswd(ctx context.Context, gopts GlobalOptions, opts KeyPasswdOption
 }
 
 func changePassword(ctx context.Context, repo *repository.Repository, gopts GlobalOptions, opts KeyPasswdOptions) error {
+	// the old key is removed afterwards
+	if err := repo.CheckRemove(restic.KeyFile); err != nil {
+		return errors.Fatal(err.Error())
+	}
+
+	oldKey, err := repository.LoadKey(ctx, repo, repo.KeyID())
+	if err != nil {
+		return err
+	}
+	// the new key replaces the old one, thus it keeps the metadata
+	keyOpts := repository.KeyOptions{
+		KDF:          opts.KDF,
+		Label:        oldKey.Label,
+		Capabilities: oldKey.Capabilities,
+		Admin:        repo.AdminKey(),
+	}
+	if keyOpts.KDF == "" {
+		keyOpts.KDF = oldKey.KDF
+	}
+	if oldKey.Expires != nil {
+		keyOpts.Expires = *oldKey.Expires
+	}
+
 	pw, err := getNewPassword(ctx, gopts, opts.NewPasswordFile, opts.InsecureNoPassword)
 	if err != nil {
 		return err
 	}
 
-	id, err := repository.AddKey(ctx, repo, pw, "", "", repo.Key())
+	id, err := repository.AddKey(ctx, repo, pw, repo.Key(), keyOpts)
 	if err != nil {
 		return errors.Fatalf("creating new key failed: %v\n", err)
 	}
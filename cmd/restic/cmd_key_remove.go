@@ -58,6 +58,9 @@ func deleteKey(ctx context.Context, repo *repository.Repository, idPrefix string
 	if id == repo.KeyID() {
 		return errors.Fatal("refusing to remove key currently used to access repository")
 	}
+	if err := repo.CheckRemove(restic.KeyFile); err != nil {
+		return errors.Fatal(err.Error())
+	}
 
 	err = repository.RemoveKey(ctx, repo, id)
 	if err != nil {
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/ui/termstatus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newKeyRotateMasterCommand() *cobra.Command {
+	var opts KeyRotateMasterOptions
+
+	cmd := &cobra.Command{
+		Use:   "rotate-master",
+		Short: "Replace the master key and re-encrypt all data in the repository",
+		Long: `
+The "rotate-master" sub-command replaces the master key of the repository,
+for example after a key file and its password were leaked. Unlike "key passwd",
+which only encrypts the existing master key using a new password, this creates
+a new random master key and re-encrypts all pack files, indexes, snapshots,
+holds and journal files using it. For asymmetric repositories, the key pair is
+replaced as well.
+
+The IDs of blobs stay the same, but snapshots get new IDs. Holds are updated to
+the new snapshot IDs, the "parent" and "original" fields of snapshots still
+refer to the old IDs. The new master key is stored in a new key which uses the
+password specified via --new-password-file or entered interactively, and which
+keeps the label, capabilities, expiry time and key derivation function of the
+current key. Afterwards, all other keys are removed as they still contain the
+old master key, new keys must be added using "key add".
+
+The command can be interrupted at any time. To continue, run the command again
+using the new password. Until the rotation has finished, the repository cannot
+be accessed using other keys.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+Exit status is 10 if the repository does not exist.
+Exit status is 11 if the repository is already locked.
+Exit status is 12 if the password is incorrect.
+	`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			term, cancel := setupTermstatus()
+			defer cancel()
+			return runKeyRotateMaster(cmd.Context(), globalOptions, opts, args, term)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// KeyRotateMasterOptions collects all options for the key rotate-master command.
+type KeyRotateMasterOptions struct {
+	NewPasswordFile    string
+	InsecureNoPassword bool
+}
+
+func (opts *KeyRotateMasterOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&opts.NewPasswordFile, "new-password-file", "", "", "`file` from which to read the new password")
+	flags.BoolVar(&opts.InsecureNoPassword, "new-insecure-no-password", false, "use an empty password for the new key (insecure)")
+}
+
+func runKeyRotateMaster(ctx context.Context, gopts GlobalOptions, opts KeyRotateMasterOptions, args []string, term *termstatus.Terminal) error {
+	if len(args) > 0 {
+		return fmt.Errorf("the key rotate-master command expects no arguments, only options - please see `restic help key rotate-master` for usage and flags")
+	}
+
+	// the password is required again to create the final key if an
+	// interrupted rotation is resumed
+	var err error
+	gopts.password, err = ReadPassword(ctx, gopts, "enter password for repository: ")
+	if err != nil {
+		return err
+	}
+
+	ctx, repo, unlock, err := openWithExclusiveLock(ctx, gopts, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	printer := newTerminalProgressPrinter(gopts.verbosity, term)
+
+	err = repository.RotateMasterKey(ctx, repo, repository.RotateMasterKeyOptions{
+		Password: gopts.password,
+		NewPassword: func() (string, error) {
+			return getNewPassword(ctx, gopts, opts.NewPasswordFile, opts.InsecureNoPassword)
+		},
+	}, printer)
+	if err != nil {
+		return err
+	}
+
+	Verbosef("saved new key as %s\n", repo.KeyID())
+	return nil
+}
@@ -68,6 +68,11 @@ The default path templates are:
     "hosts/%h/%T"
     "tags/%t/%T"
 
+With the default path templates, the directory "timeline" additionally contains
+a merged view of all snapshots for each host. Each file is listed once for each
+distinct content as "name@time", using the time of the first snapshot which
+contains that content.
+
 EXIT STATUS
 ===========
 
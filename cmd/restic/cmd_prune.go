@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"math"
+	"os"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
@@ -29,6 +32,14 @@ func newPruneCommand() *cobra.Command {
 The "prune" command checks the repository and removes data that is not
 referenced and therefore not needed any more.
 
+The plan of which packs to keep, repack and delete can be written to a file
+using "--save-plan" without modifying the repository. After reviewing the plan,
+it can be executed using "--execute-plan". The execution fails if index files
+were added or removed, or if snapshots were created in the meantime. Before
+executing a plan, the snapshots are loaded again to verify that the plan does
+not remove any data which is still in use. Options which influence the planning
+are ignored when executing a plan.
+
 EXIT STATUS
 ===========
 
@@ -70,11 +81,16 @@ type PruneOptions struct {
 
 	SmallPackSize  string
 	SmallPackBytes uint64
+
+	SavePlan    string
+	ExecutePlan string
 }
 
 func (opts *PruneOptions) AddFlags(f *pflag.FlagSet) {
 	opts.AddLimitedFlags(f)
 	f.BoolVarP(&opts.DryRun, "dry-run", "n", false, "do not modify the repository, just print what would be done")
+	f.StringVar(&opts.SavePlan, "save-plan", "", "write the prune plan to `file` without modifying the repository")
+	f.StringVar(&opts.ExecutePlan, "execute-plan", "", "execute the prune plan stored in `file`")
 	f.StringVarP(&opts.UnsafeNoSpaceRecovery, "unsafe-recover-no-free-space", "", "", "UNSAFE, READ THE DOCUMENTATION BEFORE USING! Try to recover a repository stuck with no free space. Do not use without trying out 'prune --max-repack-size 0' first.")
 }
 
@@ -152,6 +168,13 @@ func verifyPruneOptions(opts *PruneOptions) error {
 		opts.RepackSmall = true
 	}
 
+	if opts.SavePlan != "" && opts.ExecutePlan != "" {
+		return errors.Fatal("--save-plan and --execute-plan are mutually exclusive")
+	}
+	if opts.ExecutePlan != "" && opts.UnsafeNoSpaceRecovery != "" {
+		return errors.Fatal("--execute-plan and --unsafe-recover-no-free-space are mutually exclusive")
+	}
+
 	return nil
 }
 
@@ -165,6 +188,14 @@ func runPrune(ctx context.Context, opts PruneOptions, gopts GlobalOptions, term
 		return errors.Fatal("disabled compression and `--repack-uncompressed` are mutually exclusive")
 	}
 
+	if opts.SavePlan != "" {
+		if gopts.NoLock {
+			// the plan must match the state of the repository it is saved with
+			return errors.Fatal("--no-lock cannot be used with --save-plan")
+		}
+		opts.DryRun = true
+	}
+
 	if gopts.NoLock && !opts.DryRun {
 		return errors.Fatal("--no-lock is only applicable in combination with --dry-run for prune command")
 	}
@@ -187,11 +218,16 @@ func runPrune(ctx context.Context, opts PruneOptions, gopts GlobalOptions, term
 }
 
 func runPruneWithRepo(ctx context.Context, opts PruneOptions, gopts GlobalOptions, repo *repository.Repository, ignoreSnapshots restic.IDSet, term *termstatus.Terminal) error {
-	if repo.Cache() == nil {
+	if repo.Cache() == nil && !gopts.JSON {
 		Print("warning: running prune without a cache, this may be very slow!\n")
 	}
 
-	printer := newTerminalProgressPrinter(gopts.verbosity, term)
+	var printer progress.Printer
+	if !gopts.JSON {
+		printer = newTerminalProgressPrinter(gopts.verbosity, term)
+	} else {
+		printer = newJSONErrorPrinter(term)
+	}
 
 	printer.P("loading indexes...\n")
 	// loading the index before the snapshots is ok, as we use an exclusive lock here
@@ -214,9 +250,18 @@ func runPruneWithRepo(ctx context.Context, opts PruneOptions, gopts GlobalOption
 		RepackUncompressed:  opts.RepackUncompressed,
 	}
 
-	plan, err := repository.PlanPrune(ctx, popts, repo, func(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet) error {
-		return getUsedBlobs(ctx, repo, usedBlobs, ignoreSnapshots, printer)
-	}, printer)
+	var plan *repository.PrunePlan
+	// snapshots used to plan the prune
+	snapshotIDs := restic.NewIDSet()
+	if opts.ExecutePlan != "" {
+		plan, err = loadPrunePlan(ctx, opts.ExecutePlan, popts, repo, func(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet) error {
+			return getUsedBlobs(ctx, repo, usedBlobs, ignoreSnapshots, snapshotIDs, printer)
+		}, snapshotIDs)
+	} else {
+		plan, err = repository.PlanPrune(ctx, popts, repo, func(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet) error {
+			return getUsedBlobs(ctx, repo, usedBlobs, ignoreSnapshots, snapshotIDs, printer)
+		}, printer)
+	}
 	if err != nil {
 		return err
 	}
@@ -224,6 +269,13 @@ func runPruneWithRepo(ctx context.Context, opts PruneOptions, gopts GlobalOption
 		return ctx.Err()
 	}
 
+	if opts.SavePlan != "" {
+		if err := savePrunePlan(opts.SavePlan, plan, snapshotIDs); err != nil {
+			return err
+		}
+		printer.P("saved prune plan to %v\n", opts.SavePlan)
+	}
+
 	if popts.DryRun {
 		printer.P("\nWould have made the following changes:")
 	}
@@ -236,7 +288,54 @@ func runPruneWithRepo(ctx context.Context, opts PruneOptions, gopts GlobalOption
 	// Trigger GC to reset garbage collection threshold
 	runtime.GC()
 
-	return plan.Execute(ctx, printer)
+	err = plan.Execute(ctx, printer)
+	if err != nil {
+		return err
+	}
+
+	if gopts.JSON {
+		term.Print(ui.ToJSONString(pruneSummary{
+			MessageType: "summary",
+			DryRun:      popts.DryRun,
+			Stats:       plan.Stats(),
+		}))
+	}
+	return nil
+}
+
+// pruneSummary is printed at the end of prune if JSON output is requested.
+type pruneSummary struct {
+	MessageType string                `json:"message_type"` // "summary"
+	DryRun      bool                  `json:"dry_run"`
+	Stats       repository.PruneStats `json:"stats"`
+}
+
+func savePrunePlan(filename string, plan *repository.PrunePlan, snapshots restic.IDSet) error {
+	saved, err := plan.Save(snapshots)
+	if err != nil {
+		return errors.Fatalf("cannot save prune plan: %v", err)
+	}
+	buf, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(buf, '\n'), 0o600)
+}
+
+func loadPrunePlan(ctx context.Context, filename string, opts repository.PruneOptions, repo *repository.Repository, usedBlobs func(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet) error, snapshots restic.IDSet) (*repository.PrunePlan, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Fatalf("cannot read prune plan: %v", err)
+	}
+	var saved repository.SavedPrunePlan
+	if err := json.Unmarshal(buf, &saved); err != nil {
+		return nil, errors.Fatalf("cannot parse prune plan %v: %v", filename, err)
+	}
+	plan, err := repository.LoadPrunePlan(ctx, opts, repo, &saved, usedBlobs, snapshots)
+	if err != nil {
+		return nil, errors.Fatalf("cannot execute prune plan: %v", err)
+	}
+	return plan, nil
 }
 
 // printPruneStats prints out the statistics
@@ -281,7 +380,9 @@ func printPruneStats(printer progress.Printer, stats repository.PruneStats) erro
 	return nil
 }
 
-func getUsedBlobs(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet, ignoreSnapshots restic.IDSet, printer progress.Printer) error {
+// getUsedBlobs adds all blobs referenced by the snapshots to usedBlobs and
+// the IDs of those snapshots to snapshotIDs.
+func getUsedBlobs(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet, ignoreSnapshots restic.IDSet, snapshotIDs restic.IDSet, printer progress.Printer) error {
 	var snapshotTrees restic.IDs
 	printer.P("loading all snapshots...\n")
 	err := restic.ForAllSnapshots(ctx, repo, repo, ignoreSnapshots,
@@ -292,12 +393,25 @@ func getUsedBlobs(ctx context.Context, repo restic.Repository, usedBlobs restic.
 			}
 			debug.Log("add snapshot %v (tree %v)", id, *sn.Tree)
 			snapshotTrees = append(snapshotTrees, *sn.Tree)
+			snapshotIDs.Insert(id)
 			return nil
 		})
 	if err != nil {
 		return errors.Fatalf("failed loading snapshot: %v", err)
 	}
 
+	// The data of a held snapshot which was removed by other means can still be
+	// recovered as long as it is not pruned.
+	holds, err := restic.LoadHolds(ctx, repo, repo, time.Now())
+	if err != nil {
+		return errors.Fatalf("failed loading holds: %v", err)
+	}
+	for id := range holds {
+		if !snapshotIDs.Has(id) {
+			return errors.Fatalf("snapshot %v is held but missing, refusing to prune", id.Str())
+		}
+	}
+
 	printer.P("finding data that is still in use for %d snapshots\n", len(snapshotTrees))
 
 	bar := printer.NewCounter("snapshots")
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"path/filepath"
@@ -265,3 +266,38 @@ func TestPruneRepackSmallerThanSmoke(t *testing.T) {
 		MaxUnused:     "5%",
 	})
 }
+
+func TestPruneSavedPlan(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	createPrunableRepo(t, env)
+	planFile := filepath.Join(env.base, "plan.json")
+	packsBefore := listPacks(env.gopts, t)
+
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0%", SavePlan: planFile})
+	rtest.Assert(t, listPacks(env.gopts, t).Equals(packsBefore), "saving a plan must not modify the repository")
+
+	buf := bytes.NewBuffer(nil)
+	gopts := env.gopts
+	gopts.JSON = true
+	gopts.stdout = buf
+	rtest.OK(t, testRunPruneOutput(gopts, PruneOptions{MaxUnused: "5%", ExecutePlan: planFile, DryRun: true}))
+	var summary pruneSummary
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &summary))
+	rtest.Equals(t, "summary", summary.MessageType)
+	rtest.Assert(t, summary.DryRun, "expected dry run")
+	rtest.Assert(t, summary.Stats.Packs.Remove+summary.Stats.Packs.Repack > 0, "plan does not modify the repository: %v", summary.Stats)
+
+	// a new snapshot could use data which the plan removes
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, BackupOptions{}, env.gopts)
+	testRunPruneMustFail(t, env.gopts, PruneOptions{MaxUnused: "5%", ExecutePlan: planFile})
+
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0%", SavePlan: planFile})
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "5%", ExecutePlan: planFile})
+	testRunCheck(t, env.gopts)
+	rtest.Assert(t, !listPacks(env.gopts, t).Equals(packsBefore), "plan was not executed")
+
+	// the plan cannot be executed twice
+	testRunPruneMustFail(t, env.gopts, PruneOptions{MaxUnused: "5%", ExecutePlan: planFile})
+}
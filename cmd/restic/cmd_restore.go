@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/restic/restic/hostinger"
@@ -35,6 +39,20 @@ repository.
 To only restore a specific subfolder, you can use the "snapshotID:subfolder"
 syntax, where "subfolder" is a path within the snapshot.
 
+The --manifest option writes a JSON lines file with one record per restored,
+overwritten, unchanged, skipped, deleted or failed item. Each record contains
+the action, node type, size, how the file content was handled and the error,
+if any.
+
+To restore a single file or directory to a given location, use
+"--from-node snapshotID:path" together with "--to". The item is restored
+exactly as it is stored in the snapshot, for example after looking it up using
+the "history" or "find" command.
+
+The --as-of option selects the newest snapshot created before the given time
+instead of the latest one. It can only be used with the snapshotID "latest" and
+takes the --host, --tag and --path filters into account.
+
 EXIT STATUS
 ===========
 
@@ -61,6 +79,7 @@ Exit status is 12 if the password is incorrect.
 type RestoreOptions struct {
 	filter.ExcludePatternOptions
 	filter.IncludePatternOptions
+	ownerMapOptions
 	Target string
 	restic.SnapshotFilter
 	DryRun              bool
@@ -71,6 +90,11 @@ type RestoreOptions struct {
 	ExcludeXattrPattern []string
 	IncludeXattrPattern []string
 	ScopeSymlinks       string
+	Confine             bool
+	Manifest            string
+	FromNode            string
+	To                  string
+	AsOf                string
 }
 
 func (opts *RestoreOptions) AddFlags(f *pflag.FlagSet) {
@@ -81,6 +105,7 @@ func (opts *RestoreOptions) AddFlags(f *pflag.FlagSet) {
 
 	f.StringArrayVar(&opts.ExcludeXattrPattern, "exclude-xattr", nil, "exclude xattr by `pattern` (can be specified multiple times)")
 	f.StringArrayVar(&opts.IncludeXattrPattern, "include-xattr", nil, "include xattr by `pattern` (can be specified multiple times)")
+	opts.ownerMapOptions.AddFlags(f)
 
 	initSingleSnapshotFilter(f, &opts.SnapshotFilter)
 	f.BoolVar(&opts.DryRun, "dry-run", false, "do not write any data, just show what would be done")
@@ -89,6 +114,11 @@ func (opts *RestoreOptions) AddFlags(f *pflag.FlagSet) {
 	f.Var(&opts.Overwrite, "overwrite", "overwrite behavior, one of (always|if-changed|if-newer|never)")
 	f.BoolVar(&opts.Delete, "delete", false, "delete files from target directory if they do not exist in snapshot. Use '--dry-run -vv' to check what would be deleted")
 	f.StringVar(&opts.ScopeSymlinks, "scope-symlinks", "", "do not extract symlinks that are targeting files outside this path")
+	f.BoolVar(&opts.Confine, "confine", false, "resolve all paths relative to the target directory and report items that would be written outside of it (Linux only)")
+	f.StringVar(&opts.Manifest, "manifest", "", "write a JSON lines record for each restored, skipped, deleted or failed item to `file`")
+	f.StringVar(&opts.FromNode, "from-node", "", "restore only the item at `snapshotID:path` (requires --to)")
+	f.StringVar(&opts.To, "to", "", "`path` to restore the item selected by --from-node to")
+	f.StringVar(&opts.AsOf, "as-of", "", "use the newest snapshot created before `time` instead of the latest one")
 }
 
 func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
@@ -107,6 +137,24 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	hasExcludes := len(excludePatternFns) > 0
 	hasIncludes := len(includePatternFns) > 0
 
+	if opts.FromNode != "" {
+		switch {
+		case len(args) > 0:
+			return errors.Fatal("--from-node cannot be combined with a snapshot ID argument")
+		case opts.To == "":
+			return errors.Fatal("please specify the path to restore the item to (--to)")
+		case opts.Target != "":
+			return errors.Fatal("--from-node and --target are mutually exclusive")
+		case hasExcludes || hasIncludes:
+			return errors.Fatal("--from-node cannot be combined with include or exclude patterns")
+		case opts.Delete:
+			return errors.Fatal("--from-node and --delete are mutually exclusive")
+		}
+		args = []string{opts.FromNode}
+	} else if opts.To != "" {
+		return errors.Fatal("--to can only be used together with --from-node")
+	}
+
 	switch {
 	case len(args) == 0:
 		return errors.Fatal("no snapshot ID specified")
@@ -114,7 +162,7 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		return errors.Fatalf("more than one snapshot ID specified: %v", args)
 	}
 
-	if opts.Target == "" {
+	if opts.Target == "" && opts.FromNode == "" {
 		return errors.Fatal("please specify a directory to restore to (--target)")
 	}
 
@@ -126,12 +174,32 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		return errors.Fatal("--dry-run and --verify are mutually exclusive")
 	}
 
+	if opts.Confine && runtime.GOOS != "linux" {
+		return errors.Fatal("--confine is only supported on Linux")
+	}
+
 	if opts.Delete && filepath.Clean(opts.Target) == "/" && !hasExcludes && !hasIncludes {
 		return errors.Fatal("'--target / --delete' must be combined with an include or exclude filter")
 	}
 
+	ownerMap, err := opts.OwnerMap()
+	if err != nil {
+		return err
+	}
+
 	snapshotIDString := args[0]
 
+	var asOf time.Time
+	if opts.AsOf != "" {
+		if id, _, _ := strings.Cut(snapshotIDString, ":"); id != "latest" {
+			return errors.Fatal("--as-of can only be used with the snapshot ID \"latest\"")
+		}
+		asOf, err = parseTime(opts.AsOf)
+		if err != nil {
+			return err
+		}
+	}
+
 	debug.Log("restore %v to %v", snapshotIDString, opts.Target)
 
 	ctx, repo, unlock, err := openWithReadLock(ctx, gopts, gopts.NoLock)
@@ -144,11 +212,22 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		Hosts: opts.Hosts,
 		Paths: opts.Paths,
 		Tags:  opts.Tags,
+
+		TimestampLimit: asOf,
 	}).FindLatest(ctx, repo, repo, snapshotIDString)
 	if err != nil {
 		return errors.Fatalf("failed to find snapshot: %v", err)
 	}
 
+	// --from-node restores a single item of the directory which contains it
+	var nodeName string
+	if opts.FromNode != "" {
+		subfolder, nodeName = path.Split(path.Clean("/" + subfolder))
+		if nodeName == "" {
+			return errors.Fatal("--from-node requires the path of an item within the snapshot")
+		}
+	}
+
 	bar := newIndexTerminalProgress(gopts.Quiet, gopts.JSON, term)
 	err = repo.LoadIndex(ctx, bar)
 	if err != nil {
@@ -160,6 +239,25 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		return err
 	}
 
+	target := opts.Target
+	if nodeName != "" {
+		tree, err := restic.LoadTree(ctx, repo, *sn.Tree)
+		if err != nil {
+			return err
+		}
+		if tree.Find(nodeName) == nil {
+			return errors.Fatalf("path %v not found in snapshot %v", path.Join(subfolder, nodeName), sn.ID().Str())
+		}
+
+		target, err = prepareNodeRestore(opts.To, opts.Overwrite)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = os.RemoveAll(target)
+		}()
+	}
+
 	msg := ui.NewMessage(term, gopts.verbosity)
 	var printer restoreui.ProgressPrinter
 	if gopts.JSON {
@@ -169,12 +267,29 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	}
 
 	progress := restoreui.NewProgress(printer, calculateProgressInterval(!gopts.Quiet, gopts.JSON))
+	var manifest *restoreui.Manifest
+	if opts.Manifest != "" {
+		f, err := os.Create(opts.Manifest)
+		if err != nil {
+			return errors.Fatalf("unable to create manifest: %v", err)
+		}
+		manifest = restoreui.NewManifest(f)
+		progress.SetManifest(manifest)
+		defer func() {
+			// keep the records written so far if the restore is aborted
+			_ = manifest.Flush()
+			_ = f.Close()
+		}()
+	}
+
 	res := restorer.NewRestorer(repo, sn, restorer.Options{
 		DryRun:    opts.DryRun,
 		Sparse:    opts.Sparse,
 		Progress:  progress,
 		Overwrite: opts.Overwrite,
 		Delete:    opts.Delete,
+		OwnerMap:  ownerMap,
+		Confine:   opts.Confine,
 	})
 
 	totalErrors := 0
@@ -237,6 +352,10 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		res.SelectFilter = selectIncludeFilter
 	}
 
+	if nodeName != "" {
+		res.SelectFilter = selectNodeFilter(nodeName)
+	}
+
 	if opts.ScopeSymlinks != "" {
 		res.NodeFilter = hostinger.SymlinkScopeNodeFilter(opts.ScopeSymlinks)
 	}
@@ -247,28 +366,38 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	}
 
 	if !gopts.JSON {
-		msg.P("restoring %s to %s\n", res.Snapshot(), opts.Target)
+		if nodeName != "" {
+			msg.P("restoring %s from %s to %s\n", path.Join(subfolder, nodeName), res.Snapshot(), opts.To)
+		} else {
+			msg.P("restoring %s to %s\n", res.Snapshot(), opts.Target)
+		}
 	}
 
-	countRestoredFiles, err := res.RestoreTo(ctx, opts.Target)
+	countRestoredFiles, err := res.RestoreTo(ctx, target)
 	if err != nil {
 		return err
 	}
 
 	progress.Finish()
 
+	if manifest != nil {
+		if err := manifest.Flush(); err != nil {
+			return errors.Fatalf("unable to write manifest: %v", err)
+		}
+	}
+
 	if totalErrors > 0 {
 		return errors.Fatalf("There were %d errors\n", totalErrors)
 	}
 
 	if opts.Verify {
 		if !gopts.JSON {
-			msg.P("verifying files in %s\n", opts.Target)
+			msg.P("verifying files in %s\n", target)
 		}
 		var count int
 		t0 := time.Now()
 		bar := newTerminalProgressMax(!gopts.Quiet && !gopts.JSON && stdoutIsTerminal(), 0, "files verified", term)
-		count, err = res.VerifyFiles(ctx, opts.Target, countRestoredFiles, bar)
+		count, err = res.VerifyFiles(ctx, target, countRestoredFiles, bar)
 		if err != nil {
 			return err
 		}
@@ -277,14 +406,51 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		}
 
 		if !gopts.JSON {
-			msg.P("finished verifying %d files in %s (took %s)\n", count, opts.Target,
+			msg.P("finished verifying %d files in %s (took %s)\n", count, target,
 				time.Since(t0).Round(time.Millisecond))
 		}
 	}
 
+	if nodeName != "" && !opts.DryRun {
+		if err := os.Rename(filepath.Join(target, nodeName), opts.To); err != nil {
+			return errors.Fatalf("unable to move restored item to %v: %v", opts.To, err)
+		}
+	}
+
 	return nil
 }
 
+// prepareNodeRestore checks that the item selected by --from-node can be
+// restored to dst and returns a temporary directory next to dst to restore the
+// item to. The item is moved to dst once it has been restored completely.
+func prepareNodeRestore(dst string, overwrite restorer.OverwriteBehavior) (string, error) {
+	fi, err := os.Lstat(dst)
+	switch {
+	case err == nil && fi.IsDir():
+		return "", errors.Fatalf("%v already exists and is a directory", dst)
+	case err == nil && overwrite == restorer.OverwriteNever:
+		return "", errors.Fatalf("%v already exists", dst)
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return "", errors.Fatalf("unable to check %v: %v", dst, err)
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(dst), ".restic-restore-")
+	if err != nil {
+		return "", errors.Fatalf("unable to create temporary directory: %v", err)
+	}
+	return tmp, nil
+}
+
+// selectNodeFilter returns a SelectFilter which only selects the item name
+// directly below the restored tree and everything it contains.
+func selectNodeFilter(name string) func(item string, isDir bool) (bool, bool) {
+	location := filepath.Join(string(filepath.Separator), name)
+	return func(item string, isDir bool) (selectedForRestore bool, childMayBeSelected bool) {
+		selectedForRestore = item == location || strings.HasPrefix(item, location+string(filepath.Separator))
+		return selectedForRestore, selectedForRestore && isDir
+	}
+}
+
 func getXattrSelectFilter(opts RestoreOptions) (func(xattrName string) bool, error) {
 	hasXattrExcludes := len(opts.ExcludeXattrPattern) > 0
 	hasXattrIncludes := len(opts.IncludeXattrPattern) > 0
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/restorer"
 	rtest "github.com/restic/restic/internal/test"
 	"github.com/restic/restic/internal/ui/termstatus"
 )
@@ -420,3 +421,74 @@ func TestRestoreDefaultLayout(t *testing.T) {
 	rtest.RemoveAll(t, filepath.Join(env.base, "repo"))
 	rtest.RemoveAll(t, target)
 }
+
+func testRunRestoreNode(opts RestoreOptions, gopts GlobalOptions) error {
+	return withTermStatus(gopts, func(ctx context.Context, term *termstatus.Terminal) error {
+		return runRestore(ctx, opts, gopts, term, nil)
+	})
+}
+
+func TestRestoreFromNode(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testRunInit(t, env.gopts)
+
+	p := filepath.Join(env.testdata, "dir", "file")
+	rtest.OK(t, os.MkdirAll(filepath.Dir(p), 0755))
+	rtest.OK(t, os.WriteFile(p, []byte("first"), 0644))
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{TimeStamp: "2025-01-01 10:00:00"}, env.gopts)
+	first := testListSnapshots(t, env.gopts, 1)[0]
+
+	rtest.OK(t, os.WriteFile(p, []byte("second"), 0644))
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{TimeStamp: "2025-01-02 10:00:00"}, env.gopts)
+
+	dst := filepath.Join(env.base, "restored")
+
+	// latest version
+	rtest.OK(t, testRunRestoreNode(RestoreOptions{FromNode: "latest:/testdata/dir/file", To: dst}, env.gopts))
+	buf, err := os.ReadFile(dst)
+	rtest.OK(t, err)
+	rtest.Equals(t, "second", string(buf))
+
+	// newest version before the given time, replacing the existing file
+	rtest.OK(t, testRunRestoreNode(RestoreOptions{FromNode: "latest:/testdata/dir/file", To: dst, AsOf: "2025-01-01 12:00"}, env.gopts))
+	buf, err = os.ReadFile(dst)
+	rtest.OK(t, err)
+	rtest.Equals(t, "first", string(buf))
+
+	// only the temporary directory must be removed
+	entries, err := os.ReadDir(env.base)
+	rtest.OK(t, err)
+	for _, entry := range entries {
+		rtest.Assert(t, !strings.HasPrefix(entry.Name(), ".restic-restore-"), "temporary directory %v was not removed", entry.Name())
+	}
+
+	// restore a directory from a specific snapshot
+	dstDir := filepath.Join(env.base, "restored-dir")
+	rtest.OK(t, testRunRestoreNode(RestoreOptions{FromNode: first.String() + ":/testdata/dir", To: dstDir}, env.gopts))
+	buf, err = os.ReadFile(filepath.Join(dstDir, "file"))
+	rtest.OK(t, err)
+	rtest.Equals(t, "first", string(buf))
+
+	var ow restorer.OverwriteBehavior
+	rtest.OK(t, ow.Set("never"))
+	for _, opts := range []RestoreOptions{
+		{FromNode: "latest:/testdata/dir/missing", To: filepath.Join(env.base, "missing")},
+		{FromNode: "latest:/", To: filepath.Join(env.base, "root")},
+		{FromNode: "latest:/testdata/dir/file"},
+		{FromNode: "latest:/testdata/dir/file", To: dstDir},
+		{FromNode: "latest:/testdata/dir/file", To: dst, Overwrite: ow},
+		{FromNode: first.String() + ":/testdata/dir/file", To: dst, AsOf: "2025-01-01"},
+		{FromNode: "latest:/testdata/dir/file", To: dst, AsOf: "2024-01-01"},
+	} {
+		rtest.Assert(t, testRunRestoreNode(opts, env.gopts) != nil, "expected restore with %+v to fail", opts)
+	}
+
+	// --as-of for a complete snapshot
+	restoreDir := filepath.Join(env.base, "restore-as-of")
+	rtest.OK(t, testRunRestoreAssumeFailure("latest", RestoreOptions{Target: restoreDir, AsOf: "2025-01-01 23:59:59"}, env.gopts))
+	buf, err = os.ReadFile(filepath.Join(restoreDir, "testdata", "dir", "file"))
+	rtest.OK(t, err)
+	rtest.Equals(t, "first", string(buf))
+}
@@ -122,7 +122,7 @@ func (opts *RewriteOptions) AddFlags(f *pflag.FlagSet) {
 // be updated accordingly.
 type rewriteFilterFunc func(ctx context.Context, sn *restic.Snapshot) (restic.ID, *restic.SnapshotSummary, error)
 
-func rewriteSnapshot(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot, opts RewriteOptions) (bool, error) {
+func rewriteSnapshot(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot, opts RewriteOptions, holds restic.Holds) (bool, error) {
 	if sn.Tree == nil {
 		return false, errors.Errorf("snapshot %v has nil tree", sn.ID().Str())
 	}
@@ -182,11 +182,15 @@ func rewriteSnapshot(ctx context.Context, repo *repository.Repository, sn *resti
 	}
 
 	return filterAndReplaceSnapshot(ctx, repo, sn,
-		filter, opts.DryRun, opts.Forget, metadata, "rewrite")
+		filter, opts.DryRun, opts.Forget, holds, metadata, "rewrite")
 }
 
+// filterAndReplaceSnapshot never removes snapshots which are held according to
+// holds, neither empty ones nor the originals when forget is set.
 func filterAndReplaceSnapshot(ctx context.Context, repo restic.Repository, sn *restic.Snapshot,
-	filter rewriteFilterFunc, dryRun bool, forget bool, newMetadata *snapshotMetadata, addTag string) (bool, error) {
+	filter rewriteFilterFunc, dryRun bool, forget bool, holds restic.Holds, newMetadata *snapshotMetadata, addTag string) (bool, error) {
+
+	holdReason, held := holds.Reason(*sn.ID())
 
 	wg, wgCtx := errgroup.WithContext(ctx)
 	repo.StartPackUploader(wgCtx, wg)
@@ -208,6 +212,10 @@ func filterAndReplaceSnapshot(ctx context.Context, repo restic.Repository, sn *r
 	}
 
 	if filteredTree.IsNull() {
+		if held {
+			Warnf("not removing empty snapshot %v, it is %v\n", sn.ID().Str(), holdReason)
+			return false, nil
+		}
 		if dryRun {
 			Verbosef("would delete empty snapshot\n")
 		} else {
@@ -231,6 +239,10 @@ func filterAndReplaceSnapshot(ctx context.Context, repo restic.Repository, sn *r
 	}
 
 	debug.Log("Snapshot %v modified", sn)
+	if forget && held {
+		Warnf("keeping original snapshot %v, it is %v\n", sn.ID().Str(), holdReason)
+		forget = false
+	}
 	if dryRun {
 		Verbosef("would save new snapshot\n")
 
@@ -309,6 +321,16 @@ func runRewrite(ctx context.Context, opts RewriteOptions, gopts GlobalOptions, a
 	}
 	defer unlock()
 
+	if opts.Forget {
+		if err := repo.CheckRemove(restic.SnapshotFile); err != nil {
+			return errors.Fatal(err.Error())
+		}
+	}
+	holds, err := restic.LoadHolds(ctx, repo, repo, time.Now())
+	if err != nil {
+		return err
+	}
+
 	snapshotLister, err := restic.MemorizeList(ctx, repo, restic.SnapshotFile)
 	if err != nil {
 		return err
@@ -322,7 +344,7 @@ func runRewrite(ctx context.Context, opts RewriteOptions, gopts GlobalOptions, a
 	changedCount := 0
 	for sn := range FindFilteredSnapshots(ctx, snapshotLister, repo, &opts.SnapshotFilter, args) {
 		Verbosef("\n%v\n", sn)
-		changed, err := rewriteSnapshot(ctx, repo, sn, opts)
+		changed, err := rewriteSnapshot(ctx, repo, sn, opts, holds)
 		if err != nil {
 			return errors.Fatalf("unable to rewrite snapshot ID %q: %v", sn.ID().Str(), err)
 		}
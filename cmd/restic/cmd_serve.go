@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "serve",
+		Short:             "Serve the repository via network protocols",
+		GroupID:           cmdGroupDefault,
+		DisableAutoGenTag: true,
+	}
+
+	cmd.AddCommand(
+		newServeRESTCommand(),
+	)
+	registerServeWebDAVCommand(cmd)
+	return cmd
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/backend/rest/server"
+	"github.com/restic/restic/internal/errors"
+)
+
+func newServeRESTCommand() *cobra.Command {
+	var opts ServeRESTOptions
+
+	cmd := &cobra.Command{
+		Use:   "rest [flags]",
+		Short: "Serve the repository location via the REST backend protocol",
+		Long: `
+The "serve rest" command serves the repository location via HTTP, using the
+same protocol as the rest-server project. Clients access it via a repository
+URL like rest:http://host:8000/. The served location can use any backend, the
+data stored in it is neither decrypted nor checked, so no password is required.
+
+Repositories in subdirectories of the location can be accessed by appending the
+path to the URL, for example rest:http://host:8000/alice/laptop/. With
+--private-repos, users can only access the repositories below the directory
+named after their username.
+
+Clients are authenticated using an htpasswd file with bcrypt or SHA1 password
+hashes, which is passed via --htpasswd-file. To serve the repository without
+authentication, pass --no-auth.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServeREST(cmd.Context(), opts, globalOptions, args)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// ServeRESTOptions collects all options for the serve rest command.
+type ServeRESTOptions struct {
+	Listen       string
+	AppendOnly   bool
+	HtpasswdFile string
+	NoAuth       bool
+	PrivateRepos bool
+	TLS          bool
+	TLSCert      string
+	TLSKey       string
+}
+
+func (opts *ServeRESTOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&opts.Listen, "listen", ":8000", "listen on `address`, use unix:/path for a unix socket")
+	f.BoolVar(&opts.AppendOnly, "append-only", false, "reject removing files other than locks")
+	f.StringVar(&opts.HtpasswdFile, "htpasswd-file", "", "authenticate users via the htpasswd `file`")
+	f.BoolVar(&opts.NoAuth, "no-auth", false, "do not authenticate users (insecure)")
+	f.BoolVar(&opts.PrivateRepos, "private-repos", false, "users can only access the repositories below the directory named after their username")
+	f.BoolVar(&opts.TLS, "tls", false, "serve via HTTPS")
+	f.StringVar(&opts.TLSCert, "tls-cert", "", "TLS certificate `file`")
+	f.StringVar(&opts.TLSKey, "tls-key", "", "TLS private key `file`")
+}
+
+func (opts *ServeRESTOptions) Check() error {
+	if opts.HtpasswdFile == "" && !opts.NoAuth {
+		return errors.Fatal("either --htpasswd-file or --no-auth must be specified")
+	}
+	if opts.HtpasswdFile != "" && opts.NoAuth {
+		return errors.Fatal("--htpasswd-file and --no-auth cannot be combined")
+	}
+	if opts.PrivateRepos && opts.NoAuth {
+		return errors.Fatal("--private-repos requires authentication")
+	}
+	if opts.TLS && (opts.TLSCert == "" || opts.TLSKey == "") {
+		return errors.Fatal("--tls requires --tls-cert and --tls-key")
+	}
+	if !opts.TLS && (opts.TLSCert != "" || opts.TLSKey != "") {
+		return errors.Fatal("--tls-cert and --tls-key require --tls")
+	}
+	return nil
+}
+
+// subrepoLocation returns the location of the repository at path below the
+// location s.
+func subrepoLocation(s, path string) string {
+	if path == "" {
+		return s
+	}
+	if strings.HasSuffix(s, ":") {
+		return s + path
+	}
+	return strings.TrimSuffix(s, "/") + "/" + path
+}
+
+// listen opens a listener for address, which is either a TCP address or a
+// unix socket prefixed with "unix:".
+func listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", address)
+}
+
+func runServeREST(ctx context.Context, opts ServeRESTOptions, gopts GlobalOptions, args []string) error {
+	if len(args) > 0 {
+		return errors.Fatal("the serve rest command expects no arguments, only options - please see `restic help serve rest` for usage and flags")
+	}
+	if err := opts.Check(); err != nil {
+		return err
+	}
+
+	ln, err := listen(opts.Listen)
+	if err != nil {
+		return errors.Fatalf("unable to listen: %v", err)
+	}
+	Printf("start server on %v\n", ln.Addr())
+
+	return serveREST(ctx, opts, gopts, ln)
+}
+
+// serveREST serves the repository location via ln until ctx is cancelled.
+func serveREST(ctx context.Context, opts ServeRESTOptions, gopts GlobalOptions, ln net.Listener) error {
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	repo, err := ReadRepo(gopts)
+	if err != nil {
+		return err
+	}
+
+	srvOpts := server.Options{
+		AppendOnly:   opts.AppendOnly,
+		PrivateRepos: opts.PrivateRepos,
+		Logf: func(format string, args ...interface{}) {
+			Warnf(format+"\n", args...)
+		},
+	}
+	if opts.HtpasswdFile != "" {
+		srvOpts.Users, err = server.LoadHtpasswd(opts.HtpasswdFile)
+		if err != nil {
+			return errors.Fatalf("unable to load htpasswd file: %v", err)
+		}
+	}
+
+	open := func(ctx context.Context, path string, create bool) (backend.Backend, error) {
+		return innerOpen(ctx, subrepoLocation(repo, path), gopts, gopts.extended, create)
+	}
+	srv := server.New(open, srvOpts)
+	defer func() {
+		_ = srv.Close()
+	}()
+
+	return serveHTTP(ctx, srv, ln, opts.TLSCert, opts.TLSKey)
+}
+
+// serveHTTP serves handler on ln until ctx is cancelled. If certFile and
+// keyFile are set, HTTPS is used.
+func serveHTTP(ctx context.Context, handler http.Handler, ln net.Listener, certFile, keyFile string) error {
+	httpSrv := &http.Server{
+		Handler: handler,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = httpSrv.Close()
+		case <-done:
+		}
+	}()
+
+	var err error
+	if certFile != "" {
+		err = httpSrv.ServeTLS(ln, certFile, keyFile)
+	} else {
+		err = httpSrv.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestServeREST(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	rtest.OK(t, err)
+
+	// the server lists files once per request
+	srvGopts := env.gopts
+	srvGopts.backendTestHook = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveREST(ctx, ServeRESTOptions{NoAuth: true, AppendOnly: true}, srvGopts, ln)
+	}()
+	defer func() {
+		cancel()
+		rtest.Equals(t, context.Canceled, <-done)
+	}()
+
+	env.gopts.Repo = "rest:http://" + ln.Addr().String() + "/sub/"
+	rtest.OK(t, runInit(context.TODO(), InitOptions{}, env.gopts, nil))
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{}, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 1)
+	testRunCheck(t, env.gopts)
+
+	_, err = os.Stat(filepath.Join(env.repo, "sub", "config"))
+	rtest.OK(t, err)
+
+	// removing snapshots is not possible in append-only mode
+	_ = testRunForgetMayFail(env.gopts, ForgetOptions{}, snapshotIDs[0].String())
+	testListSnapshots(t, env.gopts, 1)
+}
@@ -0,0 +1,189 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/restic/restic/internal/backend/rest/server"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fuse"
+	"github.com/restic/restic/internal/restic"
+)
+
+func registerServeWebDAVCommand(cmd *cobra.Command) {
+	cmd.AddCommand(newServeWebDAVCommand())
+}
+
+func newServeWebDAVCommand() *cobra.Command {
+	var opts ServeWebDAVOptions
+
+	cmd := &cobra.Command{
+		Use:   "webdav [flags]",
+		Short: "Serve the snapshots read-only via WebDAV and HTTP",
+		Long: `
+The "serve webdav" command serves the contents of the snapshots via WebDAV,
+using the same directory structure as the "mount" command. It does not require
+FUSE. WebDAV clients can access the snapshots via a URL like http://host:8080/,
+web browsers get a directory listing and can download individual files. Range
+requests are supported.
+
+Clients are authenticated using an htpasswd file with bcrypt or SHA1 password
+hashes, which is passed via --htpasswd-file. To serve the snapshots without
+authentication, pass --no-auth.
+
+The snapshots can be filtered using --host, --tag and --path. The directory
+structure can be changed using --path-template and --time-template, see
+"restic help mount" for details.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful.
+Exit status is 1 if there was any error.
+Exit status is 10 if the repository does not exist.
+Exit status is 11 if the repository is already locked.
+Exit status is 12 if the password is incorrect.
+`,
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServeWebDAV(cmd.Context(), opts, globalOptions, args)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// ServeWebDAVOptions collects all options for the serve webdav command.
+type ServeWebDAVOptions struct {
+	Listen       string
+	HtpasswdFile string
+	NoAuth       bool
+	TLS          bool
+	TLSCert      string
+	TLSKey       string
+	restic.SnapshotFilter
+	TimeTemplate  string
+	PathTemplates []string
+}
+
+func (opts *ServeWebDAVOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&opts.Listen, "listen", ":8080", "listen on `address`, use unix:/path for a unix socket")
+	f.StringVar(&opts.HtpasswdFile, "htpasswd-file", "", "authenticate users via the htpasswd `file`")
+	f.BoolVar(&opts.NoAuth, "no-auth", false, "do not authenticate users (insecure)")
+	f.BoolVar(&opts.TLS, "tls", false, "serve via HTTPS")
+	f.StringVar(&opts.TLSCert, "tls-cert", "", "TLS certificate `file`")
+	f.StringVar(&opts.TLSKey, "tls-key", "", "TLS private key `file`")
+
+	initMultiSnapshotFilter(f, &opts.SnapshotFilter, true)
+
+	f.StringArrayVar(&opts.PathTemplates, "path-template", nil, "set `template` for path names (can be specified multiple times)")
+	f.StringVar(&opts.TimeTemplate, "time-template", time.RFC3339, "set `template` to use for times")
+}
+
+func (opts *ServeWebDAVOptions) Check() error {
+	if opts.HtpasswdFile == "" && !opts.NoAuth {
+		return errors.Fatal("either --htpasswd-file or --no-auth must be specified")
+	}
+	if opts.HtpasswdFile != "" && opts.NoAuth {
+		return errors.Fatal("--htpasswd-file and --no-auth cannot be combined")
+	}
+	if opts.TLS && (opts.TLSCert == "" || opts.TLSKey == "") {
+		return errors.Fatal("--tls requires --tls-cert and --tls-key")
+	}
+	if !opts.TLS && (opts.TLSCert != "" || opts.TLSKey != "") {
+		return errors.Fatal("--tls-cert and --tls-key require --tls")
+	}
+	if opts.TimeTemplate == "" {
+		return errors.Fatal("time template string cannot be empty")
+	}
+	if strings.HasPrefix(opts.TimeTemplate, "/") || strings.HasSuffix(opts.TimeTemplate, "/") {
+		return errors.Fatal("time template string cannot start or end with '/'")
+	}
+	return nil
+}
+
+func runServeWebDAV(ctx context.Context, opts ServeWebDAVOptions, gopts GlobalOptions, args []string) error {
+	if len(args) > 0 {
+		return errors.Fatal("the serve webdav command expects no arguments, only options - please see `restic help serve webdav` for usage and flags")
+	}
+	if err := opts.Check(); err != nil {
+		return err
+	}
+
+	ln, err := listen(opts.Listen)
+	if err != nil {
+		return errors.Fatalf("unable to listen: %v", err)
+	}
+	Printf("start server on %v\n", ln.Addr())
+
+	return serveWebDAV(ctx, opts, gopts, ln)
+}
+
+// serveWebDAV serves the snapshots in the repository via ln until ctx is
+// cancelled.
+func serveWebDAV(ctx context.Context, opts ServeWebDAVOptions, gopts GlobalOptions, ln net.Listener) error {
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	var users *server.Htpasswd
+	if opts.HtpasswdFile != "" {
+		var err error
+		users, err = server.LoadHtpasswd(opts.HtpasswdFile)
+		if err != nil {
+			return errors.Fatalf("unable to load htpasswd file: %v", err)
+		}
+	}
+
+	ctx, repo, unlock, err := openWithReadLock(ctx, gopts, gopts.NoLock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bar := newIndexProgress(gopts.Quiet, gopts.JSON)
+	err = repo.LoadIndex(ctx, bar)
+	if err != nil {
+		return err
+	}
+
+	root := fuse.NewRoot(repo, fuse.Config{
+		Filter:        opts.SnapshotFilter,
+		TimeTemplate:  opts.TimeTemplate,
+		PathTemplates: opts.PathTemplates,
+	})
+	handler := fuse.NewWebDAVHandler(fuse.NewWebDAVFileSystem(root), func(r *http.Request, err error) {
+		if err != nil {
+			Warnf("%v %v failed: %v\n", r.Method, r.URL.Path, err)
+		}
+	})
+
+	return serveHTTP(ctx, basicAuth(users, handler), ln, opts.TLSCert, opts.TLSKey)
+}
+
+// basicAuth returns a handler which authenticates all requests using users
+// before passing them to next. If users is nil, all requests are passed on.
+func basicAuth(users *server.Htpasswd, next http.Handler) http.Handler {
+	if users == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || !users.Validate(user, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="restic"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
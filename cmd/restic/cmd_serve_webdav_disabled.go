@@ -0,0 +1,11 @@
+//go:build !darwin && !freebsd && !linux
+// +build !darwin,!freebsd,!linux
+
+package main
+
+import "github.com/spf13/cobra"
+
+func registerServeWebDAVCommand(_ *cobra.Command) {
+	// the WebDAV server uses the fuse file system, which is not supported on
+	// these platforms
+}
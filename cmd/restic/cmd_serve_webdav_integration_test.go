@@ -0,0 +1,80 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestServeWebDAV(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testRunInit(t, env.gopts)
+	rtest.OK(t, os.MkdirAll(filepath.Join(env.testdata, "dir"), 0755))
+	rtest.OK(t, os.WriteFile(filepath.Join(env.testdata, "dir", "file"), []byte("content"), 0644))
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, BackupOptions{}, env.gopts)
+	snapshotID := testListSnapshots(t, env.gopts, 1)[0]
+
+	// the password of bob is "secret"
+	htpasswd := filepath.Join(env.base, "htpasswd")
+	rtest.OK(t, os.WriteFile(htpasswd, []byte("bob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0600))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	rtest.OK(t, err)
+
+	// the snapshots are listed again once they changed
+	srvGopts := env.gopts
+	srvGopts.backendTestHook = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		opts := ServeWebDAVOptions{HtpasswdFile: htpasswd, TimeTemplate: time.RFC3339}
+		done <- serveWebDAV(ctx, opts, srvGopts, ln)
+	}()
+	defer func() {
+		cancel()
+		rtest.Equals(t, context.Canceled, <-done)
+	}()
+
+	get := func(path, user, password string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+path, nil)
+		rtest.OK(t, err)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		rtest.OK(t, err)
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		body, err := io.ReadAll(resp.Body)
+		rtest.OK(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	status, _ := get("/", "", "")
+	rtest.Equals(t, http.StatusUnauthorized, status)
+	status, _ = get("/", "bob", "wrong")
+	rtest.Equals(t, http.StatusUnauthorized, status)
+
+	status, body := get("/ids/", "bob", "secret")
+	rtest.Equals(t, http.StatusOK, status)
+	rtest.Assert(t, strings.Contains(body, snapshotID.Str()), "listing does not contain snapshot %v: %v", snapshotID.Str(), body)
+
+	status, body = get("/ids/"+snapshotID.Str()+"/testdata/dir/file", "bob", "secret")
+	rtest.Equals(t, http.StatusOK, status)
+	rtest.Equals(t, "content", body)
+}
@@ -6,9 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
-	"github.com/restic/chunker"
 	"github.com/restic/restic/internal/crypto"
 	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
@@ -47,6 +47,9 @@ The modes are:
 * raw-data: Counts the size of blobs in the repository, regardless of
   how many files reference them.
 * blobs-per-file: A combination of files-by-contents and raw-data.
+* attribution: Splits the raw-data size between groups of snapshots as
+  selected by --group-by. Blobs referenced by several groups are shared
+  equally between them.
 
 Refer to the online manual for more details about each mode.
 
@@ -68,7 +71,7 @@ Exit status is 12 if the password is incorrect.
 
 	opts.AddFlags(cmd.Flags())
 	must(cmd.RegisterFlagCompletionFunc("mode", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return []string{countModeRestoreSize, countModeUniqueFilesByContents, countModeBlobsPerFile, countModeRawData}, cobra.ShellCompDirectiveDefault
+		return []string{countModeRestoreSize, countModeUniqueFilesByContents, countModeBlobsPerFile, countModeRawData, countModeAttribution}, cobra.ShellCompDirectiveDefault
 	}))
 	return cmd
 }
@@ -78,11 +81,16 @@ type StatsOptions struct {
 	// the mode of counting to perform (see consts for available modes)
 	countMode string
 
+	// GroupBy determines the groups for the attribution mode
+	GroupBy restic.SnapshotGroupByOptions
+
 	restic.SnapshotFilter
 }
 
 func (opts *StatsOptions) AddFlags(f *pflag.FlagSet) {
-	f.StringVar(&opts.countMode, "mode", countModeRestoreSize, "counting mode: restore-size (default), files-by-contents, blobs-per-file or raw-data")
+	f.StringVar(&opts.countMode, "mode", countModeRestoreSize, "counting mode: restore-size (default), files-by-contents, blobs-per-file, raw-data or attribution")
+	opts.GroupBy = restic.SnapshotGroupByOptions{Host: true, Path: true}
+	f.VarP(&opts.GroupBy, "group-by", "g", "`group` snapshots by host, paths and/or tags, separated by comma (attribution mode only)")
 	initMultiSnapshotFilter(f, &opts.SnapshotFilter, true)
 }
 
@@ -121,6 +129,10 @@ func runStats(ctx context.Context, opts StatsOptions, gopts GlobalOptions, args
 		Printf("scanning...\n")
 	}
 
+	if opts.countMode == countModeAttribution {
+		return statsAttribution(ctx, repo, snapshotLister, opts, gopts, args)
+	}
+
 	// create a container for the stats (and other needed state)
 	stats := &statsContainer{
 		uniqueFiles:    make(map[fileID]struct{}),
@@ -311,6 +323,10 @@ func verifyStatsInput(opts StatsOptions) error {
 	case countModeUniqueFilesByContents:
 	case countModeBlobsPerFile:
 	case countModeRawData:
+	case countModeAttribution:
+		if !opts.GroupBy.Host && !opts.GroupBy.Path && !opts.GroupBy.Tag {
+			return fmt.Errorf("attribution mode requires --group-by")
+		}
 	case countModeDebug:
 	default:
 		return fmt.Errorf("unknown counting mode: %s (use the -h flag to get a list of supported modes)", opts.countMode)
@@ -356,9 +372,178 @@ const (
 	countModeUniqueFilesByContents = "files-by-contents"
 	countModeBlobsPerFile          = "blobs-per-file"
 	countModeRawData               = "raw-data"
+	countModeAttribution           = "attribution"
 	countModeDebug                 = "debug"
 )
 
+// statsAttributionGroup holds the usage of a single group of snapshots in
+// attribution mode.
+type statsAttributionGroup struct {
+	GroupKey        restic.SnapshotGroupKey `json:"group_key"`
+	SnapshotsCount  int                     `json:"snapshots_count"`
+	UniqueSize      uint64                  `json:"unique_size"`
+	UniqueBlobCount uint64                  `json:"unique_blob_count"`
+	SharedSize      uint64                  `json:"shared_size"`
+	SharedBlobCount uint64                  `json:"shared_blob_count"`
+	AttributedSize  uint64                  `json:"attributed_size"`
+}
+
+// statsAttributionResult is the result of the attribution mode. The sum of
+// the attributed sizes of all groups is equal to the total size.
+type statsAttributionResult struct {
+	Groups          []statsAttributionGroup `json:"groups"`
+	TotalSize       uint64                  `json:"total_size"`
+	TotalBlobCount  uint64                  `json:"total_blob_count"`
+	SharedSize      uint64                  `json:"shared_size"`
+	SharedBlobCount uint64                  `json:"shared_blob_count"`
+	SnapshotsCount  int                     `json:"snapshots_count"`
+}
+
+func statsAttribution(ctx context.Context, repo restic.Repository, snapshotLister restic.Lister, opts StatsOptions, gopts GlobalOptions, args []string) error {
+	var snapshots restic.Snapshots
+	for sn := range FindFilteredSnapshots(ctx, snapshotLister, repo, &opts.SnapshotFilter, args) {
+		if sn.Tree == nil {
+			return fmt.Errorf("snapshot %s has nil tree", sn.ID().Str())
+		}
+		snapshots = append(snapshots, sn)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	snapshotGroups, _, err := restic.GroupSnapshots(snapshots, opts.GroupBy)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(snapshotGroups))
+	for k := range snapshotGroups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := &statsAttributionResult{
+		Groups:         make([]statsAttributionGroup, len(keys)),
+		SnapshotsCount: len(snapshots),
+	}
+
+	// blobGroups records the indexes of all groups which reference a blob
+	blobGroups := make(map[restic.BlobHandle][]int)
+	for i, k := range keys {
+		group := &result.Groups[i]
+		if err := json.Unmarshal([]byte(k), &group.GroupKey); err != nil {
+			return err
+		}
+		group.SnapshotsCount = len(snapshotGroups[k])
+
+		var trees restic.IDs
+		for _, sn := range snapshotGroups[k] {
+			trees = append(trees, *sn.Tree)
+		}
+
+		blobs := restic.NewBlobSet()
+		err := restic.FindUsedBlobs(ctx, repo, trees, blobs, nil)
+		if err != nil {
+			return fmt.Errorf("error walking snapshots of group %v: %v", group.GroupKey.String(), err)
+		}
+		for h := range blobs {
+			blobGroups[h] = append(blobGroups[h], i)
+		}
+	}
+
+	err = attributeBlobs(result, blobGroups, func(h restic.BlobHandle) (uint64, error) {
+		pbs := repo.LookupBlob(h.Type, h.ID)
+		if len(pbs) == 0 {
+			return 0, fmt.Errorf("blob %v not found", h)
+		}
+		return uint64(pbs[0].Length), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if gopts.JSON {
+		err = json.NewEncoder(globalOptions.stdout).Encode(result)
+		if err != nil {
+			return fmt.Errorf("encoding output: %v", err)
+		}
+		return nil
+	}
+
+	type line struct {
+		Group                              string
+		Snapshots                          int
+		UniqueSize, SharedSize, Attributed string
+	}
+
+	tab := table.New()
+	tab.AddColumn("Group", "{{ .Group }}")
+	tab.AddColumn("Snapshots", "{{ .Snapshots }}")
+	tab.AddColumn("Unique", "{{ .UniqueSize }}")
+	tab.AddColumn("Shared", "{{ .SharedSize }}")
+	tab.AddColumn("Attributed", "{{ .Attributed }}")
+	for _, group := range result.Groups {
+		tab.AddRow(line{
+			Group:      group.GroupKey.String(),
+			Snapshots:  group.SnapshotsCount,
+			UniqueSize: ui.FormatBytes(group.UniqueSize),
+			SharedSize: ui.FormatBytes(group.SharedSize),
+			Attributed: ui.FormatBytes(group.AttributedSize),
+		})
+	}
+
+	Printf("Stats in %s mode:\n", opts.countMode)
+	if err := tab.Write(globalOptions.stdout); err != nil {
+		return err
+	}
+	Printf("     Snapshots processed:  %d\n", result.SnapshotsCount)
+	Printf("        Total Blob Count:  %d\n", result.TotalBlobCount)
+	Printf("              Total Size:  %-5s\n", ui.FormatBytes(result.TotalSize))
+	Printf("             Shared Size:  %-5s\n", ui.FormatBytes(result.SharedSize))
+
+	return nil
+}
+
+// attributeBlobs splits the size of each blob between the groups referencing
+// it. A blob referenced by n groups is apportioned in equal parts, the
+// remainder of the division is assigned to the first groups, such that no
+// byte is lost or counted twice.
+func attributeBlobs(result *statsAttributionResult, blobGroups map[restic.BlobHandle][]int, blobSize func(restic.BlobHandle) (uint64, error)) error {
+	for h, groups := range blobGroups {
+		size, err := blobSize(h)
+		if err != nil {
+			return err
+		}
+
+		result.TotalSize += size
+		result.TotalBlobCount++
+
+		if len(groups) == 1 {
+			group := &result.Groups[groups[0]]
+			group.UniqueSize += size
+			group.UniqueBlobCount++
+			group.AttributedSize += size
+			continue
+		}
+
+		result.SharedSize += size
+		result.SharedBlobCount++
+
+		n := uint64(len(groups))
+		for i, idx := range groups {
+			group := &result.Groups[idx]
+			group.SharedSize += size
+			group.SharedBlobCount++
+			group.AttributedSize += size / n
+			if uint64(i) < size%n {
+				group.AttributedSize++
+			}
+		}
+	}
+
+	return nil
+}
+
 func statsDebug(ctx context.Context, repo restic.Repository) error {
 	Warnf("Collecting size statistics\n\n")
 	for _, t := range []restic.FileType{restic.KeyFile, restic.LockFile, restic.IndexFile, restic.PackFile} {
@@ -393,7 +578,7 @@ func statsDebugFileType(ctx context.Context, repo restic.Lister, tpe restic.File
 func statsDebugBlobs(ctx context.Context, repo restic.Repository) ([restic.NumBlobTypes]*sizeHistogram, error) {
 	var hist [restic.NumBlobTypes]*sizeHistogram
 	for i := 0; i < len(hist); i++ {
-		hist[i] = newSizeHistogram(2 * chunker.MaxSize)
+		hist[i] = newSizeHistogram(2 * uint64(repo.Config().ChunkerParams().MaxSize))
 	}
 
 	err := repo.ListBlobs(ctx, func(pb restic.PackedBlob) {
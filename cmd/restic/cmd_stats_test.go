@@ -3,6 +3,7 @@ package main
 import (
 	"testing"
 
+	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
 
@@ -60,3 +61,45 @@ func TestSizeHistogramString(t *testing.T) {
 		rtest.Equals(t, "Count: 3\nTotal Size: 11 B\nSize          Count\n-------------------\n  0 - 0 Byte  1\n  1 - 9 Byte  1\n10 - 42 Byte  1\n-------------------\n", h.String())
 	})
 }
+
+func TestAttributeBlobs(t *testing.T) {
+	unique := restic.BlobHandle{Type: restic.DataBlob, ID: restic.NewRandomID()}
+	shared := restic.BlobHandle{Type: restic.DataBlob, ID: restic.NewRandomID()}
+	sharedAll := restic.BlobHandle{Type: restic.TreeBlob, ID: restic.NewRandomID()}
+	sizes := map[restic.BlobHandle]uint64{
+		unique:    100,
+		shared:    11,
+		sharedAll: 10,
+	}
+
+	result := &statsAttributionResult{Groups: make([]statsAttributionGroup, 3)}
+	err := attributeBlobs(result, map[restic.BlobHandle][]int{
+		unique:    {0},
+		shared:    {0, 1},
+		sharedAll: {0, 1, 2},
+	}, func(h restic.BlobHandle) (uint64, error) {
+		return sizes[h], nil
+	})
+	rtest.OK(t, err)
+
+	rtest.Equals(t, uint64(121), result.TotalSize)
+	rtest.Equals(t, uint64(3), result.TotalBlobCount)
+	rtest.Equals(t, uint64(21), result.SharedSize)
+	rtest.Equals(t, uint64(2), result.SharedBlobCount)
+
+	rtest.Equals(t, uint64(100), result.Groups[0].UniqueSize)
+	rtest.Equals(t, uint64(0), result.Groups[1].UniqueSize)
+	rtest.Equals(t, uint64(21), result.Groups[0].SharedSize)
+	rtest.Equals(t, uint64(10), result.Groups[2].SharedSize)
+
+	// 100 + 6 + 4, 5 + 3, 3
+	rtest.Equals(t, uint64(110), result.Groups[0].AttributedSize)
+	rtest.Equals(t, uint64(8), result.Groups[1].AttributedSize)
+	rtest.Equals(t, uint64(3), result.Groups[2].AttributedSize)
+
+	var sum uint64
+	for _, group := range result.Groups {
+		sum += group.AttributedSize
+	}
+	rtest.Equals(t, result.TotalSize, sum)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -28,6 +29,9 @@ add tags to/remove tags from the existing set.
 
 When no snapshotID is given, all snapshots matching the host, tag and path filter criteria are modified.
 
+Changing the tags saves the snapshot under a new ID. Holds on the snapshot, see
+"restic hold", are moved to the new ID.
+
 EXIT STATUS
 ===========
 
@@ -76,7 +80,7 @@ type changedSnapshotsSummary struct {
 	ChangedSnapshots int    `json:"changed_snapshots"`
 }
 
-func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot, setTags, addTags, removeTags []string, printFunc func(changedSnapshot)) (bool, error) {
+func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot, setTags, addTags, removeTags []string, holds restic.Holds, printFunc func(changedSnapshot)) (bool, error) {
 	var changed bool
 
 	if len(setTags) != 0 {
@@ -107,6 +111,11 @@ func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Sna
 
 		debug.Log("old snapshot %v saved as a new snapshot %v", sn.ID(), id)
 
+		// Move the holds to the new snapshot before the old one is removed.
+		if err = moveHolds(ctx, repo, holds[*sn.ID()], id); err != nil {
+			return false, err
+		}
+
 		// Remove the old snapshot.
 		if err = repo.RemoveUnpacked(ctx, restic.WriteableSnapshotFile, *sn.ID()); err != nil {
 			return false, err
@@ -119,6 +128,24 @@ func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Sna
 	return changed, nil
 }
 
+// moveHolds replaces the holds by copies which hold the snapshot id.
+func moveHolds(ctx context.Context, repo *repository.Repository, holds []*restic.Hold, id restic.ID) error {
+	for _, h := range holds {
+		oldID := *h.ID()
+		moved := *h
+		moved.Snapshot = id
+		newID, err := restic.SaveHold(ctx, repo, &moved)
+		if err != nil {
+			return err
+		}
+		if err := repo.RemoveUnpacked(ctx, restic.WriteableHoldFile, oldID); err != nil {
+			return err
+		}
+		debug.Log("hold %v moved to snapshot %v as %v", oldID, id, newID)
+	}
+	return nil
+}
+
 func runTag(ctx context.Context, opts TagOptions, gopts GlobalOptions, term *termstatus.Terminal, args []string) error {
 	if len(opts.SetTags) == 0 && len(opts.AddTags) == 0 && len(opts.RemoveTags) == 0 {
 		return errors.Fatal("nothing to do!")
@@ -134,6 +161,17 @@ func runTag(ctx context.Context, opts TagOptions, gopts GlobalOptions, term *ter
 	}
 	defer unlock()
 
+	// changing tags replaces the snapshot
+	if err := repo.CheckRemove(restic.SnapshotFile); err != nil {
+		return errors.Fatal(err.Error())
+	}
+
+	// holds must follow the snapshots to their new IDs
+	holds, err := restic.LoadHolds(ctx, repo, repo, time.Now())
+	if err != nil {
+		return err
+	}
+
 	printFunc := func(c changedSnapshot) {
 		Verboseff("old snapshot ID: %v -> new snapshot ID: %v\n", c.OldSnapshotID, c.NewSnapshotID)
 	}
@@ -157,7 +195,7 @@ func runTag(ctx context.Context, opts TagOptions, gopts GlobalOptions, term *ter
 	}
 
 	for sn := range FindFilteredSnapshots(ctx, repo, repo, &opts.SnapshotFilter, args) {
-		changed, err := changeTags(ctx, repo, sn, opts.SetTags.Flatten(), opts.AddTags.Flatten(), opts.RemoveTags.Flatten(), printFunc)
+		changed, err := changeTags(ctx, repo, sn, opts.SetTags.Flatten(), opts.AddTags.Flatten(), opts.RemoveTags.Flatten(), holds, printFunc)
 		if err != nil {
 			Warnf("unable to modify the tags for snapshot ID %q, ignoring: %v\n", sn.ID(), err)
 			continue
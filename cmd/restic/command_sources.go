@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/ui"
+	"github.com/restic/restic/internal/ui/backup"
+)
+
+// commandSource is a command whose standard output is stored as a file by
+// backup --commands-from. The command is started once the file is read.
+type commandSource struct {
+	Filename string   `json:"filename"`
+	Command  []string `json:"command"`
+	Timeout  string   `json:"timeout,omitempty"`
+
+	ctx       context.Context
+	timeout   time.Duration
+	logOutput io.Writer
+
+	m        sync.Mutex
+	reader   *fs.CommandReader
+	err      error
+	start    time.Time
+	duration time.Duration
+}
+
+// readCommandSources reads the JSON list of command sources from filename.
+func readCommandSources(filename string) ([]*commandSource, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Fatalf("unable to read command sources: %v", err)
+	}
+
+	var sources []*commandSource
+	if err := json.Unmarshal(buf, &sources); err != nil {
+		return nil, errors.Fatalf("unable to parse command sources in %v: %v", filename, err)
+	}
+	if len(sources) == 0 {
+		return nil, errors.Fatalf("no command sources found in %v", filename)
+	}
+
+	names := make(map[string]struct{})
+	for _, src := range sources {
+		if src.Filename == "" || len(src.Command) == 0 {
+			return nil, errors.Fatalf("command source %q: filename and command must be set", src.Filename)
+		}
+		src.Filename = path.Join("/", src.Filename)
+		if src.Filename == "/" {
+			return nil, errors.Fatalf("command source %q: invalid filename", src.Filename)
+		}
+
+		if src.Timeout != "" {
+			src.timeout, err = time.ParseDuration(src.Timeout)
+			if err != nil || src.timeout <= 0 {
+				return nil, errors.Fatalf("command source %v: invalid timeout %q", src.Filename, src.Timeout)
+			}
+		}
+
+		names[src.Filename] = struct{}{}
+	}
+
+	// a filename must neither be used twice nor be a directory of another file
+	for _, src := range sources {
+		for dir := path.Dir(src.Filename); dir != "/"; dir = path.Dir(dir) {
+			if _, ok := names[dir]; ok {
+				return nil, errors.Fatalf("command source %v conflicts with %v", dir, src.Filename)
+			}
+		}
+	}
+	if len(names) != len(sources) {
+		return nil, errors.Fatal("command sources must use different filenames")
+	}
+
+	return sources, nil
+}
+
+// Read starts the command on the first call and returns its output.
+func (src *commandSource) Read(p []byte) (int, error) {
+	src.m.Lock()
+	defer src.m.Unlock()
+
+	if src.reader == nil && src.err == nil {
+		src.start = time.Now()
+		src.reader, src.err = fs.NewCommandReaderWithOptions(src.ctx, src.Command, src.logOutput,
+			fs.CommandReaderOptions{Timeout: src.timeout, NonFatal: true})
+		if src.err != nil {
+			src.duration = time.Since(src.start)
+		}
+	}
+	if src.err != nil {
+		return 0, src.err
+	}
+
+	n, err := src.reader.Read(p)
+	if err != nil {
+		src.duration = time.Since(src.start)
+		if err != io.EOF {
+			src.err = err
+		}
+	}
+	return n, err
+}
+
+func (src *commandSource) Close() error {
+	src.m.Lock()
+	defer src.m.Unlock()
+
+	if src.reader == nil {
+		return nil
+	}
+	err := src.reader.Close()
+	if err != nil && src.err == nil {
+		src.duration = time.Since(src.start)
+		src.err = err
+	}
+	return err
+}
+
+// commandSourceStatus reports the outcome of a command source.
+type commandSourceStatus struct {
+	MessageType string   `json:"message_type"` // "command_source"
+	Filename    string   `json:"filename"`
+	Command     []string `json:"command"`
+	Status      string   `json:"status"` // "success", "failed" or "not_run"
+	ExitCode    int      `json:"exit_code"`
+	Duration    float64  `json:"duration_seconds"`
+	Error       string   `json:"error,omitempty"`
+	Stderr      string   `json:"stderr,omitempty"`
+}
+
+// Status returns the outcome of running the command.
+func (src *commandSource) Status() commandSourceStatus {
+	src.m.Lock()
+	defer src.m.Unlock()
+
+	status := commandSourceStatus{
+		MessageType: "command_source",
+		Filename:    src.Filename,
+		Command:     src.Command,
+		Status:      "success",
+		ExitCode:    -1,
+		Duration:    src.duration.Seconds(),
+	}
+	if src.reader != nil {
+		status.ExitCode = src.reader.ExitCode()
+		status.Stderr = src.reader.Stderr()
+	} else if src.err == nil {
+		status.Status = "not_run"
+	}
+	if src.err != nil {
+		status.Status = "failed"
+		status.Error = src.err.Error()
+	}
+	return status
+}
+
+// reportCommandSources prints the status of all command sources and returns
+// whether any command failed.
+func reportCommandSources(sources []*commandSource, term ui.Terminal, printer backup.ProgressPrinter, json bool) (failed bool) {
+	for _, src := range sources {
+		status := src.Status()
+		if status.Status == "failed" {
+			failed = true
+		}
+
+		if json {
+			term.Print(ui.ToJSONString(status))
+			continue
+		}
+
+		switch status.Status {
+		case "success":
+			printer.V("command for %v succeeded in %.1fs", status.Filename, status.Duration)
+		case "not_run":
+			term.Error(fmt.Sprintf("command for %v was not run", status.Filename))
+		case "failed":
+			msg := fmt.Sprintf("command for %v failed: %v", status.Filename, status.Error)
+			if status.Stderr != "" {
+				msg += "\n  stderr: " + strings.ReplaceAll(status.Stderr, "\n", "\n  stderr: ")
+			}
+			term.Error(msg)
+		}
+	}
+	return failed
+}
@@ -72,6 +72,8 @@ type GlobalOptions struct {
 	PackSize           uint
 	NoExtraVerify      bool
 	InsecureNoPassword bool
+	Profile            string
+	ProfilesFile       string
 
 	backend.TransportOptions
 	limiter.Limits
@@ -122,6 +124,8 @@ func (opts *GlobalOptions) AddFlags(f *pflag.FlagSet) {
 	f.StringSliceVarP(&opts.Options, "option", "o", []string{}, "set extended option (`key=value`, can be specified multiple times)")
 	f.StringVar(&opts.HTTPUserAgent, "http-user-agent", "", "set a http user agent for outgoing http requests")
 	f.DurationVar(&opts.StuckRequestTimeout, "stuck-request-timeout", 5*time.Minute, "`duration` after which to retry stuck requests")
+	f.StringVar(&opts.Profile, "profile", "", "use the settings of the profile `name` from the profiles file (default: $RESTIC_PROFILE)")
+	f.StringVar(&opts.ProfilesFile, "profiles-file", "", "`file` to read the profiles from (default: $RESTIC_PROFILES_FILE or restic/profiles.yaml in the user config directory)")
 
 	opts.Repo = os.Getenv("RESTIC_REPOSITORY")
 	opts.RepositoryFile = os.Getenv("RESTIC_REPOSITORY_FILE")
@@ -144,6 +148,9 @@ func (opts *GlobalOptions) AddFlags(f *pflag.FlagSet) {
 	if os.Getenv("RESTIC_HTTP_USER_AGENT") != "" {
 		opts.HTTPUserAgent = os.Getenv("RESTIC_HTTP_USER_AGENT")
 	}
+
+	opts.Profile = os.Getenv("RESTIC_PROFILE")
+	opts.ProfilesFile = os.Getenv("RESTIC_PROFILES_FILE")
 }
 
 func (opts *GlobalOptions) PreRun(needsPassword bool) error {
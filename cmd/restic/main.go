@@ -46,6 +46,9 @@ The full documentation can be found at https://restic.readthedocs.io/ .
 		DisableAutoGenTag: true,
 
 		PersistentPreRunE: func(c *cobra.Command, _ []string) error {
+			if err := applyProfile(c, &globalOptions); err != nil {
+				return err
+			}
 			return globalOptions.PreRun(needsPassword(c.Name()))
 		},
 	}
@@ -71,6 +74,7 @@ The full documentation can be found at https://restic.readthedocs.io/ .
 		newCacheCommand(),
 		newCatCommand(),
 		newCheckCommand(),
+		newConfigCommand(),
 		newCopyCommand(),
 		newDiffCommand(),
 		newDumpCommand(),
@@ -78,6 +82,8 @@ The full documentation can be found at https://restic.readthedocs.io/ .
 		newFindCommand(),
 		newForgetCommand(),
 		newGenerateCommand(),
+		newHistoryCommand(),
+		newHoldCommand(),
 		newInitCommand(),
 		newKeyCommand(),
 		newListCommand(),
@@ -90,6 +96,7 @@ The full documentation can be found at https://restic.readthedocs.io/ .
 		newRepairCommand(),
 		newRestoreCommand(),
 		newRewriteCommand(),
+		newServeCommand(),
 		newSnapshotsCommand(),
 		newStatsCommand(),
 		newTagCommand(),
@@ -110,7 +117,7 @@ The full documentation can be found at https://restic.readthedocs.io/ .
 // user for authentication).
 func needsPassword(cmd string) bool {
 	switch cmd {
-	case "cache", "generate", "help", "options", "self-update", "version", "__complete":
+	case "cache", "generate", "help", "options", "rest", "self-update", "show", "version", "__complete":
 		return false
 	default:
 		return true
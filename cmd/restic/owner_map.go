@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/spf13/pflag"
+)
+
+// ownerMapOptions collects the options to rewrite the ownership of items
+// when extracting them from a snapshot.
+type ownerMapOptions struct {
+	MapUID         []string
+	MapGID         []string
+	MapOwnerByName bool
+}
+
+func (opts *ownerMapOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringArrayVar(&opts.MapUID, "map-uid", nil, "map the user id `old:new` stored in the snapshot to another id (can be specified multiple times)")
+	f.StringArrayVar(&opts.MapGID, "map-gid", nil, "map the group id `old:new` stored in the snapshot to another id (can be specified multiple times)")
+	f.BoolVar(&opts.MapOwnerByName, "map-owner-by-name", false, "map ownership using the user and group names stored in the snapshot, falls back to --map-uid and --map-gid for unknown names")
+}
+
+// OwnerMap returns the ownership mapping described by the options. It returns
+// nil if no mapping is requested.
+func (opts *ownerMapOptions) OwnerMap() (*fs.OwnerMap, error) {
+	if len(opts.MapUID) == 0 && len(opts.MapGID) == 0 && !opts.MapOwnerByName {
+		return nil, nil
+	}
+
+	uids, err := parseIDMappings(opts.MapUID)
+	if err != nil {
+		return nil, errors.Fatalf("--map-uid: %v", err)
+	}
+	gids, err := parseIDMappings(opts.MapGID)
+	if err != nil {
+		return nil, errors.Fatalf("--map-gid: %v", err)
+	}
+
+	return &fs.OwnerMap{
+		UIDs:   uids,
+		GIDs:   gids,
+		ByName: opts.MapOwnerByName,
+	}, nil
+}
+
+func parseIDMappings(mappings []string) (map[uint32]uint32, error) {
+	ids := make(map[uint32]uint32, len(mappings))
+	for _, mapping := range mappings {
+		from, to, err := fs.ParseIDMapping(mapping)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := ids[from]; ok && prev != to {
+			return nil, errors.Errorf("conflicting mappings for id %d", from)
+		}
+		ids[from] = to
+	}
+	return ids, nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// profileGlobalSection is the name of the profile section which contains the
+// global options.
+const profileGlobalSection = "global"
+
+// profileFile is the format of the profiles file. Each profile consists of
+// sections, which are either "global" or the name of a command. A section
+// maps flag names to their values, lists are passed as repeated flags.
+//
+//	profiles:
+//	  web:
+//	    global:
+//	      repo: sftp:backup@host:/srv/restic
+//	      password-command: cat /etc/restic/password
+//	    backup:
+//	      exclude: ["*.tmp", "/var/cache"]
+//	      tag: [web]
+//	    forget:
+//	      keep-daily: 7
+type profileFile struct {
+	Profiles map[string]map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// configProfile contains the settings of a single profile.
+type configProfile struct {
+	Name     string
+	Filename string
+	Sections map[string]map[string]interface{}
+}
+
+// profileEnv lists the environment variables which are used as default value
+// for a flag. A flag set via the environment is not overwritten by a profile.
+var profileEnv = map[string][]string{
+	"repo":             {"RESTIC_REPOSITORY"},
+	"repository-file":  {"RESTIC_REPOSITORY_FILE"},
+	"password-file":    {"RESTIC_PASSWORD_FILE"},
+	"password-command": {"RESTIC_PASSWORD_COMMAND"},
+	"key-hint":         {"RESTIC_KEY_HINT"},
+	"cacert":           {"RESTIC_CACERT"},
+	"tls-client-cert":  {"RESTIC_TLS_CLIENT_CERT"},
+	"compression":      {"RESTIC_COMPRESSION"},
+	"pack-size":        {"RESTIC_PACK_SIZE"},
+	"http-user-agent":  {"RESTIC_HTTP_USER_AGENT"},
+	"host":             {"RESTIC_HOST"},
+	"read-concurrency": {"RESTIC_READ_CONCURRENCY"},
+	"walk-concurrency": {"RESTIC_WALK_CONCURRENCY"},
+}
+
+// profileExclusive lists flags which must not be combined. If one of them is
+// set via a flag or the environment, the others are not set by a profile.
+var profileExclusive = [][]string{
+	{"repo", "repository-file"},
+	{"password-file", "password-command"},
+}
+
+// profileAnnotation marks flags which were set from a profile.
+const profileAnnotation = "restic_profile"
+
+// Sources of a setting as reported by `config show`.
+const (
+	settingSourceFlag    = "flag"
+	settingSourceEnv     = "environment"
+	settingSourceProfile = "profile"
+)
+
+func defaultProfilesFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "restic", "profiles.yaml")
+}
+
+// loadProfile reads the profile name from the profiles file filename. If
+// filename is empty, the default profiles file is used.
+func loadProfile(filename, name string) (*configProfile, error) {
+	if filename == "" {
+		filename = defaultProfilesFile()
+	}
+	if filename == "" {
+		return nil, errors.Fatal("unable to locate the profiles file, use --profiles-file")
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Fatalf("unable to read profiles file: %v", err)
+	}
+
+	var f profileFile
+	if err := yaml.Unmarshal(buf, &f); err != nil {
+		return nil, errors.Fatalf("unable to parse profiles file %v: %v", filename, err)
+	}
+
+	sections, ok := f.Profiles[name]
+	if !ok {
+		return nil, errors.Fatalf("profile %q not found in %v", name, filename)
+	}
+
+	return &configProfile{Name: name, Filename: filename, Sections: sections}, nil
+}
+
+// sectionName returns the name of the profile section for cmd, for example
+// "backup" or "key add".
+func sectionName(cmd *cobra.Command) string {
+	path := strings.Fields(cmd.CommandPath())
+	return strings.Join(path[1:], " ")
+}
+
+// profileValues converts a value from the profiles file to a list of flag
+// values.
+func profileValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			switch item.(type) {
+			case []interface{}, map[string]interface{}:
+				return nil, errors.New("nested values are not supported")
+			}
+			values = append(values, fmt.Sprint(item))
+		}
+		return values, nil
+	case map[string]interface{}:
+		return nil, errors.New("nested values are not supported")
+	case nil:
+		return nil, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+// settingSource returns whether the flag was set via the command line, the
+// environment or a profile. An empty string is returned otherwise.
+func settingSource(flags *pflag.FlagSet, name string) string {
+	f := flags.Lookup(name)
+	if f != nil && f.Changed {
+		if _, ok := f.Annotations[profileAnnotation]; ok {
+			return settingSourceProfile
+		}
+		return settingSourceFlag
+	}
+	for _, env := range profileEnv[name] {
+		if os.Getenv(env) != "" {
+			return settingSourceEnv
+		}
+	}
+	return ""
+}
+
+// overridden returns whether the flag was set via the command line or the
+// environment, either directly or by setting a mutually exclusive flag.
+func overridden(flags *pflag.FlagSet, name string) bool {
+	isSet := func(name string) bool {
+		source := settingSource(flags, name)
+		return source == settingSourceFlag || source == settingSourceEnv
+	}
+
+	if isSet(name) {
+		return true
+	}
+	for _, group := range profileExclusive {
+		for _, member := range group {
+			if member != name {
+				continue
+			}
+			for _, other := range group {
+				if isSet(other) {
+					return true
+				}
+			}
+		}
+	}
+	if name == "password-file" || name == "password-command" {
+		// an explicitly passed password always takes precedence
+		return os.Getenv("RESTIC_PASSWORD") != ""
+	}
+	return false
+}
+
+// applySection sets all flags listed in the section, unless they are
+// overridden by the command line or the environment.
+func applySection(flags *pflag.FlagSet, section string, settings map[string]interface{}) error {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if flags.Lookup(name) == nil {
+			return errors.Fatalf("unknown option %q in profile section %q", name, section)
+		}
+		if overridden(flags, name) {
+			continue
+		}
+
+		values, err := profileValues(settings[name])
+		if err != nil {
+			return errors.Fatalf("invalid value for %q in profile section %q: %v", name, section, err)
+		}
+		for _, value := range values {
+			if err := flags.Set(name, value); err != nil {
+				return errors.Fatalf("invalid value for %q in profile section %q: %v", name, section, err)
+			}
+		}
+		if err := flags.SetAnnotation(name, profileAnnotation, []string{section}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply sets the global options and the options of cmd from the profile.
+func (p *configProfile) apply(cmd *cobra.Command) error {
+	for section := range p.Sections {
+		if section == profileGlobalSection {
+			continue
+		}
+		if c, _, err := cmd.Root().Find(strings.Fields(section)); err != nil || sectionName(c) != section {
+			return errors.Fatalf("unknown command %q in profile %q", section, p.Name)
+		}
+	}
+
+	err := applySection(cmd.Root().PersistentFlags(), profileGlobalSection, p.Sections[profileGlobalSection])
+	if err != nil {
+		return err
+	}
+
+	section := sectionName(cmd)
+	if section == "" {
+		return nil
+	}
+	return applySection(cmd.LocalFlags(), section, p.Sections[section])
+}
+
+// applyProfile loads the profile selected via --profile, if any, and applies
+// it to cmd.
+func applyProfile(cmd *cobra.Command, opts *GlobalOptions) error {
+	if opts.Profile == "" {
+		return nil
+	}
+
+	p, err := loadProfile(opts.ProfilesFile, opts.Profile)
+	if err != nil {
+		return err
+	}
+	return p.apply(cmd)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+const testProfiles = `
+profiles:
+  web:
+    global:
+      repo: /srv/restic
+      password-file: /etc/restic/password
+    backup:
+      tag: [web, daily]
+      exclude-caches: true
+`
+
+func newProfileTestCommand() (*cobra.Command, *GlobalOptions, *BackupOptions) {
+	var gopts GlobalOptions
+	var opts BackupOptions
+
+	root := &cobra.Command{Use: "restic"}
+	gopts.AddFlags(root.PersistentFlags())
+	backup := &cobra.Command{Use: "backup", RunE: func(*cobra.Command, []string) error { return nil }}
+	opts.AddFlags(backup.Flags())
+	root.AddCommand(backup)
+
+	return backup, &gopts, &opts
+}
+
+func writeTestProfiles(t *testing.T, data string) string {
+	filename := filepath.Join(rtest.TempDir(t), "profiles.yaml")
+	rtest.OK(t, os.WriteFile(filename, []byte(data), 0600))
+	return filename
+}
+
+func TestProfileApply(t *testing.T) {
+	for _, env := range []string{"RESTIC_REPOSITORY", "RESTIC_REPOSITORY_FILE", "RESTIC_PASSWORD", "RESTIC_PASSWORD_FILE", "RESTIC_PASSWORD_COMMAND"} {
+		t.Setenv(env, "")
+	}
+	filename := writeTestProfiles(t, testProfiles)
+
+	p, err := loadProfile(filename, "web")
+	rtest.OK(t, err)
+
+	cmd, gopts, opts := newProfileTestCommand()
+	rtest.OK(t, cmd.Root().ParseFlags([]string{"--password-command", "pass"}))
+	rtest.OK(t, cmd.ParseFlags(nil))
+	rtest.OK(t, p.apply(cmd))
+
+	rtest.Equals(t, "/srv/restic", gopts.Repo)
+	// password-file is mutually exclusive with the password-command flag
+	rtest.Equals(t, "", gopts.PasswordFile)
+	rtest.Equals(t, "pass", gopts.PasswordCommand)
+	rtest.Equals(t, restic.TagList{"web", "daily"}, opts.Tags.Flatten())
+	rtest.Equals(t, true, opts.ExcludeCaches)
+
+	flags := cmd.Root().PersistentFlags()
+	rtest.Equals(t, settingSourceProfile, settingSource(flags, "repo"))
+	rtest.Equals(t, settingSourceFlag, settingSource(flags, "password-command"))
+	rtest.Equals(t, "", settingSource(flags, "password-file"))
+}
+
+func TestProfileEnvironmentPrecedence(t *testing.T) {
+	t.Setenv("RESTIC_REPOSITORY", "/from/env")
+	t.Setenv("RESTIC_PASSWORD", "secret")
+	filename := writeTestProfiles(t, testProfiles)
+
+	p, err := loadProfile(filename, "web")
+	rtest.OK(t, err)
+
+	cmd, gopts, _ := newProfileTestCommand()
+	rtest.OK(t, p.apply(cmd))
+
+	rtest.Equals(t, "/from/env", gopts.Repo)
+	rtest.Equals(t, "", gopts.PasswordFile)
+	rtest.Equals(t, settingSourceEnv, settingSource(cmd.Root().PersistentFlags(), "repo"))
+}
+
+func TestProfileErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, data, profile string
+	}{
+		{"missing profile", testProfiles, "db"},
+		{"unknown command", "profiles: {web: {foobar: {tag: x}}}", "web"},
+		{"unknown option", "profiles: {web: {backup: {foobar: x}}}", "web"},
+		{"global option in command section", "profiles: {web: {backup: {repo: x}}}", "web"},
+		{"nested value", "profiles: {web: {backup: {tag: [[x]]}}}", "web"},
+		{"invalid value", "profiles: {web: {backup: {exclude-caches: maybe}}}", "web"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filename := writeTestProfiles(t, test.data)
+			p, err := loadProfile(filename, test.profile)
+			if err == nil {
+				cmd, _, _ := newProfileTestCommand()
+				err = p.apply(cmd)
+			}
+			rtest.Assert(t, err != nil, "expected an error")
+		})
+	}
+}
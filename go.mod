@@ -41,6 +41,7 @@ require (
 	golang.org/x/text v0.31.0
 	golang.org/x/time v0.11.0
 	google.golang.org/api v0.227.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -97,5 +98,4 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250313205543-e70fdf4c4cb4 // indirect
 	google.golang.org/grpc v1.71.0 // indirect
 	google.golang.org/protobuf v1.36.5 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,39 +1,256 @@
 package hostinger
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/restic/restic/internal/archiver"
 	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/fs"
 )
 
-// RejectSymlinksOutsideScope rejects symlinks that target
-// files outside of the specified path.
-func RejectSymlinksOutsideScope(scopePath string) (archiver.RejectFunc, error) {
-	var err error
+// DanglingSymlinkPolicy determines how symlinks are handled whose target does
+// not exist.
+type DanglingSymlinkPolicy int
 
-	if !filepath.IsAbs(scopePath) {
-		scopePath, err = filepath.Abs(scopePath)
+// Constants for the different dangling symlink policies
+const (
+	// DanglingSymlinkDrop excludes all dangling symlinks.
+	DanglingSymlinkDrop DanglingSymlinkPolicy = iota
+	// DanglingSymlinkKeep keeps dangling symlinks whose target would be
+	// located within one of the scopes.
+	DanglingSymlinkKeep
+	// DanglingSymlinkKeepAsIs keeps all dangling symlinks, regardless of
+	// their target.
+	DanglingSymlinkKeepAsIs
+	DanglingSymlinkInvalid
+)
+
+// Set implements the method needed for pflag command flag parsing.
+func (p *DanglingSymlinkPolicy) Set(s string) error {
+	switch s {
+	case "drop":
+		*p = DanglingSymlinkDrop
+	case "keep":
+		*p = DanglingSymlinkKeep
+	case "keep-as-is":
+		*p = DanglingSymlinkKeepAsIs
+	default:
+		*p = DanglingSymlinkInvalid
+		return fmt.Errorf("invalid dangling symlink policy %q, must be one of (drop|keep|keep-as-is)", s)
+	}
+
+	return nil
+}
+
+func (p *DanglingSymlinkPolicy) String() string {
+	switch *p {
+	case DanglingSymlinkDrop:
+		return "drop"
+	case DanglingSymlinkKeep:
+		return "keep"
+	case DanglingSymlinkKeepAsIs:
+		return "keep-as-is"
+	default:
+		return "invalid"
+	}
+}
+
+func (p *DanglingSymlinkPolicy) Type() string {
+	return "policy"
+}
+
+// maxSymlinkHops limits the number of symlinks followed while resolving a
+// single path.
+const maxSymlinkHops = 255
+
+// SymlinkScope rejects items whose resolved location is not contained in one
+// of several scope directories. Containment is checked per path component,
+// thus the scope "/home/user1" does not contain "/home/user10".
+type SymlinkScope struct {
+	scopes   []string
+	dangling DanglingSymlinkPolicy
+
+	// Excluded is called once for each rejected item along with the reason.
+	Excluded func(item, reason string)
+
+	m        sync.Mutex
+	reported map[string]struct{}
+}
+
+// NewSymlinkScope returns a SymlinkScope which allows the given scope
+// directories. Relative scopes are interpreted relative to the current
+// working directory.
+func NewSymlinkScope(scopes []string, dangling DanglingSymlinkPolicy) (*SymlinkScope, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("no symlink scope specified")
+	}
+	if dangling == DanglingSymlinkInvalid {
+		return nil, errors.New("invalid dangling symlink policy")
+	}
+
+	s := &SymlinkScope{
+		dangling: dangling,
+		Excluded: func(string, string) {},
+		reported: make(map[string]struct{}),
+	}
+
+	for _, scope := range scopes {
+		scope, err := filepath.Abs(scope)
 		if err != nil {
 			return nil, err
 		}
+
+		// the scope itself may be located below a symlink
+		resolved, dangling, err := resolveSymlinks(scope)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve scope %v: %w", scope, err)
+		}
+		if !dangling {
+			scope = resolved
+		}
+
+		s.scopes = append(s.scopes, scope)
+	}
+
+	return s, nil
+}
+
+// contains returns whether path is located within one of the scopes.
+func (s *SymlinkScope) contains(path string) bool {
+	for _, scope := range s.scopes {
+		if fs.HasPathPrefix(scope, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns whether the item at path should be included. Otherwise, it
+// also returns the reason why it was rejected.
+func (s *SymlinkScope) Check(path string) (include bool, reason string) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false, fmt.Sprintf("cannot determine absolute path: %v", err)
 	}
 
+	resolved, dangling, err := resolveSymlinks(path)
+	if err != nil {
+		return false, fmt.Sprintf("cannot resolve symlink: %v", err)
+	}
+
+	if dangling {
+		switch s.dangling {
+		case DanglingSymlinkKeepAsIs:
+			return true, ""
+		case DanglingSymlinkKeep:
+			if s.contains(resolved) {
+				return true, ""
+			}
+			return false, fmt.Sprintf("dangling symlink to %v is outside of scope", resolved)
+		default:
+			return false, fmt.Sprintf("dangling symlink to %v", resolved)
+		}
+	}
+
+	if !s.contains(resolved) {
+		return false, fmt.Sprintf("resolves to %v which is outside of scope", resolved)
+	}
+	return true, ""
+}
+
+// RejectFunc returns a function which rejects all items that are not
+// contained in one of the scopes.
+func (s *SymlinkScope) RejectFunc() archiver.RejectFunc {
 	return func(path string, _ *fs.ExtendedFileInfo, _ fs.FS) bool {
-		target, err := filepath.EvalSymlinks(path)
+		include, reason := s.Check(path)
+		if include {
+			return false
+		}
+
+		debug.Log("rejecting %v: %v", path, reason)
+		s.report(path, reason)
+		return true
+	}
+}
+
+// report calls Excluded only once per item, as both the scanner and the
+// archiver check each item.
+func (s *SymlinkScope) report(path, reason string) {
+	s.m.Lock()
+	_, ok := s.reported[path]
+	if !ok {
+		s.reported[path] = struct{}{}
+	}
+	s.m.Unlock()
+
+	if !ok {
+		s.Excluded(path, reason)
+	}
+}
+
+// resolveSymlinks resolves all symlinks contained in the absolute path p one
+// path component at a time. Relative link targets are interpreted relative to
+// the directory containing the link. If a path component does not exist, the
+// remaining path is appended lexically and dangling is set.
+func resolveSymlinks(p string) (resolved string, dangling bool, err error) {
+	if !filepath.IsAbs(p) {
+		return "", false, fmt.Errorf("path %v is not absolute", p)
+	}
+
+	volume := filepath.VolumeName(p)
+	resolved = volume + string(filepath.Separator)
+	rest := p[len(volume):]
+	hops := 0
+
+	for rest != "" {
+		var component string
+		component, rest, _ = strings.Cut(strings.TrimLeft(rest, string(filepath.Separator)), string(filepath.Separator))
+
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, component)
+		fi, err := os.Lstat(next)
 		if err != nil {
-			// reject symlink if we cannot determine the target
-			debug.Log("could not eval symlinks: %s", path)
-			return true
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+				return filepath.Join(next, rest), true, nil
+			}
+			return "", false, err
 		}
 
-		if !strings.HasPrefix(target, scopePath) {
-			debug.Log("eval path of %s (%s) is outside of scope: %s", path, target, scopePath)
-			return true
+		if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
 		}
 
-		return false
-	}, nil
+		hops++
+		if hops > maxSymlinkHops {
+			return "", false, fmt.Errorf("too many levels of symbolic links in %v", p)
+		}
+
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", false, err
+		}
+
+		if filepath.IsAbs(target) {
+			volume = filepath.VolumeName(target)
+			resolved = volume + string(filepath.Separator)
+			target = target[len(volume):]
+		}
+		rest = target + string(filepath.Separator) + rest
+	}
+
+	return resolved, false, nil
 }
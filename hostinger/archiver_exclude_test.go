@@ -12,7 +12,7 @@ import (
 
 // TestRejectSymlinksOutsideScopeExcludesBySymlinkScope is for testing
 // the instance of --scope-symlinks parameter
-func TestRejectSymlinksOutsideScopeExcludesBySymlinkScope(t *testing.T) {
+func TestSymlinkScopeExcludesBySymlinkScope(t *testing.T) {
 	tempDir := test.TempDir(t)
 
 	scopePath := filepath.Join(tempDir, "foodir")
@@ -80,7 +80,9 @@ func TestRejectSymlinksOutsideScopeExcludesBySymlinkScope(t *testing.T) {
 	test.OK(t, err)
 
 	// create rejection function
-	scopeExclude, _ := RejectSymlinksOutsideScope(scopePath)
+	scope, err := NewSymlinkScope([]string{scopePath}, DanglingSymlinkDrop)
+	test.OK(t, err)
+	scopeExclude := scope.RejectFunc()
 
 	// test a case when the file itself is not a symlink
 	// but it still should be excluded
@@ -120,3 +122,93 @@ func TestRejectSymlinksOutsideScopeExcludesBySymlinkScope(t *testing.T) {
 		}
 	}
 }
+
+func TestSymlinkScopeComponentContainment(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(test.TempDir(t))
+	test.OK(t, err)
+
+	for _, dir := range []string{"home/user1", "home/user10", "home/user2"} {
+		test.OK(t, os.MkdirAll(filepath.Join(tempDir, filepath.FromSlash(dir)), 0700))
+	}
+	test.OK(t, os.Symlink(filepath.Join(tempDir, "home", "user10"), filepath.Join(tempDir, "home", "user1", "neighbour")))
+	test.OK(t, os.Symlink("../user2", filepath.Join(tempDir, "home", "user1", "second")))
+	test.OK(t, os.Symlink("sub/../../user1", filepath.Join(tempDir, "home", "user1", "self")))
+
+	scope, err := NewSymlinkScope([]string{filepath.Join(tempDir, "home", "user1")}, DanglingSymlinkDrop)
+	test.OK(t, err)
+
+	include, reason := scope.Check(filepath.Join(tempDir, "home", "user1", "neighbour"))
+	test.Assert(t, !include, "symlink to /home/user10 must not be contained in /home/user1")
+	test.Assert(t, strings.Contains(reason, "outside of scope"), "unexpected reason %q", reason)
+
+	include, _ = scope.Check(filepath.Join(tempDir, "home", "user1", "second"))
+	test.Assert(t, !include, "symlink to /home/user2 must not be contained in /home/user1")
+
+	// the link target is resolved component by component, "sub" does not exist
+	include, _ = scope.Check(filepath.Join(tempDir, "home", "user1", "self"))
+	test.Assert(t, !include, "dangling symlink must be dropped")
+
+	// multiple scopes
+	scope, err = NewSymlinkScope([]string{
+		filepath.Join(tempDir, "home", "user1"),
+		filepath.Join(tempDir, "home", "user2"),
+	}, DanglingSymlinkDrop)
+	test.OK(t, err)
+
+	include, _ = scope.Check(filepath.Join(tempDir, "home", "user1", "second"))
+	test.Assert(t, include, "symlink to /home/user2 must be contained in the second scope")
+	include, _ = scope.Check(filepath.Join(tempDir, "home", "user1", "neighbour"))
+	test.Assert(t, !include, "symlink to /home/user10 must not be contained in any scope")
+}
+
+func TestSymlinkScopeDanglingPolicy(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(test.TempDir(t))
+	test.OK(t, err)
+
+	scopeDir := filepath.Join(tempDir, "scope")
+	test.OK(t, os.MkdirAll(scopeDir, 0700))
+	inside := filepath.Join(scopeDir, "inside")
+	outside := filepath.Join(scopeDir, "outside")
+	test.OK(t, os.Symlink("missing/file", inside))
+	test.OK(t, os.Symlink(filepath.Join(tempDir, "missing"), outside))
+
+	for _, tc := range []struct {
+		policy  string
+		inside  bool
+		outside bool
+	}{
+		{"drop", false, false},
+		{"keep", true, false},
+		{"keep-as-is", true, true},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			var policy DanglingSymlinkPolicy
+			test.OK(t, policy.Set(tc.policy))
+			test.Equals(t, tc.policy, policy.String())
+
+			scope, err := NewSymlinkScope([]string{scopeDir}, policy)
+			test.OK(t, err)
+
+			excluded := make(map[string]string)
+			scope.Excluded = func(item, reason string) {
+				excluded[item] = reason
+			}
+			reject := scope.RejectFunc()
+
+			for path, include := range map[string]bool{inside: tc.inside, outside: tc.outside} {
+				fi, err := os.Lstat(path)
+				test.OK(t, err)
+				// called twice to check that each item is only reported once
+				for i := 0; i < 2; i++ {
+					rejected := reject(path, fs.ExtendedStat(fi), fs.Local{})
+					test.Equals(t, !include, rejected)
+				}
+				_, reported := excluded[path]
+				test.Equals(t, !include, reported)
+			}
+		})
+	}
+
+	var policy DanglingSymlinkPolicy
+	test.Assert(t, policy.Set("invalid") != nil, "expected error for invalid policy")
+}
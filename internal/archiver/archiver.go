@@ -100,8 +100,11 @@ type Archiver struct {
 	blobSaver *blobSaver
 	fileSaver *fileSaver
 	treeSaver *treeSaver
-	mu        sync.Mutex
-	summary   *Summary
+	// walkers limits the number of additional goroutines which traverse
+	// directories
+	walkers chan struct{}
+	mu      sync.Mutex
+	summary *Summary
 
 	// Error is called for all errors that occur during backup.
 	Error ErrorFunc
@@ -131,6 +134,11 @@ type Archiver struct {
 
 	// Flags controlling change detection. See doc/040_backup.rst for details.
 	ChangeIgnoreFlags uint
+
+	// ChangeSource, if set, reports which items have changed since the parent
+	// snapshot. Unchanged items are copied from the parent snapshot without
+	// reading them.
+	ChangeSource ChangeSource
 }
 
 // Flags for the ChangeIgnoreFlags bitfield.
@@ -154,6 +162,12 @@ type Options struct {
 	// SaveTreeConcurrency sets how many trees are marshalled and saved to the
 	// repo concurrently.
 	SaveTreeConcurrency uint
+
+	// WalkConcurrency sets how many goroutines traverse directories
+	// concurrently, including the one calling Snapshot(). If it's set to
+	// zero, directories are traversed sequentially. The resulting trees do
+	// not depend on this setting.
+	WalkConcurrency uint
 }
 
 // ApplyDefaults returns a copy of o with the default options set for all unset
@@ -181,6 +195,10 @@ func (o Options) ApplyDefaults() Options {
 		o.SaveTreeConcurrency = uint(runtime.GOMAXPROCS(0)) + o.ReadConcurrency
 	}
 
+	if o.WalkConcurrency == 0 {
+		o.WalkConcurrency = 1
+	}
+
 	return o
 }
 
@@ -321,41 +339,99 @@ func (arch *Archiver) saveDir(ctx context.Context, snPath string, dir string, me
 		return futureNode{}, err
 	}
 
+	nodes, err := arch.saveDirEntries(ctx, snPath, dir, names, previous)
+	if err != nil {
+		return futureNode{}, err
+	}
+
+	fn := arch.treeSaver.Save(ctx, snPath, dir, treeNode, nodes, complete)
+
+	return fn, nil
+}
+
+type saveEntryResult struct {
+	fn       futureNode
+	excluded bool
+	err      error
+}
+
+// saveDirEntries saves the entries names of the directory dir and returns the
+// nodes in the same order. If a walker is available, an entry is saved in a
+// separate goroutine, otherwise it is saved directly. All entries have been
+// passed on to the file and tree savers once saveDirEntries returns, such that
+// the tree of dir is always saved after the trees of its subdirectories.
+func (arch *Archiver) saveDirEntries(ctx context.Context, snPath, dir string, names []string, previous *restic.Tree) ([]futureNode, error) {
+	results := make([]saveEntryResult, len(names))
+	var wg sync.WaitGroup
+	// wait for all started goroutines before returning
+	defer wg.Wait()
+
 	nodes := make([]futureNode, 0, len(names))
+	handleResult := func(pathname string, res saveEntryResult) error {
+		// return error early if possible
+		if res.err != nil {
+			err := arch.error(pathname, res.err)
+			if err == nil {
+				// ignore error
+				return nil
+			}
+			return err
+		}
 
-	for _, name := range names {
+		if !res.excluded {
+			nodes = append(nodes, res.fn)
+		}
+		return nil
+	}
+
+	// number of entries which have already been handled
+	handled := 0
+	async := false
+	for i, name := range names {
 		// test if context has been cancelled
 		if ctx.Err() != nil {
 			debug.Log("context has been cancelled, aborting")
-			return futureNode{}, ctx.Err()
+			return nil, ctx.Err()
 		}
 
 		pathname := arch.FS.Join(dir, name)
 		oldNode := previous.Find(name)
 		snItem := join(snPath, name)
-		fn, excluded, err := arch.save(ctx, snItem, pathname, oldNode)
-
-		// return error early if possible
-		if err != nil {
-			err = arch.error(pathname, err)
-			if err == nil {
-				// ignore error
-				continue
-			}
-
-			return futureNode{}, err
-		}
 
-		if excluded {
+		select {
+		case arch.walkers <- struct{}{}:
+			async = true
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-arch.walkers
+					wg.Done()
+				}()
+				res := &results[i]
+				res.fn, res.excluded, res.err = arch.save(ctx, snItem, pathname, oldNode)
+			}()
 			continue
+		default:
 		}
 
-		nodes = append(nodes, fn)
+		res := &results[i]
+		res.fn, res.excluded, res.err = arch.save(ctx, snItem, pathname, oldNode)
+		if !async {
+			// all previous entries were saved synchronously
+			if err := handleResult(pathname, *res); err != nil {
+				return nil, err
+			}
+			handled++
+		}
 	}
 
-	fn := arch.treeSaver.Save(ctx, snPath, dir, treeNode, nodes, complete)
-
-	return fn, nil
+	wg.Wait()
+	for i := handled; i < len(names); i++ {
+		if err := handleResult(arch.FS.Join(dir, names[i]), results[i]); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
 }
 
 func (arch *Archiver) dirToNodeAndEntries(snPath, dir string, meta fs.File) (node *restic.Node, names []string, err error) {
@@ -476,6 +552,10 @@ func (arch *Archiver) save(ctx context.Context, snPath, target string, previous
 		return futureNode{}, true, nil
 	}
 
+	if fn, ok := arch.reuseUnchanged(snPath, target, abstarget, previous, start); ok {
+		return fn, false, nil
+	}
+
 	meta, err := arch.FS.OpenFile(target, fs.O_NOFOLLOW, true)
 	if err != nil {
 		debug.Log("open metadata for %v returned error: %v", target, err)
@@ -615,6 +695,40 @@ func (arch *Archiver) save(ctx context.Context, snPath, target string, previous
 	return fn, false, nil
 }
 
+// reuseUnchanged returns the previous node if the change source reports that
+// the item has not changed since the parent snapshot. Directories are reused
+// including their subtree.
+func (arch *Archiver) reuseUnchanged(snPath, target, abstarget string, previous *restic.Node, start time.Time) (futureNode, bool) {
+	if arch.ChangeSource == nil || previous == nil || arch.ChangeSource.Changed(abstarget) {
+		return futureNode{}, false
+	}
+
+	switch previous.Type {
+	case restic.NodeTypeDir:
+		if previous.Subtree == nil {
+			return futureNode{}, false
+		}
+		debug.Log("%v is unchanged according to the change source, reusing subtree %v", target, previous.Subtree.Str())
+		arch.trackItem(snPath+"/", previous, previous, ItemStats{}, time.Since(start))
+	case restic.NodeTypeFile:
+		if !arch.allBlobsPresent(previous) {
+			return futureNode{}, false
+		}
+		debug.Log("%v is unchanged according to the change source, using old list of blobs", target)
+		arch.trackItem(snPath, previous, previous, ItemStats{}, time.Since(start))
+		arch.CompleteBlob(previous.Size)
+	default:
+		debug.Log("%v is unchanged according to the change source", target)
+		arch.trackItem(snPath, previous, previous, ItemStats{}, time.Since(start))
+	}
+
+	return newFutureNodeWithResult(futureNodeResult{
+		snPath: snPath,
+		target: target,
+		node:   previous,
+	}), true
+}
+
 // fileChanged tries to detect whether a file's content has changed compared
 // to the contents of node, which describes the same path in the parent backup.
 // It should only be run for regular files.
@@ -798,6 +912,9 @@ type SnapshotOptions struct {
 	ProgramVersion string
 	// SkipIfUnchanged omits the snapshot creation if it is identical to the parent snapshot.
 	SkipIfUnchanged bool
+	// Incomplete is called after all data was saved, if it returns true the
+	// snapshot is marked as incomplete. May be nil.
+	Incomplete func() bool
 }
 
 // loadParentTree loads a tree referenced by snapshot id. If id is null, nil is returned.
@@ -827,12 +944,19 @@ func (arch *Archiver) runWorkers(ctx context.Context, wg *errgroup.Group) {
 
 	arch.fileSaver = newFileSaver(ctx, wg,
 		arch.blobSaver.Save,
-		arch.Repo.Config().ChunkerPolynomial,
+		arch.Repo.Config().ChunkerParams(),
 		arch.Options.ReadConcurrency, arch.Options.SaveBlobConcurrency)
 	arch.fileSaver.CompleteBlob = arch.CompleteBlob
 	arch.fileSaver.NodeFromFileInfo = arch.nodeFromFileInfo
 
 	arch.treeSaver = newTreeSaver(ctx, wg, arch.Options.SaveTreeConcurrency, arch.blobSaver.Save, arch.Error)
+
+	// the goroutine calling Snapshot() also traverses directories
+	walkers := arch.Options.WalkConcurrency
+	if walkers > 0 {
+		walkers--
+	}
+	arch.walkers = make(chan struct{}, walkers)
 }
 
 func (arch *Archiver) stopWorkers() {
@@ -850,79 +974,179 @@ func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts Snaps
 		BackupStart: opts.BackupStart,
 	}
 
-	cleanTargets, err := resolveRelativeTargets(arch.FS, targets)
+	atree, err := arch.prepareTargets(targets)
+	if err != nil {
+		return nil, restic.ID{}, nil, err
+	}
+
+	var rootTreeID restic.ID
+
+	wgUp, wgUpCtx := errgroup.WithContext(ctx)
+	arch.Repo.StartPackUploader(wgUpCtx, wgUp)
+
+	wgUp.Go(func() error {
+		var err error
+		rootTreeID, err = arch.saveTargets(wgUpCtx, atree, opts.ParentSnapshot)
+		if err != nil {
+			return err
+		}
+
+		return arch.Repo.Flush(ctx)
+	})
+	err = wgUp.Wait()
 	if err != nil {
 		return nil, restic.ID{}, nil, err
 	}
+	arch.summary.BackupEnd = time.Now()
 
-	atree, err := newTree(arch.FS, cleanTargets)
+	sn, id, err := arch.saveSnapshot(ctx, targets, rootTreeID, arch.summary, opts)
 	if err != nil {
 		return nil, restic.ID{}, nil, err
 	}
+	return sn, id, arch.summary, nil
+}
 
-	var rootTreeID restic.ID
+// BatchItem describes one of the snapshots created by SnapshotBatch.
+type BatchItem struct {
+	Targets []string
+	Options SnapshotOptions
+}
+
+// BatchResult contains the outcome of archiving a single BatchItem. Snapshot
+// is nil and ID is null if the snapshot creation was skipped.
+type BatchResult struct {
+	Snapshot *restic.Snapshot
+	ID       restic.ID
+	Summary  *Summary
+	Err      error
+}
+
+// SnapshotBatch creates a separate snapshot for each item. In contrast to
+// calling Snapshot repeatedly, all items share a single pack uploader, thus
+// data of several items can end up in the same pack file. The snapshots are
+// only saved once all data has been flushed to the repository.
+//
+// An error while archiving an item is returned in its result and does not
+// affect the other items. The returned error is only set if the batch as a
+// whole failed.
+func (arch *Archiver) SnapshotBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	results := make([]BatchResult, len(items))
+	rootTreeIDs := make([]restic.ID, len(items))
 
 	wgUp, wgUpCtx := errgroup.WithContext(ctx)
 	arch.Repo.StartPackUploader(wgUpCtx, wgUp)
 
 	wgUp.Go(func() error {
-		wg, wgCtx := errgroup.WithContext(wgUpCtx)
-		start := time.Now()
-
-		wg.Go(func() error {
-			arch.runWorkers(wgCtx, wg)
+		for i, item := range items {
+			arch.summary = &Summary{
+				BackupStart: item.Options.BackupStart,
+			}
+			results[i].Summary = arch.summary
 
-			debug.Log("starting snapshot")
-			fn, nodeCount, err := arch.saveTree(wgCtx, "/", atree, arch.loadParentTree(wgCtx, opts.ParentSnapshot), func(_ *restic.Node, is ItemStats) {
-				arch.trackItem("/", nil, nil, is, time.Since(start))
-			})
+			atree, err := arch.prepareTargets(item.Targets)
 			if err != nil {
-				return err
+				results[i].Err = err
+				continue
 			}
 
-			fnr := fn.take(wgCtx)
-			if fnr.err != nil {
-				return fnr.err
+			rootTreeIDs[i], err = arch.saveTargets(wgUpCtx, atree, item.Options.ParentSnapshot)
+			if wgUpCtx.Err() != nil {
+				return wgUpCtx.Err()
 			}
-
-			if wgCtx.Err() != nil {
-				return wgCtx.Err()
+			if err != nil {
+				results[i].Err = err
+				continue
 			}
+			arch.summary.BackupEnd = time.Now()
+		}
 
-			if nodeCount == 0 {
-				return errors.New("snapshot is empty")
-			}
+		return arch.Repo.Flush(ctx)
+	})
+	err := wgUp.Wait()
+	if err != nil {
+		return nil, err
+	}
 
-			rootTreeID = *fnr.node.Subtree
-			arch.stopWorkers()
-			return nil
-		})
+	for i, item := range items {
+		if results[i].Err != nil {
+			continue
+		}
+		results[i].Snapshot, results[i].ID, results[i].Err = arch.saveSnapshot(ctx, item.Targets, rootTreeIDs[i], results[i].Summary, item.Options)
+	}
+
+	return results, nil
+}
+
+// prepareTargets builds the tree of targets which is passed to saveTargets.
+func (arch *Archiver) prepareTargets(targets []string) (*tree, error) {
+	cleanTargets, err := resolveRelativeTargets(arch.FS, targets)
+	if err != nil {
+		return nil, err
+	}
 
-		err = wg.Wait()
-		debug.Log("err is %v", err)
+	return newTree(arch.FS, cleanTargets)
+}
 
+// saveTargets archives atree and returns the ID of the root tree. The pack
+// uploader must already be running.
+func (arch *Archiver) saveTargets(ctx context.Context, atree *tree, parent *restic.Snapshot) (restic.ID, error) {
+	var rootTreeID restic.ID
+
+	wg, wgCtx := errgroup.WithContext(ctx)
+	start := time.Now()
+
+	wg.Go(func() error {
+		arch.runWorkers(wgCtx, wg)
+
+		debug.Log("starting snapshot")
+		fn, nodeCount, err := arch.saveTree(wgCtx, "/", atree, arch.loadParentTree(wgCtx, parent), func(_ *restic.Node, is ItemStats) {
+			arch.trackItem("/", nil, nil, is, time.Since(start))
+		})
 		if err != nil {
-			debug.Log("error while saving tree: %v", err)
 			return err
 		}
 
-		return arch.Repo.Flush(ctx)
+		fnr := fn.take(wgCtx)
+		if fnr.err != nil {
+			return fnr.err
+		}
+
+		if wgCtx.Err() != nil {
+			return wgCtx.Err()
+		}
+
+		if nodeCount == 0 {
+			return errors.New("snapshot is empty")
+		}
+
+		rootTreeID = *fnr.node.Subtree
+		arch.stopWorkers()
+		return nil
 	})
-	err = wgUp.Wait()
+
+	err := wg.Wait()
+	debug.Log("err is %v", err)
+
 	if err != nil {
-		return nil, restic.ID{}, nil, err
+		debug.Log("error while saving tree: %v", err)
+		return restic.ID{}, err
 	}
+	return rootTreeID, nil
+}
 
+// saveSnapshot stores a snapshot for the root tree rootTreeID. If the
+// snapshot creation is skipped, nil and a null ID are returned.
+func (arch *Archiver) saveSnapshot(ctx context.Context, targets []string, rootTreeID restic.ID, summary *Summary, opts SnapshotOptions) (*restic.Snapshot, restic.ID, error) {
 	if opts.ParentSnapshot != nil && opts.SkipIfUnchanged {
 		ps := opts.ParentSnapshot
 		if ps.Tree != nil && rootTreeID.Equal(*ps.Tree) {
-			return nil, restic.ID{}, arch.summary, nil
+			return nil, restic.ID{}, nil
 		}
 	}
 
 	sn, err := restic.NewSnapshot(targets, opts.Tags, opts.Hostname, opts.Time)
 	if err != nil {
-		return nil, restic.ID{}, nil, err
+		return nil, restic.ID{}, err
 	}
 
 	sn.ProgramVersion = opts.ProgramVersion
@@ -931,29 +1155,31 @@ func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts Snaps
 		sn.Parent = opts.ParentSnapshot.ID()
 	}
 	sn.Tree = &rootTreeID
-	arch.summary.BackupEnd = time.Now()
+	if opts.Incomplete != nil {
+		sn.Incomplete = opts.Incomplete()
+	}
 	sn.Summary = &restic.SnapshotSummary{
-		BackupStart: arch.summary.BackupStart,
-		BackupEnd:   arch.summary.BackupEnd,
-
-		FilesNew:            arch.summary.Files.New,
-		FilesChanged:        arch.summary.Files.Changed,
-		FilesUnmodified:     arch.summary.Files.Unchanged,
-		DirsNew:             arch.summary.Dirs.New,
-		DirsChanged:         arch.summary.Dirs.Changed,
-		DirsUnmodified:      arch.summary.Dirs.Unchanged,
-		DataBlobs:           arch.summary.ItemStats.DataBlobs,
-		TreeBlobs:           arch.summary.ItemStats.TreeBlobs,
-		DataAdded:           arch.summary.ItemStats.DataSize + arch.summary.ItemStats.TreeSize,
-		DataAddedPacked:     arch.summary.ItemStats.DataSizeInRepo + arch.summary.ItemStats.TreeSizeInRepo,
-		TotalFilesProcessed: arch.summary.Files.New + arch.summary.Files.Changed + arch.summary.Files.Unchanged,
-		TotalBytesProcessed: arch.summary.ProcessedBytes,
+		BackupStart: summary.BackupStart,
+		BackupEnd:   summary.BackupEnd,
+
+		FilesNew:            summary.Files.New,
+		FilesChanged:        summary.Files.Changed,
+		FilesUnmodified:     summary.Files.Unchanged,
+		DirsNew:             summary.Dirs.New,
+		DirsChanged:         summary.Dirs.Changed,
+		DirsUnmodified:      summary.Dirs.Unchanged,
+		DataBlobs:           summary.ItemStats.DataBlobs,
+		TreeBlobs:           summary.ItemStats.TreeBlobs,
+		DataAdded:           summary.ItemStats.DataSize + summary.ItemStats.TreeSize,
+		DataAddedPacked:     summary.ItemStats.DataSizeInRepo + summary.ItemStats.TreeSizeInRepo,
+		TotalFilesProcessed: summary.Files.New + summary.Files.Changed + summary.Files.Unchanged,
+		TotalBytesProcessed: summary.ProcessedBytes,
 	}
 
 	id, err := restic.SaveSnapshot(ctx, arch.Repo, sn)
 	if err != nil {
-		return nil, restic.ID{}, nil, err
+		return nil, restic.ID{}, err
 	}
 
-	return sn, id, arch.summary, nil
+	return sn, id, nil
 }
@@ -1440,6 +1440,58 @@ func TestArchiverSnapshot(t *testing.T) {
 	}
 }
 
+func TestArchiverSnapshotBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{
+		"user1": TestDir{
+			"foo": TestFile{Content: "foo"},
+		},
+		"user2": TestDir{
+			"bar": TestFile{Content: "bar"},
+		},
+	})
+	back := rtest.Chdir(t, tempdir)
+	defer back()
+
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	results, err := arch.SnapshotBatch(ctx, []BatchItem{
+		{Targets: []string{"user1"}, Options: SnapshotOptions{Time: time.Now()}},
+		{Targets: []string{"user2"}, Options: SnapshotOptions{Time: time.Now()}},
+	})
+	rtest.OK(t, err)
+	rtest.Equals(t, 2, len(results))
+	rtest.OK(t, results[0].Err)
+	rtest.OK(t, results[1].Err)
+
+	TestEnsureSnapshot(t, repo, results[0].ID, TestDir{"user1": TestDir{"foo": TestFile{Content: "foo"}}})
+	TestEnsureSnapshot(t, repo, results[1].ID, TestDir{"user2": TestDir{"bar": TestFile{Content: "bar"}}})
+	rtest.Equals(t, uint(1), results[0].Summary.Files.New)
+	rtest.Equals(t, uint(1), results[1].Summary.Files.New)
+	checker.TestCheckRepo(t, repo, false)
+
+	// each item uses its own parent snapshot
+	parent1, err := restic.LoadSnapshot(ctx, repo, results[0].ID)
+	rtest.OK(t, err)
+	parent2, err := restic.LoadSnapshot(ctx, repo, results[1].ID)
+	rtest.OK(t, err)
+
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "user2", "bar"), []byte("modified"), 0o644))
+	results2, err := arch.SnapshotBatch(ctx, []BatchItem{
+		{Targets: []string{"user1"}, Options: SnapshotOptions{Time: time.Now(), ParentSnapshot: parent1, SkipIfUnchanged: true}},
+		{Targets: []string{"user2"}, Options: SnapshotOptions{Time: time.Now(), ParentSnapshot: parent2, SkipIfUnchanged: true}},
+		{Targets: []string{"missing"}, Options: SnapshotOptions{Time: time.Now()}},
+	})
+	rtest.OK(t, err)
+	rtest.OK(t, results2[0].Err)
+	rtest.OK(t, results2[1].Err)
+	rtest.Assert(t, results2[2].Err != nil, "expected error for missing target")
+	rtest.Assert(t, results2[0].Snapshot == nil, "expected unchanged snapshot to be skipped")
+	rtest.Equals(t, results[1].ID, *results2[1].Snapshot.Parent)
+	TestEnsureSnapshot(t, repo, results2[1].ID, TestDir{"user2": TestDir{"bar": TestFile{Content: "modified"}}})
+}
+
 func TestResolveRelativeTargetsSpecial(t *testing.T) {
 	var tests = []struct {
 		name     string
@@ -2173,6 +2225,147 @@ func TestArchiverAbortEarlyOnError(t *testing.T) {
 	}
 }
 
+func TestArchiverChangeSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{
+		"changed": TestDir{
+			"file": TestFile{Content: "old content"},
+		},
+		"unchanged": TestDir{
+			"file": TestFile{Content: "old content"},
+			"sub": TestDir{
+				"other": TestFile{Content: "other content"},
+			},
+		},
+		"deleted": TestDir{
+			"file":  TestFile{Content: "deleted content"},
+			"other": TestFile{Content: "other content"},
+		},
+	})
+
+	back := rtest.Chdir(t, tempdir)
+	defer back()
+
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	parent, _, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+	rtest.OK(t, err)
+
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "changed", "file"), []byte("new and longer content"), 0o644))
+	// not reported by the change source, thus the old content must be kept
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "unchanged", "file"), []byte("new and longer content"), 0o644))
+	rtest.OK(t, os.Remove(filepath.Join(tempdir, "deleted", "file")))
+
+	changes, err := NewChangeList([]string{
+		filepath.Join(tempdir, "changed", "file"),
+		filepath.Join(tempdir, "deleted", "file"),
+	})
+	rtest.OK(t, err)
+
+	testFS := &TrackFS{
+		FS:     fs.Track{FS: fs.Local{}},
+		opened: make(map[string]uint),
+	}
+	arch = New(repo, testFS, Options{})
+	arch.ChangeSource = changes
+	_, id, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), ParentSnapshot: parent})
+	rtest.OK(t, err)
+
+	for name := range testFS.opened {
+		rtest.Assert(t, !strings.Contains(name, "unchanged"), "unchanged item %v was opened", name)
+	}
+
+	TestEnsureSnapshot(t, repo, id, TestDir{
+		"changed": TestDir{
+			"file": TestFile{Content: "new and longer content"},
+		},
+		"unchanged": TestDir{
+			"file": TestFile{Content: "old content"},
+			"sub": TestDir{
+				"other": TestFile{Content: "other content"},
+			},
+		},
+		"deleted": TestDir{
+			"other": TestFile{Content: "other content"},
+		},
+	})
+
+	sn, err := restic.LoadSnapshot(ctx, repo, id)
+	rtest.OK(t, err)
+	oldTree, err := restic.LoadTree(ctx, repo, *parent.Tree)
+	rtest.OK(t, err)
+	newTree, err := restic.LoadTree(ctx, repo, *sn.Tree)
+	rtest.OK(t, err)
+	rtest.Equals(t, *oldTree.Find("unchanged").Subtree, *newTree.Find("unchanged").Subtree)
+
+	checker.TestCheckRepo(t, repo, false)
+}
+
+// wideTestDir returns a directory tree with dirs directories on each of the
+// depth levels, each directory contains files small files.
+func wideTestDir(depth, dirs, files int) TestDir {
+	dir := TestDir{}
+	for i := 0; i < files; i++ {
+		dir[fmt.Sprintf("file%d", i)] = TestFile{Content: fmt.Sprintf("content of file %d at depth %d", i, depth)}
+	}
+	if depth > 0 {
+		for i := 0; i < dirs; i++ {
+			dir[fmt.Sprintf("dir%d", i)] = wideTestDir(depth-1, dirs, files)
+		}
+	}
+	return dir
+}
+
+func TestArchiverWalkConcurrency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := wideTestDir(3, 4, 3)
+	tempdir, repo := prepareTempdirRepoSrc(t, src)
+	back := rtest.Chdir(t, tempdir)
+	defer back()
+
+	var treeID restic.ID
+	for _, walkers := range []uint{1, 2, 8} {
+		arch := New(repo, fs.Track{FS: fs.Local{}}, Options{WalkConcurrency: walkers})
+		sn, id, summary, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+		rtest.OK(t, err)
+		TestEnsureSnapshot(t, repo, id, src)
+		rtest.Equals(t, uint(4+16+64), summary.Dirs.New)
+
+		// the trees must not depend on the number of walkers
+		if treeID.IsNull() {
+			treeID = *sn.Tree
+		}
+		rtest.Equals(t, treeID, *sn.Tree, fmt.Sprintf("walkers %d", walkers))
+	}
+
+	checker.TestCheckRepo(t, repo, false)
+}
+
+func BenchmarkArchiverWalkConcurrency(b *testing.B) {
+	src := wideTestDir(2, 8, 10)
+	tempdir, repo := prepareTempdirRepoSrc(b, src)
+	back := rtest.Chdir(b, tempdir)
+	defer back()
+
+	for _, walkers := range []uint{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("walkers-%d", walkers), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			for i := 0; i < b.N; i++ {
+				arch := New(repo, fs.Track{FS: fs.Local{}}, Options{WalkConcurrency: walkers})
+				_, _, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func snapshot(t testing.TB, repo archiverRepo, fs fs.FS, parent *restic.Snapshot, filename string) (*restic.Snapshot, *restic.Node) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
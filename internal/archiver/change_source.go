@@ -0,0 +1,102 @@
+package archiver
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// ChangeSource reports which items may have changed since the parent snapshot
+// was created. If set, the archiver reuses the nodes of unchanged files and
+// directories from the parent snapshot without reading them from the file
+// system, so the subtree IDs of unchanged directories are kept as is.
+//
+// A ChangeSource must report all changes made since the parent snapshot,
+// otherwise the new snapshot contains outdated data.
+type ChangeSource interface {
+	// Changed returns true if the item with the absolute path, or anything
+	// below it, may have been modified, created or deleted.
+	Changed(path string) bool
+}
+
+// ChangeList is a ChangeSource based on a list of changed or deleted paths,
+// for example as recorded by a file system watcher. All parent directories of
+// a listed path are considered changed, as are all items below it.
+type ChangeList struct {
+	paths map[string]struct{}
+	dirs  map[string]struct{}
+}
+
+// statically ensure that ChangeList implements ChangeSource.
+var _ ChangeSource = &ChangeList{}
+
+// NewChangeList returns a ChangeList for the given absolute paths.
+func NewChangeList(paths []string) (*ChangeList, error) {
+	l := &ChangeList{
+		paths: make(map[string]struct{}),
+		dirs:  make(map[string]struct{}),
+	}
+
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			return nil, errors.Errorf("changed path %q is not absolute", p)
+		}
+		p = filepath.Clean(p)
+		l.paths[p] = struct{}{}
+
+		for dir := filepath.Dir(p); ; dir = filepath.Dir(dir) {
+			if _, ok := l.dirs[dir]; ok {
+				break
+			}
+			l.dirs[dir] = struct{}{}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+
+	return l, nil
+}
+
+// ReadChangeList reads a ChangeList from rd, which contains one absolute path
+// per line. Empty lines are ignored.
+func ReadChangeList(rd io.Reader) (*ChangeList, error) {
+	var paths []string
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewChangeList(paths)
+}
+
+// Changed returns true if path or one of its parent directories is listed,
+// or if path is a parent directory of a listed path.
+func (l *ChangeList) Changed(path string) bool {
+	path = filepath.Clean(path)
+	if _, ok := l.dirs[path]; ok {
+		return true
+	}
+
+	for {
+		if _, ok := l.paths[path]; ok {
+			return true
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,42 @@
+package archiver
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestChangeList(t *testing.T) {
+	root := rtest.TempDir(t)
+	changes, err := ReadChangeList(strings.NewReader(strings.Join([]string{
+		filepath.Join(root, "data", "changed.txt"),
+		"",
+		filepath.Join(root, "removed") + string(filepath.Separator),
+	}, "\n")))
+	rtest.OK(t, err)
+
+	for _, test := range []struct {
+		path    string
+		changed bool
+	}{
+		{"", true},
+		{"data", true},
+		{filepath.Join("data", "changed.txt"), true},
+		{filepath.Join("data", "other.txt"), false},
+		{filepath.Join("data", "sub"), false},
+		{"removed", true},
+		{filepath.Join("removed", "file"), true},
+		{"removed-other", false},
+	} {
+		p := filepath.Join(root, test.path)
+		rtest.Equals(t, test.changed, changes.Changed(p), "path %v", p)
+	}
+
+	// the root directory is a parent of all paths
+	rtest.Equals(t, true, changes.Changed(filepath.Dir(root)))
+
+	_, err = NewChangeList([]string{filepath.Join("relative", "path")})
+	rtest.Assert(t, err != nil, "expected error for relative path")
+}
@@ -17,6 +17,8 @@ const (
 	SnapshotFile
 	IndexFile
 	ConfigFile
+	JournalFile
+	HoldFile
 )
 
 func (t FileType) String() string {
@@ -35,6 +37,10 @@ func (t FileType) String() string {
 		s = "index"
 	case ConfigFile:
 		s = "config"
+	case JournalFile:
+		s = "journal"
+	case HoldFile:
+		s = "hold"
 	}
 	return s
 }
@@ -63,6 +69,8 @@ func (h Handle) Valid() error {
 	case SnapshotFile:
 	case IndexFile:
 	case ConfigFile:
+	case JournalFile:
+	case HoldFile:
 	default:
 		return errors.Errorf("invalid Type %d", h.Type)
 	}
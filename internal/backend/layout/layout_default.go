@@ -21,6 +21,8 @@ var defaultLayoutPaths = map[backend.FileType]string{
 	backend.IndexFile:    "index",
 	backend.LockFile:     "locks",
 	backend.KeyFile:      "keys",
+	backend.JournalFile:  "journal",
+	backend.HoldFile:     "holds",
 }
 
 func NewDefaultLayout(path string, join func(...string) string) *DefaultLayout {
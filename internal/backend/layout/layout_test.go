@@ -110,6 +110,8 @@ func TestDefaultLayout(t *testing.T) {
 			filepath.Join(tempdir, "index"),
 			filepath.Join(tempdir, "locks"),
 			filepath.Join(tempdir, "keys"),
+			filepath.Join(tempdir, "journal"),
+			filepath.Join(tempdir, "holds"),
 		}
 
 		for i := 0; i < 256; i++ {
@@ -185,6 +187,8 @@ func TestRESTLayout(t *testing.T) {
 			strings.Join([]string{url, "index"}, "/"),
 			strings.Join([]string{url, "locks"}, "/"),
 			strings.Join([]string{url, "keys"}, "/"),
+			strings.Join([]string{url, "journal"}, "/"),
+			strings.Join([]string{url, "holds"}, "/"),
 		}
 
 		sort.Strings(want)
@@ -4,16 +4,21 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/backend/local"
 	"github.com/restic/restic/internal/backend/rest"
+	"github.com/restic/restic/internal/backend/rest/server"
 	"github.com/restic/restic/internal/backend/test"
 	rtest "github.com/restic/restic/internal/test"
 )
@@ -148,6 +153,29 @@ func runRESTServer(ctx context.Context, t testing.TB, dir, reqListenAddr string)
 	return url, cleanup
 }
 
+// runInTreeServer serves the directory dir using the built-in REST server.
+func runInTreeServer(t testing.TB, dir string, opts server.Options) *url.URL {
+	open := func(ctx context.Context, path string, create bool) (backend.Backend, error) {
+		cfg := local.NewConfig()
+		cfg.Path = filepath.Join(dir, filepath.FromSlash(path))
+		if create {
+			return local.Create(ctx, cfg)
+		}
+		return local.Open(ctx, cfg)
+	}
+
+	srv := server.New(open, opts)
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		rtest.OK(t, srv.Close())
+	})
+
+	url, err := url.Parse(ts.URL + "/restic-test/")
+	rtest.OK(t, err)
+	return url
+}
+
 func newTestSuite(url *url.URL, minimalData bool) *test.Suite[rest.Config] {
 	return &test.Suite[rest.Config]{
 		MinimalData: minimalData,
@@ -180,6 +208,11 @@ func TestBackendREST(t *testing.T) {
 	newTestSuite(serverURL, false).RunTests(t)
 }
 
+func TestBackendRESTInTreeServer(t *testing.T) {
+	serverURL := runInTreeServer(t, rtest.TempDir(t), server.Options{})
+	newTestSuite(serverURL, false).RunTests(t)
+}
+
 func TestBackendRESTExternalServer(t *testing.T) {
 	repostr := os.Getenv("RESTIC_TEST_REST_REPOSITORY")
 	if repostr == "" {
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// Htpasswd contains the users and password hashes read from an htpasswd
+// file. Passwords hashed using bcrypt and SHA1 are supported.
+type Htpasswd struct {
+	users map[string]string
+
+	// successful bcrypt validations are cached, as bcrypt is too slow to
+	// run for each request
+	m     sync.Mutex
+	cache map[string][sha256.Size]byte
+}
+
+// LoadHtpasswd reads the htpasswd file filename.
+func LoadHtpasswd(filename string) (*Htpasswd, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h, err := ParseHtpasswd(f)
+	if err != nil {
+		return nil, errors.Wrap(err, filename)
+	}
+	return h, nil
+}
+
+// ParseHtpasswd parses the htpasswd file read from rd.
+func ParseHtpasswd(rd io.Reader) (*Htpasswd, error) {
+	h := &Htpasswd{
+		users: make(map[string]string),
+		cache: make(map[string][sha256.Size]byte),
+	}
+
+	sc := bufio.NewScanner(rd)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok || user == "" {
+			return nil, errors.Errorf("line %d: invalid entry", line)
+		}
+		if !strings.HasPrefix(hash, "$2") && !strings.HasPrefix(hash, "{SHA}") {
+			return nil, errors.Errorf("line %d: unsupported password hash for user %q, use bcrypt or SHA1", line, user)
+		}
+		h.users[user] = hash
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Validate returns true if password is correct for user.
+func (h *Htpasswd) Validate(user, password string) bool {
+	hash, ok := h.users[user]
+	if !ok {
+		return false
+	}
+
+	if sha, ok := strings.CutPrefix(hash, "{SHA}"); ok {
+		sum := sha1.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(sha), []byte(base64.StdEncoding.EncodeToString(sum[:]))) == 1
+	}
+
+	sum := sha256.Sum256([]byte(user + ":" + hash + ":" + password))
+	h.m.Lock()
+	cached, ok := h.cache[user]
+	h.m.Unlock()
+	if ok && subtle.ConstantTimeCompare(cached[:], sum[:]) == 1 {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+
+	h.m.Lock()
+	h.cache[user] = sum
+	h.m.Unlock()
+	return true
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestHtpasswd(t *testing.T) {
+	// both passwords are "secret"
+	h, err := ParseHtpasswd(strings.NewReader(`
+# comment
+alice:$2a$05$D8rxaDgPYYUaSqoIjr0XYe1bphWcNPWEL/cZPD4NzbhXcuzeLT92y
+bob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=
+`))
+	rtest.OK(t, err)
+
+	for _, test := range []struct {
+		user, password string
+		valid          bool
+	}{
+		{"alice", "secret", true},
+		// validated from the cache
+		{"alice", "secret", true},
+		{"alice", "wrong", false},
+		{"bob", "secret", true},
+		{"bob", "wrong", false},
+		{"carol", "secret", false},
+	} {
+		rtest.Equals(t, test.valid, h.Validate(test.user, test.password), "%v:%v", test.user, test.password)
+	}
+
+	_, err = ParseHtpasswd(strings.NewReader("carol:$apr1$abc$def\n"))
+	rtest.Assert(t, err != nil, "expected error for unsupported hash")
+}
@@ -0,0 +1,539 @@
+// Package server implements the server side of the REST backend protocol, it
+// allows serving an arbitrary backend via HTTP.
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+)
+
+// the REST API protocol version is negotiated via HTTP request headers, these
+// must match the constants used by the rest backend.
+const (
+	contentTypeV1 = "application/vnd.x.restic.rest.v1"
+	contentTypeV2 = "application/vnd.x.restic.rest.v2"
+)
+
+// DefaultMaxFileSize is the default limit for the size of uploaded files. Pack
+// files are at most 128 MiB large, plus the size of the last blob.
+const DefaultMaxFileSize = 1024 * 1024 * 1024
+
+// maxMemoryFileSize is the size up to which uploaded files are kept in memory,
+// larger files are stored in a temporary file until they are saved.
+const maxMemoryFileSize = 8 * 1024 * 1024
+
+// typeDirs maps the directory names used in request paths to file types.
+var typeDirs = map[string]backend.FileType{
+	"data":      backend.PackFile,
+	"keys":      backend.KeyFile,
+	"locks":     backend.LockFile,
+	"snapshots": backend.SnapshotFile,
+	"index":     backend.IndexFile,
+	"journal":   backend.JournalFile,
+	"holds":     backend.HoldFile,
+}
+
+// OpenFunc returns the backend for the repository at path, which is relative
+// to the served location and uses slashes as separator. The path is empty for
+// the repository at the root. If create is true, the repository is about to be
+// initialized.
+type OpenFunc func(ctx context.Context, path string, create bool) (backend.Backend, error)
+
+// Options configures a Server.
+type Options struct {
+	// AppendOnly prevents removing files, except for locks. Existing files
+	// are never overwritten.
+	AppendOnly bool
+
+	// Users is used to authenticate clients via HTTP basic auth. If it is
+	// nil, no authentication is required.
+	Users *Htpasswd
+
+	// PrivateRepos restricts each user to the repositories below the
+	// directory named after the user. Requires Users to be set.
+	PrivateRepos bool
+
+	// MaxFileSize limits the size of uploaded files. DefaultMaxFileSize is
+	// used if it is zero.
+	MaxFileSize int64
+
+	// Logf is called for errors which occur while handling a request, it
+	// may be nil.
+	Logf func(format string, args ...interface{})
+}
+
+// Server serves repositories via the REST backend protocol.
+type Server struct {
+	open OpenFunc
+	opts Options
+
+	m     sync.Mutex
+	repos map[string]backend.Backend
+}
+
+// New returns a new Server which opens the repositories via open.
+func New(open OpenFunc, opts Options) *Server {
+	return &Server{
+		open:  open,
+		opts:  opts,
+		repos: make(map[string]backend.Backend),
+	}
+}
+
+// Close closes all backends opened by the server.
+func (s *Server) Close() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var firstErr error
+	for path, be := range s.repos {
+		if err := be.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.repos, path)
+	}
+	return firstErr
+}
+
+// request is a parsed request path.
+type request struct {
+	// repo is the path of the repository, relative to the served location
+	repo string
+	// isList is true if the files of type h.Type are listed
+	isList bool
+	// h is the file accessed by the request, h.Type is zero for requests
+	// to the repository itself
+	h backend.Handle
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "\\:")
+}
+
+// parsePath splits the request path into the repository and the file within.
+func parsePath(path string) (request, bool) {
+	trailingSlash := strings.HasSuffix(path, "/")
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s == "" {
+			continue
+		}
+		if !validName(s) {
+			return request{}, false
+		}
+		segments = append(segments, s)
+	}
+
+	var req request
+	n := len(segments)
+	switch {
+	case n >= 1 && segments[n-1] == "config":
+		req.h = backend.Handle{Type: backend.ConfigFile}
+		segments = segments[:n-1]
+	case n >= 1 && trailingSlash && typeDirs[segments[n-1]] != 0:
+		req.isList = true
+		req.h = backend.Handle{Type: typeDirs[segments[n-1]]}
+		segments = segments[:n-1]
+	case n >= 2 && typeDirs[segments[n-2]] != 0:
+		req.h = backend.Handle{Type: typeDirs[segments[n-2]], Name: segments[n-1]}
+		segments = segments[:n-2]
+	}
+
+	req.repo = strings.Join(segments, "/")
+	return req, true
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	debug.Log(format, args...)
+	if s.opts.Logf != nil {
+		s.opts.Logf(format, args...)
+	}
+}
+
+// backend returns the backend for the repository at path.
+func (s *Server) backend(ctx context.Context, path string, create bool) (backend.Backend, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	be, ok := s.repos[path]
+	if ok && !create {
+		return be, nil
+	}
+
+	newBe, err := s.open(ctx, path, create)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		_ = be.Close()
+	}
+	s.repos[path] = newBe
+	return newBe, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	debug.Log("%v %v", r.Method, r.URL.Path)
+
+	req, ok := parsePath(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid path", http.StatusNotFound)
+		return
+	}
+
+	if !s.authorize(w, r, req.repo) {
+		return
+	}
+
+	if req.h.Type == 0 {
+		s.handleRepo(w, r, req.repo)
+		return
+	}
+
+	be, err := s.backend(r.Context(), req.repo, false)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+
+	switch {
+	case req.isList && r.Method == http.MethodGet:
+		s.list(w, r, be, req.h.Type)
+	case req.isList:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	case r.Method == http.MethodHead:
+		s.stat(w, r, be, req.h)
+	case r.Method == http.MethodGet:
+		s.load(w, r, be, req.h)
+	case r.Method == http.MethodPost:
+		s.save(w, r, be, req.h)
+	case r.Method == http.MethodDelete:
+		s.remove(w, r, be, req.h)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// authorize checks the credentials of the client and whether it may access
+// the repository. If not, an error is returned to the client.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, repo string) bool {
+	if s.opts.Users == nil {
+		return true
+	}
+
+	user, password, ok := r.BasicAuth()
+	if !ok || !s.opts.Users.Validate(user, password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="restic"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return false
+	}
+
+	if s.opts.PrivateRepos && repo != user && !strings.HasPrefix(repo, user+"/") {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *Server) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	s.logf("%v %v failed: %v", r.Method, r.URL.Path, err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// backendError returns an error to the client, a missing file is reported
+// as such.
+func (s *Server) backendError(w http.ResponseWriter, r *http.Request, be backend.Backend, err error) {
+	if be.IsNotExist(err) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	s.internalError(w, r, err)
+}
+
+// handleRepo handles requests to the repository itself, only creating a
+// repository is supported.
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request, repo string) {
+	if r.Method != http.MethodPost || r.URL.Query().Get("create") != "true" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	be, err := s.backend(r.Context(), repo, false)
+	if err != nil {
+		s.internalError(w, r, err)
+		return
+	}
+
+	// creating an existing repository is a no-op
+	_, err = be.Stat(r.Context(), backend.Handle{Type: backend.ConfigFile})
+	if err == nil {
+		return
+	}
+	if !be.IsNotExist(err) {
+		s.internalError(w, r, err)
+		return
+	}
+
+	if _, err := s.backend(r.Context(), repo, true); err != nil {
+		s.internalError(w, r, err)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, be backend.Backend, t backend.FileType) {
+	type listItem struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	}
+
+	items := []listItem{}
+	err := be.List(r.Context(), t, func(fi backend.FileInfo) error {
+		items = append(items, listItem{Name: fi.Name, Size: fi.Size})
+		return nil
+	})
+	if err != nil {
+		s.backendError(w, r, be, err)
+		return
+	}
+
+	var result interface{} = items
+	contentType := contentTypeV2
+	if !strings.Contains(r.Header.Get("Accept"), contentTypeV2) {
+		names := make([]string, 0, len(items))
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		result = names
+		contentType = contentTypeV1
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		s.logf("%v %v failed: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+func (s *Server) stat(w http.ResponseWriter, r *http.Request, be backend.Backend, h backend.Handle) {
+	fi, err := be.Stat(r.Context(), h)
+	if err != nil {
+		s.backendError(w, r, be, err)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(fi.Size, 10))
+}
+
+// parseRange parses the value of a Range header. Only a single range of the
+// form "bytes=start-" or "bytes=start-end" is supported. The returned length
+// is zero if the range extends to the end of the file.
+func parseRange(s string) (offset int64, length int, err error) {
+	spec, ok := strings.CutPrefix(s, "bytes=")
+	if !ok {
+		return 0, 0, errors.Errorf("unsupported range %q", s)
+	}
+	start, end, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, errors.Errorf("invalid range %q", s)
+	}
+
+	offset, err = strconv.ParseInt(start, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, 0, errors.Errorf("invalid range %q", s)
+	}
+	if end == "" {
+		return offset, 0, nil
+	}
+
+	last, err := strconv.ParseInt(end, 10, 64)
+	if err != nil || last < offset {
+		return 0, 0, errors.Errorf("invalid range %q", s)
+	}
+	return offset, int(last - offset + 1), nil
+}
+
+func (s *Server) load(w http.ResponseWriter, r *http.Request, be backend.Backend, h backend.Handle) {
+	fi, err := be.Stat(r.Context(), h)
+	if err != nil {
+		s.backendError(w, r, be, err)
+		return
+	}
+
+	var offset int64
+	var length int
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		offset, length, err = parseRange(rangeHeader)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if offset >= fi.Size && !(offset == 0 && fi.Size == 0) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fi.Size))
+			http.Error(w, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		// the backends expect a range within the file
+		if length == 0 || offset+int64(length) > fi.Size {
+			length = int(fi.Size - offset)
+		}
+		if fi.Size > 0 {
+			status = http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(length)-1, fi.Size))
+		}
+	} else {
+		length = int(fi.Size)
+	}
+
+	if length == 0 {
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(status)
+		return
+	}
+
+	started := false
+	err = be.Load(r.Context(), h, length, offset, func(rd io.Reader) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", strconv.Itoa(length))
+			w.WriteHeader(status)
+		}
+		_, err := io.Copy(w, rd)
+		return err
+	})
+	if err != nil {
+		if !started {
+			s.backendError(w, r, be, err)
+			return
+		}
+		// the response is incomplete, abort it so that the client notices
+		s.logf("%v %v failed: %v", r.Method, r.URL.Path, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *Server) save(w http.ResponseWriter, r *http.Request, be backend.Backend, h backend.Handle) {
+	// files are never overwritten
+	_, err := be.Stat(r.Context(), h)
+	if err == nil {
+		http.Error(w, "file already exists", http.StatusForbidden)
+		return
+	}
+	if !be.IsNotExist(err) {
+		s.internalError(w, r, err)
+		return
+	}
+
+	maxFileSize := s.opts.MaxFileSize
+	if maxFileSize == 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+	if r.ContentLength > maxFileSize {
+		http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, maxFileSize)
+
+	var rd backend.RewindReader
+	var sum []byte
+	if r.ContentLength >= 0 && r.ContentLength <= maxMemoryFileSize {
+		buf, err := io.ReadAll(body)
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		hash := sha256.Sum256(buf)
+		sum = hash[:]
+		rd = backend.NewByteReader(buf, be.Hasher())
+	} else {
+		f, err := os.CreateTemp("", "restic-serve-rest-")
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+		defer func() {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}()
+
+		var beHash []byte
+		sum, beHash, err = spoolUpload(f, body, be.Hasher())
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		rd, err = backend.NewFileReader(f, beHash)
+		if err != nil {
+			s.internalError(w, r, err)
+			return
+		}
+	}
+
+	if r.ContentLength >= 0 && rd.Length() != r.ContentLength {
+		http.Error(w, "incomplete upload", http.StatusBadRequest)
+		return
+	}
+
+	// all files except the config are named after the hash of their content
+	if h.Type != backend.ConfigFile && hex.EncodeToString(sum) != h.Name {
+		http.Error(w, "file content does not match its name", http.StatusBadRequest)
+		return
+	}
+
+	err = be.Save(r.Context(), h, rd)
+	if err != nil {
+		s.internalError(w, r, err)
+	}
+}
+
+// spoolUpload copies the uploaded file to f and returns the SHA-256 hash of its
+// content and the hash computed by hasher, which may be nil.
+func spoolUpload(f io.Writer, body io.Reader, hasher hash.Hash) (sum []byte, beHash []byte, err error) {
+	sha := sha256.New()
+	writers := []io.Writer{f, sha}
+	if hasher != nil {
+		writers = append(writers, hasher)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), body); err != nil {
+		return nil, nil, err
+	}
+
+	if hasher != nil {
+		beHash = hasher.Sum(nil)
+	}
+	return sha.Sum(nil), beHash, nil
+}
+
+// uploadError reports an error which occurred while reading the uploaded file.
+func uploadError(w http.ResponseWriter, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request, be backend.Backend, h backend.Handle) {
+	if s.opts.AppendOnly && h.Type != backend.LockFile {
+		http.Error(w, "repository is append-only", http.StatusForbidden)
+		return
+	}
+
+	err := be.Remove(r.Context(), h)
+	if err != nil {
+		s.backendError(w, r, be, err)
+	}
+}
@@ -0,0 +1,203 @@
+package server_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/backend/local"
+	"github.com/restic/restic/internal/backend/rest/server"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func newTestServer(t *testing.T, opts server.Options) string {
+	dir := rtest.TempDir(t)
+	open := func(ctx context.Context, path string, create bool) (backend.Backend, error) {
+		cfg := local.NewConfig()
+		cfg.Path = filepath.Join(dir, filepath.FromSlash(path))
+		if create {
+			return local.Create(ctx, cfg)
+		}
+		return local.Open(ctx, cfg)
+	}
+
+	srv := server.New(open, opts)
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		rtest.OK(t, srv.Close())
+	})
+	return ts.URL
+}
+
+func request(t *testing.T, method, url, user, body string, header ...string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	rtest.OK(t, err)
+	if user != "" {
+		req.SetBasicAuth(user, "secret-"+user)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	rtest.OK(t, err)
+	buf, err := io.ReadAll(resp.Body)
+	rtest.OK(t, err)
+	rtest.OK(t, resp.Body.Close())
+	return resp.StatusCode, string(buf)
+}
+
+func hashName(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestServerSaveLoad(t *testing.T) {
+	url := newTestServer(t, server.Options{})
+	data := "snapshot data"
+	file := url + "/repo/snapshots/" + hashName(data)
+
+	status, _ := request(t, http.MethodPost, url+"/repo/?create=true", "", "")
+	rtest.Equals(t, http.StatusOK, status)
+
+	status, _ = request(t, http.MethodPost, url+"/repo/snapshots/"+hashName("other"), "", data)
+	rtest.Equals(t, http.StatusBadRequest, status)
+
+	status, _ = request(t, http.MethodPost, file, "", data)
+	rtest.Equals(t, http.StatusOK, status)
+	// files must not be overwritten
+	status, _ = request(t, http.MethodPost, file, "", data)
+	rtest.Equals(t, http.StatusForbidden, status)
+
+	status, body := request(t, http.MethodGet, file, "", "")
+	rtest.Equals(t, http.StatusOK, status)
+	rtest.Equals(t, data, body)
+
+	status, body = request(t, http.MethodGet, file, "", "", "Range", "bytes=9-")
+	rtest.Equals(t, http.StatusPartialContent, status)
+	rtest.Equals(t, "data", body)
+
+	status, body = request(t, http.MethodGet, file, "", "", "Range", "bytes=2-4")
+	rtest.Equals(t, http.StatusPartialContent, status)
+	rtest.Equals(t, "aps", body)
+
+	status, _ = request(t, http.MethodGet, file, "", "", "Range", "bytes=100-")
+	rtest.Equals(t, http.StatusRequestedRangeNotSatisfiable, status)
+
+	status, body = request(t, http.MethodGet, url+"/repo/snapshots/", "", "", "Accept", "application/vnd.x.restic.rest.v2")
+	rtest.Equals(t, http.StatusOK, status)
+	rtest.Equals(t, fmt.Sprintf(`[{"name":%q,"size":%d}]`+"\n", hashName(data), len(data)), body)
+
+	status, body = request(t, http.MethodGet, url+"/repo/snapshots/", "", "")
+	rtest.Equals(t, http.StatusOK, status)
+	rtest.Equals(t, fmt.Sprintf(`[%q]`+"\n", hashName(data)), body)
+
+	status, _ = request(t, http.MethodGet, url+"/repo/../snapshots/", "", "")
+	rtest.Equals(t, http.StatusNotFound, status)
+
+	status, _ = request(t, http.MethodDelete, file, "", "")
+	rtest.Equals(t, http.StatusOK, status)
+	status, _ = request(t, http.MethodHead, file, "", "")
+	rtest.Equals(t, http.StatusNotFound, status)
+}
+
+// uploadChunked uploads data without specifying its length.
+func uploadChunked(t *testing.T, url, data string) int {
+	req, err := http.NewRequest(http.MethodPost, url, io.MultiReader(strings.NewReader(data)))
+	rtest.OK(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	rtest.OK(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	rtest.OK(t, resp.Body.Close())
+	return resp.StatusCode
+}
+
+func TestServerSaveLarge(t *testing.T) {
+	url := newTestServer(t, server.Options{})
+
+	// files larger than 8 MiB and files without length are stored in a
+	// temporary file until they are saved
+	for i, data := range []string{string(rtest.Random(23, 9*1024*1024)), "small file"} {
+		file := url + "/data/" + hashName(data)
+		if i == 0 {
+			status, _ := request(t, http.MethodPost, file, "", data)
+			rtest.Equals(t, http.StatusOK, status)
+		} else {
+			rtest.Equals(t, http.StatusOK, uploadChunked(t, file, data))
+		}
+
+		status, body := request(t, http.MethodGet, file, "", "")
+		rtest.Equals(t, http.StatusOK, status)
+		rtest.Assert(t, body == data, "unexpected content of file %v", i)
+	}
+
+	rtest.Equals(t, http.StatusBadRequest, uploadChunked(t, url+"/data/"+hashName("other"), "data"))
+}
+
+func TestServerMaxFileSize(t *testing.T) {
+	url := newTestServer(t, server.Options{MaxFileSize: 10})
+	data := "more than ten bytes"
+
+	status, _ := request(t, http.MethodPost, url+"/data/"+hashName(data), "", data)
+	rtest.Equals(t, http.StatusRequestEntityTooLarge, status)
+	rtest.Equals(t, http.StatusRequestEntityTooLarge, uploadChunked(t, url+"/data/"+hashName(data), data))
+	status, _ = request(t, http.MethodHead, url+"/data/"+hashName(data), "", "")
+	rtest.Equals(t, http.StatusNotFound, status)
+
+	status, _ = request(t, http.MethodPost, url+"/data/"+hashName("data"), "", "data")
+	rtest.Equals(t, http.StatusOK, status)
+}
+
+func TestServerAppendOnly(t *testing.T) {
+	url := newTestServer(t, server.Options{AppendOnly: true})
+
+	for _, dir := range []string{"snapshots", "locks"} {
+		status, _ := request(t, http.MethodPost, url+"/"+dir+"/"+hashName("data"), "", "data")
+		rtest.Equals(t, http.StatusOK, status)
+	}
+
+	status, _ := request(t, http.MethodDelete, url+"/snapshots/"+hashName("data"), "", "")
+	rtest.Equals(t, http.StatusForbidden, status)
+	status, _ = request(t, http.MethodDelete, url+"/locks/"+hashName("data"), "", "")
+	rtest.Equals(t, http.StatusOK, status)
+}
+
+func TestServerPrivateRepos(t *testing.T) {
+	var htpasswd strings.Builder
+	for _, user := range []string{"alice", "bob"} {
+		hash, err := bcrypt.GenerateFromPassword([]byte("secret-"+user), bcrypt.MinCost)
+		rtest.OK(t, err)
+		htpasswd.WriteString(user + ":" + string(hash) + "\n")
+	}
+	users, err := server.ParseHtpasswd(strings.NewReader(htpasswd.String()))
+	rtest.OK(t, err)
+
+	url := newTestServer(t, server.Options{Users: users, PrivateRepos: true})
+
+	for _, test := range []struct {
+		user, path string
+		status     int
+	}{
+		{"", "/alice/config", http.StatusUnauthorized},
+		{"mallory", "/alice/config", http.StatusUnauthorized},
+		{"bob", "/alice/config", http.StatusUnauthorized},
+		{"bob", "/config", http.StatusUnauthorized},
+		{"alice", "/alice/config", http.StatusNotFound},
+		{"alice", "/alice/sub/config", http.StatusNotFound},
+		{"alice", "/alice2/config", http.StatusUnauthorized},
+	} {
+		status, _ := request(t, http.MethodGet, url+test.path, test.user, "")
+		rtest.Equals(t, test.status, status, "user %q path %v", test.user, test.path)
+	}
+}
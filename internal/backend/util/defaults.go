@@ -31,7 +31,9 @@ func DefaultDelete(ctx context.Context, be backend.Backend) error {
 		backend.KeyFile,
 		backend.LockFile,
 		backend.SnapshotFile,
-		backend.IndexFile}
+		backend.IndexFile,
+		backend.JournalFile,
+		backend.HoldFile}
 
 	for _, t := range alltypes {
 		err := be.List(ctx, t, func(fi backend.FileInfo) error {
@@ -439,6 +439,47 @@ func (c *Checker) GetPacks() map[restic.ID]int64 {
 	return c.packs
 }
 
+// JournalError is returned for an invalid entry of the deletion journal.
+type JournalError struct {
+	ID  restic.ID
+	Err error
+}
+
+func (e *JournalError) Error() string {
+	return "journal " + e.ID.String() + ": " + e.Err.Error()
+}
+
+// Journal verifies the signatures of all entries of the deletion journal.
+// Journal entries in a repository which is not append-only are reported as
+// errors, as this indicates that the append-only setting was removed. errChan
+// is closed after all entries have been checked.
+func (c *Checker) Journal(ctx context.Context, errChan chan<- error) {
+	defer close(errChan)
+
+	cfg := c.repo.Config()
+	err := c.repo.List(ctx, restic.JournalFile, func(id restic.ID, _ int64) error {
+		var err error
+		if cfg.AppendOnly {
+			_, err = repository.LoadJournalEntry(ctx, c.repo, cfg, id)
+		} else {
+			err = errors.New("found in a repository that is not append-only")
+		}
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case errChan <- &JournalError{ID: id, Err: err}:
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		errChan <- err
+	}
+}
+
 // ReadData loads all data from the repository and checks the integrity.
 func (c *Checker) ReadData(ctx context.Context, errChan chan<- error) {
 	c.ReadPacks(ctx, c.packs, nil, errChan)
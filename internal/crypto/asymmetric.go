@@ -0,0 +1,198 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// PublicKeySize is the size of an X25519 public key in bytes.
+const PublicKeySize = 32
+
+// ErrNoDecryptionKey is returned when data of an asymmetric repository is
+// decrypted using a write-only key.
+var ErrNoDecryptionKey = errors.New("write-only key cannot decrypt data")
+
+const sealingKeyInfo = "restic sealing key"
+
+// PublicKey is an X25519 public key. In asymmetric repositories, blobs and
+// snapshots are encrypted with a key derived from the repository public key
+// and an ephemeral key pair. Only the ephemeral public key is stored.
+type PublicKey [PublicKeySize]byte
+
+// ParsePublicKey converts the hex representation of a public key.
+func ParsePublicKey(s string) (PublicKey, error) {
+	var pub PublicKey
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return pub, errors.Wrap(err, "hex.DecodeString")
+	}
+	if len(b) != PublicKeySize {
+		return pub, errors.Errorf("invalid length for public key: %d", len(b))
+	}
+	copy(pub[:], b)
+	return pub, nil
+}
+
+// String returns the hex representation of the public key.
+func (pub PublicKey) String() string {
+	return hex.EncodeToString(pub[:])
+}
+
+// IsNull returns true iff the public key is not set.
+func (pub PublicKey) IsNull() bool {
+	return pub == PublicKey{}
+}
+
+// MarshalJSON returns the JSON encoding of pub.
+func (pub PublicKey) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + pub.String() + `"`), nil
+}
+
+// UnmarshalJSON parses the JSON-encoded data and stores the result in pub.
+func (pub *PublicKey) UnmarshalJSON(b []byte) error {
+	if len(b) < 2 || b[0] != '"' || b[len(b)-1] != '"' {
+		return fmt.Errorf("invalid public key: %q", b)
+	}
+	p, err := ParsePublicKey(string(b[1 : len(b)-1]))
+	if err != nil {
+		return err
+	}
+	*pub = p
+	return nil
+}
+
+// NewRandomKeyPair returns a new X25519 key pair for an asymmetric repository.
+// It panics on error so that the program is safely terminated.
+func NewRandomKeyPair() (PublicKey, []byte) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		panic("unable to generate key pair: " + err.Error())
+	}
+
+	var pub PublicKey
+	copy(pub[:], priv.PublicKey().Bytes())
+	return pub, priv.Bytes()
+}
+
+// NewSealingKey returns a new random key for encrypting data which can only be
+// decrypted using the private key belonging to pub. The returned ephemeral
+// public key must be stored alongside the data and is required to derive the
+// key again using OpenSealingKey.
+func NewSealingKey(pub PublicKey) (*Key, PublicKey, error) {
+	var ephemeral PublicKey
+
+	remote, err := ecdh.X25519().NewPublicKey(pub[:])
+	if err != nil {
+		return nil, ephemeral, errors.Wrap(err, "NewPublicKey")
+	}
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, ephemeral, errors.Wrap(err, "GenerateKey")
+	}
+	copy(ephemeral[:], priv.PublicKey().Bytes())
+
+	secret, err := priv.ECDH(remote)
+	if err != nil {
+		return nil, ephemeral, errors.Wrap(err, "ECDH")
+	}
+
+	k, err := deriveSealingKey(secret, ephemeral, pub)
+	return k, ephemeral, err
+}
+
+// OpenSealingKey derives the key which was returned by NewSealingKey together
+// with the ephemeral public key. This requires that k contains the private
+// key of the repository, otherwise ErrNoDecryptionKey is returned.
+func (k *Key) OpenSealingKey(ephemeral PublicKey) (*Key, error) {
+	if len(k.DecryptionKey) == 0 {
+		return nil, ErrNoDecryptionKey
+	}
+
+	priv, err := ecdh.X25519().NewPrivateKey(k.DecryptionKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewPrivateKey")
+	}
+	remote, err := ecdh.X25519().NewPublicKey(ephemeral[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "NewPublicKey")
+	}
+	secret, err := priv.ECDH(remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "ECDH")
+	}
+
+	var pub PublicKey
+	copy(pub[:], priv.PublicKey().Bytes())
+	sk, err := deriveSealingKey(secret, ephemeral, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	// data sealed before the master key was rotated uses the previous key pair
+	if k.Previous != nil && len(k.Previous.DecryptionKey) != 0 {
+		sk.Previous, err = k.Previous.OpenSealingKey(ephemeral)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sk, nil
+}
+
+// PublicKey returns the public key matching the decryption key of k.
+func (k *Key) PublicKey() (PublicKey, error) {
+	var pub PublicKey
+	if len(k.DecryptionKey) == 0 {
+		return pub, ErrNoDecryptionKey
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(k.DecryptionKey)
+	if err != nil {
+		return pub, errors.Wrap(err, "NewPrivateKey")
+	}
+	copy(pub[:], priv.PublicKey().Bytes())
+	return pub, nil
+}
+
+// WriteOnly returns a copy of k without the decryption key. The copy contains
+// the public key matching the decryption key instead.
+func (k *Key) WriteOnly() (*Key, error) {
+	wo := &Key{MACKey: k.MACKey, EncryptionKey: k.EncryptionKey, DataPublicKey: k.DataPublicKey}
+	if len(k.DecryptionKey) != 0 {
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		wo.DataPublicKey = &pub
+	}
+	if k.Previous != nil {
+		var err error
+		wo.Previous, err = k.Previous.WriteOnly()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return wo, nil
+}
+
+// deriveSealingKey expands the shared secret into encryption and message
+// authentication keys. Both public keys are used as salt.
+func deriveSealingKey(secret []byte, ephemeral, pub PublicKey) (*Key, error) {
+	salt := make([]byte, 0, 2*PublicKeySize)
+	salt = append(salt, ephemeral[:]...)
+	salt = append(salt, pub[:]...)
+
+	derived, err := hkdf.Key(sha256.New, secret, salt, sealingKeyInfo, aesKeySize+macKeySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "hkdf.Key")
+	}
+
+	k := &Key{}
+	copy(k.EncryptionKey[:], derived[:aesKeySize])
+	macKeyFromSlice(&k.MACKey, derived[aesKeySize:])
+	return k, nil
+}
@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSealingKey(t *testing.T) {
+	pub, priv := NewRandomKeyPair()
+	k := NewRandomKey()
+	k.DecryptionKey = priv
+
+	derivedPub, err := k.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derivedPub != pub {
+		t.Fatalf("wrong public key, want %v, got %v", pub, derivedPub)
+	}
+
+	sealKey, ephemeral, err := NewSealingKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openKey, err := k.OpenSealingKey(ephemeral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealKey.EncryptionKey != openKey.EncryptionKey || sealKey.MACKey != openKey.MACKey {
+		t.Fatal("derived keys do not match")
+	}
+
+	nonce := NewRandomNonce()
+	plaintext := []byte("secret data")
+	ciphertext := sealKey.Seal(nil, nonce, plaintext, nil)
+	buf, err := openKey.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, plaintext) {
+		t.Fatalf("wrong plaintext %q", buf)
+	}
+
+	// a different ephemeral key results in a different key
+	other, otherEphemeral, err := NewSealingKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherEphemeral == ephemeral || other.EncryptionKey == sealKey.EncryptionKey {
+		t.Fatal("sealing keys are not random")
+	}
+
+	wo, err := k.WriteOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wo.DataPublicKey == nil || *wo.DataPublicKey != pub {
+		t.Fatal("write-only copy must contain the public key")
+	}
+	_, err = wo.OpenSealingKey(ephemeral)
+	if !errors.Is(err, ErrNoDecryptionKey) {
+		t.Fatalf("expected ErrNoDecryptionKey, got %v", err)
+	}
+}
+
+func TestPublicKeyJSON(t *testing.T) {
+	pub, _ := NewRandomKeyPair()
+
+	buf, err := json.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != `"`+pub.String()+`"` {
+		t.Fatalf("unexpected JSON %s", buf)
+	}
+
+	var pub2 PublicKey
+	if err := json.Unmarshal(buf, &pub2); err != nil {
+		t.Fatal(err)
+	}
+	if pub2 != pub {
+		t.Fatalf("wrong public key, want %v, got %v", pub, pub2)
+	}
+
+	if err := json.Unmarshal([]byte(`"abcd"`), &pub2); err == nil {
+		t.Fatal("expected error for invalid public key")
+	}
+}
+
+func TestPreviousKey(t *testing.T) {
+	oldPub, oldPriv := NewRandomKeyPair()
+	old := NewRandomKey()
+	old.DecryptionKey = oldPriv
+
+	_, newPriv := NewRandomKeyPair()
+	k := NewRandomKey()
+	k.DecryptionKey = newPriv
+	k.Previous = old
+
+	nonce := NewRandomNonce()
+	plaintext := []byte("old data")
+	ciphertext := old.Seal(nil, nonce, plaintext, nil)
+
+	buf, err := k.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, plaintext) {
+		t.Fatalf("wrong plaintext %q", buf)
+	}
+	_, err = k.WithoutPrevious().Open(nil, nonce, ciphertext, nil)
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+
+	// data sealed for the previous key pair can still be opened
+	sealKey, ephemeral, err := NewSealingKey(oldPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext = sealKey.Seal(nil, nonce, plaintext, nil)
+	openKey, err := k.OpenSealingKey(ephemeral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err = openKey.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, plaintext) {
+		t.Fatalf("wrong plaintext %q", buf)
+	}
+
+	wo, err := k.WriteOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wo.Previous == nil || len(wo.Previous.DecryptionKey) != 0 {
+		t.Fatal("write-only copy must contain the previous key without decryption key")
+	}
+
+	// the previous key is stored as part of the master key
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var k2 Key
+	if err := json.Unmarshal(data, &k2); err != nil {
+		t.Fatal(err)
+	}
+	if k2.Previous == nil || k2.Previous.EncryptionKey != old.EncryptionKey {
+		t.Fatal("previous key was not restored")
+	}
+}
@@ -36,6 +36,21 @@ var (
 type Key struct {
 	MACKey        `json:"mac"`
 	EncryptionKey `json:"encrypt"`
+
+	// DecryptionKey is the X25519 private key of an asymmetric repository.
+	// It is required to decrypt blobs and snapshots, write-only keys do not
+	// contain it.
+	DecryptionKey []byte `json:"decrypt,omitempty"`
+
+	// DataPublicKey is the public key of an asymmetric repository, which is
+	// stored in write-only keys instead of the decryption key. It is used to
+	// verify the public key in the repository config.
+	DataPublicKey *PublicKey `json:"data_public_key,omitempty"`
+
+	// Previous is the master key which is replaced while the master key is
+	// rotated. Data which cannot be authenticated using this key is decrypted
+	// using Previous instead, new data is always encrypted using this key.
+	Previous *Key `json:"previous,omitempty"`
 }
 
 // EncryptionKey is key used for encryption
@@ -307,6 +322,9 @@ func (k *Key) Open(dst, nonce, ciphertext, _ []byte) ([]byte, error) {
 
 	// verify mac
 	if !poly1305Verify(ct, nonce, &k.MACKey, mac) {
+		if k.Previous != nil {
+			return k.Previous.Open(dst, nonce, ciphertext, nil)
+		}
 		return nil, ErrUnauthenticated
 	}
 
@@ -322,6 +340,12 @@ func (k *Key) Open(dst, nonce, ciphertext, _ []byte) ([]byte, error) {
 	return ret, nil
 }
 
+// WithoutPrevious returns a copy of k which cannot decrypt data encrypted
+// using the previous master key.
+func (k *Key) WithoutPrevious() *Key {
+	return &Key{MACKey: k.MACKey, EncryptionKey: k.EncryptionKey, DecryptionKey: k.DecryptionKey, DataPublicKey: k.DataPublicKey}
+}
+
 // Valid tests if the key is valid.
 func (k *Key) Valid() bool {
 	return k.EncryptionKey.Valid() && k.MACKey.Valid()
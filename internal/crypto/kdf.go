@@ -7,27 +7,72 @@ import (
 	"github.com/restic/restic/internal/errors"
 
 	sscrypt "github.com/elithrar/simple-scrypt"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/scrypt"
 )
 
 const saltLength = 64
 
+// Names of the supported key derivation functions.
+const (
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+)
+
 // Params are the default parameters used for the key derivation function KDF().
 type Params struct {
+	// KDF is the name of the key derivation function, scrypt is used if empty.
+	KDF string
+
+	// parameters for scrypt
 	N int
 	R int
 	P int
+
+	// parameters for Argon2id, Memory is specified in KiB
+	Time    uint32
+	Memory  uint32
+	Threads uint8
 }
 
 // DefaultKDFParams are the default parameters used for Calibrate and KDF().
 var DefaultKDFParams = Params{
-	N: sscrypt.DefaultParams.N,
-	R: sscrypt.DefaultParams.R,
-	P: sscrypt.DefaultParams.P,
+	KDF: KDFScrypt,
+	N:   sscrypt.DefaultParams.N,
+	R:   sscrypt.DefaultParams.R,
+	P:   sscrypt.DefaultParams.P,
+}
+
+// DefaultArgon2idParams are the default parameters used for Calibrate with
+// Argon2id. They follow the second recommended option of RFC 9106.
+var DefaultArgon2idParams = Params{
+	KDF:     KDFArgon2id,
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// limits for the Argon2id parameters, which prevent key files from requesting
+// excessive resources
+const (
+	maxArgon2idTime   = 1 << 16
+	maxArgon2idMemory = 4 * 1024 * 1024
+)
+
+// Calibrate determines new parameters of the KDF kdf for the current
+// hardware. The memory limit is specified in MiB.
+func Calibrate(kdf string, timeout time.Duration, memory int) (Params, error) {
+	switch kdf {
+	case "", KDFScrypt:
+		return calibrateScrypt(timeout, memory)
+	case KDFArgon2id:
+		return calibrateArgon2id(timeout, memory)
+	default:
+		return Params{}, errors.Errorf("unsupported KDF %q", kdf)
+	}
 }
 
-// Calibrate determines new KDF parameters for the current hardware.
-func Calibrate(timeout time.Duration, memory int) (Params, error) {
+func calibrateScrypt(timeout time.Duration, memory int) (Params, error) {
 	defaultParams := sscrypt.Params{
 		N:       DefaultKDFParams.N,
 		R:       DefaultKDFParams.R,
@@ -42,49 +87,107 @@ func Calibrate(timeout time.Duration, memory int) (Params, error) {
 	}
 
 	return Params{
-		N: params.N,
-		R: params.R,
-		P: params.P,
+		KDF: KDFScrypt,
+		N:   params.N,
+		R:   params.R,
+		P:   params.P,
 	}, nil
 }
 
-// KDF derives encryption and message authentication keys from the password
-// using the supplied parameters N, R and P and the Salt.
-func KDF(p Params, salt []byte, password string) (*Key, error) {
-	if len(salt) != saltLength {
-		return nil, errors.Errorf("scrypt() called with invalid salt bytes (len %d)", len(salt))
+// calibrateArgon2id increases the number of passes of Argon2id until the
+// timeout is reached. The memory is limited to the given number of MiB.
+func calibrateArgon2id(timeout time.Duration, memory int) (Params, error) {
+	params := DefaultArgon2idParams
+	if memory > 0 && uint32(memory)*1024 < params.Memory {
+		params.Memory = uint32(memory) * 1024
+	}
+	if err := checkArgon2idParams(params); err != nil {
+		return DefaultArgon2idParams, err
 	}
 
-	// make sure we have valid parameters
-	params := sscrypt.Params{
-		N:       p.N,
-		R:       p.R,
-		P:       p.P,
-		DKLen:   sscrypt.DefaultParams.DKLen,
-		SaltLen: len(salt),
+	salt, err := NewSalt()
+	if err != nil {
+		return DefaultArgon2idParams, err
 	}
 
-	if err := params.Check(); err != nil {
-		return nil, errors.Wrap(err, "Check")
+	start := time.Now()
+	argon2.IDKey([]byte("password"), salt, params.Time, params.Memory, params.Threads, macKeySize+aesKeySize)
+	elapsed := time.Since(start)
+
+	// the runtime grows linearly with the number of passes
+	if elapsed > 0 && elapsed < timeout {
+		passes := uint64(params.Time) * uint64(timeout) / uint64(elapsed)
+		params.Time = uint32(min(passes, maxArgon2idTime))
 	}
+	return params, nil
+}
 
-	derKeys := &Key{}
+func checkArgon2idParams(p Params) error {
+	if p.Time < 1 || p.Time > maxArgon2idTime {
+		return errors.Errorf("invalid Argon2id time parameter %d", p.Time)
+	}
+	if p.Threads < 1 {
+		return errors.Errorf("invalid Argon2id threads parameter %d", p.Threads)
+	}
+	if p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2idMemory {
+		return errors.Errorf("invalid Argon2id memory parameter %d", p.Memory)
+	}
+	return nil
+}
+
+// KDF derives encryption and message authentication keys from the password
+// using the supplied parameters and the Salt. The parameters N, R and P are
+// used for scrypt, Time, Memory and Threads for Argon2id.
+func KDF(p Params, salt []byte, password string) (*Key, error) {
+	if len(salt) != saltLength {
+		return nil, errors.Errorf("KDF() called with invalid salt bytes (len %d)", len(salt))
+	}
 
 	keybytes := macKeySize + aesKeySize
-	scryptKeys, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, keybytes)
-	if err != nil {
-		return nil, errors.Wrap(err, "scrypt.Key")
+	var derived []byte
+
+	switch p.KDF {
+	case "", KDFScrypt:
+		// make sure we have valid parameters
+		params := sscrypt.Params{
+			N:       p.N,
+			R:       p.R,
+			P:       p.P,
+			DKLen:   sscrypt.DefaultParams.DKLen,
+			SaltLen: len(salt),
+		}
+
+		if err := params.Check(); err != nil {
+			return nil, errors.Wrap(err, "Check")
+		}
+
+		var err error
+		derived, err = scrypt.Key([]byte(password), salt, p.N, p.R, p.P, keybytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "scrypt.Key")
+		}
+
+	case KDFArgon2id:
+		if err := checkArgon2idParams(p); err != nil {
+			return nil, err
+		}
+		derived = argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(keybytes))
+
+	default:
+		return nil, errors.Errorf("unsupported KDF %q", p.KDF)
 	}
 
-	if len(scryptKeys) != keybytes {
-		return nil, errors.Errorf("invalid numbers of bytes expanded from scrypt(): %d", len(scryptKeys))
+	if len(derived) != keybytes {
+		return nil, errors.Errorf("invalid numbers of bytes expanded from %v: %d", p.KDF, len(derived))
 	}
 
-	// first 32 byte of scrypt output is the encryption key
-	copy(derKeys.EncryptionKey[:], scryptKeys[:aesKeySize])
+	derKeys := &Key{}
+
+	// first 32 byte of the KDF output is the encryption key
+	copy(derKeys.EncryptionKey[:], derived[:aesKeySize])
 
-	// next 32 byte of scrypt output is the mac key, in the form k||r
-	macKeyFromSlice(&derKeys.MACKey, scryptKeys[aesKeySize:])
+	// next 32 byte of the KDF output is the mac key, in the form k||r
+	macKeyFromSlice(&derKeys.MACKey, derived[aesKeySize:])
 
 	return derKeys, nil
 }
@@ -6,9 +6,56 @@ import (
 )
 
 func TestCalibrate(t *testing.T) {
-	params, err := Calibrate(100*time.Millisecond, 50)
+	for _, kdf := range []string{KDFScrypt, KDFArgon2id} {
+		params, err := Calibrate(kdf, 100*time.Millisecond, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.KDF != kdf {
+			t.Fatalf("wrong KDF, want %v, got %v", kdf, params.KDF)
+		}
+		t.Logf("testing calibrate, params after: %v", params)
+	}
+}
+
+func TestKDF(t *testing.T) {
+	salt, err := NewSalt()
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("testing calibrate, params after: %v", params)
+
+	for _, params := range []Params{
+		{KDF: KDFScrypt, N: 128, R: 1, P: 1},
+		{KDF: KDFArgon2id, Time: 1, Memory: 64, Threads: 1},
+	} {
+		k1, err := KDF(params, salt, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		k2, err := KDF(params, salt, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		k3, err := KDF(params, salt, "other password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !k1.Valid() || k1.EncryptionKey != k2.EncryptionKey || k1.MACKey != k2.MACKey {
+			t.Fatalf("%v: deriving the same key twice returned different results", params.KDF)
+		}
+		if k1.EncryptionKey == k3.EncryptionKey {
+			t.Fatalf("%v: different passwords returned the same key", params.KDF)
+		}
+	}
+
+	for _, params := range []Params{
+		{KDF: "unknown"},
+		{KDF: KDFArgon2id, Time: 0, Memory: 64, Threads: 1},
+		{KDF: KDFArgon2id, Time: 1, Memory: 4, Threads: 1},
+		{KDF: KDFArgon2id, Time: 1, Memory: maxArgon2idMemory + 1, Threads: 1},
+	} {
+		if _, err := KDF(params, salt, "password"); err == nil {
+			t.Fatalf("expected error for invalid parameters %v", params)
+		}
+	}
 }
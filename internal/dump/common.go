@@ -6,6 +6,7 @@ import (
 	"path"
 
 	"github.com/restic/restic/internal/bloblru"
+	"github.com/restic/restic/internal/fs"
 	"github.com/restic/restic/internal/restic"
 	"github.com/restic/restic/internal/walker"
 	"golang.org/x/sync/errgroup"
@@ -18,6 +19,9 @@ type Dumper struct {
 	format string
 	repo   restic.Loader
 	w      io.Writer
+
+	// OwnerMap rewrites the ownership stored in archive headers, may be nil.
+	OwnerMap *fs.OwnerMap
 }
 
 func New(format string, repo restic.Loader, w io.Writer) *Dumper {
@@ -39,9 +43,13 @@ func (d *Dumper) DumpTree(ctx context.Context, tree *restic.Tree, rootPath strin
 
 	switch d.format {
 	case "tar":
-		return d.dumpTar(ctx, ch)
+		return d.dumpTar(ctx, d.w, ch)
+	case "tar.gz", "tar.zst":
+		return d.dumpCompressedTar(ctx, ch)
 	case "zip":
 		return d.dumpZip(ctx, ch)
+	case "cpio":
+		return d.dumpCpio(ctx, ch)
 	default:
 		panic("unknown dump format")
 	}
@@ -0,0 +1,133 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+)
+
+// cpio mode bits for the file type, see cpio(5)
+const (
+	cpioModeDir     = 0o040000
+	cpioModeReg     = 0o100000
+	cpioModeSymlink = 0o120000
+)
+
+// cpioTrailer is the name of the entry which marks the end of the archive.
+const cpioTrailer = "TRAILER!!!"
+
+// cpioWriter writes an archive in the "new ASCII" (newc) cpio format.
+type cpioWriter struct {
+	w       io.Writer
+	written int64
+	ino     uint32
+}
+
+func (cw *cpioWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
+
+// pad writes zeros until the archive is aligned to four bytes.
+func (cw *cpioWriter) pad() error {
+	var zeros [3]byte
+	_, err := cw.Write(zeros[:(4-cw.written%4)%4])
+	return err
+}
+
+// writeHeader writes the header for an entry followed by its name. The
+// header fields are limited to 32 bits.
+func (cw *cpioWriter) writeHeader(name string, mode uint32, uid, gid, nlink uint32, mtime int64, size uint64) error {
+	if size > math.MaxUint32 {
+		return errors.Errorf("%v is too large for the cpio format", name)
+	}
+	mtime = max(0, min(mtime, math.MaxUint32))
+
+	cw.ino++
+	_, err := fmt.Fprintf(cw, "070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%s\x00",
+		cw.ino, mode, uid, gid, nlink, mtime, size,
+		0, 0, 0, 0, // device numbers
+		len(name)+1, 0, name)
+	if err != nil {
+		return err
+	}
+	return cw.pad()
+}
+
+func (d *Dumper) dumpCpio(ctx context.Context, ch <-chan *restic.Node) (err error) {
+	w := &cpioWriter{w: d.w}
+
+	defer func() {
+		if err == nil {
+			err = w.writeHeader(cpioTrailer, 0, 0, 0, 1, 0, 0)
+			err = errors.Wrap(err, "Close")
+		}
+	}()
+
+	for node := range ch {
+		if err := d.dumpNodeCpio(ctx, node, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dumper) dumpNodeCpio(ctx context.Context, node *restic.Node, w *cpioWriter) error {
+	node = d.OwnerMap.MapNode(node)
+
+	relPath, err := filepath.Rel("/", node.Path)
+	if err != nil {
+		return err
+	}
+	name := filepath.ToSlash(relPath)
+
+	mode := uint32(node.Mode.Perm())
+	if node.Mode&os.ModeSetuid != 0 {
+		mode |= cISUID
+	}
+	if node.Mode&os.ModeSetgid != 0 {
+		mode |= cISGID
+	}
+	if node.Mode&os.ModeSticky != 0 {
+		mode |= cISVTX
+	}
+
+	uid, gid := uint32(tarIdentifier(node.UID)), uint32(tarIdentifier(node.GID))
+	mtime := node.ModTime.Unix()
+
+	switch node.Type {
+	case restic.NodeTypeDir:
+		return w.writeHeader(name, mode|cpioModeDir, uid, gid, 2, mtime, 0)
+
+	case restic.NodeTypeSymlink:
+		if err := w.writeHeader(name, mode|cpioModeSymlink, uid, gid, 1, mtime, uint64(len(node.LinkTarget))); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, node.LinkTarget); err != nil {
+			return errors.Wrap(err, "Write")
+		}
+		return w.pad()
+
+	case restic.NodeTypeFile:
+		if err := w.writeHeader(name, mode|cpioModeReg, uid, gid, 1, mtime, node.Size); err != nil {
+			return err
+		}
+		start := w.written
+		if err := d.writeNode(ctx, w, node); err != nil {
+			return err
+		}
+		// the size in the header cannot be corrected afterwards
+		if n := w.written - start; n != int64(node.Size) {
+			return errors.Errorf("content of %v has size %d, expected %d", node.Path, n, node.Size)
+		}
+		return w.pad()
+	}
+	return nil
+}
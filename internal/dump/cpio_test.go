@@ -0,0 +1,136 @@
+package dump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWriteCpio(t *testing.T) {
+	WriteTest(t, "cpio", checkCpio)
+}
+
+type cpioEntry struct {
+	name  string
+	mode  uint32
+	mtime int64
+	data  []byte
+}
+
+// readCpio parses an archive in the newc format.
+func readCpio(rd *bytes.Reader) ([]cpioEntry, error) {
+	var entries []cpioEntry
+	offset := func() int64 { return rd.Size() - int64(rd.Len()) }
+	skipPadding := func() {
+		_, _ = rd.Seek((4-offset()%4)%4, io.SeekCurrent)
+	}
+
+	for {
+		header := make([]byte, 110)
+		if _, err := io.ReadFull(rd, header); err != nil {
+			return nil, err
+		}
+		if string(header[:6]) != "070701" {
+			return nil, fmt.Errorf("invalid magic %q", header[:6])
+		}
+		field := func(i int) uint32 {
+			v, err := strconv.ParseUint(string(header[6+8*i:14+8*i]), 16, 32)
+			if err != nil {
+				panic(err)
+			}
+			return uint32(v)
+		}
+
+		name := make([]byte, field(11))
+		if _, err := io.ReadFull(rd, name); err != nil {
+			return nil, err
+		}
+		skipPadding()
+		data := make([]byte, field(6))
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		skipPadding()
+
+		entry := cpioEntry{
+			name:  string(name[:len(name)-1]),
+			mode:  field(1),
+			mtime: int64(field(5)),
+			data:  data,
+		}
+		if entry.name == cpioTrailer {
+			if rd.Len() != 0 {
+				return nil, fmt.Errorf("%d bytes after trailer", rd.Len())
+			}
+			return entries, nil
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func checkCpio(_ *testing.T, testDir string, srcCpio *bytes.Buffer) error {
+	entries, err := readCpio(bytes.NewReader(srcCpio.Bytes()))
+	if err != nil {
+		return err
+	}
+
+	fileNumber := 0
+	err = filepath.Walk(testDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Name() != filepath.Base(testDir) {
+			fileNumber++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(entries) != fileNumber {
+		return fmt.Errorf("not the same amount of files got %v want %v", len(entries), fileNumber)
+	}
+
+	for _, entry := range entries {
+		matchPath := filepath.Join(testDir, entry.name)
+		match, err := os.Lstat(matchPath)
+		if err != nil {
+			return err
+		}
+
+		if entry.mtime != match.ModTime().Unix() {
+			return fmt.Errorf("%v: modTime does not match, got: %v, want: %v", entry.name, entry.mtime, match.ModTime().Unix())
+		}
+		if os.FileMode(entry.mode).Perm() != match.Mode().Perm() {
+			return fmt.Errorf("%v: mode does not match, got: %o, want: %v", entry.name, entry.mode, match.Mode())
+		}
+
+		switch {
+		case match.IsDir():
+			if entry.mode&cpioModeDir == 0 || len(entry.data) != 0 {
+				return fmt.Errorf("%v: expected directory, got mode %o", entry.name, entry.mode)
+			}
+		case match.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(matchPath)
+			if err != nil {
+				return err
+			}
+			if entry.mode&0o170000 != cpioModeSymlink || string(entry.data) != target {
+				return fmt.Errorf("%v: symlink does not match, got %q want %q", entry.name, entry.data, target)
+			}
+		default:
+			contents, err := os.ReadFile(matchPath)
+			if err != nil {
+				return err
+			}
+			if entry.mode&0o170000 != cpioModeReg || !bytes.Equal(entry.data, contents) {
+				return fmt.Errorf("%v: contents does not match, got %q want %q", entry.name, entry.data, contents)
+			}
+		}
+	}
+	return nil
+}
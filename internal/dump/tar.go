@@ -4,16 +4,20 @@ import (
 	"archive/tar"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/restic"
 )
 
-func (d *Dumper) dumpTar(ctx context.Context, ch <-chan *restic.Node) (err error) {
-	w := tar.NewWriter(d.w)
+func (d *Dumper) dumpTar(ctx context.Context, dst io.Writer, ch <-chan *restic.Node) (err error) {
+	w := tar.NewWriter(dst)
 
 	defer func() {
 		if err == nil {
@@ -30,6 +34,33 @@ func (d *Dumper) dumpTar(ctx context.Context, ch <-chan *restic.Node) (err error
 	return nil
 }
 
+// dumpCompressedTar writes a tar archive which is compressed while it is
+// written, using gzip or zstd depending on the format.
+func (d *Dumper) dumpCompressedTar(ctx context.Context, ch <-chan *restic.Node) (err error) {
+	var w io.WriteCloser
+	switch d.format {
+	case "tar.gz":
+		w = gzip.NewWriter(d.w)
+	case "tar.zst":
+		w, err = zstd.NewWriter(d.w)
+		if err != nil {
+			return err
+		}
+	default:
+		panic("unknown compression for dump format " + d.format)
+	}
+
+	defer func() {
+		// always close the compressor to release its resources
+		cerr := w.Close()
+		if err == nil {
+			err = errors.Wrap(cerr, "Close")
+		}
+	}()
+
+	return d.dumpTar(ctx, w, ch)
+}
+
 // copied from archive/tar.FileInfoHeader
 const (
 	// Mode constants from the USTAR spec:
@@ -49,6 +80,8 @@ func tarIdentifier(id uint32) int {
 }
 
 func (d *Dumper) dumpNodeTar(ctx context.Context, node *restic.Node, w *tar.Writer) error {
+	node = d.OwnerMap.MapNode(node)
+
 	relPath, err := filepath.Rel("/", node.Path)
 	if err != nil {
 		return err
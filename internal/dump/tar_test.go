@@ -13,6 +13,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
@@ -21,6 +26,33 @@ func TestWriteTar(t *testing.T) {
 	WriteTest(t, "tar", checkTar)
 }
 
+func TestWriteCompressedTar(t *testing.T) {
+	WriteTest(t, "tar.gz", func(t *testing.T, testDir string, srcTar *bytes.Buffer) error {
+		rd, err := gzip.NewReader(srcTar)
+		if err != nil {
+			return err
+		}
+		buf, err := io.ReadAll(rd)
+		if err != nil {
+			return err
+		}
+		return checkTar(t, testDir, bytes.NewBuffer(buf))
+	})
+
+	WriteTest(t, "tar.zst", func(t *testing.T, testDir string, srcTar *bytes.Buffer) error {
+		rd, err := zstd.NewReader(srcTar)
+		if err != nil {
+			return err
+		}
+		defer rd.Close()
+		buf, err := io.ReadAll(rd)
+		if err != nil {
+			return err
+		}
+		return checkTar(t, testDir, bytes.NewBuffer(buf))
+	})
+}
+
 func checkTar(t *testing.T, testDir string, srcTar *bytes.Buffer) error {
 	tr := tar.NewReader(srcTar)
 
@@ -141,3 +173,32 @@ func TestFieldTooLong(t *testing.T) {
 	rtest.Assert(t, strings.Contains(err.Error(), node.Path),
 		"no filename in %q", err)
 }
+
+func TestTarOwnerMap(t *testing.T) {
+	node := restic.Node{
+		Name:  "file",
+		Path:  "/file",
+		Type:  restic.NodeTypeFile,
+		Mode:  0644,
+		UID:   1000,
+		GID:   100,
+		User:  "olduser",
+		Group: "users",
+	}
+
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	d := New("tar", repository.TestRepository(t), buf)
+	d.OwnerMap = &fs.OwnerMap{
+		UIDs: map[uint32]uint32{1000: 2000},
+	}
+	rtest.OK(t, d.dumpNodeTar(context.Background(), &node, w))
+	rtest.OK(t, w.Close())
+
+	hdr, err := tar.NewReader(buf).Next()
+	rtest.OK(t, err)
+	rtest.Equals(t, 2000, hdr.Uid)
+	rtest.Equals(t, 100, hdr.Gid)
+	rtest.Equals(t, "", hdr.Uname)
+	rtest.Equals(t, "users", hdr.Gname)
+}
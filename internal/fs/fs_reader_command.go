@@ -5,7 +5,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/restic/restic/internal/errors"
 )
@@ -14,8 +18,14 @@ import (
 // a io.ReadCloser. Close() waits for the command to terminate, reporting
 // any error back to the caller.
 type CommandReader struct {
-	cmd    *exec.Cmd
-	stdout io.ReadCloser
+	cmd     *exec.Cmd
+	stdout  io.ReadCloser
+	opts    CommandReaderOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stderr  *stderrTail
+	logPipe *os.File
+	logDone chan struct{}
 
 	// cmd.Wait() must only be called once. Prevent duplicate executions in
 	// Read() and Close().
@@ -28,38 +38,127 @@ type CommandReader struct {
 	alreadyClosedReadErr error
 }
 
+// CommandReaderOptions configures how a CommandReader runs the command.
+type CommandReaderOptions struct {
+	// Timeout kills the command if it runs longer, zero means no timeout.
+	Timeout time.Duration
+	// NonFatal reports a failed command using a regular error instead of a
+	// fatal error, which aborts the backup.
+	NonFatal bool
+}
+
+// maxStderrTail is the number of bytes of the stderr output kept by a
+// CommandReader.
+const maxStderrTail = 4096
+
+// stderrGracePeriod is how long a CommandReader waits for the remaining stderr
+// output after the command has exited. Processes started by the command may
+// keep stderr open.
+const stderrGracePeriod = time.Second
+
+// stderrTail keeps the last lines written to stderr by a command.
+type stderrTail struct {
+	m     sync.Mutex
+	lines []string
+	size  int
+}
+
+func (t *stderrTail) add(line string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.lines = append(t.lines, line)
+	t.size += len(line) + 1
+	for len(t.lines) > 1 && t.size > maxStderrTail {
+		t.size -= len(t.lines[0]) + 1
+		t.lines = t.lines[1:]
+	}
+}
+
+func (t *stderrTail) String() string {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return strings.Join(t.lines, "\n")
+}
+
 func NewCommandReader(ctx context.Context, args []string, logOutput io.Writer) (*CommandReader, error) {
+	return NewCommandReaderWithOptions(ctx, args, logOutput, CommandReaderOptions{})
+}
+
+// NewCommandReaderWithOptions starts the command args. Lines written by the
+// command to stderr are passed to logOutput.
+func NewCommandReaderWithOptions(ctx context.Context, args []string, logOutput io.Writer, opts CommandReaderOptions) (*CommandReader, error) {
 	if len(args) == 0 {
 		return nil, fmt.Errorf("no command was specified as argument")
 	}
 
+	cancel := func() {}
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+
 	// Prepare command and stdout
 	command := exec.CommandContext(ctx, args[0], args[1:]...)
 	stdout, err := command.StdoutPipe()
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to setup stdout pipe: %w", err)
 	}
 
-	// Use a Go routine to handle the stderr to avoid deadlocks
-	stderr, err := command.StderrPipe()
+	// Use a Go routine to handle the stderr to avoid deadlocks. The pipe is
+	// not created using StderrPipe(), as Wait() would then block until all
+	// processes which inherited stderr have closed it.
+	stderr, stderrWriter, err := os.Pipe()
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to setup stderr pipe: %w", err)
 	}
+	command.Stderr = stderrWriter
+
+	err = command.Start()
+	// the command holds its own copy of the write end
+	_ = stderrWriter.Close()
+	if err != nil {
+		_ = stderr.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	fp := &CommandReader{
+		cmd:     command,
+		stdout:  stdout,
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		stderr:  &stderrTail{},
+		logPipe: stderr,
+		logDone: make(chan struct{}),
+	}
 	go func() {
+		defer close(fp.logDone)
 		sc := bufio.NewScanner(stderr)
 		for sc.Scan() {
+			fp.stderr.add(sc.Text())
 			_, _ = fmt.Fprintf(logOutput, "subprocess %v: %v\n", command.Args[0], sc.Text())
 		}
 	}()
 
-	if err := command.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command: %w", err)
-	}
+	return fp, nil
+}
 
-	return &CommandReader{
-		cmd:    command,
-		stdout: stdout,
-	}, nil
+// Stderr returns the last lines the command has written to stderr.
+func (fp *CommandReader) Stderr() string {
+	return fp.stderr.String()
+}
+
+// ExitCode returns the exit code of the command, or -1 if the command has not
+// exited yet or was terminated by a signal.
+func (fp *CommandReader) ExitCode() int {
+	if fp.cmd.ProcessState == nil {
+		return -1
+	}
+	return fp.cmd.ProcessState.ExitCode()
 }
 
 // Read populate the array with data from the process stdout.
@@ -84,10 +183,28 @@ func (fp *CommandReader) Read(p []byte) (int, error) {
 }
 
 func (fp *CommandReader) wait() error {
+	defer fp.cancel()
+
 	err := fp.cmd.Wait()
+
+	// collect the remaining stderr output
+	select {
+	case <-fp.logDone:
+	case <-time.After(stderrGracePeriod):
+	}
+	_ = fp.logPipe.Close()
+
 	if err != nil {
+		if fp.opts.Timeout > 0 && errors.Is(fp.ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("command timed out after %v", fp.opts.Timeout)
+		} else {
+			err = fmt.Errorf("command failed: %w", err)
+		}
+		if fp.opts.NonFatal {
+			return err
+		}
 		// Use a fatal error to abort the snapshot.
-		return errors.Fatal(fmt.Errorf("command failed: %w", err).Error())
+		return errors.Fatal(err.Error())
 	}
 	return nil
 }
@@ -6,7 +6,9 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/fs"
 	"github.com/restic/restic/internal/test"
 )
@@ -51,3 +53,36 @@ func TestCommandReaderOutput(t *testing.T) {
 
 	test.Equals(t, "hello world", strings.TrimSpace(buf.String()))
 }
+
+func TestCommandReaderStderr(t *testing.T) {
+	var log bytes.Buffer
+	reader, err := fs.NewCommandReaderWithOptions(context.TODO(), []string{"sh", "-c", "echo data; echo first >&2; echo second >&2; exit 3"}, &log, fs.CommandReaderOptions{NonFatal: true})
+	test.OK(t, err)
+
+	_, err = io.Copy(io.Discard, reader)
+	test.Assert(t, err != nil && !errors.IsFatal(err), "expected non-fatal error, got %v", err)
+	test.Equals(t, 3, reader.ExitCode())
+	test.Equals(t, "first\nsecond", reader.Stderr())
+	test.Equals(t, "subprocess sh: first\nsubprocess sh: second\n", log.String())
+}
+
+func TestCommandReaderTimeout(t *testing.T) {
+	reader, err := fs.NewCommandReaderWithOptions(context.TODO(), []string{"sleep", "10"}, io.Discard, fs.CommandReaderOptions{Timeout: 100 * time.Millisecond})
+	test.OK(t, err)
+
+	_, err = io.Copy(io.Discard, reader)
+	test.Assert(t, err != nil && errors.IsFatal(err), "expected fatal error, got %v", err)
+	test.Assert(t, strings.Contains(err.Error(), "timed out"), "unexpected error %v", err)
+}
+
+func TestCommandReaderStderrInherited(t *testing.T) {
+	// the background process keeps stderr open after the command has exited
+	reader, err := fs.NewCommandReader(context.TODO(), []string{"sh", "-c", "sleep 10 >/dev/null & echo data; echo msg >&2"}, io.Discard)
+	test.OK(t, err)
+
+	start := time.Now()
+	_, err = io.Copy(io.Discard, reader)
+	test.OK(t, err)
+	test.Assert(t, time.Since(start) < 5*time.Second, "reader waited for the background process")
+	test.Equals(t, "msg", reader.Stderr())
+}
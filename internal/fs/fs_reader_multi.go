@@ -0,0 +1,144 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// MultiReader is a file system which provides the files of several Readers.
+// Each Reader must use a different absolute Name, directories are created as
+// needed.
+type MultiReader struct {
+	Readers []*Reader
+}
+
+// statically ensure that MultiReader implements FS.
+var _ FS = &MultiReader{}
+
+func (fs *MultiReader) reader(name string) *Reader {
+	for _, rd := range fs.Readers {
+		if rd.Name == name {
+			return rd
+		}
+	}
+	return nil
+}
+
+// cleanName returns the cleaned path name, "." refers to the root directory.
+func (fs *MultiReader) cleanName(name string) string {
+	name = path.Clean(name)
+	if name == "." {
+		return "/"
+	}
+	return name
+}
+
+// entries returns the names of the entries in the directory dir, and whether
+// the directory exists.
+func (fs *MultiReader) entries(dir string) ([]string, bool) {
+	var entries []string
+	found := dir == "/"
+	for _, rd := range fs.Readers {
+		for p := rd.Name; p != "/" && p != "."; p = path.Dir(p) {
+			if path.Dir(p) == dir {
+				found = true
+				if !slices.Contains(entries, path.Base(p)) {
+					entries = append(entries, path.Base(p))
+				}
+				break
+			}
+		}
+	}
+	return entries, found
+}
+
+// VolumeName returns leading volume name, for the MultiReader file system
+// it's always the empty string.
+func (fs *MultiReader) VolumeName(_ string) string {
+	return ""
+}
+
+func (fs *MultiReader) OpenFile(name string, flag int, metadataOnly bool) (File, error) {
+	if flag & ^(O_RDONLY|O_NOFOLLOW) != 0 {
+		return nil, pathError("open", name,
+			fmt.Errorf("invalid combination of flags 0x%x", flag))
+	}
+
+	name = fs.cleanName(name)
+	if rd := fs.reader(name); rd != nil {
+		return rd.OpenFile(name, flag, metadataOnly)
+	}
+
+	if entries, ok := fs.entries(name); ok {
+		return fakeDir{
+			entries: entries,
+			fakeFile: fakeFile{
+				name: name,
+				fi:   fs.dirInfo(name),
+			},
+		}, nil
+	}
+
+	return nil, pathError("open", name, syscall.ENOENT)
+}
+
+func (fs *MultiReader) dirInfo(name string) *ExtendedFileInfo {
+	return &ExtendedFileInfo{
+		Name:    fs.Base(name),
+		Mode:    os.ModeDir | 0755,
+		ModTime: time.Now(),
+	}
+}
+
+// Lstat returns the FileInfo structure describing the named file.
+func (fs *MultiReader) Lstat(name string) (*ExtendedFileInfo, error) {
+	name = fs.cleanName(name)
+	if rd := fs.reader(name); rd != nil {
+		return rd.Lstat(name)
+	}
+	if _, ok := fs.entries(name); ok {
+		return fs.dirInfo(name), nil
+	}
+	return nil, pathError("lstat", name, os.ErrNotExist)
+}
+
+// Join joins any number of path elements into a single path.
+func (fs *MultiReader) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+// Separator returns the OS and FS dependent separator for dirs/subdirs/files.
+func (fs *MultiReader) Separator() string {
+	return "/"
+}
+
+// IsAbs reports whether the path is absolute. For the MultiReader, this is
+// always the case.
+func (fs *MultiReader) IsAbs(_ string) bool {
+	return true
+}
+
+// Abs returns an absolute representation of path. For the MultiReader, all
+// paths are absolute.
+func (fs *MultiReader) Abs(p string) (string, error) {
+	return path.Clean(p), nil
+}
+
+// Clean returns the cleaned path.
+func (fs *MultiReader) Clean(p string) string {
+	return path.Clean(p)
+}
+
+// Base returns the last element of p.
+func (fs *MultiReader) Base(p string) string {
+	return path.Base(p)
+}
+
+// Dir returns p without the last element.
+func (fs *MultiReader) Dir(p string) string {
+	return path.Dir(p)
+}
@@ -0,0 +1,63 @@
+package fs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/test"
+)
+
+func TestFSMultiReader(t *testing.T) {
+	now := time.Now()
+	newReader := func(name string, data []byte) *Reader {
+		return &Reader{
+			Name:       name,
+			ReadCloser: io.NopCloser(bytes.NewReader(data)),
+			Mode:       0644,
+			Size:       int64(len(data)),
+			ModTime:    now,
+		}
+	}
+
+	data1 := test.Random(23, 1000)
+	data2 := test.Random(42, 2000)
+	fs := &MultiReader{Readers: []*Reader{
+		newReader("/mysql/shop.sql", data1),
+		newReader("/mysql/blog.sql", data2),
+		newReader("/postgres.sql", data2),
+	}}
+
+	for dir, want := range map[string][]string{
+		"/":      {"mysql", "postgres.sql"},
+		".":      {"mysql", "postgres.sql"},
+		"/mysql": {"blog.sql", "shop.sql"},
+	} {
+		f, err := fs.OpenFile(dir, O_RDONLY, false)
+		test.OK(t, err)
+		entries, err := f.Readdirnames(-1)
+		test.OK(t, err)
+		sort.Strings(entries)
+		test.Equals(t, want, entries)
+		test.OK(t, f.Close())
+	}
+
+	fi, err := fs.Lstat("/mysql")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/mysql", time.Time{}, os.ModeDir|0755, true)
+
+	fi, err = fs.Lstat("/mysql/blog.sql")
+	test.OK(t, err)
+	test.Equals(t, int64(len(data2)), fi.Size)
+
+	verifyFileContentOpenFile(t, fs, "/mysql/shop.sql", data1)
+	verifyFileContentOpenFile(t, fs, "/postgres.sql", data2)
+
+	_, err = fs.Lstat("/mysql/missing.sql")
+	test.Assert(t, os.IsNotExist(err), "unexpected error %v", err)
+	_, err = fs.OpenFile("/missing", O_RDONLY, false)
+	test.Assert(t, err != nil, "expected error opening missing file")
+}
@@ -36,14 +36,15 @@ func buildBasicNode(path string, fi *ExtendedFileInfo) *restic.Node {
 		ModTime: fi.ModTime,
 	}
 
-	node.Type = nodeTypeFromFileInfo(fi.Mode)
+	node.Type = NodeTypeFromFileMode(fi.Mode)
 	if node.Type == restic.NodeTypeFile {
 		node.Size = uint64(fi.Size)
 	}
 	return node
 }
 
-func nodeTypeFromFileInfo(mode os.FileMode) restic.NodeType {
+// NodeTypeFromFileMode returns the node type for a file with the given mode.
+func NodeTypeFromFileMode(mode os.FileMode) restic.NodeType {
 	switch mode & os.ModeType {
 	case 0:
 		return restic.NodeTypeFile
@@ -231,7 +232,11 @@ func mkfifo(path string, mode uint32) (err error) {
 
 // NodeRestoreMetadata restores node metadata
 func NodeRestoreMetadata(node *restic.Node, path string, warn func(msg string), xattrSelectFilter func(xattrName string) bool) error {
-	err := nodeRestoreMetadata(node, path, warn, xattrSelectFilter)
+	err := nodeRestoreMetadata(node, path, chmod, warn, xattrSelectFilter)
+	return filterRestoreMetadataError(path, err)
+}
+
+func filterRestoreMetadataError(path string, err error) error {
 	if err != nil {
 		// It is common to have permission errors for folders like /home
 		// unless you're running as root, so ignore those.
@@ -246,7 +251,7 @@ func NodeRestoreMetadata(node *restic.Node, path string, warn func(msg string),
 	return err
 }
 
-func nodeRestoreMetadata(node *restic.Node, path string, warn func(msg string), xattrSelectFilter func(xattrName string) bool) error {
+func nodeRestoreMetadata(node *restic.Node, path string, chmodFn func(name string, mode os.FileMode) error, warn func(msg string), xattrSelectFilter func(xattrName string) bool) error {
 	var firsterr error
 
 	if err := lchown(path, int(node.UID), int(node.GID)); err != nil {
@@ -278,7 +283,7 @@ func nodeRestoreMetadata(node *restic.Node, path string, warn func(msg string),
 	// calling Chmod below will no longer allow any modifications to be made on the file and the
 	// calls above would fail.
 	if node.Type != restic.NodeTypeSymlink {
-		if err := chmod(path, node.Mode); err != nil {
+		if err := chmodFn(path, node.Mode); err != nil {
 			if firsterr == nil {
 				firsterr = errors.WithStack(err)
 			}
@@ -0,0 +1,98 @@
+package fs
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+
+	"golang.org/x/sys/unix"
+)
+
+// procFdPath returns a path which refers to name within the directory opened
+// as dir. The kernel resolves the path relative to the open file descriptor,
+// thus a concurrent rename or a symlink planted in place of a parent
+// directory cannot redirect the path elsewhere.
+func procFdPath(fd uintptr, name string) string {
+	path := "/proc/self/fd/" + strconv.Itoa(int(fd))
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+// NodeCreateIn creates the node as name within the directory dir but does NOT
+// restore node meta data. A symlink at name is never followed.
+func NodeCreateIn(dir *os.File, name string, node *restic.Node) error {
+	debug.Log("create node %v in %v as %v", node.Name, dir.Name(), name)
+
+	dirfd := int(dir.Fd())
+	var err error
+
+	switch node.Type {
+	case restic.NodeTypeDir:
+		err = unix.Mkdirat(dirfd, name, uint32(node.Mode.Perm()))
+		if errors.Is(err, unix.EEXIST) {
+			err = nil
+		}
+	case restic.NodeTypeFile:
+		var fd int
+		fd, err = unix.Openat(dirfd, name, unix.O_CREAT|unix.O_WRONLY|unix.O_TRUNC|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0600)
+		if err == nil {
+			err = unix.Close(fd)
+		}
+	case restic.NodeTypeSymlink:
+		err = unix.Symlinkat(node.LinkTarget, dirfd, name)
+	case restic.NodeTypeDev:
+		err = unix.Mknodat(dirfd, name, unix.S_IFBLK|0600, int(node.Device))
+	case restic.NodeTypeCharDev:
+		err = unix.Mknodat(dirfd, name, unix.S_IFCHR|0600, int(node.Device))
+	case restic.NodeTypeFifo:
+		err = unix.Mknodat(dirfd, name, unix.S_IFIFO|0600, 0)
+	case restic.NodeTypeSocket:
+		return nil
+	default:
+		return errors.Errorf("filetype %q not implemented", node.Type)
+	}
+
+	if err != nil {
+		return &os.PathError{Op: "create", Path: dir.Name() + "/" + name, Err: err}
+	}
+	return nil
+}
+
+// NodeRestoreMetadataIn restores the metadata of the item name within the
+// directory dir. In contrast to NodeRestoreMetadata, a symlink at name is
+// never followed, such that no item outside of dir can be modified.
+func NodeRestoreMetadataIn(dir *os.File, name string, node *restic.Node, warn func(msg string), xattrSelectFilter func(xattrName string) bool) error {
+	// Except for chmod, all operations used to restore the metadata do not
+	// follow a symlink in the last path component.
+	path := procFdPath(dir.Fd(), name)
+	err := nodeRestoreMetadata(node, path, chmodNoFollow, warn, xattrSelectFilter)
+	return filterRestoreMetadataError(dir.Name()+"/"+name, err)
+}
+
+// chmodNoFollow changes the mode of the named file. Unlike chmod, it does not
+// follow a symlink in the last path component, but leaves the symlink as is.
+func chmodNoFollow(name string, mode os.FileMode) error {
+	fd, err := unix.Open(name, unix.O_PATH|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer func() {
+		_ = unix.Close(fd)
+	}()
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return &os.PathError{Op: "fstat", Path: name, Err: err}
+	}
+	if stat.Mode&unix.S_IFMT == unix.S_IFLNK {
+		return nil
+	}
+
+	// a path below /proc/self/fd refers to the already opened inode
+	return chmod(procFdPath(uintptr(fd), ""), mode)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package fs
+
+import (
+	"os"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+)
+
+// NodeCreateIn is not supported on this platform.
+func NodeCreateIn(_ *os.File, _ string, _ *restic.Node) error {
+	return errors.New("creating nodes relative to a directory is not supported on this platform")
+}
+
+// NodeRestoreMetadataIn is not supported on this platform.
+func NodeRestoreMetadataIn(_ *os.File, _ string, _ *restic.Node, _ func(msg string), _ func(xattrName string) bool) error {
+	return errors.New("restoring metadata relative to a directory is not supported on this platform")
+}
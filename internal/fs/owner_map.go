@@ -0,0 +1,258 @@
+package fs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/restic/restic/internal/restic"
+)
+
+// OwnerMap rewrites the numeric owner and group of nodes. This is required
+// when restoring a snapshot on a machine on which the same accounts use
+// different user and group ids.
+type OwnerMap struct {
+	// UIDs and GIDs map the ids stored in a snapshot to the ids used instead.
+	UIDs map[uint32]uint32
+	GIDs map[uint32]uint32
+	// ByName resolves the user and group names stored in a node on the local
+	// system. Ids whose names cannot be resolved fall back to UIDs and GIDs.
+	ByName bool
+}
+
+// ParseIDMapping parses an id mapping in the form "old:new".
+func ParseIDMapping(s string) (from, to uint32, err error) {
+	oldID, newID, found := strings.Cut(s, ":")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid id mapping %q, expected old:new", s)
+	}
+
+	parsed, err := strconv.ParseUint(oldID, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid id mapping %q: %w", s, err)
+	}
+	from = uint32(parsed)
+
+	parsed, err = strconv.ParseUint(newID, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid id mapping %q: %w", s, err)
+	}
+	to = uint32(parsed)
+
+	return from, to, nil
+}
+
+// MapNode returns a node with the ownership rewritten according to the map.
+// The original node is never modified. If the map is nil or does not affect
+// the node, then node itself is returned.
+func (m *OwnerMap) MapNode(node *restic.Node) *restic.Node {
+	if m == nil || node == nil {
+		return node
+	}
+
+	uid, uidByName := m.mapUID(node.UID, node.User)
+	gid, gidByName := m.mapGID(node.GID, node.Group)
+	xattrs := m.mapACLs(node)
+
+	if uid == node.UID && gid == node.GID && xattrs == nil {
+		return node
+	}
+
+	mapped := *node
+	if uid != node.UID {
+		mapped.UID = uid
+		if !uidByName {
+			// the stored user name belongs to the old id
+			mapped.User = ""
+		}
+	}
+	if gid != node.GID {
+		mapped.GID = gid
+		if !gidByName {
+			mapped.Group = ""
+		}
+	}
+	if xattrs != nil {
+		mapped.ExtendedAttributes = xattrs
+	}
+	return &mapped
+}
+
+// mapUID returns the new uid and whether it was resolved using the user name.
+func (m *OwnerMap) mapUID(uid uint32, name string) (uint32, bool) {
+	if m.ByName && name != "" {
+		if id, ok := lookupUID(name); ok {
+			return id, true
+		}
+	}
+	if id, ok := m.UIDs[uid]; ok {
+		return id, false
+	}
+	return uid, false
+}
+
+// mapGID returns the new gid and whether it was resolved using the group name.
+func (m *OwnerMap) mapGID(gid uint32, name string) (uint32, bool) {
+	if m.ByName && name != "" {
+		if id, ok := lookupGID(name); ok {
+			return id, true
+		}
+	}
+	if id, ok := m.GIDs[gid]; ok {
+		return id, false
+	}
+	return gid, false
+}
+
+// mapACLs rewrites the ids within the Linux POSIX ACLs of node. It returns
+// nil if no extended attribute was changed.
+func (m *OwnerMap) mapACLs(node *restic.Node) []restic.ExtendedAttribute {
+	var xattrs []restic.ExtendedAttribute
+
+	for i, attr := range node.ExtendedAttributes {
+		if attr.Name != "system.posix_acl_access" && attr.Name != "system.posix_acl_default" {
+			continue
+		}
+
+		// ACL entries only contain ids, thus names are only known for
+		// entries that refer to the owner of the node
+		value, changed := mapLinuxACL(attr.Value, func(id uint32) uint32 {
+			name := ""
+			if id == node.UID {
+				name = node.User
+			}
+			newID, _ := m.mapUID(id, name)
+			return newID
+		}, func(id uint32) uint32 {
+			name := ""
+			if id == node.GID {
+				name = node.Group
+			}
+			newID, _ := m.mapGID(id, name)
+			return newID
+		})
+		if !changed {
+			continue
+		}
+
+		if xattrs == nil {
+			xattrs = make([]restic.ExtendedAttribute, len(node.ExtendedAttributes))
+			copy(xattrs, node.ExtendedAttributes)
+		}
+		xattrs[i].Value = value
+	}
+
+	return xattrs
+}
+
+// Tags of Linux ACL entries which contain a user or group id.
+const (
+	aclTagUser  = 0x02
+	aclTagGroup = 0x08
+)
+
+// mapLinuxACL rewrites the user and group ids in a Linux ACL in its binary
+// xattr format. Malformed ACLs are returned unchanged.
+func mapLinuxACL(acl []byte, mapUID, mapGID func(uint32) uint32) ([]byte, bool) {
+	if len(acl) < 4 || (len(acl)-4)%8 != 0 || binary.LittleEndian.Uint32(acl) != 2 {
+		return acl, false
+	}
+
+	var mapped []byte
+	for pos := 4; pos < len(acl); pos += 8 {
+		tag := binary.LittleEndian.Uint16(acl[pos:])
+		id := binary.LittleEndian.Uint32(acl[pos+4:])
+
+		var newID uint32
+		switch tag {
+		case aclTagUser:
+			newID = mapUID(id)
+		case aclTagGroup:
+			newID = mapGID(id)
+		default:
+			continue
+		}
+		if newID == id {
+			continue
+		}
+
+		if mapped == nil {
+			mapped = make([]byte, len(acl))
+			copy(mapped, acl)
+		}
+		binary.LittleEndian.PutUint32(mapped[pos+4:], newID)
+	}
+
+	if mapped == nil {
+		return acl, false
+	}
+	return mapped, true
+}
+
+type idLookupResult struct {
+	id uint32
+	ok bool
+}
+
+var (
+	uidByNameCache      = make(map[string]idLookupResult)
+	uidByNameCacheMutex = sync.RWMutex{}
+)
+
+// Cached uid lookup by user name.
+func lookupUID(name string) (uint32, bool) {
+	uidByNameCacheMutex.RLock()
+	res, ok := uidByNameCache[name]
+	uidByNameCacheMutex.RUnlock()
+
+	if ok {
+		return res.id, res.ok
+	}
+
+	u, err := user.Lookup(name)
+	if err == nil {
+		id, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err == nil {
+			res = idLookupResult{uint32(id), true}
+		}
+	}
+
+	uidByNameCacheMutex.Lock()
+	uidByNameCache[name] = res
+	uidByNameCacheMutex.Unlock()
+
+	return res.id, res.ok
+}
+
+var (
+	gidByNameCache      = make(map[string]idLookupResult)
+	gidByNameCacheMutex = sync.RWMutex{}
+)
+
+// Cached gid lookup by group name.
+func lookupGID(name string) (uint32, bool) {
+	gidByNameCacheMutex.RLock()
+	res, ok := gidByNameCache[name]
+	gidByNameCacheMutex.RUnlock()
+
+	if ok {
+		return res.id, res.ok
+	}
+
+	g, err := user.LookupGroup(name)
+	if err == nil {
+		id, err := strconv.ParseUint(g.Gid, 10, 32)
+		if err == nil {
+			res = idLookupResult{uint32(id), true}
+		}
+	}
+
+	gidByNameCacheMutex.Lock()
+	gidByNameCache[name] = res
+	gidByNameCacheMutex.Unlock()
+
+	return res.id, res.ok
+}
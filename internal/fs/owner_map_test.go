@@ -0,0 +1,171 @@
+package fs
+
+import (
+	"encoding/binary"
+	"os/user"
+	"testing"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestParseIDMapping(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		from, to uint32
+		valid    bool
+	}{
+		{"1000:2000", 1000, 2000, true},
+		{"0:0", 0, 0, true},
+		{"4294967295:1", 4294967295, 1, true},
+		{"1000", 0, 0, false},
+		{"1000:", 0, 0, false},
+		{":1000", 0, 0, false},
+		{"a:1", 0, 0, false},
+		{"-1:1", 0, 0, false},
+		{"4294967296:1", 0, 0, false},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			from, to, err := ParseIDMapping(test.input)
+			if !test.valid {
+				rtest.Assert(t, err != nil, "expected error for %q", test.input)
+				return
+			}
+			rtest.OK(t, err)
+			rtest.Equals(t, test.from, from)
+			rtest.Equals(t, test.to, to)
+		})
+	}
+}
+
+func buildLinuxACL(entries ...[3]uint32) []byte {
+	acl := make([]byte, 4, 4+8*len(entries))
+	binary.LittleEndian.PutUint32(acl, 2)
+	for _, e := range entries {
+		entry := make([]byte, 8)
+		binary.LittleEndian.PutUint16(entry, uint16(e[0]))
+		binary.LittleEndian.PutUint16(entry[2:], uint16(e[1]))
+		binary.LittleEndian.PutUint32(entry[4:], e[2])
+		acl = append(acl, entry...)
+	}
+	return acl
+}
+
+func TestOwnerMapNumeric(t *testing.T) {
+	acl := buildLinuxACL(
+		[3]uint32{0x01, 6, 0xffffffff},
+		[3]uint32{aclTagUser, 6, 1000},
+		[3]uint32{aclTagUser, 4, 1001},
+		[3]uint32{0x04, 4, 0xffffffff},
+		[3]uint32{aclTagGroup, 4, 100},
+		[3]uint32{0x20, 0, 0xffffffff},
+	)
+	node := &restic.Node{
+		Name:  "file",
+		Type:  restic.NodeTypeFile,
+		UID:   1000,
+		GID:   100,
+		User:  "olduser",
+		Group: "oldgroup",
+		ExtendedAttributes: []restic.ExtendedAttribute{
+			{Name: "user.foo", Value: []byte("bar")},
+			{Name: "system.posix_acl_access", Value: acl},
+		},
+	}
+
+	m := &OwnerMap{
+		UIDs: map[uint32]uint32{1000: 2000},
+		GIDs: map[uint32]uint32{100: 200},
+	}
+	mapped := m.MapNode(node)
+
+	rtest.Equals(t, uint32(2000), mapped.UID)
+	rtest.Equals(t, uint32(200), mapped.GID)
+	rtest.Equals(t, "", mapped.User)
+	rtest.Equals(t, "", mapped.Group)
+	rtest.Equals(t, []byte("bar"), mapped.ExtendedAttributes[0].Value)
+
+	expectedACL := buildLinuxACL(
+		[3]uint32{0x01, 6, 0xffffffff},
+		[3]uint32{aclTagUser, 6, 2000},
+		[3]uint32{aclTagUser, 4, 1001},
+		[3]uint32{0x04, 4, 0xffffffff},
+		[3]uint32{aclTagGroup, 4, 200},
+		[3]uint32{0x20, 0, 0xffffffff},
+	)
+	rtest.Equals(t, expectedACL, mapped.ExtendedAttributes[1].Value)
+
+	// the original node must not be modified
+	rtest.Equals(t, uint32(1000), node.UID)
+	rtest.Equals(t, uint32(100), node.GID)
+	rtest.Equals(t, "olduser", node.User)
+	rtest.Equals(t, acl, node.ExtendedAttributes[1].Value)
+}
+
+func TestOwnerMapUnchanged(t *testing.T) {
+	node := &restic.Node{Name: "file", UID: 1, GID: 2}
+
+	var m *OwnerMap
+	rtest.Assert(t, m.MapNode(node) == node, "nil map must return the node unchanged")
+
+	m = &OwnerMap{UIDs: map[uint32]uint32{1000: 2000}}
+	rtest.Assert(t, m.MapNode(node) == node, "unaffected node must be returned unchanged")
+}
+
+func TestOwnerMapMalformedACL(t *testing.T) {
+	node := &restic.Node{
+		Name: "file",
+		UID:  1000,
+		ExtendedAttributes: []restic.ExtendedAttribute{
+			{Name: "system.posix_acl_access", Value: []byte{1, 2, 3}},
+		},
+	}
+
+	m := &OwnerMap{UIDs: map[uint32]uint32{1000: 2000}}
+	mapped := m.MapNode(node)
+	rtest.Equals(t, uint32(2000), mapped.UID)
+	rtest.Equals(t, []byte{1, 2, 3}, mapped.ExtendedAttributes[0].Value)
+}
+
+func TestOwnerMapByName(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("unable to determine current user: %v", err)
+	}
+	g, err := user.LookupGroupId(u.Gid)
+	if err != nil {
+		t.Skipf("unable to determine current group: %v", err)
+	}
+	uid, ok := lookupUID(u.Username)
+	if !ok {
+		t.Skip("user ids are not numeric on this platform")
+	}
+	gid, ok := lookupGID(g.Name)
+	if !ok {
+		t.Skip("group ids are not numeric on this platform")
+	}
+
+	node := &restic.Node{
+		Name:  "file",
+		UID:   uid + 1,
+		GID:   gid + 1,
+		User:  u.Username,
+		Group: g.Name,
+	}
+
+	m := &OwnerMap{
+		UIDs:   map[uint32]uint32{uid + 1: 12345},
+		ByName: true,
+	}
+	mapped := m.MapNode(node)
+	rtest.Equals(t, uid, mapped.UID)
+	rtest.Equals(t, gid, mapped.GID)
+	rtest.Equals(t, u.Username, mapped.User)
+	rtest.Equals(t, g.Name, mapped.Group)
+
+	// fall back to numeric mappings for unknown names
+	node.User = "restic-owner-map-unknown-user"
+	mapped = m.MapNode(node)
+	rtest.Equals(t, uint32(12345), mapped.UID)
+	rtest.Equals(t, "", mapped.User)
+}
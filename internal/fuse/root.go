@@ -57,6 +57,7 @@ func NewRoot(repo restic.Repository, cfg Config) *Root {
 	}
 
 	// set defaults, if PathTemplates is not set
+	timelineDir := ""
 	if len(cfg.PathTemplates) == 0 {
 		cfg.PathTemplates = []string{
 			"ids/%i",
@@ -64,9 +65,12 @@ func NewRoot(repo restic.Repository, cfg Config) *Root {
 			"hosts/%h/%T",
 			"tags/%t/%T",
 		}
+		timelineDir = "timeline"
 	}
 
-	root.SnapshotsDir = NewSnapshotsDir(root, func() {}, rootInode, rootInode, NewSnapshotsDirStructure(root, cfg.PathTemplates, cfg.TimeTemplate), "")
+	dirStruct := NewSnapshotsDirStructure(root, cfg.PathTemplates, cfg.TimeTemplate)
+	dirStruct.timelineDir = timelineDir
+	root.SnapshotsDir = NewSnapshotsDir(root, func() {}, rootInode, rootInode, dirStruct, "")
 
 	return root
 }
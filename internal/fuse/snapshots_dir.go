@@ -123,6 +123,8 @@ func (d *SnapshotsDir) Lookup(ctx context.Context, name string) (fs.Node, error)
 			return newSnapshotLink(d.root, forget, inode, entry.linkTarget, entry.snapshot)
 		} else if entry.snapshot != nil {
 			return newDirFromSnapshot(d.root, forget, inode, entry.snapshot)
+		} else if entry.timeline != nil {
+			return newTimelineDir(d.root, forget, inode, d.inode, nil, timelineSources(entry.timeline)), nil
 		}
 		return NewSnapshotsDir(d.root, forget, inode, d.inode, d.dirStruct, d.prefix+"/"+name), nil
 	})
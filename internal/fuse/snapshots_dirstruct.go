@@ -24,16 +24,20 @@ type MetaDirData struct {
 	snapshot   *restic.Snapshot
 	// names is set if this is a pseudo directory
 	names map[string]*MetaDirData
+	// timeline is set if this directory merges the snapshots of a host
+	timeline []*restic.Snapshot
 }
 
 // SnapshotsDirStructure contains the directory structure for snapshots.
 // It uses a paths and time template to generate a map of pathnames
 // pointing to the actual snapshots. For templates that end with a time,
-// also "latest" links are generated.
+// also "latest" links are generated. If timelineDir is set, a directory with
+// this name contains the merged timeline of the snapshots of each host.
 type SnapshotsDirStructure struct {
 	root          *Root
 	pathTemplates []string
 	timeTemplate  string
+	timelineDir   string
 
 	mutex sync.Mutex
 	// "" is the root path, subdirectory paths are assembled as parent+"/"+childFn
@@ -193,7 +197,7 @@ outer:
 
 // uniqueName returns a unique name to be used for prefix+name.
 // It appends -number to make the name unique.
-func uniqueName(entries map[string]*MetaDirData, prefix, name string) string {
+func uniqueName[T any](entries map[string]T, prefix, name string) string {
 	newname := name
 	for i := 1; ; i++ {
 		if _, ok := entries[prefix+newname]; !ok {
@@ -213,6 +217,7 @@ func (d *SnapshotsDirStructure) makeDirs(snapshots restic.Snapshots) {
 	type mountData struct {
 		sn         *restic.Snapshot
 		linkTarget string // if linkTarget!= "", this is a symlink
+		timeline   bool   // if set, sn is added to the timeline
 		childFn    string
 		child      *MetaDirData
 	}
@@ -224,7 +229,9 @@ func (d *SnapshotsDirStructure) makeDirs(snapshots restic.Snapshots) {
 		if e == nil {
 			e = &MetaDirData{}
 		}
-		if data.sn != nil {
+		if data.timeline {
+			e.timeline = append(e.timeline, data.sn)
+		} else if data.sn != nil {
 			e.snapshot = data.sn
 			e.linkTarget = data.linkTarget
 		} else {
@@ -257,6 +264,13 @@ func (d *SnapshotsDirStructure) makeDirs(snapshots restic.Snapshots) {
 		}
 	}
 
+	if d.timelineDir != "" {
+		mount("/"+d.timelineDir, mountData{})
+		for _, sn := range snapshots {
+			mount("/"+d.timelineDir+"/"+filenameFromTag(sn.Hostname), mountData{sn: sn, timeline: true})
+		}
+	}
+
 	latestTime := make(map[string]time.Time)
 	for _, sn := range snapshots {
 		for _, templ := range d.pathTemplates {
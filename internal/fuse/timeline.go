@@ -0,0 +1,264 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/anacrolix/fuse"
+	"github.com/anacrolix/fuse/fs"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/restic"
+)
+
+// timelineTimeFormat is the format of the time appended to the name of each
+// version in a timeline directory.
+const timelineTimeFormat = "2006-01-02T15:04"
+
+// Statically ensure that *timelineDir implement those interface
+var _ = fs.HandleReadDirAller(&timelineDir{})
+var _ = fs.NodeForgetter(&timelineDir{})
+var _ = fs.NodeStringLookuper(&timelineDir{})
+
+// timelineSource is a tree of a snapshot which is part of a timeline directory.
+type timelineSource struct {
+	sn   *restic.Snapshot
+	tree restic.ID
+}
+
+// timelineEntry is an item within a timeline directory. Subdirectories have a
+// list of sources, all other items are a single version.
+type timelineEntry struct {
+	node    *restic.Node
+	sources []timelineSource
+}
+
+// timelineDir merges the directories at the same path in several snapshots,
+// which must be sorted by time. Subdirectories are merged as well, while all
+// other items are listed once for each distinct content as "name@time". The
+// time is that of the first snapshot containing the content.
+type timelineDir struct {
+	root        *Root
+	forget      forgetFn
+	inode       uint64
+	parentInode uint64
+	// node is the newest version of the directory, or nil for the top level
+	// directory of the timeline
+	node    *restic.Node
+	sources []timelineSource
+	items   map[string]*timelineEntry
+	m       sync.Mutex
+	cache   treeCache
+}
+
+// timelineSources returns the sources for the root trees of the snapshots.
+// Snapshots with the same tree as an earlier snapshot are skipped.
+func timelineSources(snapshots []*restic.Snapshot) []timelineSource {
+	seen := restic.NewIDSet()
+	var sources []timelineSource
+	for _, sn := range snapshots {
+		if sn.Tree == nil || seen.Has(*sn.Tree) {
+			continue
+		}
+		seen.Insert(*sn.Tree)
+		sources = append(sources, timelineSource{sn: sn, tree: *sn.Tree})
+	}
+	return sources
+}
+
+func newTimelineDir(root *Root, forget forgetFn, inode, parentInode uint64, node *restic.Node, sources []timelineSource) *timelineDir {
+	debug.Log("new timeline dir with %d sources", len(sources))
+	return &timelineDir{
+		root:        root,
+		forget:      forget,
+		inode:       inode,
+		parentInode: parentInode,
+		node:        node,
+		sources:     sources,
+		cache:       *newTreeCache(),
+	}
+}
+
+func (d *timelineDir) open(ctx context.Context) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if d.items != nil {
+		return nil
+	}
+
+	items := make(map[string]*timelineEntry)
+	// subtrees already added to a subdirectory
+	trees := make(map[string]restic.IDSet)
+	// content of the versions already added for each name
+	versions := make(map[string]map[string]struct{})
+
+	for _, src := range d.sources {
+		tree, err := restic.LoadTree(ctx, d.root.repo, src.tree)
+		if err != nil {
+			debug.Log("  error loading tree %v: %v", src.tree, err)
+			return unwrapCtxCanceled(err)
+		}
+
+		for _, n := range tree.Nodes {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			nodes, err := replaceSpecialNodes(ctx, d.root.repo, n)
+			if err != nil {
+				return err
+			}
+			for _, node := range nodes {
+				name := cleanupNodeName(node.Name)
+
+				if node.Type == restic.NodeTypeDir {
+					if node.Subtree == nil {
+						continue
+					}
+					entry := items[name]
+					if entry == nil {
+						entry = &timelineEntry{}
+						items[name] = entry
+						trees[name] = restic.NewIDSet()
+					} else if entry.node.Type != restic.NodeTypeDir {
+						// name is already used by a version of another item
+						continue
+					}
+					entry.node = node
+					if !trees[name].Has(*node.Subtree) {
+						trees[name].Insert(*node.Subtree)
+						entry.sources = append(entry.sources, timelineSource{sn: src.sn, tree: *node.Subtree})
+					}
+					continue
+				}
+
+				content := fmt.Sprintf("%v:%v", node.Type, node.ContentID())
+				if versions[name] == nil {
+					versions[name] = make(map[string]struct{})
+				}
+				if _, ok := versions[name][content]; ok {
+					continue
+				}
+				versions[name][content] = struct{}{}
+
+				version := *node
+				version.Name = uniqueName(items, "", name+"@"+src.sn.Time.Local().Format(timelineTimeFormat))
+				items[version.Name] = &timelineEntry{node: &version}
+			}
+		}
+	}
+
+	d.items = items
+	return nil
+}
+
+func (d *timelineDir) Attr(_ context.Context, a *fuse.Attr) error {
+	a.Inode = d.inode
+	a.Mode = os.ModeDir | 0555
+	a.Uid = d.root.uid
+	a.Gid = d.root.gid
+
+	if d.node != nil {
+		a.Mode = os.ModeDir | d.node.Mode
+		if !d.root.cfg.OwnerIsRoot {
+			a.Uid = d.node.UID
+			a.Gid = d.node.GID
+		}
+		a.Atime = d.node.AccessTime
+		a.Ctime = d.node.ChangeTime
+		a.Mtime = d.node.ModTime
+	} else if len(d.sources) > 0 {
+		t := d.sources[len(d.sources)-1].sn.Time
+		a.Atime = t
+		a.Ctime = t
+		a.Mtime = t
+	}
+
+	return nil
+}
+
+func (d *timelineDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	debug.Log("ReadDirAll()")
+	err := d.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]fuse.Dirent, 0, len(d.items)+2)
+	ret = append(ret, fuse.Dirent{
+		Inode: d.inode,
+		Name:  ".",
+		Type:  fuse.DT_Dir,
+	})
+	ret = append(ret, fuse.Dirent{
+		Inode: d.parentInode,
+		Name:  "..",
+		Type:  fuse.DT_Dir,
+	})
+
+	for name, entry := range d.items {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var typ fuse.DirentType
+		switch entry.node.Type {
+		case restic.NodeTypeDir:
+			typ = fuse.DT_Dir
+		case restic.NodeTypeFile:
+			typ = fuse.DT_File
+		case restic.NodeTypeSymlink:
+			typ = fuse.DT_Link
+		}
+
+		ret = append(ret, fuse.Dirent{
+			Inode: inodeFromName(d.inode, name),
+			Type:  typ,
+			Name:  name,
+		})
+	}
+
+	return ret, nil
+}
+
+func (d *timelineDir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	debug.Log("Lookup(%v)", name)
+
+	err := d.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.cache.lookupOrCreate(name, func(forget forgetFn) (fs.Node, error) {
+		entry, ok := d.items[name]
+		if !ok {
+			return nil, syscall.ENOENT
+		}
+		inode := inodeFromName(d.inode, name)
+		node := entry.node
+		switch node.Type {
+		case restic.NodeTypeDir:
+			return newTimelineDir(d.root, forget, inode, d.inode, node, entry.sources), nil
+		case restic.NodeTypeFile:
+			return newFile(d.root, forget, inode, node)
+		case restic.NodeTypeSymlink:
+			return newLink(d.root, forget, inode, node)
+		case restic.NodeTypeDev, restic.NodeTypeCharDev, restic.NodeTypeFifo, restic.NodeTypeSocket:
+			return newOther(d.root, forget, inode, node)
+		default:
+			debug.Log("  node %v has unknown type %v", name, node.Type)
+			return nil, syscall.ENOENT
+		}
+	})
+}
+
+func (d *timelineDir) Forget() {
+	d.forget()
+}
@@ -0,0 +1,109 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package fuse
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/fuse"
+	"github.com/anacrolix/fuse/fs"
+
+	"github.com/restic/restic/internal/archiver"
+	"github.com/restic/restic/internal/bloblru"
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func timelineNames(t *testing.T, d fs.Node) []string {
+	t.Helper()
+	entries, err := d.(fs.HandleReadDirAller).ReadDirAll(context.TODO())
+	rtest.OK(t, err)
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Name != "." && entry.Name != ".." {
+			names = append(names, entry.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readTimelineFile(t *testing.T, d fs.Node, name string) string {
+	t.Helper()
+	node, err := d.(fs.NodeStringLookuper).Lookup(context.TODO(), name)
+	rtest.OK(t, err)
+
+	var attr fuse.Attr
+	rtest.OK(t, node.Attr(context.TODO(), &attr))
+	handle, err := node.(fs.NodeOpener).Open(context.TODO(), nil, nil)
+	rtest.OK(t, err)
+
+	data := make([]byte, attr.Size)
+	testRead(t, handle, 0, len(data), data)
+	return string(data)
+}
+
+func TestTimelineDir(t *testing.T) {
+	repo := repository.TestRepository(t)
+	tempdir := rtest.TempDir(t)
+	src := filepath.Join(tempdir, "src")
+	rtest.OK(t, os.MkdirAll(filepath.Join(src, "sub"), 0755))
+	defer rtest.Chdir(t, tempdir)()
+
+	var snapshots []*restic.Snapshot
+	backup := func(day int, files map[string]string) {
+		for name, content := range files {
+			rtest.OK(t, os.WriteFile(filepath.Join(src, filepath.FromSlash(name)), []byte(content), 0644))
+		}
+		sn := archiver.TestSnapshot(t, repo, "src", nil)
+		sn.Time = time.Date(2024, 5, day, 10, 0, 0, 0, time.Local)
+		snapshots = append(snapshots, sn)
+	}
+
+	backup(1, map[string]string{"file": "first", "sub/x": "1"})
+	backup(2, map[string]string{"file": "second"})
+	backup(3, map[string]string{"file": "first", "sub/x": "2"})
+	// unchanged snapshot
+	backup(4, nil)
+
+	root := &Root{repo: repo, blobCache: bloblru.New(blobCacheSize)}
+	hostDir := newTimelineDir(root, func() {}, 2, 1, nil, timelineSources(snapshots))
+	rtest.Equals(t, 3, len(hostDir.sources))
+	rtest.Equals(t, []string{"src"}, timelineNames(t, hostDir))
+
+	var attr fuse.Attr
+	rtest.OK(t, hostDir.Attr(context.TODO(), &attr))
+	rtest.Equals(t, snapshots[2].Time, attr.Mtime)
+
+	srcDir := testStableLookup(t, hostDir, "src")
+	rtest.Equals(t, []string{"file@2024-05-01T10:00", "file@2024-05-02T10:00", "sub"}, timelineNames(t, srcDir))
+	rtest.Equals(t, "first", readTimelineFile(t, srcDir, "file@2024-05-01T10:00"))
+	rtest.Equals(t, "second", readTimelineFile(t, srcDir, "file@2024-05-02T10:00"))
+
+	subDir := testStableLookup(t, srcDir, "sub")
+	rtest.Equals(t, []string{"x@2024-05-01T10:00", "x@2024-05-03T10:00"}, timelineNames(t, subDir))
+	rtest.Equals(t, "2", readTimelineFile(t, subDir, "x@2024-05-03T10:00"))
+}
+
+func TestTimelineRoot(t *testing.T) {
+	repo := repository.TestRepository(t)
+	restic.TestCreateSnapshot(t, repo, time.Unix(1460289341, 207401672), 0)
+
+	root := NewRoot(repo, Config{})
+	timeline := testStableLookup(t, root, "timeline")
+	rtest.Equals(t, []string{"foo"}, timelineNames(t, timeline))
+	testStableLookup(t, timeline, "foo")
+
+	// custom path templates do not contain the timeline
+	root = NewRoot(repo, Config{PathTemplates: []string{"ids/%i"}})
+	_, err := root.Lookup(context.TODO(), "timeline")
+	rtest.Assert(t, err != nil, "expected no timeline directory for custom path templates")
+}
@@ -0,0 +1,388 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package fuse
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/anacrolix/fuse"
+	"github.com/anacrolix/fuse/fs"
+	"golang.org/x/net/webdav"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/ui"
+)
+
+// maxSymlinkHops is the maximum number of symlinks followed for a single path.
+const maxSymlinkHops = 8
+
+// WebDAVFileSystem provides read-only access to the directory structure of a
+// Root without a fuse mount. It uses the same nodes as the fuse mount, thus
+// trees and file contents are cached in the same way.
+//
+// Symlinks are followed if their target is a relative path within the file
+// system, all other symlinks are hidden.
+type WebDAVFileSystem struct {
+	root *Root
+}
+
+// statically ensure that WebDAVFileSystem implements webdav.FileSystem.
+var _ webdav.FileSystem = &WebDAVFileSystem{}
+
+// NewWebDAVFileSystem returns a new file system for root.
+func NewWebDAVFileSystem(root *Root) *WebDAVFileSystem {
+	return &WebDAVFileSystem{root: root}
+}
+
+// lookup returns the node for name, following symlinks.
+func (fsys *WebDAVFileSystem) lookup(ctx context.Context, name string, hops int) (fs.Node, error) {
+	var node fs.Node = fsys.root
+	dir := "/"
+	components := strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/")
+	for i, component := range components {
+		if component == "" {
+			continue
+		}
+
+		lookuper, ok := node.(fs.NodeStringLookuper)
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		next, err := lookuper.Lookup(ctx, component)
+		if errors.Is(err, syscall.ENOENT) {
+			return nil, os.ErrNotExist
+		} else if err != nil {
+			return nil, err
+		}
+
+		if link, ok := next.(fs.NodeReadlinker); ok {
+			target, err := link.Readlink(ctx, &fuse.ReadlinkRequest{})
+			if err != nil {
+				return nil, err
+			}
+			if path.IsAbs(target) || hops >= maxSymlinkHops {
+				return nil, os.ErrNotExist
+			}
+			rest := path.Join(append([]string{dir, target}, components[i+1:]...)...)
+			return fsys.lookup(ctx, rest, hops+1)
+		}
+
+		node = next
+		dir = path.Join(dir, component)
+	}
+	return node, nil
+}
+
+// Stat returns information about the item name.
+func (fsys *WebDAVFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	node, err := fsys.lookup(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	return statNode(ctx, node, path.Base(path.Clean("/"+name)))
+}
+
+// OpenFile opens the item name for reading, all write access is rejected.
+func (fsys *WebDAVFileSystem) OpenFile(ctx context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	debug.Log("OpenFile(%v, %x)", name, flag)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+
+	node, err := fsys.lookup(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := statNode(ctx, node, path.Base(path.Clean("/"+name)))
+	if err != nil {
+		return nil, err
+	}
+	return &webdavFile{ctx: ctx, fsys: fsys, name: path.Clean("/" + name), node: node, info: fi}, nil
+}
+
+func (fsys *WebDAVFileSystem) Mkdir(_ context.Context, _ string, _ os.FileMode) error {
+	return os.ErrPermission
+}
+
+func (fsys *WebDAVFileSystem) RemoveAll(_ context.Context, _ string) error {
+	return os.ErrPermission
+}
+
+func (fsys *WebDAVFileSystem) Rename(_ context.Context, _, _ string) error {
+	return os.ErrPermission
+}
+
+// nodeInfo implements os.FileInfo for a node.
+type nodeInfo struct {
+	name string
+	attr fuse.Attr
+}
+
+func statNode(ctx context.Context, node fs.Node, name string) (*nodeInfo, error) {
+	fi := &nodeInfo{name: name}
+	if err := node.Attr(ctx, &fi.attr); err != nil {
+		return nil, err
+	}
+	return fi, nil
+}
+
+func (fi *nodeInfo) Name() string       { return fi.name }
+func (fi *nodeInfo) Size() int64        { return int64(fi.attr.Size) }
+func (fi *nodeInfo) Mode() os.FileMode  { return fi.attr.Mode }
+func (fi *nodeInfo) ModTime() time.Time { return fi.attr.Mtime }
+func (fi *nodeInfo) IsDir() bool        { return fi.attr.Mode.IsDir() }
+func (fi *nodeInfo) Sys() interface{}   { return nil }
+
+// webdavFile is an item opened via WebDAVFileSystem.
+type webdavFile struct {
+	ctx  context.Context
+	fsys *WebDAVFileSystem
+	name string
+	node fs.Node
+	info *nodeInfo
+
+	handle fs.HandleReader
+	offset int64
+
+	entries []os.FileInfo
+	dirPos  int
+}
+
+func (f *webdavFile) open() error {
+	opener, ok := f.node.(fs.NodeOpener)
+	if !ok {
+		return errors.Errorf("%v cannot be read", f.name)
+	}
+	handle, err := opener.Open(f.ctx, &fuse.OpenRequest{}, &fuse.OpenResponse{})
+	if err != nil {
+		return err
+	}
+	reader, ok := handle.(fs.HandleReader)
+	if !ok {
+		return errors.Errorf("%v cannot be read", f.name)
+	}
+
+	// the size of the opened file can differ from the size stored in the node
+	if node, ok := handle.(fs.Node); ok {
+		if err := node.Attr(f.ctx, &f.info.attr); err != nil {
+			return err
+		}
+	}
+	f.handle = reader
+	return nil
+}
+
+func (f *webdavFile) Read(p []byte) (int, error) {
+	if f.info.IsDir() {
+		return 0, errors.Errorf("%v is a directory", f.name)
+	}
+	if f.handle == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	remaining := f.info.Size() - f.offset
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	req := &fuse.ReadRequest{Offset: f.offset, Size: len(p)}
+	resp := &fuse.ReadResponse{Data: p}
+	if err := f.handle.Read(f.ctx, req, resp); err != nil {
+		return 0, err
+	}
+	if len(resp.Data) == 0 {
+		return 0, io.EOF
+	}
+	f.offset += int64(len(resp.Data))
+	return len(resp.Data), nil
+}
+
+func (f *webdavFile) Seek(offset int64, whence int) (int64, error) {
+	if f.handle == nil && !f.info.IsDir() {
+		// determine the real size of the file
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Readdir returns the entries of a directory, see http.File.
+func (f *webdavFile) Readdir(count int) ([]os.FileInfo, error) {
+	if f.entries == nil {
+		lister, ok := f.node.(fs.HandleReadDirAller)
+		if !ok {
+			return nil, errors.Errorf("%v is not a directory", f.name)
+		}
+		dirents, err := lister.ReadDirAll(f.ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		f.entries = make([]os.FileInfo, 0, len(dirents))
+		for _, dirent := range dirents {
+			if dirent.Name == "." || dirent.Name == ".." {
+				continue
+			}
+			fi, err := f.fsys.Stat(f.ctx, path.Join(f.name, dirent.Name))
+			if errors.Is(err, os.ErrNotExist) {
+				// symlink which cannot be followed
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			f.entries = append(f.entries, fi)
+		}
+		sort.Slice(f.entries, func(i, j int) bool {
+			return f.entries[i].Name() < f.entries[j].Name()
+		})
+	}
+
+	entries := f.entries[f.dirPos:]
+	if count <= 0 {
+		f.dirPos = len(f.entries)
+		return entries, nil
+	}
+	if len(entries) == 0 {
+		return nil, io.EOF
+	}
+	if count < len(entries) {
+		entries = entries[:count]
+	}
+	f.dirPos += len(entries)
+	return entries, nil
+}
+
+func (f *webdavFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *webdavFile) Write(_ []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (f *webdavFile) Close() error {
+	return nil
+}
+
+var dirListTemplate = template.Must(template.New("dir").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{ .Path }}</title></head>
+<body>
+<h1>{{ .Path }}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{- if ne .Path "/" }}
+<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{- end }}
+{{- range .Entries }}
+<tr><td><a href="{{ .Link }}">{{ .Name }}</a></td><td>{{ .Size }}</td><td>{{ .ModTime }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+type dirListEntry struct {
+	Name, Link, Size, ModTime string
+}
+
+// NewWebDAVHandler returns a handler which serves fsys via WebDAV. Requests
+// for directories using GET are answered with a HTML directory listing, such
+// that the snapshots can also be browsed using a web browser. Downloads
+// support range requests.
+func NewWebDAVHandler(fsys *WebDAVFileSystem, logger func(*http.Request, error)) http.Handler {
+	dav := &webdav.Handler{
+		FileSystem: fsys,
+		LockSystem: webdav.NewMemLS(),
+		Logger:     logger,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			fi, err := fsys.Stat(r.Context(), r.URL.Path)
+			if err == nil && fi.IsDir() {
+				serveDirList(w, r, fsys)
+				return
+			}
+		}
+		dav.ServeHTTP(w, r)
+	})
+}
+
+func serveDirList(w http.ResponseWriter, r *http.Request, fsys *WebDAVFileSystem) {
+	p := r.URL.Path
+	if !strings.HasSuffix(p, "/") {
+		http.Redirect(w, r, path.Base(p)+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	f, err := fsys.OpenFile(r.Context(), p, os.O_RDONLY, 0)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	entries, err := f.Readdir(0)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Path    string
+		Entries []dirListEntry
+	}{Path: p}
+	for _, fi := range entries {
+		entry := dirListEntry{
+			Name:    fi.Name(),
+			Link:    url.PathEscape(fi.Name()),
+			ModTime: fi.ModTime().Format(time.DateTime),
+		}
+		if fi.IsDir() {
+			entry.Name += "/"
+			entry.Link += "/"
+		} else {
+			entry.Size = ui.FormatBytes(uint64(fi.Size()))
+		}
+		data.Entries = append(data.Entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := dirListTemplate.Execute(w, data); err != nil {
+		debug.Log("rendering directory listing for %v failed: %v", p, err)
+	}
+}
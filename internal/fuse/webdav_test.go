@@ -0,0 +1,91 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package fuse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/archiver"
+	"github.com/restic/restic/internal/repository"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testWebDAVRequest(t *testing.T, handler http.Handler, method, target string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	body, err := io.ReadAll(resp.Body)
+	rtest.OK(t, err)
+	return resp, string(body)
+}
+
+func TestWebDAVHandler(t *testing.T) {
+	repo := repository.TestRepository(t)
+	tempdir := rtest.TempDir(t)
+	src := filepath.Join(tempdir, "src")
+	rtest.OK(t, os.MkdirAll(src, 0755))
+	rtest.OK(t, os.WriteFile(filepath.Join(src, "file"), []byte("0123456789"), 0644))
+	rtest.OK(t, os.Symlink("file", filepath.Join(src, "link")))
+	rtest.OK(t, os.Symlink("/etc/passwd", filepath.Join(src, "abslink")))
+	defer rtest.Chdir(t, tempdir)()
+	archiver.TestSnapshot(t, repo, "src", nil)
+
+	handler := NewWebDAVHandler(NewWebDAVFileSystem(NewRoot(repo, Config{TimeTemplate: time.RFC3339})), nil)
+	id := firstSnapshotID(t, repo)
+	snapshotPath := "/ids/" + id.Str()
+
+	// directory listing for browsers
+	resp, body := testWebDAVRequest(t, handler, http.MethodGet, "/", nil)
+	rtest.Equals(t, http.StatusOK, resp.StatusCode)
+	for _, name := range []string{"ids/", "snapshots/", "hosts/", "tags/", "timeline/"} {
+		rtest.Assert(t, strings.Contains(body, `href="`+name+`"`), "listing does not contain %v: %v", name, body)
+	}
+	resp, _ = testWebDAVRequest(t, handler, http.MethodGet, snapshotPath, nil)
+	rtest.Equals(t, http.StatusMovedPermanently, resp.StatusCode)
+
+	resp, body = testWebDAVRequest(t, handler, http.MethodGet, snapshotPath+"/src/", nil)
+	rtest.Equals(t, http.StatusOK, resp.StatusCode)
+	rtest.Assert(t, strings.Contains(body, `href="file"`), "listing does not contain file: %v", body)
+	rtest.Assert(t, strings.Contains(body, `href="link"`), "listing does not contain link: %v", body)
+	rtest.Assert(t, !strings.Contains(body, "abslink"), "listing contains symlink to absolute path: %v", body)
+
+	// downloads, including range requests and symlinks
+	resp, body = testWebDAVRequest(t, handler, http.MethodGet, snapshotPath+"/src/file", nil)
+	rtest.Equals(t, http.StatusOK, resp.StatusCode)
+	rtest.Equals(t, "0123456789", body)
+
+	resp, body = testWebDAVRequest(t, handler, http.MethodGet, snapshotPath+"/src/file", map[string]string{"Range": "bytes=2-5"})
+	rtest.Equals(t, http.StatusPartialContent, resp.StatusCode)
+	rtest.Equals(t, "2345", body)
+
+	resp, body = testWebDAVRequest(t, handler, http.MethodGet, "/snapshots/latest/src/link", nil)
+	rtest.Equals(t, http.StatusOK, resp.StatusCode)
+	rtest.Equals(t, "0123456789", body)
+
+	resp, _ = testWebDAVRequest(t, handler, http.MethodGet, snapshotPath+"/src/missing", nil)
+	rtest.Equals(t, http.StatusNotFound, resp.StatusCode)
+
+	// WebDAV
+	resp, body = testWebDAVRequest(t, handler, "PROPFIND", snapshotPath+"/src/", map[string]string{"Depth": "1"})
+	rtest.Equals(t, http.StatusMultiStatus, resp.StatusCode)
+	rtest.Assert(t, strings.Contains(body, snapshotPath+"/src/file"), "PROPFIND response does not contain file: %v", body)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL"} {
+		resp, _ = testWebDAVRequest(t, handler, method, snapshotPath+"/src/new", nil)
+		rtest.Assert(t, resp.StatusCode >= 400, "%v was not rejected: %v", method, resp.Status)
+	}
+}
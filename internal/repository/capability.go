@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+)
+
+// Capability is a set of operations a key is allowed to perform.
+type Capability string
+
+const (
+	// CapabilityBackup allows creating new backups, but not reading the
+	// content of files or removing anything.
+	CapabilityBackup Capability = "backup-only"
+	// CapabilityRead allows reading snapshots and the content of files, but
+	// not creating or removing anything except locks.
+	CapabilityRead Capability = "read-only"
+	// CapabilityAdmin allows all operations, including removing snapshots,
+	// data and keys.
+	CapabilityAdmin Capability = "admin"
+)
+
+// Capabilities is the set of capabilities of a key. An empty set grants all
+// capabilities, which is the case for keys created without restrictions.
+type Capabilities []Capability
+
+// ParseCapabilities parses a comma separated list of capabilities.
+func ParseCapabilities(s string) (Capabilities, error) {
+	var caps Capabilities
+	for _, name := range strings.Split(s, ",") {
+		c := Capability(strings.TrimSpace(name))
+		switch c {
+		case CapabilityBackup, CapabilityRead, CapabilityAdmin:
+		default:
+			return nil, errors.Errorf("invalid capability %q, use %q, %q or %q", name, CapabilityBackup, CapabilityRead, CapabilityAdmin)
+		}
+		if !slices.Contains(caps, c) {
+			caps = append(caps, c)
+		}
+	}
+	return caps, nil
+}
+
+// Has returns true if the set grants capability c. The admin capability
+// implies all other capabilities.
+func (caps Capabilities) Has(c Capability) bool {
+	return len(caps) == 0 || slices.Contains(caps, c) || slices.Contains(caps, CapabilityAdmin)
+}
+
+// Contains returns true if caps grants all capabilities of other.
+func (caps Capabilities) Contains(other Capabilities) bool {
+	if len(other) == 0 {
+		return caps.Has(CapabilityAdmin)
+	}
+	for _, c := range other {
+		if !caps.Has(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (caps Capabilities) String() string {
+	if len(caps) == 0 {
+		return string(CapabilityAdmin)
+	}
+	names := make([]string, 0, len(caps))
+	for _, c := range caps {
+		names = append(names, string(c))
+	}
+	return strings.Join(names, ",")
+}
+
+// CapabilityError is returned if the current key lacks the capability
+// required for an operation.
+type CapabilityError struct {
+	Capability Capability
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("the current key lacks the %q capability", e.Capability)
+}
+
+// EffectiveCapabilities returns the capabilities of k in a repository with
+// the given config, which is empty if the key cannot be used for anything.
+// Keys of append-only repositories are only admin keys if they hold the admin
+// signing key, and write-only keys of asymmetric repositories can never read
+// data.
+func (k *Key) EffectiveCapabilities(cfg restic.Config) Capabilities {
+	caps := k.Capabilities
+	if len(caps) == 0 {
+		caps = Capabilities{CapabilityAdmin}
+	}
+
+	var result Capabilities
+	add := func(c Capability) {
+		if !slices.Contains(result, c) {
+			result = append(result, c)
+		}
+	}
+	for _, c := range caps {
+		switch {
+		case c == CapabilityAdmin && cfg.AppendOnly && !k.IsAdmin(),
+			c == CapabilityAdmin && k.WriteOnly:
+			add(CapabilityBackup)
+			if !k.WriteOnly {
+				add(CapabilityRead)
+			}
+		case c == CapabilityRead && k.WriteOnly:
+		default:
+			add(c)
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities("backup-only, read-only,backup-only")
+	rtest.OK(t, err)
+	rtest.Equals(t, Capabilities{CapabilityBackup, CapabilityRead}, caps)
+	rtest.Equals(t, "backup-only,read-only", caps.String())
+
+	_, err = ParseCapabilities("write")
+	rtest.Assert(t, err != nil, "expected error for invalid capability")
+}
+
+func TestCapabilitiesHas(t *testing.T) {
+	for _, test := range []struct {
+		caps                Capabilities
+		backup, read, admin bool
+	}{
+		{nil, true, true, true},
+		{Capabilities{CapabilityAdmin}, true, true, true},
+		{Capabilities{CapabilityBackup}, true, false, false},
+		{Capabilities{CapabilityRead}, false, true, false},
+		{Capabilities{CapabilityBackup, CapabilityRead}, true, true, false},
+	} {
+		rtest.Equals(t, test.backup, test.caps.Has(CapabilityBackup))
+		rtest.Equals(t, test.read, test.caps.Has(CapabilityRead))
+		rtest.Equals(t, test.admin, test.caps.Has(CapabilityAdmin))
+	}
+
+	rtest.Assert(t, Capabilities{CapabilityAdmin}.Contains(nil), "admin should contain all capabilities")
+	rtest.Assert(t, !Capabilities{CapabilityBackup}.Contains(nil), "backup-only should not contain all capabilities")
+	rtest.Assert(t, !Capabilities{CapabilityBackup}.Contains(Capabilities{CapabilityRead}), "backup-only should not contain read-only")
+}
+
+func openWithKey(t *testing.T, repo *Repository, password string, opts KeyOptions) (*Repository, error) {
+	key, err := AddKey(context.TODO(), repo, password, repo.Key(), opts)
+	rtest.OK(t, err)
+	r, err := New(repo.be, Options{})
+	rtest.OK(t, err)
+	err = r.SearchKey(context.TODO(), password, 0, key.ID().String())
+	if err == nil {
+		rtest.OK(t, r.LoadIndex(context.TODO(), nil))
+	}
+	return r, err
+}
+
+func TestKeyCapabilities(t *testing.T) {
+	repo := TestRepository(t)
+	ctx := context.TODO()
+
+	var wg errgroup.Group
+	repo.StartPackUploader(ctx, &wg)
+	id, _, _, err := repo.SaveBlob(ctx, restic.DataBlob, []byte("content"), restic.ID{}, false)
+	rtest.OK(t, err)
+	rtest.OK(t, repo.Flush(ctx))
+	rtest.OK(t, repo.RequireCapability(CapabilityAdmin))
+
+	var capErr *CapabilityError
+
+	backup, err := openWithKey(t, repo, "backup", KeyOptions{Capabilities: Capabilities{CapabilityBackup}})
+	rtest.OK(t, err)
+	rtest.Equals(t, Capabilities{CapabilityBackup}, backup.Capabilities())
+	_, err = backup.LoadBlob(ctx, restic.DataBlob, id, nil)
+	rtest.Assert(t, errors.As(err, &capErr) && capErr.Capability == CapabilityRead, "unexpected error %v", err)
+	snID, err := backup.SaveUnpacked(ctx, restic.WriteableSnapshotFile, []byte("{}"))
+	rtest.OK(t, err)
+	err = backup.RemoveUnpacked(ctx, restic.WriteableSnapshotFile, snID)
+	rtest.Assert(t, errors.As(err, &capErr) && capErr.Capability == CapabilityAdmin, "unexpected error %v", err)
+	err = RemoveKey(ctx, backup, repo.KeyID())
+	rtest.Assert(t, errors.As(err, &capErr), "unexpected error %v", err)
+
+	read, err := openWithKey(t, repo, "read", KeyOptions{Capabilities: Capabilities{CapabilityRead}})
+	rtest.OK(t, err)
+	buf, err := read.LoadBlob(ctx, restic.DataBlob, id, nil)
+	rtest.OK(t, err)
+	rtest.Equals(t, []byte("content"), buf)
+	_, err = read.SaveUnpacked(ctx, restic.WriteableSnapshotFile, []byte("{}"))
+	rtest.Assert(t, errors.As(err, &capErr) && capErr.Capability == CapabilityBackup, "unexpected error %v", err)
+	// read-only keys must not upload packs either
+	wg = errgroup.Group{}
+	read.StartPackUploader(ctx, &wg)
+	_, _, _, err = read.SaveBlob(ctx, restic.DataBlob, []byte("other content"), restic.ID{}, false)
+	rtest.Assert(t, errors.As(err, &capErr) && capErr.Capability == CapabilityBackup, "unexpected error %v", err)
+	rtest.OK(t, read.Flush(ctx))
+
+	// locks are not restricted
+	lockID, err := (&internalRepository{read}).SaveUnpacked(ctx, restic.LockFile, []byte("{}"))
+	rtest.OK(t, err)
+	rtest.OK(t, (&internalRepository{read}).RemoveUnpacked(ctx, restic.LockFile, lockID))
+
+	rtest.OK(t, repo.RemoveUnpacked(ctx, restic.WriteableSnapshotFile, snID))
+}
+
+func TestKeyCapabilitiesAppendOnly(t *testing.T) {
+	repo, _ := testAppendOnlyRepository(t)
+
+	// keys without the admin signing key cannot be admin keys
+	user, err := openWithKey(t, repo, "user", KeyOptions{Capabilities: Capabilities{CapabilityAdmin}})
+	rtest.OK(t, err)
+	rtest.Equals(t, Capabilities{CapabilityBackup, CapabilityRead}, user.Capabilities())
+	rtest.Assert(t, user.CheckRemove(restic.SnapshotFile) == ErrAppendOnly, "expected ErrAppendOnly")
+
+	admin, err := openWithKey(t, repo, "admin", KeyOptions{Admin: repo.AdminKey()})
+	rtest.OK(t, err)
+	rtest.OK(t, admin.CheckRemove(restic.SnapshotFile))
+}
+
+func TestKeyExpires(t *testing.T) {
+	repo := TestRepository(t)
+
+	r, err := openWithKey(t, repo, "valid", KeyOptions{Expires: time.Now().Add(time.Hour)})
+	rtest.OK(t, err)
+	rtest.Assert(t, r.KeyExpires() != nil, "missing expiry time")
+
+	_, err = openWithKey(t, repo, "expired", KeyOptions{Expires: time.Now().Add(-time.Hour)})
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "expired"), "unexpected error %v", err)
+}
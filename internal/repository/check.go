@@ -68,7 +68,11 @@ func checkPackInner(ctx context.Context, r *Repository, id restic.ID, blobs []re
 	sort.Slice(blobs, func(i, j int) bool {
 		return blobs[i].Offset < blobs[j].Offset
 	})
-	idxHdrSize := pack.CalculateHeaderSize(blobs)
+	key, idxPackKey, err := r.packBlobKey(id, blobs[0].BlobHandle)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt pack: %w", err)
+	}
+	idxHdrSize := pack.CalculateHeaderSize(blobs, idxPackKey)
 	lastBlobEnd := 0
 	nonContinuousPack := false
 	for _, blob := range blobs {
@@ -89,11 +93,11 @@ func checkPackInner(ctx context.Context, r *Repository, id restic.ID, blobs []re
 	var hash restic.ID
 	var hdrBuf []byte
 	h := backend.Handle{Type: backend.PackFile, Name: id.String()}
-	err := r.be.Load(ctx, h, int(size), 0, func(rd io.Reader) error {
+	err = r.be.Load(ctx, h, int(size), 0, func(rd io.Reader) error {
 		hrd := hashing.NewReader(rd, sha256.New())
 		bufRd.Reset(hrd)
 
-		it := newPackBlobIterator(id, newBufReader(bufRd), 0, blobs, r.Key(), dec)
+		it := newPackBlobIterator(id, newBufReader(bufRd), 0, blobs, key, dec)
 		for {
 			if ctx.Err() != nil {
 				return ctx.Err()
@@ -152,11 +156,16 @@ func checkPackInner(ctx context.Context, r *Repository, id restic.ID, blobs []re
 		return &ErrPackData{PackID: id, errs: append(errs, errors.Errorf("unexpected pack id %v", hash))}
 	}
 
-	blobs, hdrSize, err := pack.List(r.Key(), bytes.NewReader(hdrBuf), int64(len(hdrBuf)))
+	blobs, packKey, hdrSize, err := pack.ListWithKey(r.Key(), bytes.NewReader(hdrBuf), int64(len(hdrBuf)))
 	if err != nil {
 		return &ErrPackData{PackID: id, errs: append(errs, err)}
 	}
 
+	if packKey != idxPackKey {
+		debug.Log("Pack key does not match, want %v, got %v", idxPackKey, packKey)
+		errs = append(errs, errors.Errorf("pack key does not match index"))
+	}
+
 	if uint32(idxHdrSize) != hdrSize {
 		debug.Log("Pack header size does not match, want %v, got %v", idxHdrSize, hdrSize)
 		errs = append(errs, errors.Errorf("pack header size does not match, want %v, got %v", idxHdrSize, hdrSize))
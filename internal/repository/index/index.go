@@ -49,6 +49,8 @@ type Index struct {
 	m      sync.RWMutex
 	byType [restic.NumBlobTypes]indexMap
 	packs  restic.IDs
+	// packKeys contains the pack keys of packs in asymmetric repositories
+	packKeys map[restic.ID]crypto.PublicKey
 
 	final   bool       // set to true for all indexes read from the backend ("finalized")
 	ids     restic.IDs // set to the IDs of the contained finalized indexes
@@ -64,8 +66,14 @@ func NewIndex() *Index {
 
 // addToPacks saves the given pack ID and return the index.
 // This procedere allows to use pack IDs which can be easily garbage collected after.
-func (idx *Index) addToPacks(id restic.ID) int {
+func (idx *Index) addToPacks(id restic.ID, packKey crypto.PublicKey) int {
 	idx.packs = append(idx.packs, id)
+	if !packKey.IsNull() {
+		if idx.packKeys == nil {
+			idx.packKeys = make(map[restic.ID]crypto.PublicKey)
+		}
+		idx.packKeys[id] = packKey
+	}
 	return len(idx.packs) - 1
 }
 
@@ -134,6 +142,12 @@ var Oversized = func(idx *Index) bool {
 // StorePack remembers the ids of all blobs of a given pack
 // in the index
 func (idx *Index) StorePack(id restic.ID, blobs []restic.Blob) {
+	idx.StorePackWithKey(id, crypto.PublicKey{}, blobs)
+}
+
+// StorePackWithKey is like StorePack, but additionally remembers the pack key
+// of a pack in an asymmetric repository.
+func (idx *Index) StorePackWithKey(id restic.ID, packKey crypto.PublicKey, blobs []restic.Blob) {
 	idx.m.Lock()
 	defer idx.m.Unlock()
 
@@ -142,7 +156,7 @@ func (idx *Index) StorePack(id restic.ID, blobs []restic.Blob) {
 	}
 
 	debug.Log("%v", blobs)
-	packIndex := idx.addToPacks(id)
+	packIndex := idx.addToPacks(id, packKey)
 
 	for _, blob := range blobs {
 		idx.store(packIndex, blob)
@@ -150,6 +164,7 @@ func (idx *Index) StorePack(id restic.ID, blobs []restic.Blob) {
 }
 
 func (idx *Index) toPackedBlob(e *indexEntry, t restic.BlobType) restic.PackedBlob {
+	packID := idx.packs[e.packIndex]
 	return restic.PackedBlob{
 		Blob: restic.Blob{
 			BlobHandle: restic.BlobHandle{
@@ -159,7 +174,8 @@ func (idx *Index) toPackedBlob(e *indexEntry, t restic.BlobType) restic.PackedBl
 			Offset:             uint(e.offset),
 			UncompressedLength: uint(e.uncompressedLength),
 		},
-		PackID: idx.packs[e.packIndex],
+		PackID:  packID,
+		PackKey: idx.packKeys[packID],
 	}
 }
 
@@ -220,8 +236,9 @@ func (idx *Index) Each(ctx context.Context, fn func(restic.PackedBlob)) error {
 }
 
 type EachByPackResult struct {
-	PackID restic.ID
-	Blobs  []restic.Blob
+	PackID  restic.ID
+	PackKey crypto.PublicKey
+	Blobs   []restic.Blob
 }
 
 // EachByPack returns a channel that yields all blobs known to the index
@@ -258,6 +275,7 @@ func (idx *Index) EachByPack(ctx context.Context, packBlacklist restic.IDSet) <-
 		for packID, packByType := range byPack {
 			var result EachByPackResult
 			result.PackID = packID
+			result.PackKey = idx.packKeys[packID]
 			for typ, p := range packByType {
 				for _, e := range p {
 					result.Blobs = append(result.Blobs, idx.toPackedBlob(e, restic.BlobType(typ)).Blob)
@@ -290,8 +308,9 @@ func (idx *Index) Packs() restic.IDSet {
 }
 
 type packJSON struct {
-	ID    restic.ID  `json:"id"`
-	Blobs []blobJSON `json:"blobs"`
+	ID    restic.ID         `json:"id"`
+	Key   *crypto.PublicKey `json:"key,omitempty"`
+	Blobs []blobJSON        `json:"blobs"`
 }
 
 type blobJSON struct {
@@ -320,6 +339,9 @@ func (idx *Index) generatePackList() ([]packJSON, error) {
 				i = len(list)
 				list = append(list, packJSON{ID: packID})
 				packs[packID] = i
+				if packKey, ok := idx.packKeys[packID]; ok {
+					list[i].Key = &packKey
+				}
 			}
 			p := &list[i]
 
@@ -467,6 +489,12 @@ func (idx *Index) merge(idx2 *Index) error {
 	packlen := len(idx.packs)
 	// first append packs as they might be accessed when looking for duplicates below
 	idx.packs = append(idx.packs, idx2.packs...)
+	for id, packKey := range idx2.packKeys {
+		if idx.packKeys == nil {
+			idx.packKeys = make(map[restic.ID]crypto.PublicKey)
+		}
+		idx.packKeys[id] = packKey
+	}
 
 	// copy all index entries of idx2 to idx
 	for typ := range idx2.byType {
@@ -512,7 +540,11 @@ func DecodeIndex(buf []byte, id restic.ID) (idx *Index, err error) {
 
 	idx = NewIndex()
 	for _, p := range idxJSON.Packs {
-		packID := idx.addToPacks(p.ID)
+		var packKey crypto.PublicKey
+		if p.Key != nil {
+			packKey = *p.Key
+		}
+		packID := idx.addToPacks(p.ID, packKey)
 
 		for _, blob := range p.Blobs {
 			idx.store(packID, restic.Blob{
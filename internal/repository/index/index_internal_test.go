@@ -3,6 +3,7 @@ package index
 import (
 	"testing"
 
+	"github.com/restic/restic/internal/crypto"
 	"github.com/restic/restic/internal/repository/pack"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
@@ -12,7 +13,7 @@ func TestIndexOversized(t *testing.T) {
 	idx := NewIndex()
 
 	// Add blobs up to indexMaxBlobs + pack.MaxHeaderEntries - 1
-	packID := idx.addToPacks(restic.NewRandomID())
+	packID := idx.addToPacks(restic.NewRandomID(), crypto.PublicKey{})
 	for i := uint(0); i < indexMaxBlobs+pack.MaxHeaderEntries-1; i++ {
 		idx.store(packID, restic.Blob{
 			BlobHandle: restic.BlobHandle{
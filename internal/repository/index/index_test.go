@@ -8,6 +8,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/restic/restic/internal/crypto"
 	"github.com/restic/restic/internal/repository/index"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
@@ -415,6 +416,34 @@ func TestIndexPacks(t *testing.T) {
 	rtest.Assert(t, packs.Equals(idxPacks), "packs in index do not match packs added to index")
 }
 
+func TestIndexPackKey(t *testing.T) {
+	idx := index.NewIndex()
+	pub, _ := crypto.NewRandomKeyPair()
+	_, packKey, err := crypto.NewSealingKey(pub)
+	rtest.OK(t, err)
+
+	keyed := restic.Blob{BlobHandle: restic.NewRandomBlobHandle(), Length: 23}
+	plain := restic.Blob{BlobHandle: restic.NewRandomBlobHandle(), Length: 42}
+	idx.StorePackWithKey(restic.NewRandomID(), packKey, []restic.Blob{keyed})
+	idx.StorePack(restic.NewRandomID(), []restic.Blob{plain})
+
+	wr := bytes.NewBuffer(nil)
+	rtest.OK(t, idx.Encode(wr))
+	idx2ID := restic.NewRandomID()
+	idx2, err := index.DecodeIndex(wr.Bytes(), idx2ID)
+	rtest.OK(t, err)
+
+	for _, i := range []*index.Index{idx, idx2} {
+		pbs := i.Lookup(keyed.BlobHandle, nil)
+		rtest.Equals(t, 1, len(pbs))
+		rtest.Equals(t, packKey, pbs[0].PackKey)
+
+		pbs = i.Lookup(plain.BlobHandle, nil)
+		rtest.Equals(t, 1, len(pbs))
+		rtest.Assert(t, pbs[0].PackKey.IsNull(), "unexpected pack key %v", pbs[0].PackKey)
+	}
+}
+
 const maxPackSize = 16 * 1024 * 1024
 
 // This function generates a (insecure) random ID, similar to NewRandomID
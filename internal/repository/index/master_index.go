@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"sync"
 
+	"github.com/restic/restic/internal/crypto"
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/restic"
 	"github.com/restic/restic/internal/ui/progress"
@@ -152,13 +153,14 @@ func (mi *MasterIndex) Insert(idx *Index) {
 	mi.idx = append(mi.idx, idx)
 }
 
-// StorePack remembers the id and pack in the index.
-func (mi *MasterIndex) StorePack(ctx context.Context, id restic.ID, blobs []restic.Blob, r restic.SaverUnpacked[restic.FileType]) error {
-	mi.storePack(id, blobs)
+// StorePack remembers the id and pack in the index. packKey is only set for
+// packs of asymmetric repositories.
+func (mi *MasterIndex) StorePack(ctx context.Context, id restic.ID, packKey crypto.PublicKey, blobs []restic.Blob, r restic.SaverUnpacked[restic.FileType]) error {
+	mi.storePack(id, packKey, blobs)
 	return mi.saveFullIndex(ctx, r)
 }
 
-func (mi *MasterIndex) storePack(id restic.ID, blobs []restic.Blob) {
+func (mi *MasterIndex) storePack(id restic.ID, packKey crypto.PublicKey, blobs []restic.Blob) {
 	mi.idxMutex.Lock()
 	defer mi.idxMutex.Unlock()
 
@@ -169,13 +171,13 @@ func (mi *MasterIndex) storePack(id restic.ID, blobs []restic.Blob) {
 
 	for _, idx := range mi.idx {
 		if !idx.Final() {
-			idx.StorePack(id, blobs)
+			idx.StorePackWithKey(id, packKey, blobs)
 			return
 		}
 	}
 
 	newIdx := NewIndex()
-	newIdx.StorePack(id, blobs)
+	newIdx.StorePackWithKey(id, packKey, blobs)
 	mi.idx = append(mi.idx, newIdx)
 }
 
@@ -434,7 +436,7 @@ func (mi *MasterIndex) Rewrite(ctx context.Context, repo restic.Unpacked[restic.
 			obsolete.Merge(restic.NewIDSet(ids...))
 
 			for pbs := range task.idx.EachByPack(wgCtx, excludePacks) {
-				newIndex.StorePack(pbs.PackID, pbs.Blobs)
+				newIndex.StorePackWithKey(pbs.PackID, pbs.PackKey, pbs.Blobs)
 				if Full(newIndex) {
 					select {
 					case saveCh <- newIndex:
@@ -530,7 +532,7 @@ func (mi *MasterIndex) SaveFallback(ctx context.Context, repo restic.SaverRemove
 			}
 
 			for pbs := range idx.EachByPack(wgCtx, excludePacks) {
-				newIndex.StorePack(pbs.PackID, pbs.Blobs)
+				newIndex.StorePackWithKey(pbs.PackID, pbs.PackKey, pbs.Blobs)
 				p.Add(1)
 				if Full(newIndex) {
 					select {
@@ -612,6 +614,7 @@ func (mi *MasterIndex) ListPacks(ctx context.Context, packs restic.IDSet) <-chan
 		// only resort a part of the index to keep the memory overhead bounded
 		for i := byte(0); i < 16; i++ {
 			packBlob := make(map[restic.ID][]restic.Blob)
+			packKeys := make(map[restic.ID]crypto.PublicKey)
 			for pack := range packs {
 				if pack[0]&0xf == i {
 					packBlob[pack] = nil
@@ -623,6 +626,7 @@ func (mi *MasterIndex) ListPacks(ctx context.Context, packs restic.IDSet) <-chan
 			err := mi.Each(ctx, func(pb restic.PackedBlob) {
 				if packs.Has(pb.PackID) && pb.PackID[0]&0xf == i {
 					packBlob[pb.PackID] = append(packBlob[pb.PackID], pb.Blob)
+					packKeys[pb.PackID] = pb.PackKey
 				}
 			})
 			if err != nil {
@@ -634,7 +638,7 @@ func (mi *MasterIndex) ListPacks(ctx context.Context, packs restic.IDSet) <-chan
 				// allow GC
 				packBlob[packID] = nil
 				select {
-				case out <- restic.PackBlobs{PackID: packID, Blobs: pbs}:
+				case out <- restic.PackBlobs{PackID: packID, PackKey: packKeys[packID], Blobs: pbs}:
 				case <-ctx.Done():
 					return
 				}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+)
+
+// ErrAppendOnly is returned when removing files from an append-only repository
+// without using an admin key.
+var ErrAppendOnly = errors.New("repository is append-only, removing files requires an admin key")
+
+// JournalEntry records the removal of files from an append-only repository.
+type JournalEntry struct {
+	Time     time.Time     `json:"time"`
+	Hostname string        `json:"hostname,omitempty"`
+	Username string        `json:"username,omitempty"`
+	KeyID    restic.ID     `json:"key_id"`
+	Files    []RemovedFile `json:"files"`
+}
+
+// RemovedFile identifies a file removed from the repository.
+type RemovedFile struct {
+	Type string    `json:"type"`
+	ID   restic.ID `json:"id"`
+}
+
+// signedJournalEntry is the representation of a JournalEntry stored in the
+// repository. The signature is computed over the exact bytes of Entry.
+type signedJournalEntry struct {
+	Entry     json.RawMessage `json:"entry"`
+	Signature []byte          `json:"signature"`
+}
+
+// CheckRemove returns an error if files of type t cannot be removed using the
+// current key. Only locks can be removed without the admin capability. In
+// append-only repositories this requires an admin key, journal files cannot be
+// removed at all.
+func (r *Repository) CheckRemove(t restic.FileType) error {
+	if t == restic.LockFile {
+		return nil
+	}
+	if r.cfg.AppendOnly && t == restic.JournalFile {
+		return errors.New("journal files of an append-only repository cannot be removed")
+	}
+	if r.cfg.AppendOnly && r.adminKey == nil {
+		return ErrAppendOnly
+	}
+	return r.RequireCapability(CapabilityAdmin)
+}
+
+// JournalRemoval records the removal of the files ids of type t in the
+// deletion journal. Later removals of these files are not recorded again.
+// This is a no-op for repositories which are not append-only.
+func (r *Repository) JournalRemoval(ctx context.Context, t restic.FileType, ids restic.IDSet) error {
+	return r.journalRemoval(ctx, t, ids)
+}
+
+func (r *Repository) journalRemoval(ctx context.Context, t restic.FileType, ids restic.IDSet) error {
+	if err := r.CheckRemove(t); err != nil {
+		return err
+	}
+	if !r.cfg.AppendOnly || t == restic.LockFile {
+		return nil
+	}
+
+	var files []RemovedFile
+	r.journaled.Lock()
+	for _, id := range ids.List() {
+		if _, ok := r.journaled.M[backend.Handle{Type: t, Name: id.String()}]; !ok {
+			files = append(files, RemovedFile{Type: t.String(), ID: id})
+		}
+	}
+	r.journaled.Unlock()
+	if len(files) == 0 {
+		return nil
+	}
+
+	entry := JournalEntry{
+		Time:  time.Now(),
+		KeyID: r.keyID,
+		Files: files,
+	}
+	entry.Hostname, _ = os.Hostname()
+	if usr, err := user.Current(); err == nil {
+		entry.Username = usr.Username
+	}
+
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+	buf, err = json.Marshal(signedJournalEntry{
+		Entry:     buf,
+		Signature: ed25519.Sign(r.adminKey, buf),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	id, err := r.saveUnpacked(ctx, restic.JournalFile, buf)
+	if err != nil {
+		return errors.Wrap(err, "saving journal entry")
+	}
+	debug.Log("journaled removal of %d files of type %v in %v", len(files), t, id)
+
+	r.journaled.Lock()
+	if r.journaled.M == nil {
+		r.journaled.M = make(map[backend.Handle]struct{})
+	}
+	for _, f := range files {
+		r.journaled.M[backend.Handle{Type: t, Name: f.ID.String()}] = struct{}{}
+	}
+	r.journaled.Unlock()
+	return nil
+}
+
+// LoadJournalEntry loads the journal entry id and verifies its signature using
+// the admin public key stored in cfg.
+func LoadJournalEntry(ctx context.Context, repo restic.LoaderUnpacked, cfg restic.Config, id restic.ID) (*JournalEntry, error) {
+	buf, err := repo.LoadUnpacked(ctx, restic.JournalFile, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var signed signedJournalEntry
+	if err := json.Unmarshal(buf, &signed); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	pub, err := hex.DecodeString(cfg.AdminPublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("repository config contains no valid admin public key")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), signed.Entry, signed.Signature) {
+		return nil, errors.New("invalid signature")
+	}
+
+	entry := &JournalEntry{}
+	if err := json.Unmarshal(signed.Entry, entry); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return entry, nil
+}
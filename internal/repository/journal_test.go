@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testAppendOnlyRepository(t *testing.T) (*Repository, backend.Backend) {
+	TestUseLowSecurityKDFParameters(t)
+	restic.TestDisableCheckPolynomial(t)
+
+	be := TestBackend(t)
+	repo, err := New(be, Options{})
+	rtest.OK(t, err)
+	pol := testChunkerPol
+	rtest.OK(t, repo.Init(context.TODO(), restic.StableRepoVersion, rtest.TestPassword, InitOptions{ChunkerPolynomial: &pol, AppendOnly: true}))
+	return repo, be
+}
+
+func listJournal(t *testing.T, repo *Repository) restic.IDs {
+	var ids restic.IDs
+	rtest.OK(t, repo.List(context.TODO(), restic.JournalFile, func(id restic.ID, _ int64) error {
+		ids = append(ids, id)
+		return nil
+	}))
+	return ids
+}
+
+func TestAppendOnlyRemove(t *testing.T) {
+	repo, be := testAppendOnlyRepository(t)
+	rtest.Assert(t, repo.AdminKey() != nil, "expected admin key after init")
+
+	// open the repository using a regular key
+	key, err := AddKey(context.TODO(), repo, "user", repo.Key(), KeyOptions{})
+	rtest.OK(t, err)
+	rtest.Assert(t, !key.IsAdmin(), "expected regular key")
+	userRepo, err := New(be, Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, userRepo.SearchKey(context.TODO(), "user", 0, key.ID().String()))
+	rtest.Assert(t, userRepo.AdminKey() == nil, "unexpected admin key")
+
+	snID, err := userRepo.SaveUnpacked(context.TODO(), restic.WriteableSnapshotFile, []byte("{}"))
+	rtest.OK(t, err)
+	err = userRepo.RemoveUnpacked(context.TODO(), restic.WriteableSnapshotFile, snID)
+	rtest.Assert(t, err == ErrAppendOnly, "expected ErrAppendOnly, got %v", err)
+	rtest.Assert(t, RemoveKey(context.TODO(), userRepo, repo.KeyID()) == ErrAppendOnly, "expected ErrAppendOnly for key removal")
+
+	// locks can always be removed
+	lockID, err := (&internalRepository{userRepo}).SaveUnpacked(context.TODO(), restic.LockFile, []byte("{}"))
+	rtest.OK(t, err)
+	rtest.OK(t, (&internalRepository{userRepo}).RemoveUnpacked(context.TODO(), restic.LockFile, lockID))
+	rtest.Equals(t, 0, len(listJournal(t, repo)))
+
+	// the admin key records the removal in the journal
+	rtest.OK(t, repo.RemoveUnpacked(context.TODO(), restic.WriteableSnapshotFile, snID))
+	journal := listJournal(t, repo)
+	rtest.Equals(t, 1, len(journal))
+
+	entry, err := LoadJournalEntry(context.TODO(), repo, repo.Config(), journal[0])
+	rtest.OK(t, err)
+	rtest.Equals(t, repo.KeyID(), entry.KeyID)
+	rtest.Equals(t, []RemovedFile{{Type: "snapshot", ID: snID}}, entry.Files)
+
+	// journal files cannot be removed, not even by admin keys
+	err = (&internalRepository{repo}).RemoveUnpacked(context.TODO(), restic.JournalFile, journal[0])
+	rtest.Assert(t, err != nil, "expected error removing journal file")
+
+	// the signature does not verify using another admin key
+	cfg := repo.Config()
+	pub, _, err := ed25519.GenerateKey(nil)
+	rtest.OK(t, err)
+	cfg.AdminPublicKey = hex.EncodeToString(pub)
+	_, err = LoadJournalEntry(context.TODO(), repo, cfg, journal[0])
+	rtest.Assert(t, err != nil, "expected signature verification to fail")
+}
+
+func TestAppendOnlyAdminKey(t *testing.T) {
+	repo, be := testAppendOnlyRepository(t)
+
+	key, err := AddKey(context.TODO(), repo, "admin", repo.Key(), KeyOptions{Admin: repo.AdminKey()})
+	rtest.OK(t, err)
+	rtest.Assert(t, key.IsAdmin(), "expected admin key")
+
+	adminRepo, err := New(be, Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, adminRepo.SearchKey(context.TODO(), "admin", 0, key.ID().String()))
+	rtest.Equals(t, repo.AdminKey(), adminRepo.AdminKey())
+	rtest.OK(t, adminRepo.CheckRemove(restic.PackFile))
+}
@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/user"
+	"sync"
 	"time"
 
 	"github.com/restic/restic/internal/errors"
@@ -30,22 +32,51 @@ type Key struct {
 	Username string    `json:"username"`
 	Hostname string    `json:"hostname"`
 
-	KDF  string `json:"kdf"`
-	N    int    `json:"N"`
-	R    int    `json:"r"`
-	P    int    `json:"p"`
-	Salt []byte `json:"salt"`
-	Data []byte `json:"data"`
+	// Label is an optional description of the key.
+	Label string `json:"label,omitempty"`
+	// Expires is the time after which the key cannot be used to open the
+	// repository anymore. It is not set for keys which do not expire.
+	Expires *time.Time `json:"expires,omitempty"`
+	// Capabilities restricts the operations allowed using the key. Keys
+	// without capabilities have all capabilities.
+	Capabilities Capabilities `json:"capabilities,omitempty"`
+
+	KDF string `json:"kdf"`
+	N   int    `json:"N"`
+	R   int    `json:"r"`
+	P   int    `json:"p"`
+	// parameters for Argon2id, these are not set for scrypt
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+	Salt    []byte `json:"salt"`
+	Data    []byte `json:"data"`
+
+	// Admin contains the encrypted seed of the admin signing key, it is only
+	// set for admin keys of append-only repositories.
+	Admin []byte `json:"admin,omitempty"`
+
+	// WriteOnly is set for keys of asymmetric repositories which do not hold
+	// the private key, such that they cannot decrypt blobs and snapshots.
+	WriteOnly bool `json:"write_only,omitempty"`
+
+	// Rotating is set for keys which also hold the previous master key while
+	// the master key is rotated, see RotateMasterKey.
+	Rotating bool `json:"rotating,omitempty"`
 
 	user   *crypto.Key
 	master *crypto.Key
+	admin  ed25519.PrivateKey
 
 	id restic.ID
 }
 
-// params tracks the parameters used for the KDF. If not set, it will be
-// calibrated on the first run of AddKey().
-var params *crypto.Params
+// params tracks the parameters used for each KDF. If not set, they will be
+// calibrated on the first run of AddKey() using the KDF.
+var (
+	params      = make(map[string]crypto.Params)
+	paramsMutex sync.Mutex
+)
 
 const (
 	// KDFTimeout specifies the maximum runtime for the KDF.
@@ -55,10 +86,26 @@ const (
 	KDFMemory = 60
 )
 
-// createMasterKey creates a new master key in the given backend and encrypts
-// it with the password.
-func createMasterKey(ctx context.Context, s *Repository, password string) (*Key, error) {
-	return AddKey(ctx, s, password, "", "", nil)
+// KeyOptions collects the settings for a new key.
+type KeyOptions struct {
+	Username string
+	Hostname string
+	// KDF is the key derivation function, scrypt is used if empty.
+	KDF   string
+	Label string
+	// Expires is the time after which the key is refused, the key does not
+	// expire if it is zero.
+	Expires      time.Time
+	Capabilities Capabilities
+	// Admin is the admin signing key of an append-only repository. If it
+	// is not nil, the new key is an admin key.
+	Admin ed25519.PrivateKey
+}
+
+// createMasterKey stores the master key in the given backend and encrypts it
+// with the password. If admin is not nil, the key is an admin key.
+func createMasterKey(ctx context.Context, s *Repository, password string, kdf string, master *crypto.Key, admin ed25519.PrivateKey) (*Key, error) {
+	return AddKey(ctx, s, password, master, KeyOptions{KDF: kdf, Admin: admin})
 }
 
 // OpenKey tries do decrypt the key specified by name with the given password.
@@ -70,17 +117,12 @@ func OpenKey(ctx context.Context, s *Repository, id restic.ID, password string)
 	}
 
 	// check KDF
-	if k.KDF != "scrypt" {
-		return nil, errors.New("only supported KDF is scrypt()")
+	if k.KDF != crypto.KDFScrypt && k.KDF != crypto.KDFArgon2id {
+		return nil, errors.Errorf("unsupported KDF %q", k.KDF)
 	}
 
 	// derive user key
-	params := crypto.Params{
-		N: k.N,
-		R: k.R,
-		P: k.P,
-	}
-	k.user, err = crypto.KDF(params, k.Salt, password)
+	k.user, err = crypto.KDF(k.kdfParams(), k.Salt, password)
 	if err != nil {
 		return nil, errors.Wrap(err, "crypto.KDF")
 	}
@@ -101,6 +143,18 @@ func OpenKey(ctx context.Context, s *Repository, id restic.ID, password string)
 	}
 	k.id = id
 
+	if len(k.Admin) > 0 {
+		nonce, ciphertext := k.Admin[:k.user.NonceSize()], k.Admin[k.user.NonceSize():]
+		seed, err := k.user.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, errors.New("invalid admin key")
+		}
+		k.admin = ed25519.NewKeyFromSeed(seed)
+	}
+
 	if !k.Valid() {
 		return nil, errors.New("Invalid key for repository")
 	}
@@ -192,29 +246,71 @@ func LoadKey(ctx context.Context, s *Repository, id restic.ID) (k *Key, err erro
 	return k, nil
 }
 
-// AddKey adds a new key to an already existing repository.
-func AddKey(ctx context.Context, s *Repository, password, username, hostname string, template *crypto.Key) (*Key, error) {
-	// make sure we have valid KDF parameters
-	if params == nil {
-		p, err := crypto.Calibrate(KDFTimeout, KDFMemory)
-		if err != nil {
-			return nil, errors.Wrap(err, "Calibrate")
-		}
+// kdfParams returns the parameters of the KDF used by the key.
+func (k *Key) kdfParams() crypto.Params {
+	return crypto.Params{
+		KDF:     k.KDF,
+		N:       k.N,
+		R:       k.R,
+		P:       k.P,
+		Time:    k.Time,
+		Memory:  k.Memory,
+		Threads: k.Threads,
+	}
+}
+
+// calibratedParams returns the parameters for kdf, which are calibrated on
+// first use.
+func calibratedParams(kdf string) (crypto.Params, error) {
+	paramsMutex.Lock()
+	defer paramsMutex.Unlock()
 
-		params = &p
-		debug.Log("calibrated KDF parameters are %v", p)
+	if p, ok := params[kdf]; ok {
+		return p, nil
+	}
+	p, err := crypto.Calibrate(kdf, KDFTimeout, KDFMemory)
+	if err != nil {
+		return crypto.Params{}, errors.Wrap(err, "Calibrate")
+	}
+	params[kdf] = p
+	debug.Log("calibrated KDF parameters are %v", p)
+	return p, nil
+}
+
+// AddKey adds a new key to an already existing repository. The user key is
+// derived from the password using the KDF from opts. If template is nil, a
+// new random master key is generated.
+func AddKey(ctx context.Context, s *Repository, password string, template *crypto.Key, opts KeyOptions) (*Key, error) {
+	kdf := opts.KDF
+	if kdf == "" {
+		kdf = crypto.KDFScrypt
+	}
+
+	// make sure we have valid KDF parameters
+	params, err := calibratedParams(kdf)
+	if err != nil {
+		return nil, err
 	}
 
 	// fill meta data about key
 	newkey := &Key{
-		Created:  time.Now(),
-		Username: username,
-		Hostname: hostname,
+		Created:      time.Now(),
+		Username:     opts.Username,
+		Hostname:     opts.Hostname,
+		Label:        opts.Label,
+		Capabilities: opts.Capabilities,
+
+		KDF:     kdf,
+		N:       params.N,
+		R:       params.R,
+		P:       params.P,
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: params.Threads,
+	}
 
-		KDF: "scrypt",
-		N:   params.N,
-		R:   params.R,
-		P:   params.P,
+	if !opts.Expires.IsZero() {
+		newkey.Expires = &opts.Expires
 	}
 
 	if newkey.Hostname == "" {
@@ -229,14 +325,13 @@ func AddKey(ctx context.Context, s *Repository, password, username, hostname str
 	}
 
 	// generate random salt
-	var err error
 	newkey.Salt, err = crypto.NewSalt()
 	if err != nil {
 		panic("unable to read enough random bytes for salt: " + err.Error())
 	}
 
 	// call KDF to derive user key
-	newkey.user, err = crypto.KDF(*params, newkey.Salt, password)
+	newkey.user, err = crypto.KDF(params, newkey.Salt, password)
 	if err != nil {
 		return nil, err
 	}
@@ -248,6 +343,8 @@ func AddKey(ctx context.Context, s *Repository, password, username, hostname str
 		// copy master keys from old key
 		newkey.master = template
 	}
+	newkey.WriteOnly = s.Config().DataPublicKey != "" && len(newkey.master.DecryptionKey) == 0
+	newkey.Rotating = newkey.master.Previous != nil
 
 	// encrypt master keys (as json) with user key
 	buf, err := json.Marshal(newkey.master)
@@ -261,6 +358,18 @@ func AddKey(ctx context.Context, s *Repository, password, username, hostname str
 	ciphertext = newkey.user.Seal(ciphertext, nonce, buf, nil)
 	newkey.Data = ciphertext
 
+	if opts.Admin != nil {
+		nonce := crypto.NewRandomNonce()
+		ciphertext := make([]byte, 0, crypto.CiphertextLength(ed25519.SeedSize))
+		ciphertext = append(ciphertext, nonce...)
+		newkey.Admin = newkey.user.Seal(ciphertext, nonce, opts.Admin.Seed(), nil)
+		newkey.admin = opts.Admin
+	}
+
+	if len(newkey.EffectiveCapabilities(s.Config())) == 0 {
+		return nil, errors.Errorf("a key with capabilities %v cannot be used in this repository", newkey.Capabilities)
+	}
+
 	// dump as json
 	buf, err = json.Marshal(newkey)
 	if err != nil {
@@ -289,8 +398,7 @@ func RemoveKey(ctx context.Context, repo *Repository, id restic.ID) error {
 		return errors.New("refusing to remove key currently used to access repository")
 	}
 
-	h := backend.Handle{Type: restic.KeyFile, Name: id.String()}
-	return repo.be.Remove(ctx, h)
+	return (&internalRepository{repo}).RemoveUnpacked(ctx, restic.KeyFile, id)
 }
 
 func (k *Key) String() string {
@@ -305,6 +413,16 @@ func (k Key) ID() restic.ID {
 	return k.id
 }
 
+// Expired returns true if the key cannot be used anymore at time now.
+func (k *Key) Expired(now time.Time) bool {
+	return k.Expires != nil && !now.Before(*k.Expires)
+}
+
+// IsAdmin returns true if the key holds the admin signing key.
+func (k *Key) IsAdmin() bool {
+	return len(k.Admin) > 0
+}
+
 // Valid tests whether the mac and encryption keys are valid (i.e. not zero)
 func (k *Key) Valid() bool {
 	return k.user.Valid() && k.master.Valid()
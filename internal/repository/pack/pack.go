@@ -19,9 +19,10 @@ import (
 type Packer struct {
 	blobs []restic.Blob
 
-	bytes uint
-	k     *crypto.Key
-	wr    io.Writer
+	bytes   uint
+	k       *crypto.Key
+	packKey crypto.PublicKey
+	wr      io.Writer
 
 	m sync.Mutex
 }
@@ -31,6 +32,13 @@ func NewPacker(k *crypto.Key, wr io.Writer) *Packer {
 	return &Packer{k: k, wr: wr}
 }
 
+// NewPackerWithKey returns a new Packer for an asymmetric repository. The
+// public key packKey is stored in the pack header, it is used to derive the
+// key the blobs are encrypted with.
+func NewPackerWithKey(k *crypto.Key, packKey crypto.PublicKey, wr io.Writer) *Packer {
+	return &Packer{k: k, packKey: packKey, wr: wr}
+}
+
 // Add saves the data read from rd as a new blob to the packer. Returned is the
 // number of bytes written to the pack plus the pack header entry size.
 func (p *Packer) Add(t restic.BlobType, id restic.ID, data []byte, uncompressedLength int) (int, error) {
@@ -52,6 +60,7 @@ func (p *Packer) Add(t restic.BlobType, id restic.ID, data []byte, uncompressedL
 
 var entrySize = uint(binary.Size(restic.BlobType(0)) + 2*headerLengthSize + len(restic.ID{}))
 var plainEntrySize = uint(binary.Size(restic.BlobType(0)) + headerLengthSize + len(restic.ID{}))
+var keyEntrySize = uint(binary.Size(restic.BlobType(0)) + crypto.PublicKeySize)
 
 // headerEntry describes the format of header entries. It serves only as
 // documentation.
@@ -70,12 +79,22 @@ type compressedHeaderEntry struct {
 	ID                 restic.ID
 }
 
+// keyHeaderEntry describes the format of the header entry which contains the
+// pack key. It is the first entry of packs in asymmetric repositories. It
+// serves only as documentation.
+type keyHeaderEntry struct {
+	Type    uint8
+	PackKey crypto.PublicKey
+}
+
+const keyEntryType = 4
+
 // Finalize writes the header for all added blobs and finalizes the pack.
 func (p *Packer) Finalize() error {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	header, err := makeHeader(p.blobs)
+	header, err := makeHeader(p.packKey, p.blobs)
 	if err != nil {
 		return err
 	}
@@ -86,7 +105,7 @@ func (p *Packer) Finalize() error {
 	encryptedHeader = p.k.Seal(encryptedHeader, nonce, header, nil)
 	encryptedHeader = binary.LittleEndian.AppendUint32(encryptedHeader, uint32(len(encryptedHeader)))
 
-	if err := verifyHeader(p.k, encryptedHeader, p.blobs); err != nil {
+	if err := verifyHeader(p.k, encryptedHeader, p.packKey, p.blobs); err != nil {
 		//nolint:revive // ignore linter warnings about error message spelling
 		return fmt.Errorf("Detected data corruption while writing pack-file header: %w\nCorrupted data is either caused by hardware issues or software bugs. Please open an issue at https://github.com/restic/restic/issues/new/choose for further troubleshooting.", err)
 	}
@@ -105,14 +124,17 @@ func (p *Packer) Finalize() error {
 	return nil
 }
 
-func verifyHeader(k *crypto.Key, header []byte, expected []restic.Blob) error {
+func verifyHeader(k *crypto.Key, header []byte, packKey crypto.PublicKey, expected []restic.Blob) error {
 	// do not offer a way to skip the pack header verification, as pack headers are usually small enough
 	// to not result in a significant performance impact
 
-	decoded, hdrSize, err := List(k, bytes.NewReader(header), int64(len(header)))
+	decoded, decodedKey, hdrSize, err := ListWithKey(k, bytes.NewReader(header), int64(len(header)))
 	if err != nil {
 		return fmt.Errorf("header decoding failed: %w", err)
 	}
+	if decodedKey != packKey {
+		return fmt.Errorf("pack key mismatch")
+	}
 	if hdrSize != uint32(len(header)) {
 		return fmt.Errorf("unexpected header size %v instead of %v", hdrSize, len(header))
 	}
@@ -129,12 +151,17 @@ func verifyHeader(k *crypto.Key, header []byte, expected []restic.Blob) error {
 
 // HeaderOverhead returns an estimate of the number of bytes written by a call to Finalize.
 func (p *Packer) HeaderOverhead() int {
-	return crypto.CiphertextLength(0) + binary.Size(uint32(0))
+	return CalculateHeaderSize(nil, p.packKey)
 }
 
 // makeHeader constructs the header for p.
-func makeHeader(blobs []restic.Blob) ([]byte, error) {
-	buf := make([]byte, 0, len(blobs)*int(entrySize))
+func makeHeader(packKey crypto.PublicKey, blobs []restic.Blob) ([]byte, error) {
+	buf := make([]byte, 0, int(keyEntrySize)+len(blobs)*int(entrySize))
+
+	if !packKey.IsNull() {
+		buf = append(buf, keyEntryType)
+		buf = append(buf, packKey[:]...)
+	}
 
 	for _, b := range blobs {
 		switch {
@@ -169,6 +196,10 @@ func (p *Packer) Merge(other *Packer, otherData io.Reader) error {
 	other.m.Lock()
 	defer other.m.Unlock()
 
+	if other.packKey != p.packKey {
+		return errors.New("cannot merge packers with different pack keys")
+	}
+
 	for _, blob := range other.blobs {
 		data := make([]byte, blob.Length)
 		_, err := io.ReadFull(otherData, data)
@@ -204,7 +235,13 @@ func (p *Packer) Count() int {
 func (p *Packer) HeaderFull() bool {
 	p.m.Lock()
 	defer p.m.Unlock()
-	return headerSize+uint(len(p.blobs)+1)*entrySize > MaxHeaderSize
+	return uint(CalculateHeaderSize(nil, p.packKey))+uint(len(p.blobs)+1)*entrySize > MaxHeaderSize
+}
+
+// PackKey returns the pack key stored in the header, it is null for packs of
+// repositories which do not use asymmetric encryption.
+func (p *Packer) PackKey() crypto.PublicKey {
+	return p.packKey
 }
 
 // Blobs returns the slice of blobs that have been written.
@@ -325,13 +362,20 @@ func (e InvalidFileError) Error() string {
 // List returns the list of entries found in a pack file and the length of the
 // header (including header size and crypto overhead)
 func List(k *crypto.Key, rd io.ReaderAt, size int64) (entries []restic.Blob, hdrSize uint32, err error) {
+	entries, _, hdrSize, err = ListWithKey(k, rd, size)
+	return entries, hdrSize, err
+}
+
+// ListWithKey is like List, but additionally returns the pack key. It is null
+// for packs of repositories which do not use asymmetric encryption.
+func ListWithKey(k *crypto.Key, rd io.ReaderAt, size int64) (entries []restic.Blob, packKey crypto.PublicKey, hdrSize uint32, err error) {
 	buf, err := readHeader(rd, size)
 	if err != nil {
-		return nil, 0, err
+		return nil, packKey, 0, err
 	}
 
 	if len(buf) < crypto.CiphertextLength(0) {
-		return nil, 0, errors.New("invalid header, too short")
+		return nil, packKey, 0, errors.New("invalid header, too short")
 	}
 
 	hdrSize = headerLengthSize + uint32(len(buf))
@@ -339,7 +383,16 @@ func List(k *crypto.Key, rd io.ReaderAt, size int64) (entries []restic.Blob, hdr
 	nonce, buf := buf[:k.NonceSize()], buf[k.NonceSize():]
 	buf, err = k.Open(buf[:0], nonce, buf, nil)
 	if err != nil {
-		return nil, 0, err
+		return nil, packKey, 0, err
+	}
+
+	// the pack key is always stored in the first entry
+	if len(buf) > 0 && buf[0] == keyEntryType {
+		if uint(len(buf)) < keyEntrySize {
+			return nil, packKey, 0, errors.Errorf("invalid pack key entry of size %d", len(buf))
+		}
+		copy(packKey[:], buf[1:keyEntrySize])
+		buf = buf[keyEntrySize:]
 	}
 
 	// might over allocate a bit if all blobs have EntrySize but only by a few percent
@@ -349,7 +402,7 @@ func List(k *crypto.Key, rd io.ReaderAt, size int64) (entries []restic.Blob, hdr
 	for len(buf) > 0 {
 		entry, headerSize, err := parseHeaderEntry(buf)
 		if err != nil {
-			return nil, 0, err
+			return nil, packKey, 0, err
 		}
 		entry.Offset = pos
 
@@ -358,7 +411,7 @@ func List(k *crypto.Key, rd io.ReaderAt, size int64) (entries []restic.Blob, hdr
 		buf = buf[headerSize:]
 	}
 
-	return entries, hdrSize, nil
+	return entries, packKey, hdrSize, nil
 }
 
 func parseHeaderEntry(p []byte) (b restic.Blob, size uint, err error) {
@@ -403,8 +456,14 @@ func CalculateEntrySize(blob restic.Blob) int {
 	return int(plainEntrySize)
 }
 
-func CalculateHeaderSize(blobs []restic.Blob) int {
+// CalculateHeaderSize returns the size of the header of a pack containing
+// blobs. packKey is null for packs of repositories which do not use
+// asymmetric encryption.
+func CalculateHeaderSize(blobs []restic.Blob, packKey crypto.PublicKey) int {
 	size := headerSize
+	if !packKey.IsNull() {
+		size += int(keyEntrySize)
+	}
 	for _, blob := range blobs {
 		size += CalculateEntrySize(blob)
 	}
@@ -421,7 +480,7 @@ func Size(ctx context.Context, mi restic.ListBlobser, onlyHdr bool) (map[restic.
 	err := mi.ListBlobs(ctx, func(blob restic.PackedBlob) {
 		size, ok := packSize[blob.PackID]
 		if !ok {
-			size = headerSize
+			size = int64(CalculateHeaderSize(nil, blob.PackKey))
 		}
 		if !onlyHdr {
 			size += int64(blob.Length)
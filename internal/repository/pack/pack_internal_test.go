@@ -207,7 +207,7 @@ func TestUnpackedVerification(t *testing.T) {
 		{damageCiphertext, "ciphertext verification failed"},
 		{damageLength, "header decoding failed"},
 	} {
-		header, err := makeHeader(blobs)
+		header, err := makeHeader(crypto.PublicKey{}, blobs)
 		rtest.OK(t, err)
 
 		if test.damage == damageData {
@@ -227,7 +227,7 @@ func TestUnpackedVerification(t *testing.T) {
 			encryptedHeader[len(encryptedHeader)-1] ^= 0x42
 		}
 
-		err = verifyHeader(k, encryptedHeader, blobs)
+		err = verifyHeader(k, encryptedHeader, crypto.PublicKey{}, blobs)
 		if test.msg == "" {
 			rtest.Assert(t, err == nil, "expected no error, got %v", err)
 		} else {
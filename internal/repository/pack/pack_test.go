@@ -25,11 +25,15 @@ type Buf struct {
 }
 
 func newPack(t testing.TB, k *crypto.Key, lengths []int) ([]Buf, []byte, uint) {
+	return newPackWithKey(t, k, crypto.PublicKey{}, lengths)
+}
+
+func newPackWithKey(t testing.TB, k *crypto.Key, packKey crypto.PublicKey, lengths []int) ([]Buf, []byte, uint) {
 	bufs := createBuffers(t, lengths)
 
 	// pack blobs
 	var buf bytes.Buffer
-	p := pack.NewPacker(k, &buf)
+	p := pack.NewPackerWithKey(k, packKey, &buf)
 	for _, b := range bufs {
 		_, err := p.Add(restic.TreeBlob, b.id, b.data, 2*len(b.data))
 		rtest.OK(t, err)
@@ -54,18 +58,23 @@ func createBuffers(t testing.TB, lengths []int) []Buf {
 }
 
 func verifyBlobs(t testing.TB, bufs []Buf, k *crypto.Key, rd io.ReaderAt, packSize uint) {
+	verifyBlobsWithKey(t, bufs, k, crypto.PublicKey{}, rd, packSize)
+}
+
+func verifyBlobsWithKey(t testing.TB, bufs []Buf, k *crypto.Key, packKey crypto.PublicKey, rd io.ReaderAt, packSize uint) {
 	written := 0
 	for _, buf := range bufs {
 		written += len(buf.data)
 	}
 
 	// read and parse it again
-	entries, hdrSize, err := pack.List(k, rd, int64(packSize))
+	entries, listedKey, hdrSize, err := pack.ListWithKey(k, rd, int64(packSize))
 	rtest.OK(t, err)
 	rtest.Equals(t, len(entries), len(bufs))
+	rtest.Equals(t, packKey, listedKey)
 
 	// check the head size calculation for consistency
-	headerSize := pack.CalculateHeaderSize(entries)
+	headerSize := pack.CalculateHeaderSize(entries, packKey)
 	written += headerSize
 
 	// check length
@@ -99,6 +108,22 @@ func TestCreatePack(t *testing.T) {
 	verifyBlobs(t, bufs, k, bytes.NewReader(packData), packSize)
 }
 
+func TestCreatePackWithKey(t *testing.T) {
+	k := crypto.NewRandomKey()
+	pub, _ := crypto.NewRandomKeyPair()
+	_, packKey, err := crypto.NewSealingKey(pub)
+	rtest.OK(t, err)
+
+	bufs, packData, packSize := newPackWithKey(t, k, packKey, testLens)
+	rtest.Equals(t, uint(len(packData)), packSize)
+	verifyBlobsWithKey(t, bufs, k, packKey, bytes.NewReader(packData), packSize)
+
+	// the pack key is not returned as a blob
+	entries, _, err := pack.List(k, bytes.NewReader(packData), int64(packSize))
+	rtest.OK(t, err)
+	rtest.Equals(t, len(testLens), len(entries))
+}
+
 var blobTypeJSON = []struct {
 	t   restic.BlobType
 	res string
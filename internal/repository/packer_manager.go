@@ -32,6 +32,7 @@ type packer struct {
 type packerManager struct {
 	tpe     restic.BlobType
 	key     *crypto.Key
+	packKey crypto.PublicKey
 	queueFn func(ctx context.Context, t restic.BlobType, p *packer) error
 
 	pm       sync.Mutex
@@ -42,11 +43,13 @@ type packerManager struct {
 const defaultPackerCount = 2
 
 // newPackerManager returns a new packer manager which writes temporary files
-// to a temporary directory
-func newPackerManager(key *crypto.Key, tpe restic.BlobType, packSize uint, packerCount int, queueFn func(ctx context.Context, t restic.BlobType, p *packer) error) *packerManager {
+// to a temporary directory. packKey is stored in the header of new packs of
+// asymmetric repositories.
+func newPackerManager(key *crypto.Key, packKey crypto.PublicKey, tpe restic.BlobType, packSize uint, packerCount int, queueFn func(ctx context.Context, t restic.BlobType, p *packer) error) *packerManager {
 	return &packerManager{
 		tpe:      tpe,
 		key:      key,
+		packKey:  packKey,
 		queueFn:  queueFn,
 		packers:  make([]*packer, packerCount),
 		packSize: packSize,
@@ -207,7 +210,12 @@ func (r *packerManager) newPacker() (pck *packer, err error) {
 	}
 
 	bufWr := bufio.NewWriter(tmpfile)
-	p := pack.NewPacker(r.key, bufWr)
+	var p *pack.Packer
+	if r.packKey.IsNull() {
+		p = pack.NewPacker(r.key, bufWr)
+	} else {
+		p = pack.NewPackerWithKey(r.key, r.packKey, bufWr)
+	}
 	pck = &packer{
 		Packer:  p,
 		tmpfile: tmpfile,
@@ -274,5 +282,5 @@ func (r *Repository) savePacker(ctx context.Context, t restic.BlobType, p *packe
 
 	// update blobs in the index
 	debug.Log("  updating blobs %v to pack %v", p.Packer.Blobs(), id)
-	return r.idx.StorePack(ctx, id, p.Packer.Blobs(), &internalRepository{r})
+	return r.idx.StorePack(ctx, id, p.Packer.PackKey(), p.Packer.Blobs(), &internalRepository{r})
 }
@@ -33,32 +33,32 @@ type PruneOptions struct {
 
 type PruneStats struct {
 	Blobs struct {
-		Used      uint
-		Duplicate uint
-		Unused    uint
-		Remove    uint
-		Repack    uint
-		Repackrm  uint
-	}
+		Used      uint `json:"used"`
+		Duplicate uint `json:"duplicate"`
+		Unused    uint `json:"unused"`
+		Remove    uint `json:"remove"`
+		Repack    uint `json:"repack"`
+		Repackrm  uint `json:"repack_removed"`
+	} `json:"blobs"`
 	Size struct {
-		Used         uint64
-		Duplicate    uint64
-		Unused       uint64
-		Remove       uint64
-		Repack       uint64
-		Repackrm     uint64
-		Unref        uint64
-		Uncompressed uint64
-	}
+		Used         uint64 `json:"used"`
+		Duplicate    uint64 `json:"duplicate"`
+		Unused       uint64 `json:"unused"`
+		Remove       uint64 `json:"remove"`
+		Repack       uint64 `json:"repack"`
+		Repackrm     uint64 `json:"repack_removed"`
+		Unref        uint64 `json:"unreferenced"`
+		Uncompressed uint64 `json:"uncompressed"`
+	} `json:"size"`
 	Packs struct {
-		Used       uint
-		Unused     uint
-		PartlyUsed uint
-		Unref      uint
-		Keep       uint
-		Repack     uint
-		Remove     uint
-	}
+		Used       uint `json:"used"`
+		Unused     uint `json:"unused"`
+		PartlyUsed uint `json:"partly_used"`
+		Unref      uint `json:"unreferenced"`
+		Keep       uint `json:"keep"`
+		Repack     uint `json:"repack"`
+		Remove     uint `json:"remove"`
+	} `json:"packs"`
 }
 
 type PrunePlan struct {
@@ -108,6 +108,9 @@ func PlanPrune(ctx context.Context, opts PruneOptions, repo *Repository, getUsed
 	if opts.SmallPackBytes > uint64(repo.packSize()) {
 		return nil, fmt.Errorf("repack-smaller-than exceeds repository packsize")
 	}
+	if err := repo.CheckRemove(restic.PackFile); err != nil {
+		return nil, err
+	}
 
 	usedBlobs := index.NewAssociatedSet[uint8](repo.idx)
 	err := getUsedBlobs(ctx, repo, usedBlobs)
@@ -552,7 +555,7 @@ func (plan *PrunePlan) Execute(ctx context.Context, printer progress.Printer) er
 	// unreferenced packs can be safely deleted first
 	if len(plan.removePacksFirst) != 0 {
 		printer.P("deleting unreferenced packs\n")
-		_ = deleteFiles(ctx, true, &internalRepository{repo}, plan.removePacksFirst, restic.PackFile, printer)
+		_ = deleteFiles(ctx, true, repo, plan.removePacksFirst, restic.PackFile, printer)
 		// forget unused data
 		plan.removePacksFirst = nil
 	}
@@ -596,7 +599,7 @@ func (plan *PrunePlan) Execute(ctx context.Context, printer progress.Printer) er
 	if plan.opts.UnsafeRecovery {
 		printer.P("deleting index files\n")
 		indexFiles := repo.idx.IDs()
-		err := deleteFiles(ctx, false, &internalRepository{repo}, indexFiles, restic.IndexFile, printer)
+		err := deleteFiles(ctx, false, repo, indexFiles, restic.IndexFile, printer)
 		if err != nil {
 			return errors.Fatalf("%s", err)
 		}
@@ -609,7 +612,7 @@ func (plan *PrunePlan) Execute(ctx context.Context, printer progress.Printer) er
 
 	if len(plan.removePacks) != 0 {
 		printer.P("removing %d old packs\n", len(plan.removePacks))
-		_ = deleteFiles(ctx, true, &internalRepository{repo}, plan.removePacks, restic.PackFile, printer)
+		_ = deleteFiles(ctx, true, repo, plan.removePacks, restic.PackFile, printer)
 	}
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -631,11 +634,17 @@ func (plan *PrunePlan) Execute(ctx context.Context, printer progress.Printer) er
 
 // deleteFiles deletes the given fileList of fileType in parallel
 // if ignoreError=true, it will print a warning if there was an error, else it will abort.
-func deleteFiles(ctx context.Context, ignoreError bool, repo restic.RemoverUnpacked[restic.FileType], fileList restic.IDSet, fileType restic.FileType, printer progress.Printer) error {
+// For append-only repositories, the removal is first recorded as a single journal entry.
+func deleteFiles(ctx context.Context, ignoreError bool, repo *Repository, fileList restic.IDSet, fileType restic.FileType, printer progress.Printer) error {
+	if err := repo.journalRemoval(ctx, fileType, fileList); err != nil {
+		printer.E("unable to record removal in the deletion journal: %v\n", err)
+		return err
+	}
+
 	bar := printer.NewCounter("files deleted")
 	defer bar.Done()
 
-	return restic.ParallelRemove(ctx, repo, fileList, fileType, func(id restic.ID, err error) error {
+	return restic.ParallelRemove(ctx, &internalRepository{repo}, fileList, fileType, func(id restic.ID, err error) error {
 		if err != nil {
 			printer.E("unable to remove %v/%v from the repository\n", fileType, id)
 			if !ignoreError {
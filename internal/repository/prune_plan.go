@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/repository/index"
+	"github.com/restic/restic/internal/restic"
+)
+
+// savedPrunePlanVersion is the version of the SavedPrunePlan format.
+const savedPrunePlanVersion = 1
+
+// SavedPrunePlan is the serializable form of a PrunePlan. It allows reviewing
+// a plan before it is executed. The index files and snapshots the plan is based
+// on are recorded to detect changes of the repository in the meantime.
+type SavedPrunePlan struct {
+	Version      int       `json:"version"`
+	Created      time.Time `json:"created"`
+	RepositoryID string    `json:"repository_id"`
+
+	Indexes   restic.IDs `json:"indexes"`
+	Snapshots restic.IDs `json:"snapshots"`
+
+	KeepPacks   restic.IDs `json:"keep_packs"`
+	RepackPacks restic.IDs `json:"repack_packs"`
+	RemovePacks restic.IDs `json:"remove_packs"`
+	// UnrefPacks are not referenced by the index and are removed first
+	UnrefPacks restic.IDs `json:"unreferenced_packs"`
+	// IgnorePacks are contained in the index, but missing in the repository
+	IgnorePacks restic.IDs         `json:"ignore_packs"`
+	KeepBlobs   restic.BlobHandles `json:"keep_blobs"`
+
+	Stats PruneStats `json:"stats"`
+}
+
+// Save returns the serializable form of the plan. snapshots must contain the
+// snapshots whose blobs were marked as used while planning.
+func (plan *PrunePlan) Save(snapshots restic.IDSet) (*SavedPrunePlan, error) {
+	if plan.repo == nil {
+		return nil, errors.New("prune plan was already executed")
+	}
+	if plan.opts.UnsafeRecovery {
+		return nil, errors.New("plans to recover a repository without free space cannot be saved")
+	}
+
+	repo := plan.repo
+	changed := restic.NewIDSet()
+	changed.Merge(plan.repackPacks)
+	changed.Merge(plan.removePacks)
+	changed.Merge(plan.ignorePacks)
+
+	saved := &SavedPrunePlan{
+		Version:      savedPrunePlanVersion,
+		Created:      time.Now(),
+		RepositoryID: repo.Config().ID,
+		Indexes:      repo.idx.IDs().List(),
+		Snapshots:    snapshots.List(),
+		KeepPacks:    repo.idx.Packs(changed).List(),
+		RepackPacks:  plan.repackPacks.List(),
+		RemovePacks:  plan.removePacks.List(),
+		UnrefPacks:   plan.removePacksFirst.List(),
+		IgnorePacks:  plan.ignorePacks.List(),
+		KeepBlobs:    restic.BlobHandles{},
+		Stats:        plan.stats,
+	}
+	if plan.keepBlobs != nil {
+		saved.KeepBlobs = plan.keepBlobs.List()
+	}
+	return saved, nil
+}
+
+// LoadPrunePlan restores a saved plan for repo, whose index must already be
+// loaded. It fails if the repository has changed in a way which could cause
+// the plan to remove data that is still in use. This is the case if any index
+// file was added or removed, or if snapshots were added since the plan was
+// created. In addition, the blobs which are in use are determined again using
+// getUsedBlobs, and the plan is rejected if it would remove any of them.
+// snapshots must contain the snapshots whose blobs getUsedBlobs marked as used.
+// Only opts.DryRun is used from opts.
+func LoadPrunePlan(ctx context.Context, opts PruneOptions, repo *Repository, saved *SavedPrunePlan, getUsedBlobs func(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet) error, snapshots restic.IDSet) (*PrunePlan, error) {
+	if saved.Version != savedPrunePlanVersion {
+		return nil, errors.Errorf("unsupported prune plan version %d", saved.Version)
+	}
+	if saved.RepositoryID != repo.Config().ID {
+		return nil, errors.New("prune plan was created for a different repository")
+	}
+	if repo.Connections() < 2 {
+		return nil, fmt.Errorf("prune requires a backend connection limit of at least two")
+	}
+	if err := repo.CheckRemove(restic.PackFile); err != nil {
+		return nil, err
+	}
+
+	if !repo.idx.IDs().Equals(restic.NewIDSet(saved.Indexes...)) {
+		return nil, errors.New("the index has changed since the prune plan was created")
+	}
+
+	packs := repo.idx.Packs(restic.NewIDSet())
+	for _, list := range []restic.IDs{saved.KeepPacks, saved.RepackPacks, saved.RemovePacks, saved.IgnorePacks} {
+		for _, id := range list {
+			if !packs.Has(id) {
+				return nil, errors.Errorf("pack %v of the prune plan is not contained in the index", id.Str())
+			}
+		}
+	}
+
+	plan := &PrunePlan{
+		removePacksFirst: restic.NewIDSet(saved.UnrefPacks...),
+		repackPacks:      restic.NewIDSet(saved.RepackPacks...),
+		removePacks:      restic.NewIDSet(saved.RemovePacks...),
+		ignorePacks:      restic.NewIDSet(saved.IgnorePacks...),
+
+		repo:  repo,
+		stats: saved.Stats,
+		opts:  PruneOptions{DryRun: opts.DryRun},
+	}
+	if len(plan.repackPacks) != 0 {
+		plan.keepBlobs = index.NewAssociatedSet[uint8](repo.idx)
+		for _, bh := range saved.KeepBlobs {
+			if !repo.idx.Has(bh) {
+				return nil, errors.Errorf("blob %v of the prune plan is not contained in the index", bh)
+			}
+			plan.keepBlobs.Insert(bh)
+		}
+	}
+
+	usedBlobs := index.NewAssociatedSet[uint8](repo.idx)
+	if err := getUsedBlobs(ctx, repo, usedBlobs); err != nil {
+		return nil, err
+	}
+	planned := restic.NewIDSet(saved.Snapshots...)
+	for id := range snapshots {
+		if !planned.Has(id) {
+			return nil, errors.Errorf("snapshot %v was created after the prune plan", id.Str())
+		}
+	}
+	if err := plan.checkUsedBlobs(ctx, usedBlobs); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// checkUsedBlobs returns an error if executing the plan would remove the last
+// copy of a blob contained in usedBlobs.
+func (plan *PrunePlan) checkUsedBlobs(ctx context.Context, usedBlobs *index.AssociatedSet[uint8]) error {
+	remaining := index.NewAssociatedSet[uint8](plan.repo.idx)
+	err := plan.repo.idx.Each(ctx, func(pb restic.PackedBlob) {
+		if !usedBlobs.Has(pb.BlobHandle) {
+			return
+		}
+		switch {
+		case plan.repackPacks.Has(pb.PackID):
+			if plan.keepBlobs != nil && plan.keepBlobs.Has(pb.BlobHandle) {
+				remaining.Insert(pb.BlobHandle)
+			}
+		case plan.removePacks.Has(pb.PackID), plan.ignorePacks.Has(pb.PackID):
+		default:
+			remaining.Insert(pb.BlobHandle)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	var missing restic.BlobHandles
+	usedBlobs.For(func(bh restic.BlobHandle, _ uint8) {
+		if !remaining.Has(bh) {
+			missing = append(missing, bh)
+		}
+	})
+	if len(missing) > 0 {
+		return errors.Errorf("the prune plan would remove %d blobs which are still in use, for example %v", len(missing), missing[0])
+	}
+	return nil
+}
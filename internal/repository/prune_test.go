@@ -2,9 +2,11 @@ package repository_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -115,6 +117,73 @@ func TestPrune(t *testing.T) {
 	}
 }
 
+func TestPrunePlanSaveLoad(t *testing.T) {
+	random := rand.New(rand.NewSource(23))
+	repo, _, be := repository.TestRepositoryWithVersion(t, 0)
+	createRandomBlobs(t, random, repo, 10, 0.5, true)
+	keep, unused := selectBlobs(t, random, repo, 0.5)
+	usedBlobs := func(ctx context.Context, repo restic.Repository, usedBlobs restic.FindBlobSet) error {
+		for blob := range keep {
+			usedBlobs.Insert(blob)
+		}
+		return nil
+	}
+
+	opts := repository.PruneOptions{
+		MaxRepackBytes: math.MaxUint64,
+		MaxUnusedBytes: func(used uint64) (unused uint64) { return 0 },
+	}
+	plan, err := repository.PlanPrune(context.TODO(), opts, repo, usedBlobs, &progress.NoopPrinter{})
+	rtest.OK(t, err)
+
+	saved, err := plan.Save(restic.NewIDSet())
+	rtest.OK(t, err)
+	buf, err := json.Marshal(saved)
+	rtest.OK(t, err)
+	var loaded repository.SavedPrunePlan
+	rtest.OK(t, json.Unmarshal(buf, &loaded))
+	rtest.Equals(t, plan.Stats(), loaded.Stats)
+	rtest.Assert(t, len(loaded.RepackPacks)+len(loaded.RemovePacks) > 0, "plan does not modify the repository")
+
+	// adding data invalidates the plan
+	repo = repository.TestOpenBackend(t, be)
+	rtest.OK(t, repo.LoadIndex(context.TODO(), nil))
+	createRandomBlobs(t, random, repo, 1, 0.5, true)
+	_, err = repository.LoadPrunePlan(context.TODO(), repository.PruneOptions{}, repo, &loaded, usedBlobs, restic.NewIDSet())
+	rtest.Assert(t, err != nil, "expected error for modified index")
+
+	// the plan for the current state can be executed
+	plan, err = repository.PlanPrune(context.TODO(), opts, repo, usedBlobs, &progress.NoopPrinter{})
+	rtest.OK(t, err)
+	saved, err = plan.Save(restic.NewIDSet())
+	rtest.OK(t, err)
+
+	repo = repository.TestOpenBackend(t, be)
+	rtest.OK(t, repo.LoadIndex(context.TODO(), nil))
+	// blobs which are used again must not be removed
+	_, err = repository.LoadPrunePlan(context.TODO(), repository.PruneOptions{}, repo, saved, func(ctx context.Context, repo restic.Repository, used restic.FindBlobSet) error {
+		rtest.OK(t, usedBlobs(ctx, repo, used))
+		for blob := range unused {
+			used.Insert(blob)
+		}
+		return nil
+	}, restic.NewIDSet())
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "still in use"), "expected error for plan removing used blobs, got %v", err)
+
+	// snapshots created after the plan are rejected
+	_, err = repository.LoadPrunePlan(context.TODO(), repository.PruneOptions{}, repo, saved, usedBlobs, restic.NewIDSet(restic.NewRandomID()))
+	rtest.Assert(t, err != nil, "expected error for new snapshot")
+
+	plan, err = repository.LoadPrunePlan(context.TODO(), repository.PruneOptions{}, repo, saved, usedBlobs, restic.NewIDSet())
+	rtest.OK(t, err)
+	rtest.OK(t, plan.Execute(context.TODO(), &progress.NoopPrinter{}))
+
+	repo = repository.TestOpenBackend(t, be)
+	checker.TestCheckRepo(t, repo, true)
+	existing := listBlobs(repo)
+	rtest.Assert(t, existing.Equals(keep), "unexpected blobs, wanted %v got %v", keep, existing)
+}
+
 /*
 1.) create repository with packsize of 2M.
 2.) create enough data for 11 packfiles (31 packs)
@@ -3,6 +3,8 @@ package repository
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"math"
@@ -10,7 +12,9 @@ import (
 	"runtime"
 	"sort"
 	"sync"
+	"time"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/klauspost/compress/zstd"
 	"github.com/restic/chunker"
 	"github.com/restic/restic/internal/backend"
@@ -40,6 +44,28 @@ type Repository struct {
 	idx   *index.MasterIndex
 	cache *cache.Cache
 
+	// zeroChunk is the ID of an all-zero chunk with the minimum chunk size
+	zeroChunk restic.ID
+
+	// capabilities and keyExpires are copied from the current key
+	capabilities Capabilities
+	keyExpires   *time.Time
+
+	// adminKey is only set if the current key is an admin key of an
+	// append-only repository
+	adminKey  ed25519.PrivateKey
+	journaled struct {
+		sync.Mutex
+		M map[backend.Handle]struct{}
+	}
+
+	// sealKey is only set for asymmetric repositories. It encrypts new blobs
+	// and snapshots and is derived from the repository public key and the
+	// ephemeral packKey. blobKeys caches the keys derived for other pack keys.
+	sealKey  *crypto.Key
+	packKey  crypto.PublicKey
+	blobKeys *lru.Cache[crypto.PublicKey, *crypto.Key]
+
 	opts Options
 
 	packerWg    *errgroup.Group
@@ -65,6 +91,27 @@ type Options struct {
 	NoExtraVerify bool
 }
 
+// InitOptions collects the settings stored in the config of a new repository.
+type InitOptions struct {
+	// ChunkerPolynomial is generated randomly if not set.
+	ChunkerPolynomial *chunker.Pol
+	// ChunkerMinSize, ChunkerMaxSize and ChunkerAvgSize set the chunk sizes
+	// of the repository. Sizes which are zero use the default.
+	ChunkerMinSize uint
+	ChunkerMaxSize uint
+	ChunkerAvgSize uint
+	// AppendOnly creates an append-only repository whose first key is an
+	// admin key.
+	AppendOnly bool
+	// KDF is the key derivation function used for the first key, scrypt is
+	// used if empty.
+	KDF string
+	// Asymmetric creates a repository whose blobs and snapshots can only be
+	// decrypted using the private key held by the first key and keys copied
+	// from it. Write-only keys can still add data.
+	Asymmetric bool
+}
+
 // CompressionMode configures if data should be compressed.
 type CompressionMode uint
 
@@ -136,8 +183,26 @@ func New(be backend.Backend, opts Options) (*Repository, error) {
 }
 
 // setConfig assigns the given config and updates the repository parameters accordingly
-func (r *Repository) setConfig(cfg restic.Config) {
+func (r *Repository) setConfig(cfg restic.Config) error {
+	r.sealKey = nil
+	r.packKey = crypto.PublicKey{}
+	if cfg.DataPublicKey != "" {
+		pub, err := crypto.ParsePublicKey(cfg.DataPublicKey)
+		if err != nil {
+			return fmt.Errorf("invalid data public key: %w", err)
+		}
+		r.sealKey, r.packKey, err = crypto.NewSealingKey(pub)
+		if err != nil {
+			return fmt.Errorf("invalid data public key: %w", err)
+		}
+		r.blobKeys, err = lru.New[crypto.PublicKey, *crypto.Key](blobKeyCacheSize)
+		if err != nil {
+			return err
+		}
+	}
 	r.cfg = cfg
+	r.zeroChunk = cfg.ChunkerParams().ZeroChunk()
+	return nil
 }
 
 // Config returns the repository configuration.
@@ -169,6 +234,59 @@ func (r *Repository) SetDryRun() {
 	r.be = dryrun.New(r.be)
 }
 
+// blobKeyCacheSize is the number of keys derived from pack keys to cache
+const blobKeyCacheSize = 1024
+
+// sealed returns true if files of type t are encrypted with a key which can
+// only be derived using the private key of an asymmetric repository.
+func (r *Repository) sealed(t restic.FileType) bool {
+	return r.sealKey != nil && t == restic.SnapshotFile
+}
+
+// WriteOnly returns true if the current key of an asymmetric repository can
+// only add data, but not decrypt blobs and snapshots.
+func (r *Repository) WriteOnly() bool {
+	return r.sealKey != nil && len(r.key.DecryptionKey) == 0
+}
+
+// blobKey returns the key to decrypt blobs stored in a pack with the given
+// pack key, or snapshots prefixed by the pack key.
+func (r *Repository) blobKey(packKey crypto.PublicKey) (*crypto.Key, error) {
+	switch {
+	case packKey.IsNull():
+		return r.key, nil
+	case r.sealKey == nil:
+		return nil, errors.New("pack key found in repository without public key")
+	case packKey == r.packKey:
+		return r.sealKey, nil
+	}
+
+	if k, ok := r.blobKeys.Get(packKey); ok {
+		return k, nil
+	}
+	k, err := r.key.OpenSealingKey(packKey)
+	if err != nil {
+		return nil, err
+	}
+	r.blobKeys.Add(packKey, k)
+	return k, nil
+}
+
+// packBlobKey returns the key to decrypt the blobs of the pack id, which
+// contains the blob bh. The pack key is looked up in the index.
+func (r *Repository) packBlobKey(id restic.ID, bh restic.BlobHandle) (*crypto.Key, crypto.PublicKey, error) {
+	if r.sealKey == nil {
+		return r.key, crypto.PublicKey{}, nil
+	}
+	for _, pb := range r.idx.Lookup(bh) {
+		if pb.PackID == id {
+			k, err := r.blobKey(pb.PackKey)
+			return k, pb.PackKey, err
+		}
+	}
+	return nil, crypto.PublicKey{}, errors.Errorf("pack %v not found in index", id.Str())
+}
+
 // LoadUnpacked loads and decrypts the file with the given type and ID.
 func (r *Repository) LoadUnpacked(ctx context.Context, t restic.FileType, id restic.ID) ([]byte, error) {
 	debug.Log("load %v with id %v", t, id)
@@ -182,8 +300,37 @@ func (r *Repository) LoadUnpacked(ctx context.Context, t restic.FileType, id res
 		return nil, err
 	}
 
-	nonce, ciphertext := buf[:r.key.NonceSize()], buf[r.key.NonceSize():]
-	plaintext, err := r.key.Open(ciphertext[:0], nonce, ciphertext, nil)
+	return r.decryptUnpacked(t, buf, r.key)
+}
+
+// decryptUnpacked decrypts buf, which contains a file of type t, using the
+// master key. buf is overwritten if the decryption succeeds.
+func (r *Repository) decryptUnpacked(t restic.FileType, buf []byte, master *crypto.Key) ([]byte, error) {
+	var err error
+	key := master
+	if r.sealed(t) {
+		if len(buf) < crypto.PublicKeySize {
+			return nil, errors.New("invalid sealed file, too short")
+		}
+		var packKey crypto.PublicKey
+		copy(packKey[:], buf)
+		buf = buf[crypto.PublicKeySize:]
+
+		if master == r.key {
+			key, err = r.blobKey(packKey)
+		} else {
+			key, err = master.OpenSealingKey(packKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(buf) < crypto.CiphertextLength(0) {
+		return nil, errors.New("invalid file, too short")
+	}
+
+	nonce, ciphertext := buf[:key.NonceSize()], buf[key.NonceSize():]
+	plaintext, err := key.Open(ciphertext[:0], nonce, ciphertext, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -228,6 +375,13 @@ func sortCachedPacksFirst(cache haver, blobs []restic.PackedBlob) {
 func (r *Repository) LoadBlob(ctx context.Context, t restic.BlobType, id restic.ID, buf []byte) ([]byte, error) {
 	debug.Log("load %v with id %v (buf len %v, cap %d)", t, id, len(buf), cap(buf))
 
+	// trees are also required to create backups
+	if t == restic.DataBlob {
+		if err := r.RequireCapability(CapabilityRead); err != nil {
+			return nil, err
+		}
+	}
+
 	// lookup packs
 	blobs := r.idx.Lookup(restic.BlobHandle{ID: id, Type: t})
 	if len(blobs) == 0 {
@@ -274,7 +428,14 @@ func (r *Repository) loadBlob(ctx context.Context, blobs []restic.PackedBlob, bu
 			continue
 		}
 
-		it := newPackBlobIterator(blob.PackID, newByteReader(buf), blob.Offset, []restic.Blob{blob.Blob}, r.key, r.getZstdDecoder())
+		key, err := r.blobKey(blob.PackKey)
+		if err != nil {
+			debug.Log("error deriving key for blob %v: %v", blob, err)
+			lastError = err
+			continue
+		}
+
+		it := newPackBlobIterator(blob.PackID, newByteReader(buf), blob.Offset, []restic.Blob{blob.Blob}, key, r.getZstdDecoder())
 		pbv, err := it.Next()
 
 		if err == nil {
@@ -368,15 +529,20 @@ func (r *Repository) saveAndEncrypt(ctx context.Context, t restic.BlobType, data
 		}
 	}
 
+	key := r.key
+	if r.sealKey != nil {
+		key = r.sealKey
+	}
+
 	nonce := crypto.NewRandomNonce()
 
 	ciphertext := make([]byte, 0, crypto.CiphertextLength(len(data)))
 	ciphertext = append(ciphertext, nonce...)
 
 	// encrypt blob
-	ciphertext = r.key.Seal(ciphertext, nonce, data, nil)
+	ciphertext = key.Seal(ciphertext, nonce, data, nil)
 
-	if err := r.verifyCiphertext(ciphertext, uncompressedLength, id); err != nil {
+	if err := r.verifyCiphertext(key, ciphertext, uncompressedLength, id); err != nil {
 		//nolint:revive // ignore linter warnings about error message spelling
 		return 0, fmt.Errorf("Detected data corruption while saving blob %v: %w\nCorrupted blobs are either caused by hardware issues or software bugs. Please open an issue at https://github.com/restic/restic/issues/new/choose for further troubleshooting.", id, err)
 	}
@@ -396,13 +562,13 @@ func (r *Repository) saveAndEncrypt(ctx context.Context, t restic.BlobType, data
 	return pm.SaveBlob(ctx, t, id, ciphertext, uncompressedLength)
 }
 
-func (r *Repository) verifyCiphertext(buf []byte, uncompressedLength int, id restic.ID) error {
+func (r *Repository) verifyCiphertext(key *crypto.Key, buf []byte, uncompressedLength int, id restic.ID) error {
 	if r.opts.NoExtraVerify {
 		return nil
 	}
 
-	nonce, ciphertext := buf[:r.key.NonceSize()], buf[r.key.NonceSize():]
-	plaintext, err := r.key.Open(nil, nonce, ciphertext, nil)
+	nonce, ciphertext := buf[:key.NonceSize()], buf[key.NonceSize():]
+	plaintext, err := key.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
 		return fmt.Errorf("decryption failed: %w", err)
 	}
@@ -466,6 +632,12 @@ func (r *internalRepository) SaveUnpacked(ctx context.Context, t restic.FileType
 }
 
 func (r *Repository) saveUnpacked(ctx context.Context, t restic.FileType, buf []byte) (id restic.ID, err error) {
+	if t != restic.LockFile {
+		if err := r.RequireCapability(CapabilityBackup); err != nil {
+			return restic.ID{}, err
+		}
+	}
+
 	p := buf
 	if t != restic.ConfigFile {
 		p, err = r.compressUnpacked(p)
@@ -474,14 +646,21 @@ func (r *Repository) saveUnpacked(ctx context.Context, t restic.FileType, buf []
 		}
 	}
 
-	ciphertext := crypto.NewBlobBuffer(len(p))
+	key := r.key
+	ciphertext := crypto.NewBlobBuffer(crypto.PublicKeySize + len(p))
 	ciphertext = ciphertext[:0]
+	if r.sealed(t) {
+		// sealed files are prefixed with the pack key
+		key = r.sealKey
+		ciphertext = append(ciphertext, r.packKey[:]...)
+	}
+	prefixLen := len(ciphertext)
 	nonce := crypto.NewRandomNonce()
 	ciphertext = append(ciphertext, nonce...)
 
-	ciphertext = r.key.Seal(ciphertext, nonce, p, nil)
+	ciphertext = key.Seal(ciphertext, nonce, p, nil)
 
-	if err := r.verifyUnpacked(ciphertext, t, buf); err != nil {
+	if err := r.verifyUnpacked(key, ciphertext[prefixLen:], t, buf); err != nil {
 		//nolint:revive // ignore linter warnings about error message spelling
 		return restic.ID{}, fmt.Errorf("Detected data corruption while saving file of type %v: %w\nCorrupted data is either caused by hardware issues or software bugs. Please open an issue at https://github.com/restic/restic/issues/new/choose for further troubleshooting.", t, err)
 	}
@@ -503,13 +682,13 @@ func (r *Repository) saveUnpacked(ctx context.Context, t restic.FileType, buf []
 	return id, nil
 }
 
-func (r *Repository) verifyUnpacked(buf []byte, t restic.FileType, expected []byte) error {
+func (r *Repository) verifyUnpacked(key *crypto.Key, buf []byte, t restic.FileType, expected []byte) error {
 	if r.opts.NoExtraVerify {
 		return nil
 	}
 
-	nonce, ciphertext := buf[:r.key.NonceSize()], buf[r.key.NonceSize():]
-	plaintext, err := r.key.Open(nil, nonce, ciphertext, nil)
+	nonce, ciphertext := buf[:key.NonceSize()], buf[key.NonceSize():]
+	plaintext, err := key.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
 		return fmt.Errorf("decryption failed: %w", err)
 	}
@@ -535,6 +714,9 @@ func (r *internalRepository) RemoveUnpacked(ctx context.Context, t restic.FileTy
 }
 
 func (r *Repository) removeUnpacked(ctx context.Context, t restic.FileType, id restic.ID) error {
+	if err := r.journalRemoval(ctx, t, restic.NewIDSet(id)); err != nil {
+		return err
+	}
 	return r.be.Remove(ctx, backend.Handle{Type: t, Name: id.String()})
 }
 
@@ -555,8 +737,8 @@ func (r *Repository) StartPackUploader(ctx context.Context, wg *errgroup.Group)
 	innerWg, ctx := errgroup.WithContext(ctx)
 	r.packerWg = innerWg
 	r.uploader = newPackerUploader(ctx, innerWg, r, r.Connections())
-	r.treePM = newPackerManager(r.key, restic.TreeBlob, r.packSize(), r.packerCount, r.uploader.QueuePacker)
-	r.dataPM = newPackerManager(r.key, restic.DataBlob, r.packSize(), r.packerCount, r.uploader.QueuePacker)
+	r.treePM = newPackerManager(r.key, r.packKey, restic.TreeBlob, r.packSize(), r.packerCount, r.uploader.QueuePacker)
+	r.dataPM = newPackerManager(r.key, r.packKey, restic.DataBlob, r.packSize(), r.packerCount, r.uploader.QueuePacker)
 
 	wg.Go(func() error {
 		return innerWg.Wait()
@@ -696,14 +878,14 @@ func (r *Repository) createIndexFromPacks(ctx context.Context, packsize map[rest
 	// a worker receives an pack ID from ch, reads the pack contents, and adds them to idx
 	worker := func() error {
 		for fi := range ch {
-			entries, _, err := r.ListPack(ctx, fi.ID, fi.Size)
+			entries, packKey, _, err := r.listPack(ctx, fi.ID, fi.Size)
 			if err != nil {
 				debug.Log("unable to list pack file %v", fi.ID.Str())
 				m.Lock()
 				invalid = append(invalid, fi.ID)
 				m.Unlock()
 			}
-			if err := r.idx.StorePack(ctx, fi.ID, entries, &internalRepository{r}); err != nil {
+			if err := r.idx.StorePack(ctx, fi.ID, packKey, entries, &internalRepository{r}); err != nil {
 				return err
 			}
 			p.Add(1)
@@ -752,6 +934,9 @@ func (r *Repository) SearchKey(ctx context.Context, password string, maxKeys int
 	if err != nil {
 		return err
 	}
+	if key.Expired(time.Now()) {
+		return fmt.Errorf("key %v expired on %v", key.ID(), key.Expires.Local().Format("2006-01-02 15:04:05"))
+	}
 
 	oldKey := r.key
 	oldKeyID := r.keyID
@@ -769,13 +954,62 @@ func (r *Repository) SearchKey(ctx context.Context, password string, maxKeys int
 		return fmt.Errorf("config cannot be loaded: %w", err)
 	}
 
-	r.setConfig(cfg)
-	return nil
+	if cfg.DataPublicKey == "" && (len(key.master.DecryptionKey) != 0 || key.master.DataPublicKey != nil) {
+		r.key = oldKey
+		r.keyID = oldKeyID
+		return fmt.Errorf("repository config does not contain the public key of key %v", key.ID())
+	}
+	if cfg.DataPublicKey != "" && len(key.master.DecryptionKey) == 0 {
+		// the public key is used to encrypt new snapshots and blobs, a
+		// write-only key must not trust the config as it can be replaced
+		// by anyone who holds the master key
+		if key.master.DataPublicKey == nil || key.master.DataPublicKey.String() != cfg.DataPublicKey {
+			r.key = oldKey
+			r.keyID = oldKeyID
+			return fmt.Errorf("public key of write-only key %v does not match the repository config", key.ID())
+		}
+	}
+	if cfg.DataPublicKey != "" && len(key.master.DecryptionKey) != 0 {
+		pub, err := key.master.PublicKey()
+		if err == nil && pub.String() != cfg.DataPublicKey && key.master.Previous != nil {
+			// the config still contains the previous public key if the rotation
+			// of the master key was interrupted before the config was replaced
+			prev, err := key.master.Previous.PublicKey()
+			if err == nil && prev.String() == cfg.DataPublicKey {
+				cfg.DataPublicKey = pub.String()
+			}
+		}
+		if err != nil || pub.String() != cfg.DataPublicKey {
+			r.key = oldKey
+			r.keyID = oldKeyID
+			return fmt.Errorf("decryption key of key %v does not match the repository config", key.ID())
+		}
+	}
+
+	caps := key.EffectiveCapabilities(cfg)
+	if len(caps) == 0 {
+		r.key = oldKey
+		r.keyID = oldKeyID
+		return fmt.Errorf("key %v has no capabilities usable in this repository", key.ID())
+	}
+	r.capabilities = caps
+	r.keyExpires = key.Expires
+
+	r.adminKey = nil
+	if cfg.AppendOnly && key.admin != nil {
+		if hex.EncodeToString(key.admin.Public().(ed25519.PublicKey)) != cfg.AdminPublicKey {
+			return fmt.Errorf("admin signing key of key %v does not match the repository config", key.ID())
+		}
+		r.adminKey = key.admin
+	}
+
+	return r.setConfig(cfg)
 }
 
 // Init creates a new master key with the supplied password, initializes and
-// saves the repository config.
-func (r *Repository) Init(ctx context.Context, version uint, password string, chunkerPolynomial *chunker.Pol) error {
+// saves the repository config. The version is raised if the options require
+// features which are not supported by the version.
+func (r *Repository) Init(ctx context.Context, version uint, password string, opts InitOptions) error {
 	if version > restic.MaxRepoVersion {
 		return fmt.Errorf("repository version %v too high", version)
 	}
@@ -813,24 +1047,67 @@ func (r *Repository) Init(ctx context.Context, version uint, password string, ch
 	if err != nil {
 		return err
 	}
-	if chunkerPolynomial != nil {
-		cfg.ChunkerPolynomial = *chunkerPolynomial
+	if opts.ChunkerPolynomial != nil {
+		cfg.ChunkerPolynomial = *opts.ChunkerPolynomial
+	}
+	if opts.ChunkerMinSize != 0 || opts.ChunkerMaxSize != 0 || opts.ChunkerAvgSize != 0 {
+		cfg.ChunkerMinSize = opts.ChunkerMinSize
+		cfg.ChunkerMaxSize = opts.ChunkerMaxSize
+		cfg.ChunkerAvgSize = opts.ChunkerAvgSize
+		params := cfg.ChunkerParams()
+		if err := params.Check(); err != nil {
+			return err
+		}
+		// only store custom chunk sizes to keep the config compatible
+		if params.IsDefault() {
+			cfg.ChunkerMinSize, cfg.ChunkerMaxSize, cfg.ChunkerAvgSize = 0, 0, 0
+		} else {
+			cfg.ChunkerMinSize, cfg.ChunkerMaxSize, cfg.ChunkerAvgSize = params.MinSize, params.MaxSize, params.AvgSize
+		}
+	}
+
+	var admin ed25519.PrivateKey
+	if opts.AppendOnly {
+		var pub ed25519.PublicKey
+		pub, admin, err = ed25519.GenerateKey(nil)
+		if err != nil {
+			return errors.Wrap(err, "GenerateKey")
+		}
+		cfg.AppendOnly = true
+		cfg.AdminPublicKey = hex.EncodeToString(pub)
+	}
+
+	master := crypto.NewRandomKey()
+	if opts.Asymmetric {
+		var pub crypto.PublicKey
+		pub, master.DecryptionKey = crypto.NewRandomKeyPair()
+		cfg.DataPublicKey = pub.String()
+	}
+	// older clients must not be able to open a repository whose features
+	// they do not understand
+	if cfg.Version < cfg.RequiredVersion() {
+		cfg.Version = cfg.RequiredVersion()
 	}
 
-	return r.init(ctx, password, cfg)
+	return r.init(ctx, password, opts.KDF, cfg, master, admin)
 }
 
-// init creates a new master key with the supplied password and uses it to save
+// init encrypts the master key with the supplied password and uses it to save
 // the config into the repo.
-func (r *Repository) init(ctx context.Context, password string, cfg restic.Config) error {
-	key, err := createMasterKey(ctx, r, password)
+func (r *Repository) init(ctx context.Context, password string, kdf string, cfg restic.Config, master *crypto.Key, admin ed25519.PrivateKey) error {
+	key, err := createMasterKey(ctx, r, password, kdf, master, admin)
 	if err != nil {
 		return err
 	}
 
 	r.key = key.master
 	r.keyID = key.ID()
-	r.setConfig(cfg)
+	r.capabilities = Capabilities{CapabilityAdmin}
+	r.keyExpires = nil
+	r.adminKey = admin
+	if err := r.setConfig(cfg); err != nil {
+		return err
+	}
 	return restic.SaveConfig(ctx, &internalRepository{r}, cfg)
 }
 
@@ -844,6 +1121,32 @@ func (r *Repository) KeyID() restic.ID {
 	return r.keyID
 }
 
+// Capabilities returns the capabilities of the current key.
+func (r *Repository) Capabilities() Capabilities {
+	return r.capabilities
+}
+
+// KeyExpires returns the expiry time of the current key, or nil if the key
+// does not expire.
+func (r *Repository) KeyExpires() *time.Time {
+	return r.keyExpires
+}
+
+// RequireCapability returns a CapabilityError if the current key lacks the
+// capability c.
+func (r *Repository) RequireCapability(c Capability) error {
+	if !r.capabilities.Has(c) {
+		return &CapabilityError{Capability: c}
+	}
+	return nil
+}
+
+// AdminKey returns the admin signing key if the current key is an admin key
+// of an append-only repository, and nil otherwise.
+func (r *Repository) AdminKey() ed25519.PrivateKey {
+	return r.adminKey
+}
+
 // List runs fn for all files of type t in the repo.
 func (r *Repository) List(ctx context.Context, t restic.FileType, fn func(restic.ID, int64) error) error {
 	return r.be.List(ctx, t, func(fi backend.FileInfo) error {
@@ -859,9 +1162,15 @@ func (r *Repository) List(ctx context.Context, t restic.FileType, fn func(restic
 // ListPack returns the list of blobs saved in the pack id and the length of
 // the pack header.
 func (r *Repository) ListPack(ctx context.Context, id restic.ID, size int64) ([]restic.Blob, uint32, error) {
+	entries, _, hdrSize, err := r.listPack(ctx, id, size)
+	return entries, hdrSize, err
+}
+
+// listPack is like ListPack, but additionally returns the pack key.
+func (r *Repository) listPack(ctx context.Context, id restic.ID, size int64) ([]restic.Blob, crypto.PublicKey, uint32, error) {
 	h := backend.Handle{Type: restic.PackFile, Name: id.String()}
 
-	entries, hdrSize, err := pack.List(r.Key(), backend.ReaderAt(ctx, r.be, h), size)
+	entries, packKey, hdrSize, err := pack.ListWithKey(r.Key(), backend.ReaderAt(ctx, r.be, h), size)
 	if err != nil {
 		if r.cache != nil {
 			// ignore error as there is not much we can do here
@@ -869,9 +1178,9 @@ func (r *Repository) ListPack(ctx context.Context, id restic.ID, size int64) ([]
 		}
 
 		// retry on error
-		entries, hdrSize, err = pack.List(r.Key(), backend.ReaderAt(ctx, r.be, h), size)
+		entries, packKey, hdrSize, err = pack.ListWithKey(r.Key(), backend.ReaderAt(ctx, r.be, h), size)
 	}
-	return entries, hdrSize, err
+	return entries, packKey, hdrSize, err
 }
 
 // Delete calls backend.Delete() if implemented, and returns an error
@@ -893,6 +1202,9 @@ func (r *Repository) Close() error {
 // If the blob was not known before, it returns the number of bytes the blob
 // occupies in the repo (compressed or not, including encryption overhead).
 func (r *Repository) SaveBlob(ctx context.Context, t restic.BlobType, buf []byte, id restic.ID, storeDuplicate bool) (newID restic.ID, known bool, size int, err error) {
+	if err := r.RequireCapability(CapabilityBackup); err != nil {
+		return restic.ID{}, false, 0, err
+	}
 
 	if int64(len(buf)) > math.MaxUint32 {
 		return restic.ID{}, false, 0, fmt.Errorf("blob is larger than 4GB")
@@ -903,8 +1215,9 @@ func (r *Repository) SaveBlob(ctx context.Context, t restic.BlobType, buf []byte
 		// Special case the hash calculation for all zero chunks. This is especially
 		// useful for sparse files containing large all zero regions. For these we can
 		// process chunks as fast as we can read the from disk.
-		if len(buf) == chunker.MinSize && restic.ZeroPrefixLen(buf) == chunker.MinSize {
-			newID = ZeroChunk()
+		minSize := int(r.cfg.ChunkerParams().MinSize)
+		if len(buf) == minSize && restic.ZeroPrefixLen(buf) == minSize {
+			newID = r.zeroChunk
 		} else {
 			newID = restic.Hash(buf)
 		}
@@ -935,7 +1248,20 @@ const maxUnusedRange = 1 * 1024 * 1024
 // then LoadBlobsFromPack will abort and not retry it. The buf passed to the callback is only valid within
 // this specific call. The callback must not keep a reference to buf.
 func (r *Repository) LoadBlobsFromPack(ctx context.Context, packID restic.ID, blobs []restic.Blob, handleBlobFn func(blob restic.BlobHandle, buf []byte, err error) error) error {
-	return streamPack(ctx, r.be.Load, r.LoadBlob, r.getZstdDecoder(), r.key, packID, blobs, handleBlobFn)
+	if len(blobs) == 0 {
+		// nothing to do
+		return nil
+	}
+	if blobs[0].Type == restic.DataBlob {
+		if err := r.RequireCapability(CapabilityRead); err != nil {
+			return err
+		}
+	}
+	key, _, err := r.packBlobKey(packID, blobs[0].BlobHandle)
+	if err != nil {
+		return err
+	}
+	return streamPack(ctx, r.be.Load, r.LoadBlob, r.getZstdDecoder(), key, packID, blobs, handleBlobFn)
 }
 
 func streamPack(ctx context.Context, beLoad backendLoadFn, loadBlobFn loadBlobFn, dec *zstd.Decoder, key *crypto.Key, packID restic.ID, blobs []restic.Blob, handleBlobFn func(blob restic.BlobHandle, buf []byte, err error) error) error {
@@ -1185,13 +1511,8 @@ func (b *packBlobIterator) Next() (packBlobValue, error) {
 	return packBlobValue{entry.BlobHandle, plaintext, err}, nil
 }
 
-var zeroChunkOnce sync.Once
-var zeroChunkID restic.ID
-
-// ZeroChunk computes and returns (cached) the ID of an all-zero chunk with size chunker.MinSize
-func ZeroChunk() restic.ID {
-	zeroChunkOnce.Do(func() {
-		zeroChunkID = restic.Hash(make([]byte, chunker.MinSize))
-	})
-	return zeroChunkID
+// ZeroChunk returns the ID of an all-zero chunk with the minimum chunk size of
+// the repository.
+func (r *Repository) ZeroChunk() restic.ID {
+	return r.zeroChunk
 }
@@ -224,7 +224,7 @@ func testStreamPack(t *testing.T, version uint) {
 	switch version {
 	case 1:
 		compress = false
-	case 2:
+	case 2, 3:
 		compress = true
 	default:
 		t.Fatal("test does not support repository version", version)
@@ -440,7 +440,7 @@ func TestBlobVerification(t *testing.T) {
 			ciphertext[42] ^= 0x42
 		}
 
-		err := repo.verifyCiphertext(ciphertext, int(uncompressedLength), id)
+		err := repo.verifyCiphertext(repo.key, ciphertext, int(uncompressedLength), id)
 		if test.msg == "" {
 			rtest.Assert(t, err == nil, "expected no error, got %v", err)
 		} else {
@@ -489,7 +489,7 @@ func TestUnpackedVerification(t *testing.T) {
 			ciphertext[42] ^= 0x42
 		}
 
-		err := repo.verifyUnpacked(ciphertext, restic.IndexFile, orig)
+		err := repo.verifyUnpacked(repo.key, ciphertext, restic.IndexFile, orig)
 		if test.msg == "" {
 			rtest.Assert(t, err == nil, "expected no error, got %v", err)
 		} else {
@@ -577,3 +577,44 @@ func TestStreamPackFallback(t *testing.T) {
 		test(t, true)
 	})
 }
+
+func TestWriteOnlyKeyConfigSwap(t *testing.T) {
+	TestUseLowSecurityKDFParameters(t)
+	restic.TestDisableCheckPolynomial(t)
+	ctx := context.TODO()
+	be := TestBackend(t)
+
+	repo, err := New(be, Options{})
+	rtest.OK(t, err)
+	pol := testChunkerPol
+	rtest.OK(t, repo.Init(ctx, restic.StableRepoVersion, rtest.TestPassword, InitOptions{Asymmetric: true, ChunkerPolynomial: &pol}))
+	template, err := repo.Key().WriteOnly()
+	rtest.OK(t, err)
+	key, err := AddKey(ctx, repo, "write-only", template, KeyOptions{})
+	rtest.OK(t, err)
+
+	wo, err := New(be, Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, wo.SearchKey(ctx, "write-only", 0, key.ID().String()))
+	original := wo.Config()
+
+	// a write-only key holds the master key and can thus replace the config
+	attacker, _ := crypto.NewRandomKeyPair()
+	for _, pub := range []string{attacker.String(), ""} {
+		cfg := original
+		cfg.DataPublicKey = pub
+		rtest.OK(t, saveNewConfig(ctx, wo, cfg))
+
+		r, err := New(be, Options{})
+		rtest.OK(t, err)
+		err = r.SearchKey(ctx, "write-only", 0, key.ID().String())
+		rtest.Assert(t, err != nil && strings.Contains(err.Error(), "public key"), "expected error for replaced public key %q, got %v", pub, err)
+		err = r.SearchKey(ctx, rtest.TestPassword, 0, "")
+		rtest.Assert(t, err != nil && strings.Contains(err.Error(), "does not"), "expected error for replaced public key %q, got %v", pub, err)
+	}
+
+	rtest.OK(t, saveNewConfig(ctx, wo, original))
+	r, err := New(be, Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, r.SearchKey(ctx, "write-only", 0, key.ID().String()))
+}
@@ -297,6 +297,32 @@ func benchmarkLoadUnpacked(b *testing.B, version uint) {
 
 var repoFixture = filepath.Join("testdata", "test-repo.tar.gz")
 
+func TestZeroChunk(t *testing.T) {
+	repository.TestUseLowSecurityKDFParameters(t)
+	restic.TestDisableCheckPolynomial(t)
+	ctx := context.TODO()
+
+	repo, err := repository.New(mem.New(), repository.Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, repo.Init(ctx, restic.StableRepoVersion, rtest.TestPassword, repository.InitOptions{
+		ChunkerMinSize: 64 * 1024,
+		ChunkerAvgSize: 256 * 1024,
+		ChunkerMaxSize: 2 * 1024 * 1024,
+	}))
+	rtest.Equals(t, uint(restic.ExtendedRepoVersion), repo.Config().Version)
+
+	// the zero chunk depends on the minimum chunk size of the repository
+	zeros := make([]byte, 64*1024)
+	rtest.Equals(t, restic.Hash(zeros), repo.ZeroChunk())
+
+	var wg errgroup.Group
+	repo.StartPackUploader(ctx, &wg)
+	id, _, _, err := repo.SaveBlob(ctx, restic.DataBlob, zeros, restic.ID{}, false)
+	rtest.OK(t, err)
+	rtest.OK(t, repo.Flush(ctx))
+	rtest.Equals(t, repo.ZeroChunk(), id)
+}
+
 func TestRepositoryLoadIndex(t *testing.T) {
 	repo, _, cleanup := repository.TestFromFixture(t, repoFixture)
 	defer cleanup()
@@ -475,12 +501,12 @@ func TestNoDoubleInit(t *testing.T) {
 	rtest.OK(t, err)
 
 	pol := r.Config().ChunkerPolynomial
-	err = repo.Init(context.TODO(), r.Config().Version, rtest.TestPassword, &pol)
+	err = repo.Init(context.TODO(), r.Config().Version, rtest.TestPassword, repository.InitOptions{ChunkerPolynomial: &pol})
 	rtest.Assert(t, strings.Contains(err.Error(), "repository master key and config already initialized"), "expected config exist error, got %q", err)
 
 	// must also prevent init if only keys exist
 	rtest.OK(t, be.Remove(context.TODO(), backend.Handle{Type: backend.ConfigFile}))
-	err = repo.Init(context.TODO(), r.Config().Version, rtest.TestPassword, &pol)
+	err = repo.Init(context.TODO(), r.Config().Version, rtest.TestPassword, repository.InitOptions{ChunkerPolynomial: &pol})
 	rtest.Assert(t, strings.Contains(err.Error(), "repository already contains keys"), "expected already contains keys error, got %q", err)
 
 	// must also prevent init if a snapshot exists and keys were deleted
@@ -490,6 +516,94 @@ func TestNoDoubleInit(t *testing.T) {
 	rtest.OK(t, be.List(context.TODO(), restic.KeyFile, func(fi backend.FileInfo) error {
 		return be.Remove(context.TODO(), backend.Handle{Type: restic.KeyFile, Name: fi.Name})
 	}))
-	err = repo.Init(context.TODO(), r.Config().Version, rtest.TestPassword, &pol)
+	err = repo.Init(context.TODO(), r.Config().Version, rtest.TestPassword, repository.InitOptions{ChunkerPolynomial: &pol})
 	rtest.Assert(t, strings.Contains(err.Error(), "repository already contains snapshots"), "expected already contains snapshots error, got %q", err)
 }
+
+func TestAsymmetricRepository(t *testing.T) {
+	repository.TestUseLowSecurityKDFParameters(t)
+	restic.TestDisableCheckPolynomial(t)
+	ctx := context.TODO()
+	be := mem.New()
+
+	repo, err := repository.New(be, repository.Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, repo.Init(ctx, restic.StableRepoVersion, rtest.TestPassword, repository.InitOptions{Asymmetric: true}))
+	rtest.Assert(t, repo.Config().DataPublicKey != "", "missing data public key in config")
+	// older clients must refuse to open asymmetric repositories
+	rtest.Equals(t, uint(restic.ExtendedRepoVersion), repo.Config().Version)
+	rtest.Assert(t, !repo.WriteOnly(), "key created by init must not be write-only")
+
+	data := rtest.Random(23, 1000)
+	var wg errgroup.Group
+	repo.StartPackUploader(ctx, &wg)
+	dataID, _, _, err := repo.SaveBlob(ctx, restic.DataBlob, data, restic.ID{}, false)
+	rtest.OK(t, err)
+	rtest.OK(t, repo.Flush(ctx))
+	sn := &restic.Snapshot{Time: time.Now(), Tree: &dataID}
+	snID, err := restic.SaveSnapshot(ctx, repo, sn)
+	rtest.OK(t, err)
+
+	template, err := repo.Key().WriteOnly()
+	rtest.OK(t, err)
+	key, err := repository.AddKey(ctx, repo, "write-only", template, repository.KeyOptions{})
+	rtest.OK(t, err)
+	rtest.Assert(t, key.WriteOnly, "new key is not marked as write-only")
+
+	// the write-only key can read the index, but neither blobs nor snapshots
+	wo, err := repository.New(be, repository.Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, wo.SearchKey(ctx, "write-only", 0, key.ID().String()))
+	rtest.Assert(t, wo.WriteOnly(), "repository opened with write-only key is not write-only")
+	rtest.OK(t, wo.LoadIndex(ctx, nil))
+
+	// write-only keys only have the backup-only capability
+	rtest.Equals(t, repository.Capabilities{repository.CapabilityBackup}, wo.Capabilities())
+	_, err = wo.LoadBlob(ctx, restic.DataBlob, dataID, nil)
+	var capErr *repository.CapabilityError
+	rtest.Assert(t, errors.As(err, &capErr), "expected CapabilityError, got %v", err)
+	_, err = restic.LoadSnapshot(ctx, wo, snID)
+	rtest.Assert(t, errors.Is(err, crypto.ErrNoDecryptionKey), "expected ErrNoDecryptionKey, got %v", err)
+
+	// existing blobs are deduplicated, new blobs and snapshots can be saved
+	data2 := rtest.Random(42, 1000)
+	wg = errgroup.Group{}
+	wo.StartPackUploader(ctx, &wg)
+	_, known, _, err := wo.SaveBlob(ctx, restic.DataBlob, data, restic.ID{}, false)
+	rtest.OK(t, err)
+	rtest.Assert(t, known, "existing blob was not deduplicated")
+	data2ID, _, _, err := wo.SaveBlob(ctx, restic.DataBlob, data2, restic.ID{}, false)
+	rtest.OK(t, err)
+	rtest.OK(t, wo.Flush(ctx))
+	sn2 := &restic.Snapshot{Time: time.Now(), Tree: &data2ID}
+	sn2ID, err := restic.SaveSnapshot(ctx, wo, sn2)
+	rtest.OK(t, err)
+
+	// the full key can decrypt everything
+	full := repository.TestOpenBackend(t, be)
+	rtest.OK(t, full.LoadIndex(ctx, nil))
+	for id, expected := range map[restic.ID][]byte{dataID: data, data2ID: data2} {
+		buf, err := full.LoadBlob(ctx, restic.DataBlob, id, nil)
+		rtest.OK(t, err)
+		rtest.Equals(t, expected, buf)
+	}
+	loaded, err := restic.LoadSnapshot(ctx, full, sn2ID)
+	rtest.OK(t, err)
+	rtest.Equals(t, data2ID, *loaded.Tree)
+
+	// the index contains the pack key, which is included in the header size
+	packs := full.LookupBlob(restic.DataBlob, data2ID)
+	rtest.Assert(t, len(packs) == 1 && !packs[0].PackKey.IsNull(), "missing pack key in index: %v", packs)
+	var size int64
+	rtest.OK(t, full.List(ctx, restic.PackFile, func(id restic.ID, sz int64) error {
+		if id == packs[0].PackID {
+			size = sz
+		}
+		return nil
+	}))
+	_, hdrSize, err := full.ListPack(ctx, packs[0].PackID, size)
+	rtest.OK(t, err)
+	rtest.Equals(t, size, int64(packs[0].Length)+int64(hdrSize))
+
+	checker.TestCheckRepo(t, full, true)
+}
@@ -0,0 +1,511 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/crypto"
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/repository/pack"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui/progress"
+)
+
+// RotateMasterKeyOptions collects the settings for RotateMasterKey.
+type RotateMasterKeyOptions struct {
+	// Password is the password of the current key.
+	Password string
+	// NewPassword returns the password for the key holding the new master
+	// key. It is only called if a new rotation is started, an interrupted
+	// rotation is resumed using the password of the current key.
+	NewPassword func() (string, error)
+}
+
+// RotateMasterKey replaces the master key of the repository by a new random
+// key. All packs, index files, snapshots, holds and journal files are
+// re-encrypted using the new master key, blob IDs stay the same. Snapshots get
+// new IDs, holds are updated accordingly. Finally, all other keys are removed
+// as they still contain the old master key.
+//
+// While the rotation is in progress, the current key is replaced by a
+// rotation key which holds both the new and the previous master key. Each
+// step only processes files which cannot be decrypted using the new master
+// key alone, thus an interrupted rotation is resumed by calling
+// RotateMasterKey again using the password of the rotation key.
+//
+// The repository must be locked exclusively.
+func RotateMasterKey(ctx context.Context, repo *Repository, opts RotateMasterKeyOptions, printer progress.Printer) error {
+	// the rotation removes files and keys
+	if err := repo.CheckRemove(restic.KeyFile); err != nil {
+		return err
+	}
+
+	password, done, err := startRotation(ctx, repo, opts, printer)
+	if err != nil {
+		return err
+	}
+
+	if !done {
+		strict := repo.Key().WithoutPrevious()
+		if err := rotateConfig(ctx, repo, strict, printer); err != nil {
+			return err
+		}
+		if err := rotatePacks(ctx, repo, strict, printer); err != nil {
+			return err
+		}
+		if err := rotateSnapshots(ctx, repo, strict, printer); err != nil {
+			return err
+		}
+		if err := rotateJournal(ctx, repo, strict, printer); err != nil {
+			return err
+		}
+
+		if err := finishRotation(ctx, repo, password); err != nil {
+			return err
+		}
+	}
+
+	// make sure that the index is readable without the previous master key
+	repo.clearIndex()
+	if err := repo.LoadIndex(ctx, nil); err != nil {
+		return fmt.Errorf("index cannot be loaded using the new master key: %w", err)
+	}
+
+	printer.P("removing old keys\n")
+	var oldKeys restic.IDs
+	err = repo.List(ctx, restic.KeyFile, func(id restic.ID, _ int64) error {
+		if id != repo.KeyID() {
+			oldKeys = append(oldKeys, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range oldKeys {
+		if err := RemoveKey(ctx, repo, id); err != nil {
+			return err
+		}
+		printer.V("removed key %v\n", id)
+	}
+
+	printer.P("done\n")
+	return nil
+}
+
+// startRotation switches to the rotation key which holds the new and the
+// previous master key, and returns its password. A rotation key for the
+// current master key is reused to resume an interrupted rotation. done is true
+// if the current key already holds the new master key, then only the old keys
+// are left to remove.
+func startRotation(ctx context.Context, repo *Repository, opts RotateMasterKeyOptions, printer progress.Printer) (password string, done bool, err error) {
+	if repo.Key().Previous != nil {
+		printer.P("resuming interrupted master key rotation\n")
+		return opts.Password, false, nil
+	}
+
+	var rotationKeys restic.IDs
+	err = repo.List(ctx, restic.KeyFile, func(id restic.ID, _ int64) error {
+		k, err := LoadKey(ctx, repo, id)
+		if err != nil {
+			return err
+		}
+		if k.Rotating {
+			rotationKeys = append(rotationKeys, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	var stale restic.IDs
+	for _, id := range rotationKeys {
+		k, err := OpenKey(ctx, repo, id, opts.Password)
+		if err != nil && !errors.Is(err, crypto.ErrUnauthenticated) {
+			return "", false, err
+		}
+		switch {
+		case err == nil && sameMasterKey(k.master.Previous, repo.Key()):
+			printer.P("resuming interrupted master key rotation\n")
+			if err := repo.SearchKey(ctx, opts.Password, 0, id.String()); err != nil {
+				return "", false, fmt.Errorf("failed to access repository with rotation key: %w", err)
+			}
+			return opts.Password, false, nil
+		case err == nil && sameMasterKey(k.master, repo.Key()):
+			return opts.Password, true, nil
+		}
+		stale = append(stale, id)
+	}
+
+	// The current key can only open the repository if the config was not
+	// yet replaced, thus the remaining rotation keys belong to a rotation
+	// which was interrupted before any data was re-encrypted.
+	for _, id := range stale {
+		debug.Log("removing stale rotation key %v", id)
+		if err := RemoveKey(ctx, repo, id); err != nil {
+			return "", false, err
+		}
+	}
+
+	keyOpts, err := currentKeyOptions(ctx, repo)
+	if err != nil {
+		return "", false, err
+	}
+	password, err = opts.NewPassword()
+	if err != nil {
+		return "", false, err
+	}
+
+	master := crypto.NewRandomKey()
+	if repo.Config().DataPublicKey != "" {
+		_, master.DecryptionKey = crypto.NewRandomKeyPair()
+	}
+	master.Previous = repo.Key()
+
+	key, err := AddKey(ctx, repo, password, master, keyOpts)
+	if err != nil {
+		return "", false, fmt.Errorf("creating rotation key failed: %w", err)
+	}
+	if err := repo.SearchKey(ctx, password, 0, key.ID().String()); err != nil {
+		_ = RemoveKey(ctx, repo, key.ID())
+		return "", false, fmt.Errorf("failed to access repository with rotation key: %w", err)
+	}
+	printer.V("created rotation key %v\n", key.ID())
+	return password, false, nil
+}
+
+// finishRotation replaces the rotation key by a key which only holds the new
+// master key.
+func finishRotation(ctx context.Context, repo *Repository, password string) error {
+	keyOpts, err := currentKeyOptions(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	key, err := AddKey(ctx, repo, password, repo.Key().WithoutPrevious(), keyOpts)
+	if err != nil {
+		return fmt.Errorf("creating new key failed: %w", err)
+	}
+	if err := repo.SearchKey(ctx, password, 0, key.ID().String()); err != nil {
+		_ = RemoveKey(ctx, repo, key.ID())
+		return fmt.Errorf("failed to access repository with new key: %w", err)
+	}
+	return nil
+}
+
+// currentKeyOptions returns the options to create a key with the same
+// metadata as the current key.
+func currentKeyOptions(ctx context.Context, repo *Repository) (KeyOptions, error) {
+	k, err := LoadKey(ctx, repo, repo.KeyID())
+	if err != nil {
+		return KeyOptions{}, err
+	}
+
+	opts := KeyOptions{
+		KDF:          k.KDF,
+		Label:        k.Label,
+		Capabilities: k.Capabilities,
+		Admin:        repo.AdminKey(),
+	}
+	if k.Expires != nil {
+		opts.Expires = *k.Expires
+	}
+	return opts, nil
+}
+
+// sameMasterKey returns true if both keys contain the same encryption and
+// message authentication keys.
+func sameMasterKey(a, b *crypto.Key) bool {
+	return a != nil && b != nil && a.MACKey == b.MACKey && a.EncryptionKey == b.EncryptionKey
+}
+
+// rotateConfig replaces the config by a copy which is encrypted using the new
+// master key. For asymmetric repositories, the copy contains the new public
+// key. A backup of the old config file is written to a temporary directory
+// first, such that the repository can be recovered if the replacement is
+// interrupted on a backend which does not support atomic replacement.
+func rotateConfig(ctx context.Context, repo *Repository, strict *crypto.Key, printer progress.Printer) error {
+	rawConfigFile, err := repo.LoadRaw(ctx, restic.ConfigFile, restic.ID{})
+	if err != nil {
+		return fmt.Errorf("load config file failed: %w", err)
+	}
+	if _, err := repo.decryptUnpacked(restic.ConfigFile, bytes.Clone(rawConfigFile), strict); err == nil {
+		return nil
+	}
+
+	backupFileName, err := writeConfigBackup(rawConfigFile, "restic-rotate-master-key-")
+	if err != nil {
+		return err
+	}
+	printer.P("replacing config, a backup of the old config file is stored in %v\n", backupFileName)
+	return replaceConfig(ctx, repo, repo.Config(), rawConfigFile, backupFileName)
+}
+
+// packRotated returns true if the header of the pack id can be decrypted using
+// the new master key alone. Packs which cannot be read using either key are
+// reported as an error.
+func (r *Repository) packRotated(ctx context.Context, strict *crypto.Key, id restic.ID, size int64) (bool, error) {
+	h := backend.Handle{Type: restic.PackFile, Name: id.String()}
+	if _, _, _, err := pack.ListWithKey(strict, backend.ReaderAt(ctx, r.be, h), size); err == nil {
+		return true, nil
+	}
+
+	if _, _, _, err := r.listPack(ctx, id, size); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// rotatePacks repacks all blobs stored in packs which are encrypted using the
+// previous master key, and rewrites the index.
+func rotatePacks(ctx context.Context, repo *Repository, strict *crypto.Key, printer progress.Printer) error {
+	printer.P("loading indexes...\n")
+	if err := repo.LoadIndex(ctx, printer.NewCounter("index files loaded")); err != nil {
+		return err
+	}
+
+	printer.P("searching packs encrypted using the old master key\n")
+	var m sync.Mutex
+	oldPacks := restic.NewIDSet()
+	newPacks := restic.NewIDSet()
+	bar := printer.NewCounter("packs checked")
+	err := restic.ParallelList(ctx, repo, restic.PackFile, repo.Connections(), func(ctx context.Context, id restic.ID, size int64) error {
+		rotated, err := repo.packRotated(ctx, strict, id, size)
+		if err != nil {
+			return fmt.Errorf("pack %v cannot be read: %w", id.Str(), err)
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		if rotated {
+			newPacks.Insert(id)
+		} else {
+			oldPacks.Insert(id)
+		}
+		bar.Add(1)
+		return nil
+	})
+	bar.Done()
+	if err != nil {
+		return err
+	}
+
+	oldIndexes := 0
+	err = forAllUnpacked(ctx, repo, restic.IndexFile, strict, func(_ restic.ID, _ []byte, rotated bool) error {
+		if !rotated {
+			oldIndexes++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(oldPacks) == 0 && oldIndexes == 0 {
+		return nil
+	}
+
+	// only blobs without a copy in a pack using the new master key must be
+	// repacked, old packs which are not indexed are removed below
+	repackPacks := restic.NewIDSet()
+	keepBlobs := restic.NewBlobSet()
+	for pb := range repo.ListPacksFromIndex(ctx, oldPacks) {
+		repackPacks.Insert(pb.PackID)
+		for _, blob := range pb.Blobs {
+			if !hasBlobCopy(repo, blob.BlobHandle, newPacks) {
+				keepBlobs.Insert(blob.BlobHandle)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if len(repackPacks) != 0 {
+		printer.P("re-encrypting %d packs\n", len(repackPacks))
+		bar := printer.NewCounter("packs repacked")
+		bar.SetMax(uint64(len(repackPacks)))
+		_, err := Repack(ctx, repo, repo, repackPacks, keepBlobs, bar, printer.P)
+		bar.Done()
+		if err != nil {
+			return err
+		}
+		if keepBlobs.Len() != 0 {
+			return errors.Errorf("%v was not repacked", keepBlobs)
+		}
+	}
+
+	err = rewriteIndexFiles(ctx, repo, oldPacks, nil, nil, printer)
+	if err != nil {
+		return err
+	}
+
+	if len(oldPacks) != 0 {
+		printer.P("removing %d old packs\n", len(oldPacks))
+		if err := deleteFiles(ctx, false, repo, oldPacks, restic.PackFile, printer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasBlobCopy returns true if the blob bh is stored in one of the packs.
+func hasBlobCopy(repo *Repository, bh restic.BlobHandle, packs restic.IDSet) bool {
+	for _, pb := range repo.LookupBlob(bh.Type, bh.ID) {
+		if packs.Has(pb.PackID) {
+			return true
+		}
+	}
+	return false
+}
+
+// rotateSnapshots saves copies of all snapshots encrypted using the previous
+// master key, points the holds to the copies and removes the old snapshots.
+func rotateSnapshots(ctx context.Context, repo *Repository, strict *crypto.Key, printer progress.Printer) error {
+	snapshots, err := rotateUnpacked(ctx, repo, restic.SnapshotFile, strict, nil, printer)
+	if err != nil {
+		return err
+	}
+
+	holds, err := rotateUnpacked(ctx, repo, restic.HoldFile, strict, func(buf []byte) ([]byte, error) {
+		var h restic.Hold
+		if err := json.Unmarshal(buf, &h); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal")
+		}
+		if id, ok := snapshots[h.Snapshot]; ok {
+			h.Snapshot = id
+		}
+		return json.Marshal(&h)
+	}, printer)
+	if err != nil {
+		return err
+	}
+
+	// old holds are removed first, as they can only be updated as long as
+	// the old snapshots exist
+	if len(holds) != 0 {
+		if err := deleteFiles(ctx, false, repo, mappedIDs(holds), restic.HoldFile, printer); err != nil {
+			return err
+		}
+	}
+	if len(snapshots) != 0 {
+		if err := deleteFiles(ctx, false, repo, mappedIDs(snapshots), restic.SnapshotFile, printer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateJournal saves copies of all journal files encrypted using the
+// previous master key. Journal files cannot be removed using the repository
+// in append-only repositories, thus the old files are removed directly.
+func rotateJournal(ctx context.Context, repo *Repository, strict *crypto.Key, printer progress.Printer) error {
+	files, err := rotateUnpacked(ctx, repo, restic.JournalFile, strict, nil, printer)
+	if err != nil {
+		return err
+	}
+
+	for id := range files {
+		h := backend.Handle{Type: restic.JournalFile, Name: id.String()}
+		if err := repo.be.Remove(ctx, h); err != nil {
+			return err
+		}
+		printer.VV("removed %v/%v\n", restic.JournalFile, id)
+	}
+	return nil
+}
+
+// rotateUnpacked saves copies of all files of type t which are encrypted
+// using the previous master key. The content is passed through rewrite if it
+// is not nil. Copies which already exist are reused, which allows resuming an
+// interrupted rotation. Returned is a map from the old to the new file IDs,
+// the old files are not removed.
+func rotateUnpacked(ctx context.Context, repo *Repository, t restic.FileType, strict *crypto.Key, rewrite func([]byte) ([]byte, error), printer progress.Printer) (map[restic.ID]restic.ID, error) {
+	// the new files are identified by the hash of their content
+	newFiles := make(map[restic.ID]restic.ID)
+	oldFiles := make(map[restic.ID][]byte)
+	err := forAllUnpacked(ctx, repo, t, strict, func(id restic.ID, plaintext []byte, rotated bool) error {
+		if rotated {
+			newFiles[restic.Hash(plaintext)] = id
+		} else {
+			oldFiles[id] = plaintext
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(oldFiles) == 0 {
+		return nil, nil
+	}
+
+	printer.P("re-encrypting %d %v files\n", len(oldFiles), t)
+	bar := printer.NewCounter("files re-encrypted")
+	bar.SetMax(uint64(len(oldFiles)))
+	defer bar.Done()
+
+	ids := make(map[restic.ID]restic.ID, len(oldFiles))
+	for id, plaintext := range oldFiles {
+		if rewrite != nil {
+			plaintext, err = rewrite(plaintext)
+			if err != nil {
+				return nil, fmt.Errorf("%v %v: %w", t, id.Str(), err)
+			}
+		}
+
+		hash := restic.Hash(plaintext)
+		newID, ok := newFiles[hash]
+		if !ok {
+			newID, err = (&internalRepository{repo}).SaveUnpacked(ctx, t, plaintext)
+			if err != nil {
+				return nil, err
+			}
+			newFiles[hash] = newID
+		}
+		printer.VV("re-encrypted %v/%v as %v\n", t, id.Str(), newID.Str())
+		ids[id] = newID
+		bar.Add(1)
+	}
+	return ids, nil
+}
+
+// forAllUnpacked calls fn for all files of type t. rotated is true if the file
+// can be decrypted using the new master key alone. fn is not called
+// concurrently.
+func forAllUnpacked(ctx context.Context, repo *Repository, t restic.FileType, strict *crypto.Key, fn func(id restic.ID, plaintext []byte, rotated bool) error) error {
+	var m sync.Mutex
+	return restic.ParallelList(ctx, repo, t, repo.Connections(), func(ctx context.Context, id restic.ID, _ int64) error {
+		buf, err := repo.LoadRaw(ctx, t, id)
+		if err != nil {
+			return err
+		}
+
+		rotated := true
+		plaintext, err := repo.decryptUnpacked(t, buf, strict)
+		if err != nil {
+			rotated = false
+			plaintext, err = repo.decryptUnpacked(t, buf, repo.key)
+		}
+		if err != nil {
+			return fmt.Errorf("%v %v cannot be decrypted: %w", t, id.Str(), err)
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		return fn(id, plaintext, rotated)
+	})
+}
+
+// mappedIDs returns the keys of the map ids.
+func mappedIDs(ids map[restic.ID]restic.ID) restic.IDSet {
+	set := restic.NewIDSet()
+	for id := range ids {
+		set.Insert(id)
+	}
+	return set
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/crypto"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+	"github.com/restic/restic/internal/ui/progress"
+	"golang.org/x/sync/errgroup"
+)
+
+const rotatedPassword = "rotated"
+
+func testRotationRepository(t *testing.T, opts InitOptions) (*Repository, backend.Backend, restic.IDs) {
+	be := TestBackend(t)
+	repo, blobs := testRotationRepositoryWithBackend(t, be, opts)
+	return repo, be, blobs
+}
+
+func testRotationRepositoryWithBackend(t *testing.T, be backend.Backend, opts InitOptions) (*Repository, restic.IDs) {
+	TestUseLowSecurityKDFParameters(t)
+	restic.TestDisableCheckPolynomial(t)
+	ctx := context.TODO()
+
+	repo, err := New(be, Options{})
+	rtest.OK(t, err)
+	pol := testChunkerPol
+	opts.ChunkerPolynomial = &pol
+	rtest.OK(t, repo.Init(ctx, restic.StableRepoVersion, rtest.TestPassword, opts))
+
+	var blobs restic.IDs
+	var wg errgroup.Group
+	repo.StartPackUploader(ctx, &wg)
+	for i := 0; i < 10; i++ {
+		id, _, _, err := repo.SaveBlob(ctx, restic.DataBlob, rtest.Random(i, 1000), restic.ID{}, false)
+		rtest.OK(t, err)
+		blobs = append(blobs, id)
+	}
+	rtest.OK(t, repo.Flush(ctx))
+
+	sn := &restic.Snapshot{Time: time.Now(), Tree: &blobs[0]}
+	snID, err := restic.SaveSnapshot(ctx, repo, sn)
+	rtest.OK(t, err)
+	_, err = restic.SaveHold(ctx, repo, restic.NewHold(snID, "test", time.Time{}))
+	rtest.OK(t, err)
+
+	// a second key which must be removed by the rotation
+	_, err = AddKey(ctx, repo, "other", repo.Key(), KeyOptions{Admin: repo.AdminKey()})
+	rtest.OK(t, err)
+	return repo, blobs
+}
+
+func openRotationRepository(t *testing.T, be backend.Backend, password string, keyHint string) *Repository {
+	repo, err := New(be, Options{})
+	rtest.OK(t, err)
+	rtest.OK(t, repo.SearchKey(context.TODO(), password, 0, keyHint))
+	return repo
+}
+
+func rotateOptions(password string) RotateMasterKeyOptions {
+	return RotateMasterKeyOptions{
+		Password: password,
+		NewPassword: func() (string, error) {
+			return rotatedPassword, nil
+		},
+	}
+}
+
+func checkRotated(t *testing.T, be backend.Backend, old *crypto.Key, blobs restic.IDs) {
+	ctx := context.TODO()
+	repo := openRotationRepository(t, be, rotatedPassword, "")
+	rtest.Assert(t, repo.Key().Previous == nil, "new key still contains the previous master key")
+	rtest.Assert(t, !sameMasterKey(repo.Key(), old), "master key was not replaced")
+
+	var keys int
+	rtest.OK(t, repo.List(ctx, restic.KeyFile, func(_ restic.ID, _ int64) error {
+		keys++
+		return nil
+	}))
+	rtest.Equals(t, 1, keys)
+
+	// nothing can be decrypted using the old master key
+	rtest.OK(t, repo.List(ctx, restic.PackFile, func(id restic.ID, size int64) error {
+		rotated, err := repo.packRotated(ctx, repo.Key(), id, size)
+		rtest.OK(t, err)
+		rtest.Assert(t, rotated, "pack %v was not rotated", id.Str())
+		return nil
+	}))
+	for _, t2 := range []restic.FileType{restic.IndexFile, restic.SnapshotFile, restic.HoldFile, restic.JournalFile} {
+		rtest.OK(t, forAllUnpacked(ctx, repo, t2, repo.Key(), func(id restic.ID, _ []byte, rotated bool) error {
+			rtest.Assert(t, rotated, "%v %v was not rotated", t2, id.Str())
+			return nil
+		}))
+	}
+
+	rtest.OK(t, repo.LoadIndex(ctx, nil))
+	for i, id := range blobs {
+		buf, err := repo.LoadBlob(ctx, restic.DataBlob, id, nil)
+		rtest.OK(t, err)
+		rtest.Equals(t, rtest.Random(i, 1000), buf)
+	}
+
+	holds, err := restic.LoadHolds(ctx, repo, repo, time.Now())
+	rtest.OK(t, err)
+	rtest.Equals(t, 1, len(holds))
+	var snapshots int
+	rtest.OK(t, restic.ForAllSnapshots(ctx, repo, repo, nil, func(id restic.ID, sn *restic.Snapshot, err error) error {
+		rtest.OK(t, err)
+		_, held := holds.Reason(id)
+		rtest.Assert(t, held, "hold does not point to snapshot %v", id.Str())
+		snapshots++
+		return nil
+	}))
+	rtest.Equals(t, 1, snapshots)
+}
+
+func TestRotateMasterKey(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts InitOptions
+	}{
+		{"symmetric", InitOptions{}},
+		{"asymmetric", InitOptions{Asymmetric: true}},
+		{"append-only", InitOptions{AppendOnly: true}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo, be, blobs := testRotationRepository(t, test.opts)
+			old := repo.Key()
+			oldConfig := repo.Config()
+
+			rtest.OK(t, RotateMasterKey(context.TODO(), repo, rotateOptions(rtest.TestPassword), &progress.NoopPrinter{}))
+			checkRotated(t, be, old, blobs)
+
+			if test.opts.Asymmetric {
+				rtest.Assert(t, repo.Config().DataPublicKey != oldConfig.DataPublicKey, "public key was not replaced")
+			}
+			if test.opts.AppendOnly {
+				rtest.Assert(t, repo.AdminKey() != nil, "new key is not an admin key")
+				rtest.Assert(t, len(listJournal(t, repo)) > 0, "missing journal entries")
+			}
+		})
+	}
+}
+
+func TestRotateMasterKeyResume(t *testing.T) {
+	ctx := context.TODO()
+	repo, be, blobs := testRotationRepository(t, InitOptions{Asymmetric: true})
+	old := repo.Key()
+	printer := &progress.NoopPrinter{}
+
+	// interrupt the rotation after the packs were re-encrypted
+	_, _, err := startRotation(ctx, repo, rotateOptions(rtest.TestPassword), printer)
+	rtest.OK(t, err)
+	strict := repo.Key().WithoutPrevious()
+	rtest.OK(t, rotateConfig(ctx, repo, strict, printer))
+	rtest.OK(t, rotatePacks(ctx, repo, strict, printer))
+
+	// the old password cannot open the repository anymore
+	r, err := New(be, Options{})
+	rtest.OK(t, err)
+	rtest.Assert(t, r.SearchKey(ctx, rtest.TestPassword, 0, "") != nil, "old key can still open the repository")
+
+	// data added using the rotation key is kept
+	repo = openRotationRepository(t, be, rotatedPassword, "")
+	rtest.Assert(t, repo.Key().Previous != nil, "expected rotation key")
+	rtest.OK(t, repo.LoadIndex(ctx, nil))
+	var wg errgroup.Group
+	repo.StartPackUploader(ctx, &wg)
+	id, _, _, err := repo.SaveBlob(ctx, restic.DataBlob, rtest.Random(10, 1000), restic.ID{}, false)
+	rtest.OK(t, err)
+	rtest.OK(t, repo.Flush(ctx))
+	blobs = append(blobs, id)
+
+	repo = openRotationRepository(t, be, rotatedPassword, "")
+	rtest.OK(t, RotateMasterKey(ctx, repo, rotateOptions(rotatedPassword), printer))
+	checkRotated(t, be, old, blobs)
+}
+
+func TestRotateMasterKeyResumeKeys(t *testing.T) {
+	ctx := context.TODO()
+	repo, be, blobs := testRotationRepository(t, InitOptions{})
+	old := repo.Key()
+	printer := &progress.NoopPrinter{}
+
+	// interrupt the rotation before the old keys are removed
+	_, _, err := startRotation(ctx, repo, rotateOptions(rtest.TestPassword), printer)
+	rtest.OK(t, err)
+	rotationKeyID := repo.KeyID()
+	strict := repo.Key().WithoutPrevious()
+	rtest.OK(t, rotateConfig(ctx, repo, strict, printer))
+	rtest.OK(t, rotatePacks(ctx, repo, strict, printer))
+	rtest.OK(t, rotateSnapshots(ctx, repo, strict, printer))
+	rtest.OK(t, rotateJournal(ctx, repo, strict, printer))
+	rtest.OK(t, finishRotation(ctx, repo, rotatedPassword))
+	rtest.Assert(t, repo.KeyID() != rotationKeyID, "rotation key was not replaced")
+
+	repo = openRotationRepository(t, be, rotatedPassword, repo.KeyID().String())
+	rtest.OK(t, RotateMasterKey(ctx, repo, rotateOptions(rotatedPassword), printer))
+	checkRotated(t, be, old, blobs)
+}
+
+func TestRotateMasterKeyStale(t *testing.T) {
+	ctx := context.TODO()
+	repo, be, blobs := testRotationRepository(t, InitOptions{})
+	old := repo.Key()
+	oldKeyID := repo.KeyID()
+	printer := &progress.NoopPrinter{}
+
+	// a rotation interrupted before the config was replaced is started again
+	_, _, err := startRotation(ctx, repo, RotateMasterKeyOptions{
+		Password: rtest.TestPassword,
+		NewPassword: func() (string, error) {
+			return "abandoned", nil
+		},
+	}, printer)
+	rtest.OK(t, err)
+
+	repo = openRotationRepository(t, be, rtest.TestPassword, oldKeyID.String())
+	rtest.OK(t, RotateMasterKey(ctx, repo, rotateOptions(rtest.TestPassword), printer))
+	checkRotated(t, be, old, blobs)
+}
+
+func TestRotateMasterKeyConfigFailure(t *testing.T) {
+	ctx := context.TODO()
+	be := &failBackend{
+		ConfigFileSavesUntilError: 1,
+		Backend:                   TestBackend(t),
+	}
+	repo, blobs := testRotationRepositoryWithBackend(t, be, InitOptions{})
+	old := repo.Key()
+
+	err := RotateMasterKey(ctx, repo, rotateOptions(rtest.TestPassword), &progress.NoopPrinter{})
+	var configErr *upgradeRepoV2Error
+	rtest.Assert(t, errors.As(err, &configErr), "unexpected error %v", err)
+	rtest.Assert(t, configErr.ReuploadOldConfigError != nil, "expected reupload error")
+
+	// the repository can be recovered using the backup of the config file
+	buf, err := os.ReadFile(configErr.BackupFilePath)
+	rtest.OK(t, err)
+	rtest.OK(t, os.Remove(configErr.BackupFilePath))
+	rtest.OK(t, os.Remove(filepath.Dir(configErr.BackupFilePath)))
+	rtest.OK(t, be.Backend.Save(ctx, backend.Handle{Type: restic.ConfigFile}, backend.NewByteReader(buf, be.Hasher())))
+
+	be.ConfigFileSavesUntilError = 1
+	repo = openRotationRepository(t, be, rotatedPassword, "")
+	rtest.OK(t, RotateMasterKey(ctx, repo, rotateOptions(rotatedPassword), &progress.NoopPrinter{}))
+	checkRotated(t, be, old, blobs)
+}
@@ -28,10 +28,19 @@ var paramsOnce sync.Once
 func TestUseLowSecurityKDFParameters(t logger) {
 	t.Logf("using low-security KDF parameters for test")
 	paramsOnce.Do(func() {
-		params = &crypto.Params{
-			N: 128,
-			R: 1,
-			P: 1,
+		paramsMutex.Lock()
+		defer paramsMutex.Unlock()
+		params[crypto.KDFScrypt] = crypto.Params{
+			KDF: crypto.KDFScrypt,
+			N:   128,
+			R:   1,
+			P:   1,
+		}
+		params[crypto.KDFArgon2id] = crypto.Params{
+			KDF:     crypto.KDFArgon2id,
+			Time:    1,
+			Memory:  64,
+			Threads: 1,
 		}
 	})
 }
@@ -64,7 +73,7 @@ func TestRepositoryWithBackend(t testing.TB, be backend.Backend, version uint, o
 		version = restic.StableRepoVersion
 	}
 	pol := testChunkerPol
-	err = repo.Init(context.TODO(), version, test.TestPassword, &pol)
+	err = repo.Init(context.TODO(), version, test.TestPassword, InitOptions{ChunkerPolynomial: &pol})
 	if err != nil {
 		t.Fatalf("TestRepository(): initialize repo failed: %v", err)
 	}
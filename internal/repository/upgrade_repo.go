@@ -30,7 +30,8 @@ func (err *upgradeRepoV2Error) Unwrap() error {
 	return err.UploadNewConfigError
 }
 
-func upgradeRepository(ctx context.Context, repo *Repository) error {
+// saveNewConfig saves cfg as the new config file of the repository.
+func saveNewConfig(ctx context.Context, repo *Repository, cfg restic.Config) error {
 	h := backend.Handle{Type: backend.ConfigFile}
 
 	if !repo.be.Properties().HasAtomicReplace {
@@ -41,10 +42,6 @@ func upgradeRepository(ctx context.Context, repo *Repository) error {
 		}
 	}
 
-	// upgrade config
-	cfg := repo.Config()
-	cfg.Version = 2
-
 	err := restic.SaveConfig(ctx, &internalRepository{repo}, cfg)
 	if err != nil {
 		return fmt.Errorf("save new config file failed: %w", err)
@@ -53,32 +50,29 @@ func upgradeRepository(ctx context.Context, repo *Repository) error {
 	return nil
 }
 
-func UpgradeRepo(ctx context.Context, repo *Repository) error {
-	if repo.Config().Version != 1 {
-		return fmt.Errorf("repository has version %v, only upgrades from version 1 are supported", repo.Config().Version)
-	}
-
-	tempdir, err := os.MkdirTemp("", "restic-migrate-upgrade-repo-v2-")
-	if err != nil {
-		return fmt.Errorf("create temp dir failed: %w", err)
-	}
-
-	h := backend.Handle{Type: restic.ConfigFile}
-
-	// read raw config file and save it to a temp dir, just in case
-	rawConfigFile, err := repo.LoadRaw(ctx, restic.ConfigFile, restic.ID{})
+// writeConfigBackup writes the raw config file to a new temporary directory
+// whose name starts with tempPrefix, and returns the path of the backup.
+func writeConfigBackup(rawConfigFile []byte, tempPrefix string) (string, error) {
+	tempdir, err := os.MkdirTemp("", tempPrefix)
 	if err != nil {
-		return fmt.Errorf("load config file failed: %w", err)
+		return "", fmt.Errorf("create temp dir failed: %w", err)
 	}
 
 	backupFileName := filepath.Join(tempdir, "config")
 	err = os.WriteFile(backupFileName, rawConfigFile, 0600)
 	if err != nil {
-		return fmt.Errorf("write config file backup to %v failed: %w", tempdir, err)
+		return "", fmt.Errorf("write config file backup to %v failed: %w", tempdir, err)
 	}
+	return backupFileName, nil
+}
 
-	// run the upgrade
-	err = upgradeRepository(ctx, repo)
+// replaceConfig replaces the config file rawConfigFile by cfg. If this fails,
+// the original file is uploaded again. The backup of the original file at
+// backupFileName is removed only if the new config was saved successfully.
+func replaceConfig(ctx context.Context, repo *Repository, cfg restic.Config, rawConfigFile []byte, backupFileName string) error {
+	h := backend.Handle{Type: restic.ConfigFile}
+
+	err := saveNewConfig(ctx, repo, cfg)
 	if err != nil {
 
 		// build an error we can return to the caller
@@ -98,6 +92,28 @@ func UpgradeRepo(ctx context.Context, repo *Repository) error {
 	}
 
 	_ = os.Remove(backupFileName)
-	_ = os.Remove(tempdir)
+	_ = os.Remove(filepath.Dir(backupFileName))
 	return nil
 }
+
+func UpgradeRepo(ctx context.Context, repo *Repository) error {
+	if repo.Config().Version != 1 {
+		return fmt.Errorf("repository has version %v, only upgrades from version 1 are supported", repo.Config().Version)
+	}
+
+	// read raw config file and save it to a temp dir, just in case
+	rawConfigFile, err := repo.LoadRaw(ctx, restic.ConfigFile, restic.ID{})
+	if err != nil {
+		return fmt.Errorf("load config file failed: %w", err)
+	}
+
+	backupFileName, err := writeConfigBackup(rawConfigFile, "restic-migrate-upgrade-repo-v2-")
+	if err != nil {
+		return err
+	}
+
+	// run the upgrade
+	cfg := repo.Config()
+	cfg.Version = 2
+	return replaceConfig(ctx, repo, cfg, rawConfigFile, backupFileName)
+}
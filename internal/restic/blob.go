@@ -35,6 +35,9 @@ func (b Blob) IsCompressed() bool {
 type PackedBlob struct {
 	Blob
 	PackID ID
+	// PackKey is only set for packs of asymmetric repositories, it is used
+	// to derive the key to decrypt the blob.
+	PackKey crypto.PublicKey
 }
 
 // BlobHandle identifies a blob of a given type.
@@ -0,0 +1,102 @@
+package restic
+
+import (
+	"io"
+	"math/bits"
+	"sync"
+
+	"github.com/restic/chunker"
+	"github.com/restic/restic/internal/errors"
+)
+
+// DefaultChunkerAvgSize is the average chunk size used by the chunker unless
+// the repository config specifies otherwise.
+const DefaultChunkerAvgSize = 1 << 20
+
+// Limits for the chunk sizes which can be stored in the repository config.
+const (
+	MinChunkerMinSize = 16 * 1024
+	MaxChunkerMaxSize = 64 * 1024 * 1024
+)
+
+// ChunkerParams contains the parameters for content defined chunking.
+type ChunkerParams struct {
+	Pol     chunker.Pol
+	MinSize uint
+	MaxSize uint
+	// AvgSize is the average number of bytes after MinSize until a chunk
+	// boundary is found, it must be a power of two.
+	AvgSize uint
+}
+
+// ChunkerParams returns the chunker parameters of the repository. Chunk sizes
+// which are not set in the config are replaced by the defaults.
+func (cfg Config) ChunkerParams() ChunkerParams {
+	p := ChunkerParams{
+		Pol:     cfg.ChunkerPolynomial,
+		MinSize: cfg.ChunkerMinSize,
+		MaxSize: cfg.ChunkerMaxSize,
+		AvgSize: cfg.ChunkerAvgSize,
+	}
+	if p.MinSize == 0 {
+		p.MinSize = chunker.MinSize
+	}
+	if p.MaxSize == 0 {
+		p.MaxSize = chunker.MaxSize
+	}
+	if p.AvgSize == 0 {
+		p.AvgSize = DefaultChunkerAvgSize
+	}
+	return p
+}
+
+// IsDefault returns true if the chunk sizes match the defaults.
+func (p ChunkerParams) IsDefault() bool {
+	return p.MinSize == chunker.MinSize && p.MaxSize == chunker.MaxSize && p.AvgSize == DefaultChunkerAvgSize
+}
+
+var defaultZeroChunkOnce sync.Once
+var defaultZeroChunkID ID
+
+// ZeroChunk returns the ID of an all-zero chunk with size MinSize. Large
+// all-zero regions of a file are split into such chunks. The ID for the default
+// minimum size is cached.
+func (p ChunkerParams) ZeroChunk() ID {
+	if p.MinSize != chunker.MinSize {
+		return Hash(make([]byte, p.MinSize))
+	}
+	defaultZeroChunkOnce.Do(func() {
+		defaultZeroChunkID = Hash(make([]byte, chunker.MinSize))
+	})
+	return defaultZeroChunkID
+}
+
+// Check returns an error if the chunk sizes are invalid.
+func (p ChunkerParams) Check() error {
+	if p.MinSize < MinChunkerMinSize {
+		return errors.Errorf("minimum chunk size %d is smaller than %d", p.MinSize, MinChunkerMinSize)
+	}
+	if p.MaxSize > MaxChunkerMaxSize {
+		return errors.Errorf("maximum chunk size %d is larger than %d", p.MaxSize, MaxChunkerMaxSize)
+	}
+	if bits.OnesCount(p.AvgSize) != 1 {
+		return errors.Errorf("average chunk size %d is not a power of two", p.AvgSize)
+	}
+	if p.MinSize >= p.AvgSize || p.AvgSize >= p.MaxSize {
+		return errors.Errorf("chunk sizes must satisfy min < avg < max, got min %d, avg %d, max %d", p.MinSize, p.AvgSize, p.MaxSize)
+	}
+	return nil
+}
+
+// NewChunker returns a chunker which splits the data read from rd.
+func (p ChunkerParams) NewChunker(rd io.Reader) *chunker.Chunker {
+	c := chunker.NewWithBoundaries(rd, p.Pol, p.MinSize, p.MaxSize)
+	c.SetAverageBits(bits.TrailingZeros(p.AvgSize))
+	return c
+}
+
+// ResetChunker reinitializes the chunker c to split the data read from rd.
+func (p ChunkerParams) ResetChunker(c *chunker.Chunker, rd io.Reader) {
+	c.ResetWithBoundaries(rd, p.Pol, p.MinSize, p.MaxSize)
+	c.SetAverageBits(bits.TrailingZeros(p.AvgSize))
+}
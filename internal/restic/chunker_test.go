@@ -0,0 +1,67 @@
+package restic_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/restic/chunker"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestChunkerParamsCheck(t *testing.T) {
+	rtest.OK(t, restic.Config{}.ChunkerParams().Check())
+	rtest.Assert(t, restic.Config{}.ChunkerParams().IsDefault(), "expected default chunker parameters")
+
+	for _, test := range []struct {
+		min, avg, max uint
+		valid         bool
+	}{
+		{64 * 1024, 256 * 1024, 2 * 1024 * 1024, true},
+		{16 * 1024, 32 * 1024, 64 * 1024, true},
+		{8 * 1024, 32 * 1024, 64 * 1024, false},
+		{64 * 1024, 300 * 1024, 2 * 1024 * 1024, false},
+		{512 * 1024, 256 * 1024, 2 * 1024 * 1024, false},
+		{64 * 1024, 256 * 1024, 256 * 1024, false},
+		{1024 * 1024, 32 * 1024 * 1024, 128 * 1024 * 1024, false},
+	} {
+		params := restic.ChunkerParams{MinSize: test.min, AvgSize: test.avg, MaxSize: test.max}
+		err := params.Check()
+		rtest.Assert(t, (err == nil) == test.valid, "unexpected result for %v: %v", params, err)
+	}
+}
+
+func TestChunkerParamsChunkSizes(t *testing.T) {
+	params := restic.Config{
+		ChunkerPolynomial: chunker.Pol(0x3DA3358B4DC173),
+		ChunkerMinSize:    16 * 1024,
+		ChunkerAvgSize:    32 * 1024,
+		ChunkerMaxSize:    64 * 1024,
+	}.ChunkerParams()
+	data := rtest.Random(23, 4*1024*1024)
+	buf := make([]byte, params.MaxSize)
+
+	chnker := params.NewChunker(bytes.NewReader(data))
+	var chunks, size int
+	for {
+		chunk, err := chnker.Next(buf)
+		if err == io.EOF {
+			break
+		}
+		rtest.OK(t, err)
+		rtest.Assert(t, chunk.Length <= params.MaxSize, "chunk %d is larger than the maximum size", chunks)
+		chunks++
+		size += int(chunk.Length)
+	}
+	rtest.Equals(t, len(data), size)
+	// the average chunk size is roughly min + avg
+	rtest.Assert(t, chunks > 50 && chunks < 150, "unexpected number of chunks %d", chunks)
+}
+
+func TestChunkerParamsZeroChunk(t *testing.T) {
+	for _, minSize := range []uint{chunker.MinSize, 64 * 1024} {
+		params := restic.ChunkerParams{MinSize: minSize}
+		rtest.Equals(t, restic.Hash(make([]byte, minSize)), params.ZeroChunk())
+	}
+}
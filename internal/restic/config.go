@@ -5,6 +5,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/restic/restic/internal/crypto"
 	"github.com/restic/restic/internal/errors"
 
 	"github.com/restic/restic/internal/debug"
@@ -17,15 +18,38 @@ type Config struct {
 	Version           uint        `json:"version"`
 	ID                string      `json:"id"`
 	ChunkerPolynomial chunker.Pol `json:"chunker_polynomial"`
+
+	// ChunkerMinSize, ChunkerMaxSize and ChunkerAvgSize override the default
+	// chunk sizes, see ChunkerParams. They are either all set or all zero.
+	ChunkerMinSize uint `json:"chunker_min_size,omitempty"`
+	ChunkerMaxSize uint `json:"chunker_max_size,omitempty"`
+	ChunkerAvgSize uint `json:"chunker_avg_size,omitempty"`
+
+	// AppendOnly restricts removing files other than locks to admin keys.
+	// Each removal is recorded in the deletion journal, which is signed
+	// using the private key matching the hex-encoded ed25519 AdminPublicKey.
+	AppendOnly     bool   `json:"append_only,omitempty"`
+	AdminPublicKey string `json:"admin_public_key,omitempty"`
+
+	// DataPublicKey is the hex-encoded X25519 public key of an asymmetric
+	// repository. Blobs and snapshots are encrypted such that they can only
+	// be decrypted using the matching private key.
+	DataPublicKey string `json:"data_public_key,omitempty"`
 }
 
 const MinRepoVersion = 1
-const MaxRepoVersion = 2
+const MaxRepoVersion = 3
 
 // StableRepoVersion is the version that is written to the config when a repository
 // is newly created with Init().
 const StableRepoVersion = 2
 
+// ExtendedRepoVersion is the first version which supports asymmetric
+// repositories, custom chunk sizes and append-only repositories. Older clients
+// refuse to open repositories using this version instead of misinterpreting
+// the data.
+const ExtendedRepoVersion = 3
+
 // JSONUnpackedLoader loads unpacked JSON.
 type JSONUnpackedLoader interface {
 	LoadJSONUnpacked(context.Context, FileType, ID, interface{}) error
@@ -51,6 +75,23 @@ func CreateConfig(version uint) (Config, error) {
 	return cfg, nil
 }
 
+// RequiredVersion returns the lowest repository version which supports the
+// features used by the config.
+func (cfg Config) RequiredVersion() uint {
+	if cfg.DataPublicKey != "" {
+		return ExtendedRepoVersion
+	}
+	// older clients would split files using the default chunk sizes
+	if cfg.ChunkerMinSize != 0 || cfg.ChunkerMaxSize != 0 || cfg.ChunkerAvgSize != 0 {
+		return ExtendedRepoVersion
+	}
+	// older clients would remove files without recording them in the journal
+	if cfg.AppendOnly {
+		return ExtendedRepoVersion
+	}
+	return MinRepoVersion
+}
+
 var checkPolynomial = true
 var checkPolynomialOnce sync.Once
 
@@ -77,6 +118,9 @@ func LoadConfig(ctx context.Context, r LoaderUnpacked) (Config, error) {
 	if cfg.Version < MinRepoVersion || cfg.Version > MaxRepoVersion {
 		return Config{}, errors.Errorf("unsupported repository version %v", cfg.Version)
 	}
+	if cfg.Version < cfg.RequiredVersion() {
+		return Config{}, errors.Errorf("repository version %v does not support the features used by the config, version %v is required", cfg.Version, cfg.RequiredVersion())
+	}
 
 	if checkPolynomial {
 		if !cfg.ChunkerPolynomial.Irreducible() {
@@ -84,6 +128,18 @@ func LoadConfig(ctx context.Context, r LoaderUnpacked) (Config, error) {
 		}
 	}
 
+	if cfg.ChunkerMinSize != 0 || cfg.ChunkerMaxSize != 0 || cfg.ChunkerAvgSize != 0 {
+		if err := cfg.ChunkerParams().Check(); err != nil {
+			return Config{}, errors.Wrap(err, "invalid chunker parameters")
+		}
+	}
+
+	if cfg.DataPublicKey != "" {
+		if _, err := crypto.ParsePublicKey(cfg.DataPublicKey); err != nil {
+			return Config{}, errors.Wrap(err, "invalid data public key")
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -2,6 +2,7 @@ package restic_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/restic/restic/internal/restic"
@@ -63,3 +64,33 @@ func TestConfig(t *testing.T) {
 	rtest.Assert(t, cfg1 == cfg2,
 		"configs aren't equal: %v != %v", cfg1, cfg2)
 }
+
+func TestConfigRequiredVersion(t *testing.T) {
+	cfg, err := restic.CreateConfig(restic.StableRepoVersion)
+	rtest.OK(t, err)
+	rtest.Equals(t, uint(restic.MinRepoVersion), cfg.RequiredVersion())
+
+	// custom chunk sizes would be ignored by clients which only support version 2
+	chunkerCfg := cfg
+	chunkerCfg.ChunkerMinSize, chunkerCfg.ChunkerAvgSize, chunkerCfg.ChunkerMaxSize = 64*1024, 256*1024, 2*1024*1024
+	rtest.Equals(t, uint(restic.ExtendedRepoVersion), chunkerCfg.RequiredVersion())
+
+	// append-only mode would be ignored by clients which only support version 2
+	appendOnlyCfg := cfg
+	appendOnlyCfg.AppendOnly = true
+	rtest.Equals(t, uint(restic.ExtendedRepoVersion), appendOnlyCfg.RequiredVersion())
+
+	// the data public key would be ignored by clients which only support version 2
+	cfg.DataPublicKey = "0000000000000000000000000000000000000000000000000000000000000000"
+	rtest.Equals(t, uint(restic.ExtendedRepoVersion), cfg.RequiredVersion())
+
+	var buf []byte
+	rtest.OK(t, restic.SaveConfig(context.TODO(), saver{func(_ restic.FileType, data []byte) (restic.ID, error) {
+		buf = data
+		return restic.ID{}, nil
+	}}, cfg))
+	_, err = restic.LoadConfig(context.TODO(), loader{func(_ restic.FileType, _ restic.ID) ([]byte, error) {
+		return buf, nil
+	}})
+	rtest.Assert(t, err != nil && strings.Contains(err.Error(), "version 3 is required"), "unexpected error %v", err)
+}
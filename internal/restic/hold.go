@@ -0,0 +1,142 @@
+package restic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hold prevents a snapshot from being removed, for example because it must be
+// retained for legal reasons. A hold without an expiry time never expires.
+type Hold struct {
+	Snapshot ID         `json:"snapshot"`
+	Reason   string     `json:"reason,omitempty"`
+	Time     time.Time  `json:"time"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Hostname string     `json:"hostname,omitempty"`
+	Username string     `json:"username,omitempty"`
+
+	id *ID
+}
+
+// NewHold returns a hold on the snapshot with the given reason for the current
+// user. If expires is the zero time, the hold does not expire.
+func NewHold(snapshot ID, reason string, expires time.Time) *Hold {
+	h := &Hold{
+		Snapshot: snapshot,
+		Reason:   reason,
+		Time:     time.Now(),
+	}
+	if !expires.IsZero() {
+		h.Expires = &expires
+	}
+
+	h.Hostname, _ = os.Hostname()
+	if usr, err := user.Current(); err == nil {
+		h.Username = usr.Username
+	}
+	return h
+}
+
+// ID returns the hold's ID.
+func (h *Hold) ID() *ID {
+	return h.id
+}
+
+// Active returns true if the hold has not expired at time now.
+func (h *Hold) Active(now time.Time) bool {
+	return h.Expires == nil || now.Before(*h.Expires)
+}
+
+func (h *Hold) String() string {
+	s := "held"
+	if h.Expires != nil {
+		s += " until " + h.Expires.Format("2006-01-02 15:04:05")
+	}
+	if h.Reason != "" {
+		s += ": " + h.Reason
+	}
+	return s
+}
+
+// LoadHold loads the hold with the id and returns it.
+func LoadHold(ctx context.Context, loader LoaderUnpacked, id ID) (*Hold, error) {
+	h := &Hold{id: &id}
+	err := LoadJSONUnpacked(ctx, loader, HoldFile, id, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hold %v: %w", id.Str(), err)
+	}
+
+	return h, nil
+}
+
+// SaveHold saves the hold h and returns its ID.
+func SaveHold(ctx context.Context, repo SaverUnpacked[WriteableFileType], h *Hold) (ID, error) {
+	id, err := SaveJSONUnpacked(ctx, repo, WriteableHoldFile, h)
+	if err != nil {
+		return ID{}, err
+	}
+	h.id = &id
+	return id, nil
+}
+
+// ForAllHolds reads all holds in parallel and calls the given function. It is
+// guaranteed that the function is not run concurrently. If the called function
+// returns an error, this function is cancelled and also returns this error.
+func ForAllHolds(ctx context.Context, be Lister, loader LoaderUnpacked, fn func(ID, *Hold, error) error) error {
+	var m sync.Mutex
+
+	return ParallelList(ctx, be, HoldFile, loader.Connections(), func(ctx context.Context, id ID, _ int64) error {
+		h, err := LoadHold(ctx, loader, id)
+		m.Lock()
+		defer m.Unlock()
+		return fn(id, h, err)
+	})
+}
+
+// Holds contains the active holds, grouped by the ID of the held snapshot.
+type Holds map[ID][]*Hold
+
+// LoadHolds returns all holds which are active at time now.
+func LoadHolds(ctx context.Context, be Lister, loader LoaderUnpacked, now time.Time) (Holds, error) {
+	holds := make(Holds)
+	err := ForAllHolds(ctx, be, loader, func(_ ID, h *Hold, err error) error {
+		if err != nil {
+			return err
+		}
+		if h.Active(now) {
+			holds[h.Snapshot] = append(holds[h.Snapshot], h)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range holds {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Time.Before(list[j].Time)
+		})
+	}
+	return holds, nil
+}
+
+// Reason returns a description of the holds on the snapshot id, and whether
+// the snapshot is held at all.
+func (h Holds) Reason(id ID) (string, bool) {
+	list, ok := h[id]
+	if !ok {
+		return "", false
+	}
+
+	reasons := make([]string, 0, len(list))
+	for _, hold := range list {
+		reasons = append(reasons, hold.String())
+	}
+	return strings.Join(reasons, ", "), true
+}
@@ -0,0 +1,38 @@
+package restic_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestLoadHolds(t *testing.T) {
+	repo := repository.TestRepository(t)
+	now := time.Now()
+
+	held := restic.NewRandomID()
+	expired := restic.NewRandomID()
+	for _, h := range []*restic.Hold{
+		restic.NewHold(held, "litigation", time.Time{}),
+		restic.NewHold(held, "audit", now.Add(time.Hour)),
+		restic.NewHold(expired, "audit", now.Add(-time.Hour)),
+	} {
+		_, err := restic.SaveHold(context.TODO(), repo, h)
+		rtest.OK(t, err)
+	}
+
+	holds, err := restic.LoadHolds(context.TODO(), repo, repo, now)
+	rtest.OK(t, err)
+	rtest.Equals(t, 1, len(holds))
+	rtest.Equals(t, 2, len(holds[held]))
+
+	reason, ok := holds.Reason(held)
+	rtest.Assert(t, ok, "snapshot is not held")
+	rtest.Equals(t, "held: litigation, held until "+now.Add(time.Hour).Format("2006-01-02 15:04:05")+": audit", reason)
+	_, ok = holds.Reason(expired)
+	rtest.Assert(t, !ok, "expired hold is still active")
+}
@@ -210,6 +210,28 @@ func (node *Node) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// ContentID returns an ID which identifies the content of the node. It is
+// derived from the list of data blobs for files and from the target for
+// symlinks, while directories use the ID of their subtree. All other nodes
+// return the null ID.
+func (node Node) ContentID() ID {
+	switch node.Type {
+	case NodeTypeFile:
+		buf := make([]byte, 0, len(node.Content)*len(ID{}))
+		for _, id := range node.Content {
+			buf = append(buf, id[:]...)
+		}
+		return Hash(buf)
+	case NodeTypeDir:
+		if node.Subtree != nil {
+			return *node.Subtree
+		}
+	case NodeTypeSymlink:
+		return Hash([]byte(node.LinkTarget))
+	}
+	return ID{}
+}
+
 func (node Node) Equals(other Node) bool {
 	if node.Name != other.Name {
 		return false
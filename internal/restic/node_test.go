@@ -94,3 +94,16 @@ func TestSymlinkSerializationFormat(t *testing.T) {
 		test.Assert(t, n2.LinkTargetRaw == nil, "quoted link target is just a helper field and must be unset after decoding")
 	}
 }
+
+func TestNodeContentID(t *testing.T) {
+	blob1, blob2 := NewRandomID(), NewRandomID()
+	tree := NewRandomID()
+
+	file := Node{Type: NodeTypeFile, Content: IDs{blob1, blob2}}
+	test.Equals(t, file.ContentID(), Node{Type: NodeTypeFile, Name: "other", Content: IDs{blob1, blob2}}.ContentID())
+	test.Assert(t, file.ContentID() != (Node{Type: NodeTypeFile, Content: IDs{blob2, blob1}}).ContentID(), "blob order must change the content ID")
+
+	test.Equals(t, tree, Node{Type: NodeTypeDir, Subtree: &tree}.ContentID())
+	test.Assert(t, !Node{Type: NodeTypeSymlink, LinkTarget: "foo"}.ContentID().IsNull(), "symlink content ID must not be null")
+	test.Equals(t, ID{}, Node{Type: NodeTypeFifo}.ContentID())
+}
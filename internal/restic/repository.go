@@ -77,6 +77,8 @@ const (
 	SnapshotFile = backend.SnapshotFile
 	IndexFile    = backend.IndexFile
 	ConfigFile   = backend.ConfigFile
+	JournalFile  = backend.JournalFile
+	HoldFile     = backend.HoldFile
 )
 
 // WriteableFileType defines the different data types that can be modified via SaveUnpacked or RemoveUnpacked.
@@ -85,12 +87,16 @@ type WriteableFileType backend.FileType
 const (
 	// WriteableSnapshotFile is the WriteableFileType for snapshots.
 	WriteableSnapshotFile = WriteableFileType(SnapshotFile)
+	// WriteableHoldFile is the WriteableFileType for snapshot holds.
+	WriteableHoldFile = WriteableFileType(HoldFile)
 )
 
 func (w *WriteableFileType) ToFileType() FileType {
 	switch *w {
 	case WriteableSnapshotFile:
 		return SnapshotFile
+	case WriteableHoldFile:
+		return HoldFile
 	default:
 		panic("invalid WriteableFileType")
 	}
@@ -127,8 +133,9 @@ type SaverRemoverUnpacked[FT FileTypes] interface {
 }
 
 type PackBlobs struct {
-	PackID ID
-	Blobs  []Blob
+	PackID  ID
+	PackKey crypto.PublicKey
+	Blobs   []Blob
 }
 
 // MasterIndex keeps track of the blobs are stored within files.
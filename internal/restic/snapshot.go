@@ -25,6 +25,10 @@ type Snapshot struct {
 	Tags     []string  `json:"tags,omitempty"`
 	Original *ID       `json:"original,omitempty"`
 
+	// Incomplete is set if not all source data could be stored, for example
+	// because a command providing the data failed.
+	Incomplete bool `json:"incomplete,omitempty"`
+
 	ProgramVersion string           `json:"program_version,omitempty"`
 	Summary        *SnapshotSummary `json:"summary,omitempty"`
 
@@ -25,6 +25,7 @@ type ExpirePolicy struct {
 	WithinMonthly Duration  // keep monthly snapshots made within this duration
 	WithinYearly  Duration  // keep yearly snapshots made within this duration
 	Tags          []TagList // keep all snapshots that include at least one of the tag lists.
+	Holds         Holds     // always keep held snapshots, this does not count as a policy.
 }
 
 func (e ExpirePolicy) String() (s string) {
@@ -109,7 +110,7 @@ func (e ExpirePolicy) Empty() bool {
 		return false
 	}
 
-	empty := ExpirePolicy{Tags: e.Tags}
+	empty := ExpirePolicy{Tags: e.Tags, Holds: e.Holds}
 	return reflect.DeepEqual(e, empty)
 }
 
@@ -237,6 +238,14 @@ func ApplyPolicy(list Snapshots, p ExpirePolicy) (keep, remove Snapshots, reason
 			}
 		}
 
+		// Held snapshots must never be removed.
+		if cur.ID() != nil {
+			if reason, ok := p.Holds.Reason(*cur.ID()); ok {
+				keepSnap = true
+				keepSnapReasons = append(keepSnapReasons, reason)
+			}
+		}
+
 		// If the timestamp of the snapshot is within the range, then keep it.
 		if !p.Within.Zero() {
 			t := latest.AddDate(-p.Within.Years, -p.Within.Months, -p.Within.Days).Add(time.Hour * time.Duration(-p.Within.Hours))
@@ -285,3 +285,32 @@ func TestApplyPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyPolicyHolds(t *testing.T) {
+	var list restic.Snapshots
+	for _, ts := range []string{"2014-09-01 10:20:30", "2014-09-02 10:20:30", "2014-09-03 10:20:30"} {
+		sn := &restic.Snapshot{Time: parseTimeUTC(ts)}
+		restic.TestSetSnapshotID(t, sn, restic.NewRandomID())
+		list = append(list, sn)
+	}
+	held := *list[0].ID()
+
+	p := restic.ExpirePolicy{
+		Last:  1,
+		Holds: restic.Holds{held: {{Snapshot: held, Reason: "legal"}}},
+	}
+	keep, remove, reasons := restic.ApplyPolicy(list, p)
+
+	// list is sorted newest first
+	if len(keep) != 2 || len(remove) != 1 || *remove[0].ID() != *list[1].ID() {
+		t.Fatalf("unexpected result, keep %v, remove %v", keep, remove)
+	}
+	if *keep[1].ID() != held || len(reasons[1].Matches) != 1 || reasons[1].Matches[0] != "held: legal" {
+		t.Fatalf("unexpected keep reason %v for %v", reasons[1].Matches, keep[1].ID())
+	}
+
+	// holds are not a policy on their own
+	if !(restic.ExpirePolicy{Holds: p.Holds}).Empty() {
+		t.Fatal("policy with only holds is not empty")
+	}
+}
@@ -0,0 +1,188 @@
+package restorer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/restic"
+)
+
+// confinedRoot performs all file system modifications of a restore relative
+// to an open handle of the target directory. Neither symlinks nor ".."
+// components can redirect an operation outside of the target, even if the
+// directory tree is modified concurrently.
+//
+// A nil *confinedRoot is valid and applies all operations directly to the
+// given paths.
+type confinedRoot struct {
+	root *os.Root
+	dst  string
+}
+
+func openConfinedRoot(dst string) (*confinedRoot, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("confined restore is only supported on Linux")
+	}
+
+	root, err := os.OpenRoot(dst)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open target directory: %w", err)
+	}
+	return &confinedRoot{root: root, dst: dst}, nil
+}
+
+func (r *confinedRoot) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.root.Close()
+}
+
+// rel returns path relative to the target directory.
+func (r *confinedRoot) rel(path string) (string, error) {
+	rel, err := filepath.Rel(r.dst, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", &os.PathError{Op: "confine", Path: path, Err: errors.New("path is outside of the restore target")}
+	}
+	return rel, nil
+}
+
+// openParent opens the parent directory of path and returns it along with
+// the last path component.
+func (r *confinedRoot) openParent(path string) (*os.File, string, error) {
+	rel, err := r.rel(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if rel == "." {
+		return nil, "", &os.PathError{Op: "confine", Path: path, Err: errors.New("cannot modify the restore target itself")}
+	}
+
+	dir, err := r.root.Open(filepath.Dir(rel))
+	if err != nil {
+		return nil, "", err
+	}
+	return dir, filepath.Base(rel), nil
+}
+
+func (r *confinedRoot) OpenFile(path string, flag int, perm os.FileMode) (*os.File, error) {
+	if r == nil {
+		return fs.OpenFile(path, flag, perm)
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.root.OpenFile(rel, flag, perm)
+}
+
+func (r *confinedRoot) Lstat(path string) (os.FileInfo, error) {
+	if r == nil {
+		return fs.Lstat(path)
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.root.Lstat(rel)
+}
+
+func (r *confinedRoot) MkdirAll(path string, perm os.FileMode) error {
+	if r == nil {
+		return fs.MkdirAll(path, perm)
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return err
+	}
+	return r.root.MkdirAll(rel, perm)
+}
+
+func (r *confinedRoot) Remove(path string) error {
+	if r == nil {
+		return fs.Remove(path)
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return err
+	}
+	return r.root.Remove(rel)
+}
+
+func (r *confinedRoot) RemoveAll(path string) error {
+	if r == nil {
+		return fs.RemoveAll(path)
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return err
+	}
+	return r.root.RemoveAll(rel)
+}
+
+func (r *confinedRoot) Link(oldname, newname string) error {
+	if r == nil {
+		return fs.Link(oldname, newname)
+	}
+	oldRel, err := r.rel(oldname)
+	if err != nil {
+		return err
+	}
+	newRel, err := r.rel(newname)
+	if err != nil {
+		return err
+	}
+	return r.root.Link(oldRel, newRel)
+}
+
+func (r *confinedRoot) ResetPermissions(path string) error {
+	if r == nil {
+		return fs.ResetPermissions(path)
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return err
+	}
+	return r.root.Chmod(rel, 0600)
+}
+
+func (r *confinedRoot) NodeCreateAt(node *restic.Node, path string) error {
+	if r == nil {
+		return fs.NodeCreateAt(node, path)
+	}
+	dir, name, err := r.openParent(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dir.Close()
+	}()
+	return fs.NodeCreateIn(dir, name, node)
+}
+
+func (r *confinedRoot) NodeRestoreMetadata(node *restic.Node, path string, warn func(msg string), xattrSelectFilter func(xattrName string) bool) error {
+	if r == nil {
+		return fs.NodeRestoreMetadata(node, path, warn, xattrSelectFilter)
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		// the metadata of the target directory itself is restored via its path
+		return fs.NodeRestoreMetadata(node, path, warn, xattrSelectFilter)
+	}
+
+	dir, name, err := r.openParent(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dir.Close()
+	}()
+	return fs.NodeRestoreMetadataIn(dir, name, node, warn, xattrSelectFilter)
+}
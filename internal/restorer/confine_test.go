@@ -0,0 +1,168 @@
+package restorer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func skipIfConfineUnsupported(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("confined restore is only supported on Linux")
+	}
+}
+
+func TestRestoreConfined(t *testing.T) {
+	skipIfConfineUnsupported(t)
+
+	snapshot := Snapshot{
+		Nodes: map[string]Node{
+			"foo": File{Data: "content: foo\n", Mode: 0o640},
+			"dirtest": Dir{
+				Mode: normalizeFileMode(0o750 | os.ModeDir),
+				Nodes: map[string]Node{
+					"file": File{Data: "content: file\n"},
+					"link": Symlink{Target: "../foo"},
+				},
+			},
+			"hardlink1": File{Data: "content: hardlink\n", Links: 2, Inode: 42},
+			"hardlink2": File{Data: "content: hardlink\n", Links: 2, Inode: 42},
+		},
+	}
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, snapshot, noopGetGenericAttributes)
+	tempdir := rtest.TempDir(t)
+
+	res := NewRestorer(repo, sn, Options{Confine: true})
+	res.Error = func(location string, err error) error {
+		t.Errorf("unexpected error for %v: %v", location, err)
+		return nil
+	}
+	_, err := res.RestoreTo(context.TODO(), tempdir)
+	rtest.OK(t, err)
+
+	for name, content := range map[string]string{
+		"foo":          "content: foo\n",
+		"dirtest/file": "content: file\n",
+		"dirtest/link": "content: foo\n",
+		"hardlink2":    "content: hardlink\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(tempdir, filepath.FromSlash(name)))
+		rtest.OK(t, err)
+		rtest.Equals(t, content, string(data))
+	}
+
+	target, err := os.Readlink(filepath.Join(tempdir, "dirtest", "link"))
+	rtest.OK(t, err)
+	rtest.Equals(t, "../foo", target)
+
+	fi, err := os.Lstat(filepath.Join(tempdir, "foo"))
+	rtest.OK(t, err)
+	rtest.Equals(t, os.FileMode(0o640), fi.Mode().Perm())
+
+	fi, err = os.Lstat(filepath.Join(tempdir, "dirtest"))
+	rtest.OK(t, err)
+	rtest.Equals(t, os.FileMode(0o750), fi.Mode().Perm())
+}
+
+func TestRestoreConfinedReportsViolations(t *testing.T) {
+	skipIfConfineUnsupported(t)
+
+	snapshot := Snapshot{
+		Nodes: map[string]Node{
+			"dirtest": Dir{
+				Nodes: map[string]Node{
+					"file": File{Data: "content: file\n"},
+				},
+			},
+		},
+	}
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, snapshot, noopGetGenericAttributes)
+
+	base := rtest.TempDir(t)
+	tempdir := filepath.Join(base, "target")
+	outside := filepath.Join(base, "outside")
+	rtest.OK(t, os.Mkdir(tempdir, 0o700))
+	rtest.OK(t, os.Mkdir(outside, 0o700))
+
+	res := NewRestorer(repo, sn, Options{Confine: true})
+	errs := make(map[string]error)
+	res.Error = func(location string, err error) error {
+		errs[location] = err
+		return nil
+	}
+	// simulate a symlink that is planted while the restore is running
+	res.NodeFilter = func(item string, _ *restic.Node) bool {
+		if item == filepath.Join(tempdir, "dirtest", "file") {
+			rtest.OK(t, os.RemoveAll(filepath.Dir(item)))
+			rtest.OK(t, os.Symlink(outside, filepath.Dir(item)))
+		}
+		return true
+	}
+	_, err := res.RestoreTo(context.TODO(), tempdir)
+	rtest.OK(t, err)
+
+	rtest.Assert(t, errs["/dirtest/file"] != nil, "missing error for /dirtest/file, got %v", errs)
+	entries, err := os.ReadDir(outside)
+	rtest.OK(t, err)
+	rtest.Equals(t, 0, len(entries))
+}
+
+func TestConfinedRootRejectsEscapes(t *testing.T) {
+	skipIfConfineUnsupported(t)
+
+	base := rtest.TempDir(t)
+	dst := filepath.Join(base, "target")
+	outside := filepath.Join(base, "outside")
+	rtest.OK(t, os.Mkdir(dst, 0o700))
+	rtest.OK(t, os.Mkdir(outside, 0o700))
+	rtest.OK(t, os.WriteFile(filepath.Join(outside, "file"), []byte("secret"), 0o600))
+	rtest.OK(t, os.Symlink(outside, filepath.Join(dst, "escape")))
+	rtest.OK(t, os.Symlink(filepath.Join(outside, "file"), filepath.Join(dst, "filelink")))
+
+	root, err := openConfinedRoot(dst)
+	rtest.OK(t, err)
+	defer func() {
+		rtest.OK(t, root.Close())
+	}()
+
+	_, err = root.OpenFile(filepath.Join(dst, "escape", "new"), fs.O_CREATE|fs.O_WRONLY, 0o600)
+	rtest.Assert(t, err != nil, "expected error when creating a file through a symlink")
+
+	err = root.MkdirAll(filepath.Join(dst, "escape", "sub"), 0o700)
+	rtest.Assert(t, err != nil, "expected error when creating a directory through a symlink")
+
+	err = root.NodeCreateAt(&restic.Node{Name: "sym", Type: restic.NodeTypeSymlink, LinkTarget: "x"}, filepath.Join(dst, "escape", "sym"))
+	rtest.Assert(t, err != nil, "expected error when creating a symlink through a symlink")
+
+	err = root.Remove(filepath.Join(base, "outside", "file"))
+	rtest.Assert(t, err != nil, "expected error when removing a file outside of the target")
+
+	// restoring the metadata must not follow the symlink
+	err = root.NodeRestoreMetadata(&restic.Node{
+		Name: "filelink",
+		Type: restic.NodeTypeFile,
+		Mode: 0o777,
+		UID:  uint32(os.Getuid()),
+		GID:  uint32(os.Getgid()),
+	}, filepath.Join(dst, "filelink"), func(string) {}, func(string) bool { return true })
+	rtest.OK(t, err)
+
+	fi, err := os.Stat(filepath.Join(outside, "file"))
+	rtest.OK(t, err)
+	rtest.Equals(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	entries, err := os.ReadDir(outside)
+	rtest.OK(t, err)
+	rtest.Equals(t, 1, len(entries))
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/feature"
-	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
 	"github.com/restic/restic/internal/ui/restore"
 )
@@ -59,6 +58,7 @@ type fileRestorer struct {
 	progress    *restore.Progress
 
 	allowRecursiveDelete bool
+	root                 *confinedRoot
 
 	dst   string
 	files []*fileInfo
@@ -70,8 +70,10 @@ func newFileRestorer(dst string,
 	blobsLoader blobsLoaderFn,
 	idx func(restic.BlobType, restic.ID) []restic.PackedBlob,
 	connections uint,
+	zeroChunk restic.ID,
 	sparse bool,
 	allowRecursiveDelete bool,
+	root *confinedRoot,
 	startWarmup startWarmupFn,
 	progress *restore.Progress) *fileRestorer {
 
@@ -82,11 +84,12 @@ func newFileRestorer(dst string,
 		idx:                  idx,
 		blobsLoader:          blobsLoader,
 		startWarmup:          startWarmup,
-		filesWriter:          newFilesWriter(workerCount, allowRecursiveDelete),
-		zeroChunk:            repository.ZeroChunk(),
+		filesWriter:          newFilesWriter(workerCount, allowRecursiveDelete, root),
+		zeroChunk:            zeroChunk,
 		sparse:               sparse,
 		progress:             progress,
 		allowRecursiveDelete: allowRecursiveDelete,
+		root:                 root,
 		workerCount:          workerCount,
 		dst:                  dst,
 		Error:                restorerAbortOnAllErrors,
@@ -250,7 +253,7 @@ func (r *fileRestorer) restoreFiles(ctx context.Context) error {
 }
 
 func (r *fileRestorer) truncateFileToSize(location string, size int64) error {
-	f, err := createFile(r.targetPath(location), size, false, r.allowRecursiveDelete)
+	f, err := createFile(r.root, r.targetPath(location), size, false, r.allowRecursiveDelete)
 	if err != nil {
 		return err
 	}
@@ -170,7 +170,7 @@ func restoreAndVerify(t *testing.T, tempdir string, content []TestFile, files ma
 	t.Helper()
 	repo := newTestRepo(content)
 
-	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, sparse, false, repo.StartWarmup, nil)
+	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, restic.Config{}.ChunkerParams().ZeroChunk(), sparse, false, nil, repo.StartWarmup, nil)
 
 	if files == nil {
 		r.files = repo.files
@@ -320,7 +320,7 @@ func TestErrorRestoreFiles(t *testing.T) {
 		return loadError
 	}
 
-	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, false, false, repo.StartWarmup, nil)
+	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, restic.Config{}.ChunkerParams().ZeroChunk(), false, false, nil, repo.StartWarmup, nil)
 	r.files = repo.files
 
 	err := r.restoreFiles(context.TODO())
@@ -361,7 +361,7 @@ func TestFatalDownloadError(t *testing.T) {
 		})
 	}
 
-	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, false, false, repo.StartWarmup, nil)
+	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, restic.Config{}.ChunkerParams().ZeroChunk(), false, false, nil, repo.StartWarmup, nil)
 	r.files = repo.files
 
 	var errors []string
@@ -20,6 +20,7 @@ import (
 type filesWriter struct {
 	buckets              []filesWriterBucket
 	allowRecursiveDelete bool
+	root                 *confinedRoot
 }
 
 type filesWriterBucket struct {
@@ -33,7 +34,7 @@ type partialFile struct {
 	sparse bool
 }
 
-func newFilesWriter(count int, allowRecursiveDelete bool) *filesWriter {
+func newFilesWriter(count int, allowRecursiveDelete bool, root *confinedRoot) *filesWriter {
 	buckets := make([]filesWriterBucket, count)
 	for b := 0; b < count; b++ {
 		buckets[b].files = make(map[string]*partialFile)
@@ -41,11 +42,12 @@ func newFilesWriter(count int, allowRecursiveDelete bool) *filesWriter {
 	return &filesWriter{
 		buckets:              buckets,
 		allowRecursiveDelete: allowRecursiveDelete,
+		root:                 root,
 	}
 }
 
-func openFile(path string) (*os.File, error) {
-	f, err := fs.OpenFile(path, fs.O_WRONLY|fs.O_NOFOLLOW, 0600)
+func openFile(root *confinedRoot, path string) (*os.File, error) {
+	f, err := root.OpenFile(path, fs.O_WRONLY|fs.O_NOFOLLOW, 0600)
 	if err != nil {
 		return nil, err
 	}
@@ -61,17 +63,17 @@ func openFile(path string) (*os.File, error) {
 	return f, nil
 }
 
-func createFile(path string, createSize int64, sparse bool, allowRecursiveDelete bool) (*os.File, error) {
-	f, err := fs.OpenFile(path, fs.O_CREATE|fs.O_WRONLY|fs.O_NOFOLLOW, 0600)
+func createFile(root *confinedRoot, path string, createSize int64, sparse bool, allowRecursiveDelete bool) (*os.File, error) {
+	f, err := root.OpenFile(path, fs.O_CREATE|fs.O_WRONLY|fs.O_NOFOLLOW, 0600)
 	if err != nil && fs.IsAccessDenied(err) {
 		// If file is readonly, clear the readonly flag by resetting the
 		// permissions of the file and try again
 		// as the metadata will be set again in the second pass and the
 		// readonly flag will be applied again if needed.
-		if err = fs.ResetPermissions(path); err != nil {
+		if err = root.ResetPermissions(path); err != nil {
 			return nil, err
 		}
-		if f, err = fs.OpenFile(path, fs.O_WRONLY|fs.O_NOFOLLOW, 0600); err != nil {
+		if f, err = root.OpenFile(path, fs.O_WRONLY|fs.O_NOFOLLOW, 0600); err != nil {
 			return nil, err
 		}
 	} else if err != nil && (errors.Is(err, syscall.ELOOP) || errors.Is(err, syscall.EISDIR)) {
@@ -111,16 +113,16 @@ func createFile(path string, createSize int64, sparse bool, allowRecursiveDelete
 
 		// not what we expected, try to get rid of it
 		if allowRecursiveDelete {
-			if err := fs.RemoveAll(path); err != nil {
+			if err := root.RemoveAll(path); err != nil {
 				return nil, err
 			}
 		} else {
-			if err := fs.Remove(path); err != nil {
+			if err := root.Remove(path); err != nil {
 				return nil, err
 			}
 		}
 		// create a new file, pass O_EXCL to make sure there are no surprises
-		f, err = fs.OpenFile(path, fs.O_CREATE|fs.O_WRONLY|fs.O_EXCL|fs.O_NOFOLLOW, 0600)
+		f, err = root.OpenFile(path, fs.O_CREATE|fs.O_WRONLY|fs.O_EXCL|fs.O_NOFOLLOW, 0600)
 		if err != nil {
 			return nil, err
 		}
@@ -176,11 +178,11 @@ func (w *filesWriter) writeToFile(path string, blob []byte, offset int64, create
 		var f *os.File
 		var err error
 		if createSize >= 0 {
-			f, err = createFile(path, createSize, sparse, w.allowRecursiveDelete)
+			f, err = createFile(w.root, path, createSize, sparse, w.allowRecursiveDelete)
 			if err != nil {
 				return nil, err
 			}
-		} else if f, err = openFile(path); err != nil {
+		} else if f, err = openFile(w.root, path); err != nil {
 			return nil, err
 		}
 
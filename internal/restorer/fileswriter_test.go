@@ -13,7 +13,7 @@ import (
 
 func TestFilesWriterBasic(t *testing.T) {
 	dir := rtest.TempDir(t)
-	w := newFilesWriter(1, false)
+	w := newFilesWriter(1, false, nil)
 
 	f1 := dir + "/f1"
 	f2 := dir + "/f2"
@@ -47,13 +47,13 @@ func TestFilesWriterRecursiveOverwrite(t *testing.T) {
 	rtest.OK(t, os.WriteFile(filepath.Join(path, "file"), []byte("data"), 0o400))
 
 	// must error if recursive delete is not allowed
-	w := newFilesWriter(1, false)
+	w := newFilesWriter(1, false, nil)
 	err := w.writeToFile(path, []byte{1}, 0, 2, false)
 	rtest.Assert(t, errors.Is(err, notEmptyDirError()), "unexpected error got %v", err)
 	rtest.Equals(t, 0, len(w.buckets[0].files))
 
 	// must replace directory
-	w = newFilesWriter(1, true)
+	w = newFilesWriter(1, true, nil)
 	rtest.OK(t, w.writeToFile(path, []byte{1, 1}, 0, 2, false))
 	rtest.Equals(t, 0, len(w.buckets[0].files))
 
@@ -133,7 +133,7 @@ func TestCreateFile(t *testing.T) {
 			for j, test := range tests {
 				path := basepath + fmt.Sprintf("%v%v", i, j)
 				sc.create(t, path)
-				f, err := createFile(path, test.size, test.isSparse, false)
+				f, err := createFile(nil, path, test.size, test.isSparse, false)
 				if sc.err == nil {
 					rtest.OK(t, err)
 					fi, err := f.Stat()
@@ -161,7 +161,7 @@ func TestCreateFileRecursiveDelete(t *testing.T) {
 	rtest.OK(t, os.WriteFile(filepath.Join(path, "file"), []byte("data"), 0o400))
 
 	// replace it
-	f, err := createFile(path, 42, false, true)
+	f, err := createFile(nil, path, 42, false, true)
 	rtest.OK(t, err)
 	fi, err := f.Stat()
 	rtest.OK(t, err)
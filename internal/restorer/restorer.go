@@ -25,6 +25,9 @@ type Restorer struct {
 	opts Options
 
 	fileList map[string]bool
+	// root confines all modifications to the target directory, nil unless
+	// Options.Confine is set
+	root *confinedRoot
 
 	Error func(location string, err error) error
 	Warn  func(message string)
@@ -46,6 +49,11 @@ type Options struct {
 	Progress  *restoreui.Progress
 	Overwrite OverwriteBehavior
 	Delete    bool
+	// OwnerMap rewrites the ownership of restored items, may be nil.
+	OwnerMap *fs.OwnerMap
+	// Confine resolves all paths relative to the target directory such that
+	// no item outside of it can be modified.
+	Confine bool
 }
 
 type OverwriteBehavior int
@@ -278,19 +286,23 @@ func (res *Restorer) traverseTreeInner(ctx context.Context, target, location str
 }
 
 func (res *Restorer) restoreNodeTo(node *restic.Node, target, location string) error {
+	replaced := false
 	if !res.opts.DryRun {
 		debug.Log("restoreNode %v %v %v", node.Name, target, location)
-		if err := fs.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		err := res.root.Remove(target)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return errors.Wrap(err, "RemoveNode")
 		}
+		replaced = err == nil
 
-		err := fs.NodeCreateAt(node, target)
+		err = res.root.NodeCreateAt(node, target)
 		if err != nil {
 			debug.Log("node.CreateAt(%s) error %v", target, err)
 			return err
 		}
 	}
 
+	res.opts.Progress.AddItemDetails(location, node.Type, replaced, "")
 	res.opts.Progress.AddProgress(location, restoreui.ActionOtherRestored, 0, 0)
 	return res.restoreNodeMetadataTo(node, target, location)
 }
@@ -300,7 +312,8 @@ func (res *Restorer) restoreNodeMetadataTo(node *restic.Node, target, location s
 		return nil
 	}
 	debug.Log("restoreNodeMetadata %v %v %v", node.Name, target, location)
-	err := fs.NodeRestoreMetadata(node, target, res.Warn, res.XattrSelectFilter)
+	node = res.opts.OwnerMap.MapNode(node)
+	err := res.root.NodeRestoreMetadata(node, target, res.Warn, res.XattrSelectFilter)
 	if err != nil {
 		debug.Log("node.RestoreMetadata(%s) error %v", target, err)
 	}
@@ -308,16 +321,21 @@ func (res *Restorer) restoreNodeMetadataTo(node *restic.Node, target, location s
 }
 
 func (res *Restorer) restoreHardlinkAt(node *restic.Node, target, path, location string) error {
+	replaced := false
 	if !res.opts.DryRun {
-		if err := fs.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		err := res.root.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return errors.Wrap(err, "RemoveCreateHardlink")
 		}
-		err := fs.Link(target, path)
+		replaced = err == nil
+
+		err = res.root.Link(target, path)
 		if err != nil {
 			return errors.WithStack(err)
 		}
 	}
 
+	res.opts.Progress.AddItemDetails(location, node.Type, replaced, "")
 	res.opts.Progress.AddProgress(location, restoreui.ActionOtherRestored, 0, 0)
 	// TODO investigate if hardlinks have separate metadata on any supported system
 	return res.restoreNodeMetadataTo(node, path, location)
@@ -328,20 +346,20 @@ func (res *Restorer) ensureDir(target string) error {
 		return nil
 	}
 
-	fi, err := fs.Lstat(target)
+	fi, err := res.root.Lstat(target)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("failed to check for directory: %w", err)
 	}
 	if err == nil && !fi.IsDir() {
 		// try to cleanup unexpected file
-		if err := fs.Remove(target); err != nil {
+		if err := res.root.Remove(target); err != nil {
 			return fmt.Errorf("failed to remove stale item: %w", err)
 		}
 	}
 
 	// create parent dir with default permissions
 	// second pass #leaveDir restores dir metadata after visiting/restoring all children
-	return fs.MkdirAll(target, 0700)
+	return res.root.MkdirAll(target, 0700)
 }
 
 // RestoreTo creates the directories and files in the snapshot below dst.
@@ -362,11 +380,22 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) (uint64, error)
 		if err := fs.MkdirAll(dst, 0700); err != nil {
 			return restoredFileCount, fmt.Errorf("cannot create target directory: %w", err)
 		}
+
+		if res.opts.Confine {
+			res.root, err = openConfinedRoot(dst)
+			if err != nil {
+				return restoredFileCount, err
+			}
+			defer func() {
+				_ = res.root.Close()
+				res.root = nil
+			}()
+		}
 	}
 
 	idx := NewHardlinkIndex[string]()
 	filerestorer := newFileRestorer(dst, res.repo.LoadBlobsFromPack, res.repo.LookupBlob,
-		res.repo.Connections(), res.opts.Sparse, res.opts.Delete, res.repo.StartWarmup, res.opts.Progress)
+		res.repo.Connections(), res.repo.Config().ChunkerParams().ZeroChunk(), res.opts.Sparse, res.opts.Delete, res.root, res.repo.StartWarmup, res.opts.Progress)
 	filerestorer.Error = res.Error
 	filerestorer.Info = res.Info
 
@@ -526,11 +555,13 @@ func (res *Restorer) removeUnexpectedFiles(ctx context.Context, target, location
 		if selectedForRestore {
 			// First collect all files that will be deleted
 			var filesToDelete []string
-			err := filepath.Walk(nodeTarget, func(path string, _ os.FileInfo, err error) error {
+			var fileTypes []restic.NodeType
+			err := filepath.Walk(nodeTarget, func(path string, fi os.FileInfo, err error) error {
 				if err != nil {
 					return err
 				}
 				filesToDelete = append(filesToDelete, path)
+				fileTypes = append(fileTypes, fs.NodeTypeFromFileMode(fi.Mode()))
 				return nil
 			})
 			if err != nil {
@@ -539,13 +570,14 @@ func (res *Restorer) removeUnexpectedFiles(ctx context.Context, target, location
 
 			if !res.opts.DryRun {
 				// Perform the deletion
-				if err := fs.RemoveAll(nodeTarget); err != nil {
+				if err := res.root.RemoveAll(nodeTarget); err != nil {
 					return err
 				}
 			}
 
 			// Report paths as deleted only after successful removal
 			for i := len(filesToDelete) - 1; i >= 0; i-- {
+				res.opts.Progress.AddItemDetails(filesToDelete[i], fileTypes[i], false, "")
 				res.opts.Progress.ReportDeletion(filesToDelete[i])
 			}
 		}
@@ -572,6 +604,7 @@ func (res *Restorer) withOverwriteCheck(ctx context.Context, node *restic.Node,
 		if isHardlink {
 			size = 0
 		}
+		res.opts.Progress.AddItemDetails(location, node.Type, false, restoreui.ContentNotChecked)
 		res.opts.Progress.AddSkippedFile(location, size)
 		return buf, nil
 	}
@@ -580,9 +613,11 @@ func (res *Restorer) withOverwriteCheck(ctx context.Context, node *restic.Node,
 	updateMetadataOnly := false
 	if node.Type == restic.NodeTypeFile && !isHardlink {
 		// if a file fails to verify, then matches is nil which results in restoring from scratch
-		matches, buf, _ = res.verifyFile(ctx, target, node, false, res.opts.Overwrite == OverwriteIfChanged, buf)
+		var verifyErr error
+		matches, buf, verifyErr = res.verifyFile(ctx, target, node, false, res.opts.Overwrite == OverwriteIfChanged, buf)
 		// skip files that are already correct completely
 		updateMetadataOnly = !matches.NeedsRestore()
+		res.opts.Progress.AddItemDetails(location, node.Type, !errors.Is(verifyErr, os.ErrNotExist), matches.contentStatus())
 	}
 
 	return buf, cb(updateMetadataOnly, matches)
@@ -703,6 +738,24 @@ func (s *fileState) NeedsRestore() bool {
 	return false
 }
 
+// contentStatus returns how the file content is handled during the restore.
+func (s *fileState) contentStatus() restoreui.ContentStatus {
+	switch {
+	case s == nil:
+		return restoreui.ContentWritten
+	case s.sizeMatches && s.blobMatches == nil:
+		return restoreui.ContentMtimeMatch
+	case !s.NeedsRestore():
+		return restoreui.ContentVerified
+	}
+	for _, match := range s.blobMatches {
+		if match {
+			return restoreui.ContentPartial
+		}
+	}
+	return restoreui.ContentWritten
+}
+
 func (s *fileState) HasMatchingBlob(i int) bool {
 	if s == nil || s.blobMatches == nil {
 		return false
@@ -1563,3 +1563,63 @@ func TestRestorerLongPath(t *testing.T) {
 	_, err = res.VerifyFiles(ctx, tmp, countRestoredFiles, nil)
 	rtest.OK(t, err)
 }
+
+func TestRestoreManifest(t *testing.T) {
+	repo := repository.TestRepository(t)
+	tempdir := rtest.TempDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	baseTime := time.Now().Add(-time.Hour)
+	newTime := baseTime.Add(time.Minute)
+	sn, _ := saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"same":    File{Data: "content: same\n", ModTime: baseTime},
+			"changed": File{Data: "content: old\n", ModTime: baseTime},
+			"kept":    File{Data: "content: kept\n", ModTime: newTime},
+			"removed": File{Data: "content: removed\n", ModTime: baseTime},
+		},
+	}, noopGetGenericAttributes)
+	res := NewRestorer(repo, sn, Options{})
+	_, err := res.RestoreTo(ctx, tempdir)
+	rtest.OK(t, err)
+
+	sn, _ = saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"same":    File{Data: "content: same\n", ModTime: newTime},
+			"changed": File{Data: "content: new\n", ModTime: newTime},
+			"kept":    File{Data: "content: other\n", ModTime: baseTime},
+			"new":     File{Data: "content: new file\n", ModTime: newTime},
+			"link":    Symlink{Target: "new", ModTime: newTime},
+		},
+	}, noopGetGenericAttributes)
+
+	var buf bytes.Buffer
+	manifest := restoreui.NewManifest(&buf)
+	progress := restoreui.NewProgress(&printerMock{}, 0)
+	progress.SetManifest(manifest)
+	res = NewRestorer(repo, sn, Options{Overwrite: OverwriteIfNewer, Delete: true, Progress: progress})
+	_, err = res.RestoreTo(ctx, tempdir)
+	rtest.OK(t, err)
+	progress.Finish()
+	rtest.OK(t, manifest.Flush())
+
+	entries := make(map[string]restoreui.ManifestEntry)
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry restoreui.ManifestEntry
+		rtest.OK(t, dec.Decode(&entry))
+		entries[entry.Item] = entry
+	}
+
+	sep := string(filepath.Separator)
+	removed := filepath.Join(tempdir, "removed")
+	rtest.Equals(t, map[string]restoreui.ManifestEntry{
+		sep + "same":    {Item: sep + "same", Action: "unchanged", Type: restic.NodeTypeFile, Size: 14, Content: restoreui.ContentVerified},
+		sep + "changed": {Item: sep + "changed", Action: "overwritten", Type: restic.NodeTypeFile, Size: 13, Content: restoreui.ContentWritten},
+		sep + "kept":    {Item: sep + "kept", Action: "skipped", Type: restic.NodeTypeFile, Size: 15, Content: restoreui.ContentNotChecked},
+		sep + "new":     {Item: sep + "new", Action: "restored", Type: restic.NodeTypeFile, Size: 18, Content: restoreui.ContentWritten},
+		sep + "link":    {Item: sep + "link", Action: "restored", Type: restic.NodeTypeSymlink},
+		removed:         {Item: removed, Action: "deleted", Type: restic.NodeTypeFile},
+	}, entries)
+}
@@ -148,6 +148,20 @@ func (b *JSONProgress) CompleteItem(messageType, item string, s archiver.ItemSta
 	}
 }
 
+// ExcludedItem reports an item which was rejected by the symlink scope.
+func (b *JSONProgress) ExcludedItem(item string, reason string) {
+	if b.v < 2 {
+		return
+	}
+
+	b.print(verboseUpdate{
+		MessageType: "verbose_status",
+		Action:      "excluded",
+		Item:        item,
+		Reason:      reason,
+	})
+}
+
 // ReportTotal sets the total stats up to now
 func (b *JSONProgress) ReportTotal(start time.Time, s archiver.ScanStats) {
 	if b.v >= 2 {
@@ -228,6 +242,7 @@ type verboseUpdate struct {
 	MetadataSize       uint64  `json:"metadata_size"`
 	MetadataSizeInRepo uint64  `json:"metadata_size_in_repo"`
 	TotalFiles         uint    `json:"total_files"`
+	Reason             string  `json:"reason,omitempty"`
 }
 
 type summaryOutput struct {
@@ -25,3 +25,9 @@ func TestJSONScannerError(t *testing.T) {
 	test.Equals(t, printer.ScannerError("/path", errors.New("error \"message\"")), nil)
 	test.Equals(t, []string{"{\"message_type\":\"error\",\"error\":{\"message\":\"error \\\"message\\\"\"},\"during\":\"scan\",\"item\":\"/path\"}\n"}, term.Errors)
 }
+
+func TestJSONExcludedItem(t *testing.T) {
+	term, printer := createJSONProgress()
+	printer.ExcludedItem("/path", "outside of scope")
+	test.Equals(t, []string{"{\"message_type\":\"verbose_status\",\"action\":\"excluded\",\"item\":\"/path\",\"duration\":0,\"data_size\":0,\"data_size_in_repo\":0,\"metadata_size\":0,\"metadata_size_in_repo\":0,\"total_files\":0,\"reason\":\"outside of scope\"}\n"}, term.Output)
+}
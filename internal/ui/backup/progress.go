@@ -16,6 +16,7 @@ type ProgressPrinter interface {
 	Error(item string, err error) error
 	ScannerError(item string, err error) error
 	CompleteItem(messageType string, item string, s archiver.ItemStats, d time.Duration)
+	ExcludedItem(item string, reason string)
 	ReportTotal(start time.Time, s archiver.ScanStats)
 	Finish(snapshotID restic.ID, summary *archiver.Summary, dryRun bool)
 	Reset()
@@ -114,6 +114,11 @@ func (b *TextProgress) CompleteItem(messageType, item string, s archiver.ItemSta
 	}
 }
 
+// ExcludedItem reports an item which was rejected by the symlink scope.
+func (b *TextProgress) ExcludedItem(item string, reason string) {
+	b.VV("excluded  %v: %v", termstatus.Quote(item), reason)
+}
+
 // ReportTotal sets the total stats up to now
 func (b *TextProgress) ReportTotal(start time.Time, s archiver.ScanStats) {
 	b.V("scan finished in %.3fs: %v files, %s",
@@ -0,0 +1,137 @@
+package restore
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/restic/restic/internal/restic"
+)
+
+// ContentStatus describes how the content of a file was handled during the
+// restore.
+type ContentStatus string
+
+// Constants for the content status of restored files.
+const (
+	// ContentNotChecked means the existing file was left as is without
+	// reading its content.
+	ContentNotChecked ContentStatus = "not_checked"
+	// ContentVerified means the existing file content matched the hashes of
+	// all blobs in the snapshot.
+	ContentVerified ContentStatus = "verified"
+	// ContentMtimeMatch means the existing file was assumed to be unchanged
+	// as its size and modification time match the snapshot.
+	ContentMtimeMatch ContentStatus = "mtime_match"
+	// ContentPartial means only the parts of the existing file whose hashes
+	// did not match the snapshot were written.
+	ContentPartial ContentStatus = "partial"
+	// ContentWritten means the whole file content was written from the
+	// repository.
+	ContentWritten ContentStatus = "written"
+)
+
+// ManifestEntry is a single record of the restore manifest.
+type ManifestEntry struct {
+	Item    string          `json:"item"`
+	Action  string          `json:"action"` // restored, overwritten, unchanged, skipped, deleted or failed
+	Type    restic.NodeType `json:"type,omitempty"`
+	Size    uint64          `json:"size"`
+	Content ContentStatus   `json:"content,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+type itemDetails struct {
+	nodeType restic.NodeType
+	replaced bool
+	content  ContentStatus
+}
+
+// Manifest writes one JSON record per restored, skipped, deleted or failed
+// item. It must only be used via Progress, which serializes all calls.
+type Manifest struct {
+	wr      *bufio.Writer
+	enc     *json.Encoder
+	err     error
+	details map[string]itemDetails
+}
+
+// NewManifest returns a manifest which writes JSON lines to wr.
+func NewManifest(wr io.Writer) *Manifest {
+	bw := bufio.NewWriter(wr)
+	return &Manifest{
+		wr:      bw,
+		enc:     json.NewEncoder(bw),
+		details: make(map[string]itemDetails),
+	}
+}
+
+func (m *Manifest) addDetails(item string, d itemDetails) {
+	m.details[item] = d
+}
+
+func (m *Manifest) write(entry ManifestEntry) {
+	if m.err != nil {
+		return
+	}
+	m.err = m.enc.Encode(entry)
+}
+
+func (m *Manifest) completeItem(action ItemAction, item string, size uint64) {
+	d := m.details[item]
+	delete(m.details, item)
+
+	entry := ManifestEntry{
+		Item:    item,
+		Type:    d.nodeType,
+		Size:    size,
+		Content: d.content,
+	}
+
+	switch action {
+	case ActionDirRestored:
+		entry.Type = restic.NodeTypeDir
+		entry.Action = "restored"
+	case ActionFileRestored, ActionFileUpdated, ActionOtherRestored:
+		if entry.Type == restic.NodeTypeInvalid {
+			entry.Type = restic.NodeTypeFile
+		}
+		entry.Action = "restored"
+		if d.replaced {
+			entry.Action = "overwritten"
+		}
+	case ActionFileUnchanged:
+		entry.Action = "unchanged"
+		if d.content == ContentNotChecked {
+			entry.Action = "skipped"
+		}
+	case ActionDeleted:
+		entry.Action = "deleted"
+	default:
+		panic("unknown message type")
+	}
+
+	m.write(entry)
+}
+
+func (m *Manifest) failedItem(item string, err error) {
+	d := m.details[item]
+	delete(m.details, item)
+
+	m.write(ManifestEntry{
+		Item:   item,
+		Action: "failed",
+		Type:   d.nodeType,
+		Error:  err.Error(),
+	})
+}
+
+// Flush writes buffered records to the underlying writer and returns the
+// first error which occurred while writing the manifest.
+func (m *Manifest) Flush() error {
+	if m.err != nil {
+		return m.err
+	}
+	m.err = m.wr.Flush()
+	return m.err
+}
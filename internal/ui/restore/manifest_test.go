@@ -0,0 +1,43 @@
+package restore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/test"
+)
+
+func TestManifest(t *testing.T) {
+	var buf bytes.Buffer
+	manifest := NewManifest(&buf)
+
+	testProgress(func(progress *Progress) bool {
+		progress.SetManifest(manifest)
+		progress.AddItemDetails("new", restic.NodeTypeFile, false, ContentWritten)
+		progress.AddProgress("new", ActionFileRestored, 10, 10)
+		progress.AddItemDetails("changed", restic.NodeTypeFile, true, ContentPartial)
+		progress.AddProgress("changed", ActionFileUpdated, 5, 5)
+		progress.AddItemDetails("same", restic.NodeTypeFile, true, ContentVerified)
+		progress.AddSkippedFile("same", 3)
+		progress.AddItemDetails("kept", restic.NodeTypeSymlink, false, ContentNotChecked)
+		progress.AddSkippedFile("kept", 0)
+		progress.AddProgress("dir", ActionDirRestored, 0, 0)
+		progress.AddItemDetails("/target/old", restic.NodeTypeFile, false, "")
+		progress.ReportDeletion("/target/old")
+		progress.AddItemDetails("broken", restic.NodeTypeFile, false, ContentWritten)
+		_ = progress.Error("broken", errors.New("write failed"))
+		return true
+	})
+	test.OK(t, manifest.Flush())
+
+	test.Equals(t, `{"item":"new","action":"restored","type":"file","size":10,"content":"written"}
+{"item":"changed","action":"overwritten","type":"file","size":5,"content":"partial"}
+{"item":"same","action":"unchanged","type":"file","size":3,"content":"verified"}
+{"item":"kept","action":"skipped","type":"symlink","size":0,"content":"not_checked"}
+{"item":"dir","action":"restored","type":"dir","size":0}
+{"item":"/target/old","action":"deleted","type":"file","size":0}
+{"item":"broken","action":"failed","type":"file","size":0,"error":"write failed"}
+`, buf.String())
+}
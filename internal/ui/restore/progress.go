@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/restic/restic/internal/restic"
 	"github.com/restic/restic/internal/ui/progress"
 )
 
@@ -25,7 +26,8 @@ type Progress struct {
 	s               State
 	started         time.Time
 
-	printer ProgressPrinter
+	printer  ProgressPrinter
+	manifest *Manifest
 }
 
 type progressInfoEntry struct {
@@ -73,6 +75,30 @@ func (p *Progress) update(runtime time.Duration, final bool) {
 	}
 }
 
+// SetManifest configures the progress to record each completed item in m.
+func (p *Progress) SetManifest(m *Manifest) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.manifest = m
+}
+
+// AddItemDetails records the node type of an item, whether it replaces an
+// existing item and how its content was handled. The details are only used
+// for the manifest and must be added before the item is completed.
+func (p *Progress) AddItemDetails(name string, nodeType restic.NodeType, replaced bool, content ContentStatus) {
+	if p == nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.manifest != nil {
+		p.manifest.addDetails(name, itemDetails{nodeType, replaced, content})
+	}
+}
+
 // AddFile starts tracking a new file with the given size
 func (p *Progress) AddFile(size uint64) {
 	if p == nil {
@@ -108,6 +134,9 @@ func (p *Progress) AddProgress(name string, action ItemAction, bytesWrittenPorti
 		p.s.FilesFinished++
 
 		p.printer.CompleteItem(action, name, bytesTotal)
+		if p.manifest != nil {
+			p.manifest.completeItem(action, name, bytesTotal)
+		}
 	}
 }
 
@@ -123,6 +152,9 @@ func (p *Progress) AddSkippedFile(name string, size uint64) {
 	p.s.AllBytesSkipped += size
 
 	p.printer.CompleteItem(ActionFileUnchanged, name, size)
+	if p.manifest != nil {
+		p.manifest.completeItem(ActionFileUnchanged, name, size)
+	}
 }
 
 func (p *Progress) ReportDeletion(name string) {
@@ -136,6 +168,9 @@ func (p *Progress) ReportDeletion(name string) {
 	defer p.m.Unlock()
 
 	p.printer.CompleteItem(ActionDeleted, name, 0)
+	if p.manifest != nil {
+		p.manifest.completeItem(ActionDeleted, name, 0)
+	}
 }
 
 func (p *Progress) Error(item string, err error) error {
@@ -146,6 +181,9 @@ func (p *Progress) Error(item string, err error) error {
 	p.m.Lock()
 	defer p.m.Unlock()
 
+	if p.manifest != nil {
+		p.manifest.failedItem(item, err)
+	}
 	return p.printer.Error(item, err)
 }
 
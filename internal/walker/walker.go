@@ -29,6 +29,10 @@ type WalkVisitor struct {
 	ProcessNode WalkFunc
 	// Optional callback
 	LeaveDir func(path string) error
+	// Optional callback, called for a `dir` node before its subtree is loaded.
+	// If it returns true, the subtree is neither loaded nor walked and
+	// ProcessNode is not called for the node.
+	SkipDir func(path string, node *restic.Node) bool
 }
 
 // Walk calls walkFn recursively for each node in root. If walkFn returns an
@@ -85,6 +89,10 @@ func walk(ctx context.Context, repo restic.BlobLoader, prefix string, parentTree
 			return errors.Errorf("subtree for node %v in tree %v is nil", node.Name, p)
 		}
 
+		if visitor.SkipDir != nil && visitor.SkipDir(p, node) {
+			continue
+		}
+
 		subtree, err := restic.LoadTree(ctx, repo, *node.Subtree)
 		err = visitor.ProcessNode(parentTreeID, p, node, err)
 		if err != nil {
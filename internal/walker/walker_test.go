@@ -8,6 +8,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
 )
 
 // TestTree is used to construct a list of trees for testing the walker.
@@ -451,3 +452,33 @@ func TestWalker(t *testing.T) {
 		})
 	}
 }
+
+func TestWalkerSkipDir(t *testing.T) {
+	repo, root := BuildTreeMap(TestTree{
+		"foo": TestFile{},
+		"subdir1": TestTree{
+			"file": TestFile{},
+		},
+		"subdir2": TestTree{
+			"other": TestFile{},
+		},
+	})
+
+	var paths []string
+	err := Walk(context.TODO(), repo, root, WalkVisitor{
+		ProcessNode: func(_ restic.ID, path string, node *restic.Node, err error) error {
+			paths = append(paths, path)
+			return err
+		},
+		SkipDir: func(path string, node *restic.Node) bool {
+			// the subtree must not be loaded
+			if path == "/subdir1" {
+				delete(repo, *node.Subtree)
+				return true
+			}
+			return false
+		},
+	})
+	rtest.OK(t, err)
+	rtest.Equals(t, []string{"/", "/foo", "/subdir2", "/subdir2/other"}, paths)
+}